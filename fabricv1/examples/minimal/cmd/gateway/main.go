@@ -0,0 +1,76 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+// v0.1 KL -- REST gateway for the minimal contract, kept out of the chaincode so that the
+//            chaincode does not link the gateway's http server and test stub
+
+// Command gateway serves the minimal contract over REST against an in-memory world state,
+// for local use without a peer. The minimal contract is the platform's default routes,
+// which are registered here as the contract's main.go registers them. Run it from the
+// example's folder, go run ./cmd/gateway -addr :8080, so that it finds openapi.json.
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	iot "github.com/ibm-watson-iot/blockchain-samples/fabricv1/platform"
+	"github.com/ibm-watson-iot/blockchain-samples/fabricv1/platform/ctgateway"
+	"github.com/ibm-watson-iot/blockchain-samples/fabricv1/platform/cttest"
+)
+
+// CONTRACTVERSION matches the contract's
+const CONTRACTVERSION = "0.1"
+
+var addr = flag.String("addr", ":8080", "address to serve the contract on")
+var openapi = flag.String("openapi", "openapi.json", "OpenAPI document written by processOpenAPI.go")
+
+var log = shim.NewLogger("iotcontractgateway")
+
+// gatewayChaincode runs the contract on the MockStub
+type gatewayChaincode struct {
+}
+
+func (t *gatewayChaincode) Init(stub shim.ChaincodeStubInterface) pb.Response {
+	return iot.Init(stub, CONTRACTVERSION)
+}
+
+func (t *gatewayChaincode) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
+	return iot.Invoke(stub)
+}
+
+func main() {
+	flag.Parse()
+	iot.SetContractLogger(log)
+	iot.RegisterDefaultRoutes()
+
+	stub := cttest.NewMockStub("gateway", new(gatewayChaincode))
+	if resp := stub.MockInit("init", []string{`{"version": "` + CONTRACTVERSION + `"}`}); resp.Status != shim.OK {
+		log.Errorf("gateway could not initialize the contract: %s", resp.Message)
+		return
+	}
+	server := ctgateway.NewServer(ctgateway.NewMockClient(stub))
+	doc, err := ioutil.ReadFile(*openapi)
+	if err != nil {
+		log.Errorf("gateway could not read %s, GET /openapi.json will fail: %s", *openapi, err)
+	}
+	server.Document = doc
+	log.Infof("serving REST gateway on %s", *addr)
+	log.Errorf("REST gateway stopped: %s", http.ListenAndServe(*addr, server))
+}
@@ -26,6 +26,7 @@ import (
 
 // Update the path to match your configuration
 //go:generate go run /local-dev/src/github.com/ibm-watson-iot/blockchain-samples/fabricv1/platform/scripts/processSchema.go
+//go:generate go run /local-dev/src/github.com/ibm-watson-iot/blockchain-samples/fabricv1/platform/scripts/processOpenAPI.go
 
 // SimpleChaincode is the receiver for all shim API
 type SimpleChaincode struct {
@@ -64,6 +64,30 @@ var samples = `
             "assetID": "This asset's world state asset ID",
             "class": {},
             "compliant": true,
+            "deleted": true,
+            "diff": {
+                "added": [
+                    {
+                        "new": "NO TYPE PROPERTY",
+                        "old": "NO TYPE PROPERTY",
+                        "path": "property path relative to asset state"
+                    }
+                ],
+                "changed": [
+                    {
+                        "new": "NO TYPE PROPERTY",
+                        "old": "NO TYPE PROPERTY",
+                        "path": "property path relative to asset state"
+                    }
+                ],
+                "removed": [
+                    {
+                        "new": "NO TYPE PROPERTY",
+                        "old": "NO TYPE PROPERTY",
+                        "path": "property path relative to asset state"
+                    }
+                ]
+            },
             "eventin": {
                 "asset": {
                     "assetID": "An asset's unique ID, e.g. barcode, VIN, etc.",
@@ -93,6 +117,7 @@ var samples = `
                     }
                 }
             },
+            "rulesversion": 789,
             "state": {
                 "asset": {
                     "assetID": "An asset's unique ID, e.g. barcode, VIN, etc.",
@@ -114,8 +139,27 @@ var samples = `
                     "temperature": 123.456
                 }
             },
+            "transition": {
+                "from": "carpe noctem",
+                "to": "carpe noctem",
+                "trigger": "The function that made the transition"
+            },
             "txnid": "Transaction UUID matching the blockchain",
-            "txnts": "Transaction timestamp matching the blockchain"
+            "txnts": "Transaction timestamp matching the blockchain",
+            "zones": {
+                "alerts": [
+                    "carpe noctem"
+                ],
+                "entered": [
+                    "carpe noctem"
+                ],
+                "exited": [
+                    "carpe noctem"
+                ],
+                "inside": [
+                    "carpe noctem"
+                ]
+            }
         },
         "assetstatearray": [
             {
@@ -125,6 +169,30 @@ var samples = `
                 "assetID": "This asset's world state asset ID",
                 "class": {},
                 "compliant": true,
+                "deleted": true,
+                "diff": {
+                    "added": [
+                        {
+                            "new": "NO TYPE PROPERTY",
+                            "old": "NO TYPE PROPERTY",
+                            "path": "property path relative to asset state"
+                        }
+                    ],
+                    "changed": [
+                        {
+                            "new": "NO TYPE PROPERTY",
+                            "old": "NO TYPE PROPERTY",
+                            "path": "property path relative to asset state"
+                        }
+                    ],
+                    "removed": [
+                        {
+                            "new": "NO TYPE PROPERTY",
+                            "old": "NO TYPE PROPERTY",
+                            "path": "property path relative to asset state"
+                        }
+                    ]
+                },
                 "eventin": {
                     "asset": {
                         "assetID": "An asset's unique ID, e.g. barcode, VIN, etc.",
@@ -154,6 +222,7 @@ var samples = `
                         }
                     }
                 },
+                "rulesversion": 789,
                 "state": {
                     "asset": {
                         "assetID": "An asset's unique ID, e.g. barcode, VIN, etc.",
@@ -175,8 +244,27 @@ var samples = `
                         "temperature": 123.456
                     }
                 },
+                "transition": {
+                    "from": "carpe noctem",
+                    "to": "carpe noctem",
+                    "trigger": "The function that made the transition"
+                },
                 "txnid": "Transaction UUID matching the blockchain",
-                "txnts": "Transaction timestamp matching the blockchain"
+                "txnts": "Transaction timestamp matching the blockchain",
+                "zones": {
+                    "alerts": [
+                        "carpe noctem"
+                    ],
+                    "entered": [
+                        "carpe noctem"
+                    ],
+                    "exited": [
+                        "carpe noctem"
+                    ],
+                    "inside": [
+                        "carpe noctem"
+                    ]
+                }
             }
         ],
         "assetstateexternal": {
@@ -187,6 +275,30 @@ var samples = `
                 "assetID": "This asset's world state asset ID",
                 "class": {},
                 "compliant": true,
+                "deleted": true,
+                "diff": {
+                    "added": [
+                        {
+                            "new": "NO TYPE PROPERTY",
+                            "old": "NO TYPE PROPERTY",
+                            "path": "property path relative to asset state"
+                        }
+                    ],
+                    "changed": [
+                        {
+                            "new": "NO TYPE PROPERTY",
+                            "old": "NO TYPE PROPERTY",
+                            "path": "property path relative to asset state"
+                        }
+                    ],
+                    "removed": [
+                        {
+                            "new": "NO TYPE PROPERTY",
+                            "old": "NO TYPE PROPERTY",
+                            "path": "property path relative to asset state"
+                        }
+                    ]
+                },
                 "eventin": {
                     "asset": {
                         "assetID": "An asset's unique ID, e.g. barcode, VIN, etc.",
@@ -216,6 +328,7 @@ var samples = `
                         }
                     }
                 },
+                "rulesversion": 789,
                 "state": {
                     "asset": {
                         "assetID": "An asset's unique ID, e.g. barcode, VIN, etc.",
@@ -237,8 +350,27 @@ var samples = `
                         "temperature": 123.456
                     }
                 },
+                "transition": {
+                    "from": "carpe noctem",
+                    "to": "carpe noctem",
+                    "trigger": "The function that made the transition"
+                },
                 "txnid": "Transaction UUID matching the blockchain",
-                "txnts": "Transaction timestamp matching the blockchain"
+                "txnts": "Transaction timestamp matching the blockchain",
+                "zones": {
+                    "alerts": [
+                        "carpe noctem"
+                    ],
+                    "entered": [
+                        "carpe noctem"
+                    ],
+                    "exited": [
+                        "carpe noctem"
+                    ],
+                    "inside": [
+                        "carpe noctem"
+                    ]
+                }
             }
         },
         "eventIOTContractPlatformInvokeResult": {
@@ -257,10 +389,61 @@ var samples = `
             "alertsRaised": [
                 "An alert name"
             ],
+            "events": [
+                {
+                    "alertsCleared": [
+                        "An alert name"
+                    ],
+                    "alertsRaised": [
+                        "An alert name"
+                    ],
+                    "assetkey": "NO TYPE PROPERTY",
+                    "changed": [
+                        "carpe noctem"
+                    ],
+                    "class": "carpe noctem",
+                    "diff": {
+                        "added": [
+                            {
+                                "new": "NO TYPE PROPERTY",
+                                "old": "NO TYPE PROPERTY",
+                                "path": "property path relative to asset state"
+                            }
+                        ],
+                        "changed": [
+                            {
+                                "new": "NO TYPE PROPERTY",
+                                "old": "NO TYPE PROPERTY",
+                                "path": "property path relative to asset state"
+                            }
+                        ],
+                        "removed": [
+                            {
+                                "new": "NO TYPE PROPERTY",
+                                "old": "NO TYPE PROPERTY",
+                                "path": "property path relative to asset state"
+                            }
+                        ]
+                    },
+                    "name": "the event name declared by the contract for the class or alert, also appended to the chaincode event name",
+                    "transition": {
+                        "from": "carpe noctem",
+                        "to": "carpe noctem",
+                        "trigger": "The function that made the transition"
+                    },
+                    "txnid": "carpe noctem",
+                    "type": "asset.updated",
+                    "zone": "the zone of a zone event"
+                }
+            ],
             "invokeresult": {
                 "message": "carpe noctem",
                 "status": "ERROR"
-            }
+            },
+            "names": [
+                "carpe noctem"
+            ],
+            "txnid": "the transaction that emitted the event"
         },
         "ioteventcommon": {
             "appdata": [
@@ -277,11 +460,18 @@ var samples = `
             }
         },
         "stateFilter": {
+            "groups": [
+                "INVALID OBJECT - MISSING PROPERTIES"
+            ],
             "match": "all",
             "select": [
                 {
+                    "op": "ne",
                     "qprop": "Qualified property to compare, for example 'asset.assetID'",
-                    "value": "Value to be compared"
+                    "value": "Value to be compared",
+                    "values": [
+                        "carpe noctem"
+                    ]
                 }
             ]
         }
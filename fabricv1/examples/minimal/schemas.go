@@ -105,13 +105,23 @@ var schemas = `
                             "filter": {
                                 "description": "Filter asset states",
                                 "properties": {
+                                    "groups": {
+                                        "description": "Nested filters with the same shape as this filter, combined with the select terms according to match",
+                                        "items": {
+                                            "type": "object"
+                                        },
+                                        "type": "array"
+                                    },
                                     "match": {
-                                        "description": "Defines how to match properties, missing property always fails match",
+                                        "description": "Defines how to combine the select terms and groups, and, or and not are aliases for all, any and none; missing property always fails match",
                                         "enum": [
                                             "n/a",
                                             "all",
                                             "any",
-                                            "none"
+                                            "none",
+                                            "and",
+                                            "or",
+                                            "not"
                                         ],
                                         "type": "string"
                                     },
@@ -119,6 +129,22 @@ var schemas = `
                                         "description": "Qualified property names and values match",
                                         "items": {
                                             "properties": {
+                                                "op": {
+                                                    "description": "Comparison operator, defaults to equality; numbers compare numerically and strings lexically; exists with value 'false' matches a missing property",
+                                                    "enum": [
+                                                        "eq",
+                                                        "ne",
+                                                        "gt",
+                                                        "gte",
+                                                        "lt",
+                                                        "lte",
+                                                        "in",
+                                                        "exists",
+                                                        "prefix",
+                                                        "regex"
+                                                    ],
+                                                    "type": "string"
+                                                },
                                                 "qprop": {
                                                     "description": "Qualified property to compare, for example 'asset.assetID'",
                                                     "type": "string"
@@ -126,6 +152,13 @@ var schemas = `
                                                 "value": {
                                                     "description": "Value to be compared",
                                                     "type": "string"
+                                                },
+                                                "values": {
+                                                    "description": "Values to be compared by the in operator",
+                                                    "items": {
+                                                        "type": "string"
+                                                    },
+                                                    "type": "array"
                                                 }
                                             },
                                             "type": "object"
@@ -185,7 +218,7 @@ var schemas = `
             "type": "object"
         },
         "deleteAssetStateHistory": {
-            "description": "Delete an asset's history from world state, transactions remain on the blockchain",
+            "description": "Delete an asset's history from world state, transactions remain on the blockchain. Fails for classes with native history, which cannot be deleted",
             "properties": {
                 "args": {
                     "items": {
@@ -275,11 +308,47 @@ var schemas = `
             "type": "object"
         },
         "initContract": {
-            "description": "Sets contract version and nickname",
+            "description": "Sets contract version and nickname, and the admin access policy",
             "properties": {
                 "args": {
                     "items": {
                         "properties": {
+                            "admin": {
+                                "description": "What a caller needs in order to call a route; each list that is present must be satisfied by one entry, and every attribute must be present with the given value or with any value when the value is empty",
+                                "properties": {
+                                    "attributes": {
+                                        "description": "Certificate attributes and their required values",
+                                        "patternProperties": {
+                                            "^.*$": {
+                                                "type": "string"
+                                            }
+                                        },
+                                        "type": "object"
+                                    },
+                                    "mspids": {
+                                        "description": "MSP IDs allowed to call the route",
+                                        "items": {
+                                            "type": "string"
+                                        },
+                                        "type": "array"
+                                    },
+                                    "ous": {
+                                        "description": "Organizational units from the caller's certificate subject",
+                                        "items": {
+                                            "type": "string"
+                                        },
+                                        "type": "array"
+                                    },
+                                    "roles": {
+                                        "description": "Matched against the caller's role attribute or OUs",
+                                        "items": {
+                                            "type": "string"
+                                        },
+                                        "type": "array"
+                                    }
+                                },
+                                "type": "object"
+                            },
                             "nickname": {
                                 "default": "IOT Contract Platform",
                                 "description": "The nickname of the current contract instance",
@@ -312,16 +381,30 @@ var schemas = `
                 "args": {
                     "items": {
                         "properties": {
+                            "bookmark": {
+                                "description": "opaque bookmark returned with the previous page, pass it back unchanged to read the next page; an empty bookmark means there are no more results",
+                                "type": "string"
+                            },
                             "filter": {
                                 "description": "Filter asset states",
                                 "properties": {
+                                    "groups": {
+                                        "description": "Nested filters with the same shape as this filter, combined with the select terms according to match",
+                                        "items": {
+                                            "type": "object"
+                                        },
+                                        "type": "array"
+                                    },
                                     "match": {
-                                        "description": "Defines how to match properties, missing property always fails match",
+                                        "description": "Defines how to combine the select terms and groups, and, or and not are aliases for all, any and none; missing property always fails match",
                                         "enum": [
                                             "n/a",
                                             "all",
                                             "any",
-                                            "none"
+                                            "none",
+                                            "and",
+                                            "or",
+                                            "not"
                                         ],
                                         "type": "string"
                                     },
@@ -329,6 +412,22 @@ var schemas = `
                                         "description": "Qualified property names and values match",
                                         "items": {
                                             "properties": {
+                                                "op": {
+                                                    "description": "Comparison operator, defaults to equality; numbers compare numerically and strings lexically; exists with value 'false' matches a missing property",
+                                                    "enum": [
+                                                        "eq",
+                                                        "ne",
+                                                        "gt",
+                                                        "gte",
+                                                        "lt",
+                                                        "lte",
+                                                        "in",
+                                                        "exists",
+                                                        "prefix",
+                                                        "regex"
+                                                    ],
+                                                    "type": "string"
+                                                },
                                                 "qprop": {
                                                     "description": "Qualified property to compare, for example 'asset.assetID'",
                                                     "type": "string"
@@ -336,6 +435,13 @@ var schemas = `
                                                 "value": {
                                                     "description": "Value to be compared",
                                                     "type": "string"
+                                                },
+                                                "values": {
+                                                    "description": "Values to be compared by the in operator",
+                                                    "items": {
+                                                        "type": "string"
+                                                    },
+                                                    "type": "array"
                                                 }
                                             },
                                             "type": "object"
@@ -344,6 +450,11 @@ var schemas = `
                                     }
                                 },
                                 "type": "object"
+                            },
+                            "pagesize": {
+                                "description": "maximum number of results to return in one page, results are returned in a paged envelope when pagesize or bookmark is passed",
+                                "minimum": 0,
+                                "type": "integer"
                             }
                         },
                         "type": "object"
@@ -359,174 +470,937 @@ var schemas = `
                     "type": "string"
                 },
                 "method": "query",
-                "result": {
-                    "description": "Array of asset states, can mix asset classes",
-                    "items": {
-                        "description": "A asset's complete state",
-                        "properties": {
-                            "alerts": {
-                                "description": "An array of alert names",
-                                "items": {
-                                    "description": "An alert name",
-                                    "type": "string"
-                                },
-                                "type": "array"
-                            },
-                            "assetID": {
-                                "description": "This asset's world state asset ID",
-                                "type": "string"
-                            },
-                            "class": {
-                                "description": "An asset's classifier definition",
-                                "properties": {
-                                    "assetidpath": "An asset's primary key, expressed as a qualified property path (see example contracts)",
-                                    "name": "An asset's class name",
-                                    "prefix": "An asset's world state prefix, used to allow iteration over all assets of a class"
-                                },
-                                "type": "object"
-                            },
-                            "compliant": {
-                                "description": "This asset has no active alerts",
-                                "type": "boolean"
-                            },
-                            "eventin": {
-                                "description": "The contract event that created this state, for example updateAsset",
+                "pagedresult": {
+                    "description": "returned instead of result when pagesize or bookmark is passed",
+                    "properties": {
+                        "bookmark": {
+                            "description": "opaque bookmark returned with the previous page, pass it back unchanged to read the next page; an empty bookmark means there are no more results",
+                            "type": "string"
+                        },
+                        "count": {
+                            "description": "number of results in this page",
+                            "type": "integer"
+                        },
+                        "results": {
+                            "description": "Array of asset states, can mix asset classes",
+                            "items": {
+                                "description": "A asset's complete state",
                                 "properties": {
-                                    "asset": {
-                                        "description": "The changeable properties for an asset, also considered its 'event' as a partial state",
+                                    "alerts": {
+                                        "description": "An array of alert names",
+                                        "items": {
+                                            "description": "An alert name",
+                                            "type": "string"
+                                        },
+                                        "type": "array"
+                                    },
+                                    "assetID": {
+                                        "description": "This asset's world state asset ID",
+                                        "type": "string"
+                                    },
+                                    "class": {
+                                        "description": "An asset's classifier definition",
                                         "properties": {
-                                            "assetID": {
-                                                "description": "An asset's unique ID, e.g. barcode, VIN, etc.",
-                                                "type": "string"
+                                            "assetidpath": "An asset's primary key, expressed as a qualified property path (see example contracts)",
+                                            "name": "An asset's class name",
+                                            "prefix": "An asset's world state prefix, used to allow iteration over all assets of a class"
+                                        },
+                                        "type": "object"
+                                    },
+                                    "compliant": {
+                                        "description": "This asset has no active alerts",
+                                        "type": "boolean"
+                                    },
+                                    "deleted": {
+                                        "description": "Marks a history state that records the deletion of the asset, native history only",
+                                        "type": "boolean"
+                                    },
+                                    "diff": {
+                                        "description": "The properties that an asset state added, removed and changed, values of private properties are never recorded",
+                                        "properties": {
+                                            "added": {
+                                                "items": {
+                                                    "properties": {
+                                                        "new": {
+                                                            "description": "the new value, absent when removed or private"
+                                                        },
+                                                        "old": {
+                                                            "description": "the prior value, absent when added or private"
+                                                        },
+                                                        "path": {
+                                                            "description": "property path relative to asset state",
+                                                            "type": "string"
+                                                        }
+                                                    },
+                                                    "type": "object"
+                                                },
+                                                "type": "array"
                                             },
-                                            "carrier": {
-                                                "description": "The carrier in possession of this asset",
-                                                "type": "string"
+                                            "changed": {
+                                                "items": {
+                                                    "properties": {
+                                                        "new": {
+                                                            "description": "the new value, absent when removed or private"
+                                                        },
+                                                        "old": {
+                                                            "description": "the prior value, absent when added or private"
+                                                        },
+                                                        "path": {
+                                                            "description": "property path relative to asset state",
+                                                            "type": "string"
+                                                        }
+                                                    },
+                                                    "type": "object"
+                                                },
+                                                "type": "array"
                                             },
-                                            "common": {
-                                                "description": "Common properties for all assets",
-                                                "properties": {
-                                                    "appdata": {
-                                                        "description": "Application managed information as an array of key:value pairs",
-                                                        "items": {
-                                                            "properties": {
-                                                                "K": {
-                                                                    "type": "string"
-                                                                },
-                                                                "V": {
-                                                                    "type": "string"
-                                                                }
-                                                            },
-                                                            "type": "object"
+                                            "removed": {
+                                                "items": {
+                                                    "properties": {
+                                                        "new": {
+                                                            "description": "the new value, absent when removed or private"
                                                         },
-                                                        "minItems": 0,
-                                                        "type": "array"
+                                                        "old": {
+                                                            "description": "the prior value, absent when added or private"
+                                                        },
+                                                        "path": {
+                                                            "description": "property path relative to asset state",
+                                                            "type": "string"
+                                                        }
                                                     },
-                                                    "deviceID": {
-                                                        "description": "A unique identifier for the device that sent the current event",
+                                                    "type": "object"
+                                                },
+                                                "type": "array"
+                                            }
+                                        },
+                                        "type": "object"
+                                    },
+                                    "eventin": {
+                                        "description": "The contract event that created this state, for example updateAsset",
+                                        "properties": {
+                                            "asset": {
+                                                "description": "The changeable properties for an asset, also considered its 'event' as a partial state",
+                                                "properties": {
+                                                    "assetID": {
+                                                        "description": "An asset's unique ID, e.g. barcode, VIN, etc.",
                                                         "type": "string"
                                                     },
-                                                    "devicetimestamp": {
-                                                        "description": "A timestamp recoded by the device that sent the current event",
+                                                    "carrier": {
+                                                        "description": "The carrier in possession of this asset",
                                                         "type": "string"
                                                     },
-                                                    "location": {
-                                                        "description": "A geographical coordinate",
+                                                    "common": {
+                                                        "description": "Common properties for all assets",
                                                         "properties": {
-                                                            "latitude": {
-                                                                "type": "number"
+                                                            "appdata": {
+                                                                "description": "Application managed information as an array of key:value pairs",
+                                                                "items": {
+                                                                    "properties": {
+                                                                        "K": {
+                                                                            "type": "string"
+                                                                        },
+                                                                        "V": {
+                                                                            "type": "string"
+                                                                        }
+                                                                    },
+                                                                    "type": "object"
+                                                                },
+                                                                "minItems": 0,
+                                                                "type": "array"
                                                             },
-                                                            "longitude": {
-                                                                "type": "number"
+                                                            "deviceID": {
+                                                                "description": "A unique identifier for the device that sent the current event",
+                                                                "type": "string"
+                                                            },
+                                                            "devicetimestamp": {
+                                                                "description": "A timestamp recoded by the device that sent the current event",
+                                                                "type": "string"
+                                                            },
+                                                            "location": {
+                                                                "description": "A geographical coordinate",
+                                                                "properties": {
+                                                                    "latitude": {
+                                                                        "type": "number"
+                                                                    },
+                                                                    "longitude": {
+                                                                        "type": "number"
+                                                                    }
+                                                                },
+                                                                "type": "object"
                                                             }
                                                         },
                                                         "type": "object"
+                                                    },
+                                                    "temperature": {
+                                                        "description": "Temperature of an asset's contents in degrees Celsuis",
+                                                        "type": "number"
                                                     }
                                                 },
+                                                "required": [
+                                                    "assetID"
+                                                ],
                                                 "type": "object"
-                                            },
-                                            "temperature": {
-                                                "description": "Temperature of an asset's contents in degrees Celsuis",
-                                                "type": "number"
                                             }
                                         },
-                                        "required": [
-                                            "assetID"
-                                        ],
                                         "type": "object"
-                                    }
-                                },
-                                "type": "object"
-                            },
-                            "eventout": {
-                                "description": "The chaincode event emitted on invoke exit, if any",
-                                "properties": {
-                                    "asset": {
-                                        "description": "A chaincode event defining the standard platform-generated result event for a contract invoke, contains an array of contributed results",
+                                    },
+                                    "eventout": {
+                                        "description": "The chaincode event emitted on invoke exit, if any",
                                         "properties": {
-                                            "name": {
-                                                "default": "EVT.IOTCP.INVOKE.RESULT",
-                                                "enum": [
-                                                    "EVT.IOTCP.INVOKE.RESULT"
-                                                ],
-                                                "type": "string"
-                                            },
-                                            "payload": {
-                                                "description": "A map of contributed results",
+                                            "asset": {
+                                                "description": "A chaincode event defining the standard platform-generated result event for a contract invoke, contains an array of contributed results",
                                                 "properties": {
-                                                    "description": "the overall status of the invoke result, defined by err",
-                                                    "properties": {
-                                                        "activeAlerts": {
-                                                            "description": "An array of alert names",
-                                                            "items": {
-                                                                "description": "An alert name",
-                                                                "type": "string"
-                                                            },
-                                                            "type": "array"
-                                                        },
-                                                        "alertsCleared": {
-                                                            "description": "An array of alert names",
-                                                            "items": {
-                                                                "description": "An alert name",
-                                                                "type": "string"
-                                                            },
-                                                            "type": "array"
-                                                        },
-                                                        "alertsRaised": {
-                                                            "description": "An array of alert names",
-                                                            "items": {
-                                                                "description": "An alert name",
-                                                                "type": "string"
-                                                            },
-                                                            "type": "array"
-                                                        },
-                                                        "invokeresult": {
-                                                            "description": "status: OK==txn succeeded, ERROR==txn failed",
+                                                    "name": {
+                                                        "default": "EVT.IOTCP.INVOKE.RESULT",
+                                                        "description": "EVT.IOTCP.INVOKE.RESULT, followed by the declared names of the invoke's events separated by colons when the contract turns the event name suffix on, e.g. EVT.IOTCP.INVOKE.RESULT:KIT.OUTOFAREA",
+                                                        "pattern": "^EVT\\.IOTCP\\.INVOKE\\.RESULT(:.+)?$",
+                                                        "type": "string"
+                                                    },
+                                                    "payload": {
+                                                        "description": "A map of contributed results",
+                                                        "properties": {
+                                                            "description": "the overall status of the invoke result, defined by err",
                                                             "properties": {
-                                                                "message": {
-                                                                    "type": "string"
+                                                                "activeAlerts": {
+                                                                    "description": "An array of alert names",
+                                                                    "items": {
+                                                                        "description": "An alert name",
+                                                                        "type": "string"
+                                                                    },
+                                                                    "type": "array"
                                                                 },
-                                                                "status": {
-                                                                    "enum": [
-                                                                        "OK",
-                                                                        "ERROR"
-                                                                    ],
+                                                                "alertsCleared": {
+                                                                    "description": "An array of alert names",
+                                                                    "items": {
+                                                                        "description": "An alert name",
+                                                                        "type": "string"
+                                                                    },
+                                                                    "type": "array"
+                                                                },
+                                                                "alertsRaised": {
+                                                                    "description": "An array of alert names",
+                                                                    "items": {
+                                                                        "description": "An alert name",
+                                                                        "type": "string"
+                                                                    },
+                                                                    "type": "array"
+                                                                },
+                                                                "events": {
+                                                                    "items": {
+                                                                        "description": "A typed event raised by an invoke, one per asset written or deleted and one per alert raised or cleared",
+                                                                        "properties": {
+                                                                            "alertsCleared": {
+                                                                                "description": "An array of alert names",
+                                                                                "items": {
+                                                                                    "description": "An alert name",
+                                                                                    "type": "string"
+                                                                                },
+                                                                                "type": "array"
+                                                                            },
+                                                                            "alertsRaised": {
+                                                                                "description": "An array of alert names",
+                                                                                "items": {
+                                                                                    "description": "An alert name",
+                                                                                    "type": "string"
+                                                                                },
+                                                                                "type": "array"
+                                                                            },
+                                                                            "assetkey": {
+                                                                                "asset": {
+                                                                                    "properties": {
+                                                                                        "assetID": {
+                                                                                            "description": "An asset's unique ID, e.g. barcode, VIN, etc.",
+                                                                                            "type": "string"
+                                                                                        }
+                                                                                    },
+                                                                                    "type": "object"
+                                                                                }
+                                                                            },
+                                                                            "changed": {
+                                                                                "description": "property paths of the asset state that the write changed",
+                                                                                "items": {
+                                                                                    "type": "string"
+                                                                                },
+                                                                                "type": "array"
+                                                                            },
+                                                                            "class": {
+                                                                                "type": "string"
+                                                                            },
+                                                                            "diff": {
+                                                                                "description": "The properties that an asset state added, removed and changed, values of private properties are never recorded",
+                                                                                "properties": {
+                                                                                    "added": {
+                                                                                        "items": {
+                                                                                            "properties": {
+                                                                                                "new": {
+                                                                                                    "description": "the new value, absent when removed or private"
+                                                                                                },
+                                                                                                "old": {
+                                                                                                    "description": "the prior value, absent when added or private"
+                                                                                                },
+                                                                                                "path": {
+                                                                                                    "description": "property path relative to asset state",
+                                                                                                    "type": "string"
+                                                                                                }
+                                                                                            },
+                                                                                            "type": "object"
+                                                                                        },
+                                                                                        "type": "array"
+                                                                                    },
+                                                                                    "changed": {
+                                                                                        "items": {
+                                                                                            "properties": {
+                                                                                                "new": {
+                                                                                                    "description": "the new value, absent when removed or private"
+                                                                                                },
+                                                                                                "old": {
+                                                                                                    "description": "the prior value, absent when added or private"
+                                                                                                },
+                                                                                                "path": {
+                                                                                                    "description": "property path relative to asset state",
+                                                                                                    "type": "string"
+                                                                                                }
+                                                                                            },
+                                                                                            "type": "object"
+                                                                                        },
+                                                                                        "type": "array"
+                                                                                    },
+                                                                                    "removed": {
+                                                                                        "items": {
+                                                                                            "properties": {
+                                                                                                "new": {
+                                                                                                    "description": "the new value, absent when removed or private"
+                                                                                                },
+                                                                                                "old": {
+                                                                                                    "description": "the prior value, absent when added or private"
+                                                                                                },
+                                                                                                "path": {
+                                                                                                    "description": "property path relative to asset state",
+                                                                                                    "type": "string"
+                                                                                                }
+                                                                                            },
+                                                                                            "type": "object"
+                                                                                        },
+                                                                                        "type": "array"
+                                                                                    }
+                                                                                },
+                                                                                "type": "object"
+                                                                            },
+                                                                            "name": {
+                                                                                "description": "the event name declared by the contract for the class or alert, also appended to the chaincode event name",
+                                                                                "type": "string"
+                                                                            },
+                                                                            "transition": {
+                                                                                "description": "The lifecycle transition made by an asset state",
+                                                                                "properties": {
+                                                                                    "from": {
+                                                                                        "type": "string"
+                                                                                    },
+                                                                                    "to": {
+                                                                                        "type": "string"
+                                                                                    },
+                                                                                    "trigger": {
+                                                                                        "description": "The function that made the transition",
+                                                                                        "type": "string"
+                                                                                    }
+                                                                                },
+                                                                                "type": "object"
+                                                                            },
+                                                                            "txnid": {
+                                                                                "type": "string"
+                                                                            },
+                                                                            "type": {
+                                                                                "enum": [
+                                                                                    "asset.created",
+                                                                                    "asset.updated",
+                                                                                    "asset.deleted",
+                                                                                    "alert.raised",
+                                                                                    "alert.cleared",
+                                                                                    "zone.entered",
+                                                                                    "zone.exited",
+                                                                                    "history.archived"
+                                                                                ],
+                                                                                "type": "string"
+                                                                            },
+                                                                            "zone": {
+                                                                                "description": "the zone of a zone event",
+                                                                                "type": "string"
+                                                                            }
+                                                                        },
+                                                                        "type": "object"
+                                                                    },
+                                                                    "type": "array"
+                                                                },
+                                                                "invokeresult": {
+                                                                    "description": "status: OK==txn succeeded, ERROR==txn failed",
+                                                                    "properties": {
+                                                                        "message": {
+                                                                            "type": "string"
+                                                                        },
+                                                                        "status": {
+                                                                            "enum": [
+                                                                                "OK",
+                                                                                "ERROR"
+                                                                            ],
+                                                                            "type": "string"
+                                                                        }
+                                                                    },
+                                                                    "type": "object"
+                                                                },
+                                                                "names": {
+                                                                    "description": "the distinct declared names of the events, sorted",
+                                                                    "items": {
+                                                                        "type": "string"
+                                                                    },
+                                                                    "type": "array"
+                                                                },
+                                                                "txnid": {
+                                                                    "description": "the transaction that emitted the event",
                                                                     "type": "string"
                                                                 }
                                                             },
                                                             "type": "object"
-                                                        }
-                                                    },
-                                                    "type": "object"
+                                                        },
+                                                        "type": "object"
+                                                    }
                                                 },
                                                 "type": "object"
                                             }
                                         },
                                         "type": "object"
-                                    }
-                                },
+                                    },
+                                    "rulesversion": {
+                                        "description": "Version of the declarative rule set that judged this state",
+                                        "type": "integer"
+                                    },
+                                    "state": {
+                                        "description": "Properties that have been received or calculated for this asset",
+                                        "properties": {
+                                            "asset": {
+                                                "description": "The changeable properties for an asset, also considered its 'event' as a partial state",
+                                                "properties": {
+                                                    "assetID": {
+                                                        "description": "An asset's unique ID, e.g. barcode, VIN, etc.",
+                                                        "type": "string"
+                                                    },
+                                                    "carrier": {
+                                                        "description": "The carrier in possession of this asset",
+                                                        "type": "string"
+                                                    },
+                                                    "common": {
+                                                        "description": "Common properties for all assets",
+                                                        "properties": {
+                                                            "appdata": {
+                                                                "description": "Application managed information as an array of key:value pairs",
+                                                                "items": {
+                                                                    "properties": {
+                                                                        "K": {
+                                                                            "type": "string"
+                                                                        },
+                                                                        "V": {
+                                                                            "type": "string"
+                                                                        }
+                                                                    },
+                                                                    "type": "object"
+                                                                },
+                                                                "minItems": 0,
+                                                                "type": "array"
+                                                            },
+                                                            "deviceID": {
+                                                                "description": "A unique identifier for the device that sent the current event",
+                                                                "type": "string"
+                                                            },
+                                                            "devicetimestamp": {
+                                                                "description": "A timestamp recoded by the device that sent the current event",
+                                                                "type": "string"
+                                                            },
+                                                            "location": {
+                                                                "description": "A geographical coordinate",
+                                                                "properties": {
+                                                                    "latitude": {
+                                                                        "type": "number"
+                                                                    },
+                                                                    "longitude": {
+                                                                        "type": "number"
+                                                                    }
+                                                                },
+                                                                "type": "object"
+                                                            }
+                                                        },
+                                                        "type": "object"
+                                                    },
+                                                    "temperature": {
+                                                        "description": "Temperature of an asset's contents in degrees Celsuis",
+                                                        "type": "number"
+                                                    }
+                                                },
+                                                "required": [
+                                                    "assetID"
+                                                ],
+                                                "type": "object"
+                                            }
+                                        },
+                                        "type": "object"
+                                    },
+                                    "transition": {
+                                        "description": "The lifecycle transition made by an asset state",
+                                        "properties": {
+                                            "from": {
+                                                "type": "string"
+                                            },
+                                            "to": {
+                                                "type": "string"
+                                            },
+                                            "trigger": {
+                                                "description": "The function that made the transition",
+                                                "type": "string"
+                                            }
+                                        },
+                                        "type": "object"
+                                    },
+                                    "txnid": {
+                                        "description": "Transaction UUID matching the blockchain",
+                                        "type": "string"
+                                    },
+                                    "txnts": {
+                                        "description": "Transaction timestamp matching the blockchain",
+                                        "type": "string"
+                                    },
+                                    "zones": {
+                                        "description": "The attached zones that hold an asset's location, and those that its move from the prior location entered and exited",
+                                        "properties": {
+                                            "alerts": {
+                                                "description": "zone alerts that this state holds raised, cleared on the next write once their zones are detached or deleted",
+                                                "items": {
+                                                    "type": "string"
+                                                },
+                                                "type": "array"
+                                            },
+                                            "entered": {
+                                                "description": "zones entered by this write",
+                                                "items": {
+                                                    "type": "string"
+                                                },
+                                                "type": "array"
+                                            },
+                                            "exited": {
+                                                "description": "zones exited by this write",
+                                                "items": {
+                                                    "type": "string"
+                                                },
+                                                "type": "array"
+                                            },
+                                            "inside": {
+                                                "description": "zones holding the location",
+                                                "items": {
+                                                    "type": "string"
+                                                },
+                                                "type": "array"
+                                            }
+                                        },
+                                        "type": "object"
+                                    }
+                                },
+                                "type": "object"
+                            },
+                            "minItems": 0,
+                            "type": "array"
+                        }
+                    },
+                    "type": "object"
+                },
+                "result": {
+                    "description": "Array of asset states, can mix asset classes",
+                    "items": {
+                        "description": "A asset's complete state",
+                        "properties": {
+                            "alerts": {
+                                "description": "An array of alert names",
+                                "items": {
+                                    "description": "An alert name",
+                                    "type": "string"
+                                },
+                                "type": "array"
+                            },
+                            "assetID": {
+                                "description": "This asset's world state asset ID",
+                                "type": "string"
+                            },
+                            "class": {
+                                "description": "An asset's classifier definition",
+                                "properties": {
+                                    "assetidpath": "An asset's primary key, expressed as a qualified property path (see example contracts)",
+                                    "name": "An asset's class name",
+                                    "prefix": "An asset's world state prefix, used to allow iteration over all assets of a class"
+                                },
+                                "type": "object"
+                            },
+                            "compliant": {
+                                "description": "This asset has no active alerts",
+                                "type": "boolean"
+                            },
+                            "deleted": {
+                                "description": "Marks a history state that records the deletion of the asset, native history only",
+                                "type": "boolean"
+                            },
+                            "diff": {
+                                "description": "The properties that an asset state added, removed and changed, values of private properties are never recorded",
+                                "properties": {
+                                    "added": {
+                                        "items": {
+                                            "properties": {
+                                                "new": {
+                                                    "description": "the new value, absent when removed or private"
+                                                },
+                                                "old": {
+                                                    "description": "the prior value, absent when added or private"
+                                                },
+                                                "path": {
+                                                    "description": "property path relative to asset state",
+                                                    "type": "string"
+                                                }
+                                            },
+                                            "type": "object"
+                                        },
+                                        "type": "array"
+                                    },
+                                    "changed": {
+                                        "items": {
+                                            "properties": {
+                                                "new": {
+                                                    "description": "the new value, absent when removed or private"
+                                                },
+                                                "old": {
+                                                    "description": "the prior value, absent when added or private"
+                                                },
+                                                "path": {
+                                                    "description": "property path relative to asset state",
+                                                    "type": "string"
+                                                }
+                                            },
+                                            "type": "object"
+                                        },
+                                        "type": "array"
+                                    },
+                                    "removed": {
+                                        "items": {
+                                            "properties": {
+                                                "new": {
+                                                    "description": "the new value, absent when removed or private"
+                                                },
+                                                "old": {
+                                                    "description": "the prior value, absent when added or private"
+                                                },
+                                                "path": {
+                                                    "description": "property path relative to asset state",
+                                                    "type": "string"
+                                                }
+                                            },
+                                            "type": "object"
+                                        },
+                                        "type": "array"
+                                    }
+                                },
+                                "type": "object"
+                            },
+                            "eventin": {
+                                "description": "The contract event that created this state, for example updateAsset",
+                                "properties": {
+                                    "asset": {
+                                        "description": "The changeable properties for an asset, also considered its 'event' as a partial state",
+                                        "properties": {
+                                            "assetID": {
+                                                "description": "An asset's unique ID, e.g. barcode, VIN, etc.",
+                                                "type": "string"
+                                            },
+                                            "carrier": {
+                                                "description": "The carrier in possession of this asset",
+                                                "type": "string"
+                                            },
+                                            "common": {
+                                                "description": "Common properties for all assets",
+                                                "properties": {
+                                                    "appdata": {
+                                                        "description": "Application managed information as an array of key:value pairs",
+                                                        "items": {
+                                                            "properties": {
+                                                                "K": {
+                                                                    "type": "string"
+                                                                },
+                                                                "V": {
+                                                                    "type": "string"
+                                                                }
+                                                            },
+                                                            "type": "object"
+                                                        },
+                                                        "minItems": 0,
+                                                        "type": "array"
+                                                    },
+                                                    "deviceID": {
+                                                        "description": "A unique identifier for the device that sent the current event",
+                                                        "type": "string"
+                                                    },
+                                                    "devicetimestamp": {
+                                                        "description": "A timestamp recoded by the device that sent the current event",
+                                                        "type": "string"
+                                                    },
+                                                    "location": {
+                                                        "description": "A geographical coordinate",
+                                                        "properties": {
+                                                            "latitude": {
+                                                                "type": "number"
+                                                            },
+                                                            "longitude": {
+                                                                "type": "number"
+                                                            }
+                                                        },
+                                                        "type": "object"
+                                                    }
+                                                },
+                                                "type": "object"
+                                            },
+                                            "temperature": {
+                                                "description": "Temperature of an asset's contents in degrees Celsuis",
+                                                "type": "number"
+                                            }
+                                        },
+                                        "required": [
+                                            "assetID"
+                                        ],
+                                        "type": "object"
+                                    }
+                                },
                                 "type": "object"
                             },
+                            "eventout": {
+                                "description": "The chaincode event emitted on invoke exit, if any",
+                                "properties": {
+                                    "asset": {
+                                        "description": "A chaincode event defining the standard platform-generated result event for a contract invoke, contains an array of contributed results",
+                                        "properties": {
+                                            "name": {
+                                                "default": "EVT.IOTCP.INVOKE.RESULT",
+                                                "description": "EVT.IOTCP.INVOKE.RESULT, followed by the declared names of the invoke's events separated by colons when the contract turns the event name suffix on, e.g. EVT.IOTCP.INVOKE.RESULT:KIT.OUTOFAREA",
+                                                "pattern": "^EVT\\.IOTCP\\.INVOKE\\.RESULT(:.+)?$",
+                                                "type": "string"
+                                            },
+                                            "payload": {
+                                                "description": "A map of contributed results",
+                                                "properties": {
+                                                    "description": "the overall status of the invoke result, defined by err",
+                                                    "properties": {
+                                                        "activeAlerts": {
+                                                            "description": "An array of alert names",
+                                                            "items": {
+                                                                "description": "An alert name",
+                                                                "type": "string"
+                                                            },
+                                                            "type": "array"
+                                                        },
+                                                        "alertsCleared": {
+                                                            "description": "An array of alert names",
+                                                            "items": {
+                                                                "description": "An alert name",
+                                                                "type": "string"
+                                                            },
+                                                            "type": "array"
+                                                        },
+                                                        "alertsRaised": {
+                                                            "description": "An array of alert names",
+                                                            "items": {
+                                                                "description": "An alert name",
+                                                                "type": "string"
+                                                            },
+                                                            "type": "array"
+                                                        },
+                                                        "events": {
+                                                            "items": {
+                                                                "description": "A typed event raised by an invoke, one per asset written or deleted and one per alert raised or cleared",
+                                                                "properties": {
+                                                                    "alertsCleared": {
+                                                                        "description": "An array of alert names",
+                                                                        "items": {
+                                                                            "description": "An alert name",
+                                                                            "type": "string"
+                                                                        },
+                                                                        "type": "array"
+                                                                    },
+                                                                    "alertsRaised": {
+                                                                        "description": "An array of alert names",
+                                                                        "items": {
+                                                                            "description": "An alert name",
+                                                                            "type": "string"
+                                                                        },
+                                                                        "type": "array"
+                                                                    },
+                                                                    "assetkey": {
+                                                                        "asset": {
+                                                                            "properties": {
+                                                                                "assetID": {
+                                                                                    "description": "An asset's unique ID, e.g. barcode, VIN, etc.",
+                                                                                    "type": "string"
+                                                                                }
+                                                                            },
+                                                                            "type": "object"
+                                                                        }
+                                                                    },
+                                                                    "changed": {
+                                                                        "description": "property paths of the asset state that the write changed",
+                                                                        "items": {
+                                                                            "type": "string"
+                                                                        },
+                                                                        "type": "array"
+                                                                    },
+                                                                    "class": {
+                                                                        "type": "string"
+                                                                    },
+                                                                    "diff": {
+                                                                        "description": "The properties that an asset state added, removed and changed, values of private properties are never recorded",
+                                                                        "properties": {
+                                                                            "added": {
+                                                                                "items": {
+                                                                                    "properties": {
+                                                                                        "new": {
+                                                                                            "description": "the new value, absent when removed or private"
+                                                                                        },
+                                                                                        "old": {
+                                                                                            "description": "the prior value, absent when added or private"
+                                                                                        },
+                                                                                        "path": {
+                                                                                            "description": "property path relative to asset state",
+                                                                                            "type": "string"
+                                                                                        }
+                                                                                    },
+                                                                                    "type": "object"
+                                                                                },
+                                                                                "type": "array"
+                                                                            },
+                                                                            "changed": {
+                                                                                "items": {
+                                                                                    "properties": {
+                                                                                        "new": {
+                                                                                            "description": "the new value, absent when removed or private"
+                                                                                        },
+                                                                                        "old": {
+                                                                                            "description": "the prior value, absent when added or private"
+                                                                                        },
+                                                                                        "path": {
+                                                                                            "description": "property path relative to asset state",
+                                                                                            "type": "string"
+                                                                                        }
+                                                                                    },
+                                                                                    "type": "object"
+                                                                                },
+                                                                                "type": "array"
+                                                                            },
+                                                                            "removed": {
+                                                                                "items": {
+                                                                                    "properties": {
+                                                                                        "new": {
+                                                                                            "description": "the new value, absent when removed or private"
+                                                                                        },
+                                                                                        "old": {
+                                                                                            "description": "the prior value, absent when added or private"
+                                                                                        },
+                                                                                        "path": {
+                                                                                            "description": "property path relative to asset state",
+                                                                                            "type": "string"
+                                                                                        }
+                                                                                    },
+                                                                                    "type": "object"
+                                                                                },
+                                                                                "type": "array"
+                                                                            }
+                                                                        },
+                                                                        "type": "object"
+                                                                    },
+                                                                    "name": {
+                                                                        "description": "the event name declared by the contract for the class or alert, also appended to the chaincode event name",
+                                                                        "type": "string"
+                                                                    },
+                                                                    "transition": {
+                                                                        "description": "The lifecycle transition made by an asset state",
+                                                                        "properties": {
+                                                                            "from": {
+                                                                                "type": "string"
+                                                                            },
+                                                                            "to": {
+                                                                                "type": "string"
+                                                                            },
+                                                                            "trigger": {
+                                                                                "description": "The function that made the transition",
+                                                                                "type": "string"
+                                                                            }
+                                                                        },
+                                                                        "type": "object"
+                                                                    },
+                                                                    "txnid": {
+                                                                        "type": "string"
+                                                                    },
+                                                                    "type": {
+                                                                        "enum": [
+                                                                            "asset.created",
+                                                                            "asset.updated",
+                                                                            "asset.deleted",
+                                                                            "alert.raised",
+                                                                            "alert.cleared",
+                                                                            "zone.entered",
+                                                                            "zone.exited",
+                                                                            "history.archived"
+                                                                        ],
+                                                                        "type": "string"
+                                                                    },
+                                                                    "zone": {
+                                                                        "description": "the zone of a zone event",
+                                                                        "type": "string"
+                                                                    }
+                                                                },
+                                                                "type": "object"
+                                                            },
+                                                            "type": "array"
+                                                        },
+                                                        "invokeresult": {
+                                                            "description": "status: OK==txn succeeded, ERROR==txn failed",
+                                                            "properties": {
+                                                                "message": {
+                                                                    "type": "string"
+                                                                },
+                                                                "status": {
+                                                                    "enum": [
+                                                                        "OK",
+                                                                        "ERROR"
+                                                                    ],
+                                                                    "type": "string"
+                                                                }
+                                                            },
+                                                            "type": "object"
+                                                        },
+                                                        "names": {
+                                                            "description": "the distinct declared names of the events, sorted",
+                                                            "items": {
+                                                                "type": "string"
+                                                            },
+                                                            "type": "array"
+                                                        },
+                                                        "txnid": {
+                                                            "description": "the transaction that emitted the event",
+                                                            "type": "string"
+                                                        }
+                                                    },
+                                                    "type": "object"
+                                                },
+                                                "type": "object"
+                                            }
+                                        },
+                                        "type": "object"
+                                    }
+                                },
+                                "type": "object"
+                            },
+                            "rulesversion": {
+                                "description": "Version of the declarative rule set that judged this state",
+                                "type": "integer"
+                            },
                             "state": {
                                 "description": "Properties that have been received or calculated for this asset",
                                 "properties": {
@@ -596,6 +1470,22 @@ var schemas = `
                                 },
                                 "type": "object"
                             },
+                            "transition": {
+                                "description": "The lifecycle transition made by an asset state",
+                                "properties": {
+                                    "from": {
+                                        "type": "string"
+                                    },
+                                    "to": {
+                                        "type": "string"
+                                    },
+                                    "trigger": {
+                                        "description": "The function that made the transition",
+                                        "type": "string"
+                                    }
+                                },
+                                "type": "object"
+                            },
                             "txnid": {
                                 "description": "Transaction UUID matching the blockchain",
                                 "type": "string"
@@ -603,6 +1493,40 @@ var schemas = `
                             "txnts": {
                                 "description": "Transaction timestamp matching the blockchain",
                                 "type": "string"
+                            },
+                            "zones": {
+                                "description": "The attached zones that hold an asset's location, and those that its move from the prior location entered and exited",
+                                "properties": {
+                                    "alerts": {
+                                        "description": "zone alerts that this state holds raised, cleared on the next write once their zones are detached or deleted",
+                                        "items": {
+                                            "type": "string"
+                                        },
+                                        "type": "array"
+                                    },
+                                    "entered": {
+                                        "description": "zones entered by this write",
+                                        "items": {
+                                            "type": "string"
+                                        },
+                                        "type": "array"
+                                    },
+                                    "exited": {
+                                        "description": "zones exited by this write",
+                                        "items": {
+                                            "type": "string"
+                                        },
+                                        "type": "array"
+                                    },
+                                    "inside": {
+                                        "description": "zones holding the location",
+                                        "items": {
+                                            "type": "string"
+                                        },
+                                        "type": "array"
+                                    }
+                                },
+                                "type": "object"
                             }
                         },
                         "type": "object"
@@ -659,11 +1583,19 @@ var schemas = `
             "type": "object"
         },
         "readAllRules": {
-            "description": "Returns an array of registered rules by class (debugging)",
+            "description": "Returns an array of registered rules by class followed by the declarative rules from the current or requested rule set version",
             "properties": {
                 "args": {
-                    "items": {},
-                    "maxItems": 0,
+                    "items": {
+                        "properties": {
+                            "version": {
+                                "description": "Rule set version to read, the current rule set when omitted",
+                                "type": "integer"
+                            }
+                        },
+                        "type": "object"
+                    },
+                    "maxItems": 1,
                     "minItems": 0,
                     "type": "array"
                 },
@@ -679,6 +1611,10 @@ var schemas = `
                     "items": {
                         "description": "A rule defines a behavior that is applied to every new asset state just before writing to world state, often raises or clears alerts",
                         "properties": {
+                            "action": {
+                                "description": "Declarative rules only, see jsonRule",
+                                "type": "string"
+                            },
                             "alerts": {
                                 "description": "An array of alert names",
                                 "items": {
@@ -696,8 +1632,78 @@ var schemas = `
                                 },
                                 "type": "object"
                             },
+                            "condition": {
+                                "description": "Filter asset states",
+                                "properties": {
+                                    "groups": {
+                                        "description": "Nested filters with the same shape as this filter, combined with the select terms according to match",
+                                        "items": {
+                                            "type": "object"
+                                        },
+                                        "type": "array"
+                                    },
+                                    "match": {
+                                        "description": "Defines how to combine the select terms and groups, and, or and not are aliases for all, any and none; missing property always fails match",
+                                        "enum": [
+                                            "n/a",
+                                            "all",
+                                            "any",
+                                            "none",
+                                            "and",
+                                            "or",
+                                            "not"
+                                        ],
+                                        "type": "string"
+                                    },
+                                    "select": {
+                                        "description": "Qualified property names and values match",
+                                        "items": {
+                                            "properties": {
+                                                "op": {
+                                                    "description": "Comparison operator, defaults to equality; numbers compare numerically and strings lexically; exists with value 'false' matches a missing property",
+                                                    "enum": [
+                                                        "eq",
+                                                        "ne",
+                                                        "gt",
+                                                        "gte",
+                                                        "lt",
+                                                        "lte",
+                                                        "in",
+                                                        "exists",
+                                                        "prefix",
+                                                        "regex"
+                                                    ],
+                                                    "type": "string"
+                                                },
+                                                "qprop": {
+                                                    "description": "Qualified property to compare, for example 'asset.assetID'",
+                                                    "type": "string"
+                                                },
+                                                "value": {
+                                                    "description": "Value to be compared",
+                                                    "type": "string"
+                                                },
+                                                "values": {
+                                                    "description": "Values to be compared by the in operator",
+                                                    "items": {
+                                                        "type": "string"
+                                                    },
+                                                    "type": "array"
+                                                }
+                                            },
+                                            "type": "object"
+                                        },
+                                        "type": "array"
+                                    }
+                                },
+                                "type": "object"
+                            },
                             "rulename": {
                                 "type": "string"
+                            },
+                            "version": {
+                                "description": "Rule set version, declarative rules only",
+                                "type": "integer"
                             }
                         },
                         "type": "object"
@@ -765,6 +1771,70 @@ var schemas = `
                             "description": "This asset has no active alerts",
                             "type": "boolean"
                         },
+                        "deleted": {
+                            "description": "Marks a history state that records the deletion of the asset, native history only",
+                            "type": "boolean"
+                        },
+                        "diff": {
+                            "description": "The properties that an asset state added, removed and changed, values of private properties are never recorded",
+                            "properties": {
+                                "added": {
+                                    "items": {
+                                        "properties": {
+                                            "new": {
+                                                "description": "the new value, absent when removed or private"
+                                            },
+                                            "old": {
+                                                "description": "the prior value, absent when added or private"
+                                            },
+                                            "path": {
+                                                "description": "property path relative to asset state",
+                                                "type": "string"
+                                            }
+                                        },
+                                        "type": "object"
+                                    },
+                                    "type": "array"
+                                },
+                                "changed": {
+                                    "items": {
+                                        "properties": {
+                                            "new": {
+                                                "description": "the new value, absent when removed or private"
+                                            },
+                                            "old": {
+                                                "description": "the prior value, absent when added or private"
+                                            },
+                                            "path": {
+                                                "description": "property path relative to asset state",
+                                                "type": "string"
+                                            }
+                                        },
+                                        "type": "object"
+                                    },
+                                    "type": "array"
+                                },
+                                "removed": {
+                                    "items": {
+                                        "properties": {
+                                            "new": {
+                                                "description": "the new value, absent when removed or private"
+                                            },
+                                            "old": {
+                                                "description": "the prior value, absent when added or private"
+                                            },
+                                            "path": {
+                                                "description": "property path relative to asset state",
+                                                "type": "string"
+                                            }
+                                        },
+                                        "type": "object"
+                                    },
+                                    "type": "array"
+                                }
+                            },
+                            "type": "object"
+                        },
                         "eventin": {
                             "description": "The contract event that created this state, for example updateAsset",
                             "properties": {
@@ -842,9 +1912,8 @@ var schemas = `
                                     "properties": {
                                         "name": {
                                             "default": "EVT.IOTCP.INVOKE.RESULT",
-                                            "enum": [
-                                                "EVT.IOTCP.INVOKE.RESULT"
-                                            ],
+                                            "description": "EVT.IOTCP.INVOKE.RESULT, followed by the declared names of the invoke's events separated by colons when the contract turns the event name suffix on, e.g. EVT.IOTCP.INVOKE.RESULT:KIT.OUTOFAREA",
+                                            "pattern": "^EVT\\.IOTCP\\.INVOKE\\.RESULT(:.+)?$",
                                             "type": "string"
                                         },
                                         "payload": {
@@ -876,6 +1945,152 @@ var schemas = `
                                                         },
                                                         "type": "array"
                                                     },
+                                                    "events": {
+                                                        "items": {
+                                                            "description": "A typed event raised by an invoke, one per asset written or deleted and one per alert raised or cleared",
+                                                            "properties": {
+                                                                "alertsCleared": {
+                                                                    "description": "An array of alert names",
+                                                                    "items": {
+                                                                        "description": "An alert name",
+                                                                        "type": "string"
+                                                                    },
+                                                                    "type": "array"
+                                                                },
+                                                                "alertsRaised": {
+                                                                    "description": "An array of alert names",
+                                                                    "items": {
+                                                                        "description": "An alert name",
+                                                                        "type": "string"
+                                                                    },
+                                                                    "type": "array"
+                                                                },
+                                                                "assetkey": {
+                                                                    "asset": {
+                                                                        "properties": {
+                                                                            "assetID": {
+                                                                                "description": "An asset's unique ID, e.g. barcode, VIN, etc.",
+                                                                                "type": "string"
+                                                                            }
+                                                                        },
+                                                                        "type": "object"
+                                                                    }
+                                                                },
+                                                                "changed": {
+                                                                    "description": "property paths of the asset state that the write changed",
+                                                                    "items": {
+                                                                        "type": "string"
+                                                                    },
+                                                                    "type": "array"
+                                                                },
+                                                                "class": {
+                                                                    "type": "string"
+                                                                },
+                                                                "diff": {
+                                                                    "description": "The properties that an asset state added, removed and changed, values of private properties are never recorded",
+                                                                    "properties": {
+                                                                        "added": {
+                                                                            "items": {
+                                                                                "properties": {
+                                                                                    "new": {
+                                                                                        "description": "the new value, absent when removed or private"
+                                                                                    },
+                                                                                    "old": {
+                                                                                        "description": "the prior value, absent when added or private"
+                                                                                    },
+                                                                                    "path": {
+                                                                                        "description": "property path relative to asset state",
+                                                                                        "type": "string"
+                                                                                    }
+                                                                                },
+                                                                                "type": "object"
+                                                                            },
+                                                                            "type": "array"
+                                                                        },
+                                                                        "changed": {
+                                                                            "items": {
+                                                                                "properties": {
+                                                                                    "new": {
+                                                                                        "description": "the new value, absent when removed or private"
+                                                                                    },
+                                                                                    "old": {
+                                                                                        "description": "the prior value, absent when added or private"
+                                                                                    },
+                                                                                    "path": {
+                                                                                        "description": "property path relative to asset state",
+                                                                                        "type": "string"
+                                                                                    }
+                                                                                },
+                                                                                "type": "object"
+                                                                            },
+                                                                            "type": "array"
+                                                                        },
+                                                                        "removed": {
+                                                                            "items": {
+                                                                                "properties": {
+                                                                                    "new": {
+                                                                                        "description": "the new value, absent when removed or private"
+                                                                                    },
+                                                                                    "old": {
+                                                                                        "description": "the prior value, absent when added or private"
+                                                                                    },
+                                                                                    "path": {
+                                                                                        "description": "property path relative to asset state",
+                                                                                        "type": "string"
+                                                                                    }
+                                                                                },
+                                                                                "type": "object"
+                                                                            },
+                                                                            "type": "array"
+                                                                        }
+                                                                    },
+                                                                    "type": "object"
+                                                                },
+                                                                "name": {
+                                                                    "description": "the event name declared by the contract for the class or alert, also appended to the chaincode event name",
+                                                                    "type": "string"
+                                                                },
+                                                                "transition": {
+                                                                    "description": "The lifecycle transition made by an asset state",
+                                                                    "properties": {
+                                                                        "from": {
+                                                                            "type": "string"
+                                                                        },
+                                                                        "to": {
+                                                                            "type": "string"
+                                                                        },
+                                                                        "trigger": {
+                                                                            "description": "The function that made the transition",
+                                                                            "type": "string"
+                                                                        }
+                                                                    },
+                                                                    "type": "object"
+                                                                },
+                                                                "txnid": {
+                                                                    "type": "string"
+                                                                },
+                                                                "type": {
+                                                                    "enum": [
+                                                                        "asset.created",
+                                                                        "asset.updated",
+                                                                        "asset.deleted",
+                                                                        "alert.raised",
+                                                                        "alert.cleared",
+                                                                        "zone.entered",
+                                                                        "zone.exited",
+                                                                        "history.archived"
+                                                                    ],
+                                                                    "type": "string"
+                                                                },
+                                                                "zone": {
+                                                                    "description": "the zone of a zone event",
+                                                                    "type": "string"
+                                                                }
+                                                            },
+                                                            "type": "object"
+                                                        },
+                                                        "type": "array"
+                                                    },
                                                     "invokeresult": {
                                                         "description": "status: OK==txn succeeded, ERROR==txn failed",
                                                         "properties": {
@@ -891,6 +2106,17 @@ var schemas = `
                                                             }
                                                         },
                                                         "type": "object"
+                                                    },
+                                                    "names": {
+                                                        "description": "the distinct declared names of the events, sorted",
+                                                        "items": {
+                                                            "type": "string"
+                                                        },
+                                                        "type": "array"
+                                                    },
+                                                    "txnid": {
+                                                        "description": "the transaction that emitted the event",
+                                                        "type": "string"
                                                     }
                                                 },
                                                 "type": "object"
@@ -903,6 +2129,10 @@ var schemas = `
                             },
                             "type": "object"
                         },
+                        "rulesversion": {
+                            "description": "Version of the declarative rule set that judged this state",
+                            "type": "integer"
+                        },
                         "state": {
                             "description": "Properties that have been received or calculated for this asset",
                             "properties": {
@@ -972,6 +2202,22 @@ var schemas = `
                             },
                             "type": "object"
                         },
+                        "transition": {
+                            "description": "The lifecycle transition made by an asset state",
+                            "properties": {
+                                "from": {
+                                    "type": "string"
+                                },
+                                "to": {
+                                    "type": "string"
+                                },
+                                "trigger": {
+                                    "description": "The function that made the transition",
+                                    "type": "string"
+                                }
+                            },
+                            "type": "object"
+                        },
                         "txnid": {
                             "description": "Transaction UUID matching the blockchain",
                             "type": "string"
@@ -979,6 +2225,40 @@ var schemas = `
                         "txnts": {
                             "description": "Transaction timestamp matching the blockchain",
                             "type": "string"
+                        },
+                        "zones": {
+                            "description": "The attached zones that hold an asset's location, and those that its move from the prior location entered and exited",
+                            "properties": {
+                                "alerts": {
+                                    "description": "zone alerts that this state holds raised, cleared on the next write once their zones are detached or deleted",
+                                    "items": {
+                                        "type": "string"
+                                    },
+                                    "type": "array"
+                                },
+                                "entered": {
+                                    "description": "zones entered by this write",
+                                    "items": {
+                                        "type": "string"
+                                    },
+                                    "type": "array"
+                                },
+                                "exited": {
+                                    "description": "zones exited by this write",
+                                    "items": {
+                                        "type": "string"
+                                    },
+                                    "type": "array"
+                                },
+                                "inside": {
+                                    "description": "zones holding the location",
+                                    "items": {
+                                        "type": "string"
+                                    },
+                                    "type": "array"
+                                }
+                            },
+                            "type": "object"
                         }
                     },
                     "type": "object"
@@ -987,7 +2267,7 @@ var schemas = `
             "type": "object"
         },
         "readAssetStateHistory": {
-            "description": "Returns history for an asset",
+            "description": "Returns history for an asset, newest first, or oldest first one page at a time when paginated",
             "properties": {
                 "args": {
                     "items": {
@@ -1001,6 +2281,10 @@ var schemas = `
                                 },
                                 "type": "object"
                             },
+                            "bookmark": {
+                                "description": "opaque bookmark returned with the previous page, pass it back unchanged to read the next page; an empty bookmark means there are no more results",
+                                "type": "string"
+                            },
                             "daterange": {
                                 "description": "if specified, dates must fall in between these values, inclusive",
                                 "properties": {
@@ -1022,13 +2306,23 @@ var schemas = `
                             "filter": {
                                 "description": "Filter asset states",
                                 "properties": {
+                                    "groups": {
+                                        "description": "Nested filters with the same shape as this filter, combined with the select terms according to match",
+                                        "items": {
+                                            "type": "object"
+                                        },
+                                        "type": "array"
+                                    },
                                     "match": {
-                                        "description": "Defines how to match properties, missing property always fails match",
+                                        "description": "Defines how to combine the select terms and groups, and, or and not are aliases for all, any and none; missing property always fails match",
                                         "enum": [
                                             "n/a",
                                             "all",
                                             "any",
-                                            "none"
+                                            "none",
+                                            "and",
+                                            "or",
+                                            "not"
                                         ],
                                         "type": "string"
                                     },
@@ -1036,6 +2330,22 @@ var schemas = `
                                         "description": "Qualified property names and values match",
                                         "items": {
                                             "properties": {
+                                                "op": {
+                                                    "description": "Comparison operator, defaults to equality; numbers compare numerically and strings lexically; exists with value 'false' matches a missing property",
+                                                    "enum": [
+                                                        "eq",
+                                                        "ne",
+                                                        "gt",
+                                                        "gte",
+                                                        "lt",
+                                                        "lte",
+                                                        "in",
+                                                        "exists",
+                                                        "prefix",
+                                                        "regex"
+                                                    ],
+                                                    "type": "string"
+                                                },
                                                 "qprop": {
                                                     "description": "Qualified property to compare, for example 'asset.assetID'",
                                                     "type": "string"
@@ -1043,29 +2353,580 @@ var schemas = `
                                                 "value": {
                                                     "description": "Value to be compared",
                                                     "type": "string"
+                                                },
+                                                "values": {
+                                                    "description": "Values to be compared by the in operator",
+                                                    "items": {
+                                                        "type": "string"
+                                                    },
+                                                    "type": "array"
                                                 }
                                             },
-                                            "type": "object"
+                                            "type": "object"
+                                        },
+                                        "type": "array"
+                                    }
+                                },
+                                "type": "object"
+                            },
+                            "pagesize": {
+                                "description": "maximum number of results to return in one page, results are returned in a paged envelope when pagesize or bookmark is passed",
+                                "minimum": 0,
+                                "type": "integer"
+                            }
+                        },
+                        "type": "object"
+                    },
+                    "maxItems": 1,
+                    "minItems": 1,
+                    "type": "array"
+                },
+                "function": {
+                    "enum": [
+                        "readAssetStateHistory"
+                    ],
+                    "type": "string"
+                },
+                "method": "query",
+                "pagedresult": {
+                    "description": "returned instead of result when pagesize or bookmark is passed",
+                    "properties": {
+                        "bookmark": {
+                            "description": "opaque bookmark returned with the previous page, pass it back unchanged to read the next page; an empty bookmark means there are no more results",
+                            "type": "string"
+                        },
+                        "count": {
+                            "description": "number of results in this page",
+                            "type": "integer"
+                        },
+                        "results": {
+                            "description": "Array of asset states, can mix asset classes",
+                            "items": {
+                                "description": "A asset's complete state",
+                                "properties": {
+                                    "alerts": {
+                                        "description": "An array of alert names",
+                                        "items": {
+                                            "description": "An alert name",
+                                            "type": "string"
+                                        },
+                                        "type": "array"
+                                    },
+                                    "assetID": {
+                                        "description": "This asset's world state asset ID",
+                                        "type": "string"
+                                    },
+                                    "class": {
+                                        "description": "An asset's classifier definition",
+                                        "properties": {
+                                            "assetidpath": "An asset's primary key, expressed as a qualified property path (see example contracts)",
+                                            "name": "An asset's class name",
+                                            "prefix": "An asset's world state prefix, used to allow iteration over all assets of a class"
+                                        },
+                                        "type": "object"
+                                    },
+                                    "compliant": {
+                                        "description": "This asset has no active alerts",
+                                        "type": "boolean"
+                                    },
+                                    "deleted": {
+                                        "description": "Marks a history state that records the deletion of the asset, native history only",
+                                        "type": "boolean"
+                                    },
+                                    "diff": {
+                                        "description": "The properties that an asset state added, removed and changed, values of private properties are never recorded",
+                                        "properties": {
+                                            "added": {
+                                                "items": {
+                                                    "properties": {
+                                                        "new": {
+                                                            "description": "the new value, absent when removed or private"
+                                                        },
+                                                        "old": {
+                                                            "description": "the prior value, absent when added or private"
+                                                        },
+                                                        "path": {
+                                                            "description": "property path relative to asset state",
+                                                            "type": "string"
+                                                        }
+                                                    },
+                                                    "type": "object"
+                                                },
+                                                "type": "array"
+                                            },
+                                            "changed": {
+                                                "items": {
+                                                    "properties": {
+                                                        "new": {
+                                                            "description": "the new value, absent when removed or private"
+                                                        },
+                                                        "old": {
+                                                            "description": "the prior value, absent when added or private"
+                                                        },
+                                                        "path": {
+                                                            "description": "property path relative to asset state",
+                                                            "type": "string"
+                                                        }
+                                                    },
+                                                    "type": "object"
+                                                },
+                                                "type": "array"
+                                            },
+                                            "removed": {
+                                                "items": {
+                                                    "properties": {
+                                                        "new": {
+                                                            "description": "the new value, absent when removed or private"
+                                                        },
+                                                        "old": {
+                                                            "description": "the prior value, absent when added or private"
+                                                        },
+                                                        "path": {
+                                                            "description": "property path relative to asset state",
+                                                            "type": "string"
+                                                        }
+                                                    },
+                                                    "type": "object"
+                                                },
+                                                "type": "array"
+                                            }
+                                        },
+                                        "type": "object"
+                                    },
+                                    "eventin": {
+                                        "description": "The contract event that created this state, for example updateAsset",
+                                        "properties": {
+                                            "asset": {
+                                                "description": "The changeable properties for an asset, also considered its 'event' as a partial state",
+                                                "properties": {
+                                                    "assetID": {
+                                                        "description": "An asset's unique ID, e.g. barcode, VIN, etc.",
+                                                        "type": "string"
+                                                    },
+                                                    "carrier": {
+                                                        "description": "The carrier in possession of this asset",
+                                                        "type": "string"
+                                                    },
+                                                    "common": {
+                                                        "description": "Common properties for all assets",
+                                                        "properties": {
+                                                            "appdata": {
+                                                                "description": "Application managed information as an array of key:value pairs",
+                                                                "items": {
+                                                                    "properties": {
+                                                                        "K": {
+                                                                            "type": "string"
+                                                                        },
+                                                                        "V": {
+                                                                            "type": "string"
+                                                                        }
+                                                                    },
+                                                                    "type": "object"
+                                                                },
+                                                                "minItems": 0,
+                                                                "type": "array"
+                                                            },
+                                                            "deviceID": {
+                                                                "description": "A unique identifier for the device that sent the current event",
+                                                                "type": "string"
+                                                            },
+                                                            "devicetimestamp": {
+                                                                "description": "A timestamp recoded by the device that sent the current event",
+                                                                "type": "string"
+                                                            },
+                                                            "location": {
+                                                                "description": "A geographical coordinate",
+                                                                "properties": {
+                                                                    "latitude": {
+                                                                        "type": "number"
+                                                                    },
+                                                                    "longitude": {
+                                                                        "type": "number"
+                                                                    }
+                                                                },
+                                                                "type": "object"
+                                                            }
+                                                        },
+                                                        "type": "object"
+                                                    },
+                                                    "temperature": {
+                                                        "description": "Temperature of an asset's contents in degrees Celsuis",
+                                                        "type": "number"
+                                                    }
+                                                },
+                                                "required": [
+                                                    "assetID"
+                                                ],
+                                                "type": "object"
+                                            }
+                                        },
+                                        "type": "object"
+                                    },
+                                    "eventout": {
+                                        "description": "The chaincode event emitted on invoke exit, if any",
+                                        "properties": {
+                                            "asset": {
+                                                "description": "A chaincode event defining the standard platform-generated result event for a contract invoke, contains an array of contributed results",
+                                                "properties": {
+                                                    "name": {
+                                                        "default": "EVT.IOTCP.INVOKE.RESULT",
+                                                        "description": "EVT.IOTCP.INVOKE.RESULT, followed by the declared names of the invoke's events separated by colons when the contract turns the event name suffix on, e.g. EVT.IOTCP.INVOKE.RESULT:KIT.OUTOFAREA",
+                                                        "pattern": "^EVT\\.IOTCP\\.INVOKE\\.RESULT(:.+)?$",
+                                                        "type": "string"
+                                                    },
+                                                    "payload": {
+                                                        "description": "A map of contributed results",
+                                                        "properties": {
+                                                            "description": "the overall status of the invoke result, defined by err",
+                                                            "properties": {
+                                                                "activeAlerts": {
+                                                                    "description": "An array of alert names",
+                                                                    "items": {
+                                                                        "description": "An alert name",
+                                                                        "type": "string"
+                                                                    },
+                                                                    "type": "array"
+                                                                },
+                                                                "alertsCleared": {
+                                                                    "description": "An array of alert names",
+                                                                    "items": {
+                                                                        "description": "An alert name",
+                                                                        "type": "string"
+                                                                    },
+                                                                    "type": "array"
+                                                                },
+                                                                "alertsRaised": {
+                                                                    "description": "An array of alert names",
+                                                                    "items": {
+                                                                        "description": "An alert name",
+                                                                        "type": "string"
+                                                                    },
+                                                                    "type": "array"
+                                                                },
+                                                                "events": {
+                                                                    "items": {
+                                                                        "description": "A typed event raised by an invoke, one per asset written or deleted and one per alert raised or cleared",
+                                                                        "properties": {
+                                                                            "alertsCleared": {
+                                                                                "description": "An array of alert names",
+                                                                                "items": {
+                                                                                    "description": "An alert name",
+                                                                                    "type": "string"
+                                                                                },
+                                                                                "type": "array"
+                                                                            },
+                                                                            "alertsRaised": {
+                                                                                "description": "An array of alert names",
+                                                                                "items": {
+                                                                                    "description": "An alert name",
+                                                                                    "type": "string"
+                                                                                },
+                                                                                "type": "array"
+                                                                            },
+                                                                            "assetkey": {
+                                                                                "asset": {
+                                                                                    "properties": {
+                                                                                        "assetID": {
+                                                                                            "description": "An asset's unique ID, e.g. barcode, VIN, etc.",
+                                                                                            "type": "string"
+                                                                                        }
+                                                                                    },
+                                                                                    "type": "object"
+                                                                                }
+                                                                            },
+                                                                            "changed": {
+                                                                                "description": "property paths of the asset state that the write changed",
+                                                                                "items": {
+                                                                                    "type": "string"
+                                                                                },
+                                                                                "type": "array"
+                                                                            },
+                                                                            "class": {
+                                                                                "type": "string"
+                                                                            },
+                                                                            "diff": {
+                                                                                "description": "The properties that an asset state added, removed and changed, values of private properties are never recorded",
+                                                                                "properties": {
+                                                                                    "added": {
+                                                                                        "items": {
+                                                                                            "properties": {
+                                                                                                "new": {
+                                                                                                    "description": "the new value, absent when removed or private"
+                                                                                                },
+                                                                                                "old": {
+                                                                                                    "description": "the prior value, absent when added or private"
+                                                                                                },
+                                                                                                "path": {
+                                                                                                    "description": "property path relative to asset state",
+                                                                                                    "type": "string"
+                                                                                                }
+                                                                                            },
+                                                                                            "type": "object"
+                                                                                        },
+                                                                                        "type": "array"
+                                                                                    },
+                                                                                    "changed": {
+                                                                                        "items": {
+                                                                                            "properties": {
+                                                                                                "new": {
+                                                                                                    "description": "the new value, absent when removed or private"
+                                                                                                },
+                                                                                                "old": {
+                                                                                                    "description": "the prior value, absent when added or private"
+                                                                                                },
+                                                                                                "path": {
+                                                                                                    "description": "property path relative to asset state",
+                                                                                                    "type": "string"
+                                                                                                }
+                                                                                            },
+                                                                                            "type": "object"
+                                                                                        },
+                                                                                        "type": "array"
+                                                                                    },
+                                                                                    "removed": {
+                                                                                        "items": {
+                                                                                            "properties": {
+                                                                                                "new": {
+                                                                                                    "description": "the new value, absent when removed or private"
+                                                                                                },
+                                                                                                "old": {
+                                                                                                    "description": "the prior value, absent when added or private"
+                                                                                                },
+                                                                                                "path": {
+                                                                                                    "description": "property path relative to asset state",
+                                                                                                    "type": "string"
+                                                                                                }
+                                                                                            },
+                                                                                            "type": "object"
+                                                                                        },
+                                                                                        "type": "array"
+                                                                                    }
+                                                                                },
+                                                                                "type": "object"
+                                                                            },
+                                                                            "name": {
+                                                                                "description": "the event name declared by the contract for the class or alert, also appended to the chaincode event name",
+                                                                                "type": "string"
+                                                                            },
+                                                                            "transition": {
+                                                                                "description": "The lifecycle transition made by an asset state",
+                                                                                "properties": {
+                                                                                    "from": {
+                                                                                        "type": "string"
+                                                                                    },
+                                                                                    "to": {
+                                                                                        "type": "string"
+                                                                                    },
+                                                                                    "trigger": {
+                                                                                        "description": "The function that made the transition",
+                                                                                        "type": "string"
+                                                                                    }
+                                                                                },
+                                                                                "type": "object"
+                                                                            },
+                                                                            "txnid": {
+                                                                                "type": "string"
+                                                                            },
+                                                                            "type": {
+                                                                                "enum": [
+                                                                                    "asset.created",
+                                                                                    "asset.updated",
+                                                                                    "asset.deleted",
+                                                                                    "alert.raised",
+                                                                                    "alert.cleared",
+                                                                                    "zone.entered",
+                                                                                    "zone.exited",
+                                                                                    "history.archived"
+                                                                                ],
+                                                                                "type": "string"
+                                                                            },
+                                                                            "zone": {
+                                                                                "description": "the zone of a zone event",
+                                                                                "type": "string"
+                                                                            }
+                                                                        },
+                                                                        "type": "object"
+                                                                    },
+                                                                    "type": "array"
+                                                                },
+                                                                "invokeresult": {
+                                                                    "description": "status: OK==txn succeeded, ERROR==txn failed",
+                                                                    "properties": {
+                                                                        "message": {
+                                                                            "type": "string"
+                                                                        },
+                                                                        "status": {
+                                                                            "enum": [
+                                                                                "OK",
+                                                                                "ERROR"
+                                                                            ],
+                                                                            "type": "string"
+                                                                        }
+                                                                    },
+                                                                    "type": "object"
+                                                                },
+                                                                "names": {
+                                                                    "description": "the distinct declared names of the events, sorted",
+                                                                    "items": {
+                                                                        "type": "string"
+                                                                    },
+                                                                    "type": "array"
+                                                                },
+                                                                "txnid": {
+                                                                    "description": "the transaction that emitted the event",
+                                                                    "type": "string"
+                                                                }
+                                                            },
+                                                            "type": "object"
+                                                        },
+                                                        "type": "object"
+                                                    }
+                                                },
+                                                "type": "object"
+                                            }
+                                        },
+                                        "type": "object"
+                                    },
+                                    "rulesversion": {
+                                        "description": "Version of the declarative rule set that judged this state",
+                                        "type": "integer"
+                                    },
+                                    "state": {
+                                        "description": "Properties that have been received or calculated for this asset",
+                                        "properties": {
+                                            "asset": {
+                                                "description": "The changeable properties for an asset, also considered its 'event' as a partial state",
+                                                "properties": {
+                                                    "assetID": {
+                                                        "description": "An asset's unique ID, e.g. barcode, VIN, etc.",
+                                                        "type": "string"
+                                                    },
+                                                    "carrier": {
+                                                        "description": "The carrier in possession of this asset",
+                                                        "type": "string"
+                                                    },
+                                                    "common": {
+                                                        "description": "Common properties for all assets",
+                                                        "properties": {
+                                                            "appdata": {
+                                                                "description": "Application managed information as an array of key:value pairs",
+                                                                "items": {
+                                                                    "properties": {
+                                                                        "K": {
+                                                                            "type": "string"
+                                                                        },
+                                                                        "V": {
+                                                                            "type": "string"
+                                                                        }
+                                                                    },
+                                                                    "type": "object"
+                                                                },
+                                                                "minItems": 0,
+                                                                "type": "array"
+                                                            },
+                                                            "deviceID": {
+                                                                "description": "A unique identifier for the device that sent the current event",
+                                                                "type": "string"
+                                                            },
+                                                            "devicetimestamp": {
+                                                                "description": "A timestamp recoded by the device that sent the current event",
+                                                                "type": "string"
+                                                            },
+                                                            "location": {
+                                                                "description": "A geographical coordinate",
+                                                                "properties": {
+                                                                    "latitude": {
+                                                                        "type": "number"
+                                                                    },
+                                                                    "longitude": {
+                                                                        "type": "number"
+                                                                    }
+                                                                },
+                                                                "type": "object"
+                                                            }
+                                                        },
+                                                        "type": "object"
+                                                    },
+                                                    "temperature": {
+                                                        "description": "Temperature of an asset's contents in degrees Celsuis",
+                                                        "type": "number"
+                                                    }
+                                                },
+                                                "required": [
+                                                    "assetID"
+                                                ],
+                                                "type": "object"
+                                            }
+                                        },
+                                        "type": "object"
+                                    },
+                                    "transition": {
+                                        "description": "The lifecycle transition made by an asset state",
+                                        "properties": {
+                                            "from": {
+                                                "type": "string"
+                                            },
+                                            "to": {
+                                                "type": "string"
+                                            },
+                                            "trigger": {
+                                                "description": "The function that made the transition",
+                                                "type": "string"
+                                            }
+                                        },
+                                        "type": "object"
+                                    },
+                                    "txnid": {
+                                        "description": "Transaction UUID matching the blockchain",
+                                        "type": "string"
+                                    },
+                                    "txnts": {
+                                        "description": "Transaction timestamp matching the blockchain",
+                                        "type": "string"
+                                    },
+                                    "zones": {
+                                        "description": "The attached zones that hold an asset's location, and those that its move from the prior location entered and exited",
+                                        "properties": {
+                                            "alerts": {
+                                                "description": "zone alerts that this state holds raised, cleared on the next write once their zones are detached or deleted",
+                                                "items": {
+                                                    "type": "string"
+                                                },
+                                                "type": "array"
+                                            },
+                                            "entered": {
+                                                "description": "zones entered by this write",
+                                                "items": {
+                                                    "type": "string"
+                                                },
+                                                "type": "array"
+                                            },
+                                            "exited": {
+                                                "description": "zones exited by this write",
+                                                "items": {
+                                                    "type": "string"
+                                                },
+                                                "type": "array"
+                                            },
+                                            "inside": {
+                                                "description": "zones holding the location",
+                                                "items": {
+                                                    "type": "string"
+                                                },
+                                                "type": "array"
+                                            }
                                         },
-                                        "type": "array"
+                                        "type": "object"
                                     }
                                 },
                                 "type": "object"
-                            }
-                        },
-                        "type": "object"
+                            },
+                            "minItems": 0,
+                            "type": "array"
+                        }
                     },
-                    "maxItems": 1,
-                    "minItems": 1,
-                    "type": "array"
-                },
-                "function": {
-                    "enum": [
-                        "readAssetStateHistory"
-                    ],
-                    "type": "string"
+                    "type": "object"
                 },
-                "method": "query",
                 "result": {
                     "description": "Array of asset states, can mix asset classes",
                     "items": {
@@ -1096,6 +2957,70 @@ var schemas = `
                                 "description": "This asset has no active alerts",
                                 "type": "boolean"
                             },
+                            "deleted": {
+                                "description": "Marks a history state that records the deletion of the asset, native history only",
+                                "type": "boolean"
+                            },
+                            "diff": {
+                                "description": "The properties that an asset state added, removed and changed, values of private properties are never recorded",
+                                "properties": {
+                                    "added": {
+                                        "items": {
+                                            "properties": {
+                                                "new": {
+                                                    "description": "the new value, absent when removed or private"
+                                                },
+                                                "old": {
+                                                    "description": "the prior value, absent when added or private"
+                                                },
+                                                "path": {
+                                                    "description": "property path relative to asset state",
+                                                    "type": "string"
+                                                }
+                                            },
+                                            "type": "object"
+                                        },
+                                        "type": "array"
+                                    },
+                                    "changed": {
+                                        "items": {
+                                            "properties": {
+                                                "new": {
+                                                    "description": "the new value, absent when removed or private"
+                                                },
+                                                "old": {
+                                                    "description": "the prior value, absent when added or private"
+                                                },
+                                                "path": {
+                                                    "description": "property path relative to asset state",
+                                                    "type": "string"
+                                                }
+                                            },
+                                            "type": "object"
+                                        },
+                                        "type": "array"
+                                    },
+                                    "removed": {
+                                        "items": {
+                                            "properties": {
+                                                "new": {
+                                                    "description": "the new value, absent when removed or private"
+                                                },
+                                                "old": {
+                                                    "description": "the prior value, absent when added or private"
+                                                },
+                                                "path": {
+                                                    "description": "property path relative to asset state",
+                                                    "type": "string"
+                                                }
+                                            },
+                                            "type": "object"
+                                        },
+                                        "type": "array"
+                                    }
+                                },
+                                "type": "object"
+                            },
                             "eventin": {
                                 "description": "The contract event that created this state, for example updateAsset",
                                 "properties": {
@@ -1173,9 +3098,8 @@ var schemas = `
                                         "properties": {
                                             "name": {
                                                 "default": "EVT.IOTCP.INVOKE.RESULT",
-                                                "enum": [
-                                                    "EVT.IOTCP.INVOKE.RESULT"
-                                                ],
+                                                "description": "EVT.IOTCP.INVOKE.RESULT, followed by the declared names of the invoke's events separated by colons when the contract turns the event name suffix on, e.g. EVT.IOTCP.INVOKE.RESULT:KIT.OUTOFAREA",
+                                                "pattern": "^EVT\\.IOTCP\\.INVOKE\\.RESULT(:.+)?$",
                                                 "type": "string"
                                             },
                                             "payload": {
@@ -1207,6 +3131,152 @@ var schemas = `
                                                             },
                                                             "type": "array"
                                                         },
+                                                        "events": {
+                                                            "items": {
+                                                                "description": "A typed event raised by an invoke, one per asset written or deleted and one per alert raised or cleared",
+                                                                "properties": {
+                                                                    "alertsCleared": {
+                                                                        "description": "An array of alert names",
+                                                                        "items": {
+                                                                            "description": "An alert name",
+                                                                            "type": "string"
+                                                                        },
+                                                                        "type": "array"
+                                                                    },
+                                                                    "alertsRaised": {
+                                                                        "description": "An array of alert names",
+                                                                        "items": {
+                                                                            "description": "An alert name",
+                                                                            "type": "string"
+                                                                        },
+                                                                        "type": "array"
+                                                                    },
+                                                                    "assetkey": {
+                                                                        "asset": {
+                                                                            "properties": {
+                                                                                "assetID": {
+                                                                                    "description": "An asset's unique ID, e.g. barcode, VIN, etc.",
+                                                                                    "type": "string"
+                                                                                }
+                                                                            },
+                                                                            "type": "object"
+                                                                        }
+                                                                    },
+                                                                    "changed": {
+                                                                        "description": "property paths of the asset state that the write changed",
+                                                                        "items": {
+                                                                            "type": "string"
+                                                                        },
+                                                                        "type": "array"
+                                                                    },
+                                                                    "class": {
+                                                                        "type": "string"
+                                                                    },
+                                                                    "diff": {
+                                                                        "description": "The properties that an asset state added, removed and changed, values of private properties are never recorded",
+                                                                        "properties": {
+                                                                            "added": {
+                                                                                "items": {
+                                                                                    "properties": {
+                                                                                        "new": {
+                                                                                            "description": "the new value, absent when removed or private"
+                                                                                        },
+                                                                                        "old": {
+                                                                                            "description": "the prior value, absent when added or private"
+                                                                                        },
+                                                                                        "path": {
+                                                                                            "description": "property path relative to asset state",
+                                                                                            "type": "string"
+                                                                                        }
+                                                                                    },
+                                                                                    "type": "object"
+                                                                                },
+                                                                                "type": "array"
+                                                                            },
+                                                                            "changed": {
+                                                                                "items": {
+                                                                                    "properties": {
+                                                                                        "new": {
+                                                                                            "description": "the new value, absent when removed or private"
+                                                                                        },
+                                                                                        "old": {
+                                                                                            "description": "the prior value, absent when added or private"
+                                                                                        },
+                                                                                        "path": {
+                                                                                            "description": "property path relative to asset state",
+                                                                                            "type": "string"
+                                                                                        }
+                                                                                    },
+                                                                                    "type": "object"
+                                                                                },
+                                                                                "type": "array"
+                                                                            },
+                                                                            "removed": {
+                                                                                "items": {
+                                                                                    "properties": {
+                                                                                        "new": {
+                                                                                            "description": "the new value, absent when removed or private"
+                                                                                        },
+                                                                                        "old": {
+                                                                                            "description": "the prior value, absent when added or private"
+                                                                                        },
+                                                                                        "path": {
+                                                                                            "description": "property path relative to asset state",
+                                                                                            "type": "string"
+                                                                                        }
+                                                                                    },
+                                                                                    "type": "object"
+                                                                                },
+                                                                                "type": "array"
+                                                                            }
+                                                                        },
+                                                                        "type": "object"
+                                                                    },
+                                                                    "name": {
+                                                                        "description": "the event name declared by the contract for the class or alert, also appended to the chaincode event name",
+                                                                        "type": "string"
+                                                                    },
+                                                                    "transition": {
+                                                                        "description": "The lifecycle transition made by an asset state",
+                                                                        "properties": {
+                                                                            "from": {
+                                                                                "type": "string"
+                                                                            },
+                                                                            "to": {
+                                                                                "type": "string"
+                                                                            },
+                                                                            "trigger": {
+                                                                                "description": "The function that made the transition",
+                                                                                "type": "string"
+                                                                            }
+                                                                        },
+                                                                        "type": "object"
+                                                                    },
+                                                                    "txnid": {
+                                                                        "type": "string"
+                                                                    },
+                                                                    "type": {
+                                                                        "enum": [
+                                                                            "asset.created",
+                                                                            "asset.updated",
+                                                                            "asset.deleted",
+                                                                            "alert.raised",
+                                                                            "alert.cleared",
+                                                                            "zone.entered",
+                                                                            "zone.exited",
+                                                                            "history.archived"
+                                                                        ],
+                                                                        "type": "string"
+                                                                    },
+                                                                    "zone": {
+                                                                        "description": "the zone of a zone event",
+                                                                        "type": "string"
+                                                                    }
+                                                                },
+                                                                "type": "object"
+                                                            },
+                                                            "type": "array"
+                                                        },
                                                         "invokeresult": {
                                                             "description": "status: OK==txn succeeded, ERROR==txn failed",
                                                             "properties": {
@@ -1222,6 +3292,17 @@ var schemas = `
                                                                 }
                                                             },
                                                             "type": "object"
+                                                        },
+                                                        "names": {
+                                                            "description": "the distinct declared names of the events, sorted",
+                                                            "items": {
+                                                                "type": "string"
+                                                            },
+                                                            "type": "array"
+                                                        },
+                                                        "txnid": {
+                                                            "description": "the transaction that emitted the event",
+                                                            "type": "string"
                                                         }
                                                     },
                                                     "type": "object"
@@ -1234,6 +3315,10 @@ var schemas = `
                                 },
                                 "type": "object"
                             },
+                            "rulesversion": {
+                                "description": "Version of the declarative rule set that judged this state",
+                                "type": "integer"
+                            },
                             "state": {
                                 "description": "Properties that have been received or calculated for this asset",
                                 "properties": {
@@ -1303,6 +3388,22 @@ var schemas = `
                                 },
                                 "type": "object"
                             },
+                            "transition": {
+                                "description": "The lifecycle transition made by an asset state",
+                                "properties": {
+                                    "from": {
+                                        "type": "string"
+                                    },
+                                    "to": {
+                                        "type": "string"
+                                    },
+                                    "trigger": {
+                                        "description": "The function that made the transition",
+                                        "type": "string"
+                                    }
+                                },
+                                "type": "object"
+                            },
                             "txnid": {
                                 "description": "Transaction UUID matching the blockchain",
                                 "type": "string"
@@ -1310,6 +3411,40 @@ var schemas = `
                             "txnts": {
                                 "description": "Transaction timestamp matching the blockchain",
                                 "type": "string"
+                            },
+                            "zones": {
+                                "description": "The attached zones that hold an asset's location, and those that its move from the prior location entered and exited",
+                                "properties": {
+                                    "alerts": {
+                                        "description": "zone alerts that this state holds raised, cleared on the next write once their zones are detached or deleted",
+                                        "items": {
+                                            "type": "string"
+                                        },
+                                        "type": "array"
+                                    },
+                                    "entered": {
+                                        "description": "zones entered by this write",
+                                        "items": {
+                                            "type": "string"
+                                        },
+                                        "type": "array"
+                                    },
+                                    "exited": {
+                                        "description": "zones exited by this write",
+                                        "items": {
+                                            "type": "string"
+                                        },
+                                        "type": "array"
+                                    },
+                                    "inside": {
+                                        "description": "zones holding the location",
+                                        "items": {
+                                            "type": "string"
+                                        },
+                                        "type": "array"
+                                    }
+                                },
+                                "type": "object"
                             }
                         },
                         "type": "object"
@@ -1321,23 +3456,107 @@ var schemas = `
             "type": "object"
         },
         "readRecentStates": {
-            "description": "Returns the state of recently updated assets",
+            "description": "Returns recently written asset states, newest first, within the retention window; supports class, since and state filters and pagination",
             "properties": {
                 "args": {
                     "items": {
                         "properties": {
                             "begin": {
-                                "description": "zero based beginning of range",
+                                "description": "zero based beginning of range, ignored when paginated",
                                 "type": "integer"
                             },
+                            "bookmark": {
+                                "description": "opaque bookmark returned with the previous page, pass it back unchanged to read the next page; an empty bookmark means there are no more results",
+                                "type": "string"
+                            },
+                            "classname": {
+                                "description": "return only states of this asset class",
+                                "type": "string"
+                            },
                             "end": {
-                                "description": "zero based end of range, absence means to end",
+                                "description": "zero based end of range, absence means the newest 40 states, ignored when paginated",
+                                "type": "integer"
+                            },
+                            "filter": {
+                                "description": "Filter asset states",
+                                "properties": {
+                                    "groups": {
+                                        "description": "Nested filters with the same shape as this filter, combined with the select terms according to match",
+                                        "items": {
+                                            "type": "object"
+                                        },
+                                        "type": "array"
+                                    },
+                                    "match": {
+                                        "description": "Defines how to combine the select terms and groups, and, or and not are aliases for all, any and none; missing property always fails match",
+                                        "enum": [
+                                            "n/a",
+                                            "all",
+                                            "any",
+                                            "none",
+                                            "and",
+                                            "or",
+                                            "not"
+                                        ],
+                                        "type": "string"
+                                    },
+                                    "select": {
+                                        "description": "Qualified property names and values match",
+                                        "items": {
+                                            "properties": {
+                                                "op": {
+                                                    "description": "Comparison operator, defaults to equality; numbers compare numerically and strings lexically; exists with value 'false' matches a missing property",
+                                                    "enum": [
+                                                        "eq",
+                                                        "ne",
+                                                        "gt",
+                                                        "gte",
+                                                        "lt",
+                                                        "lte",
+                                                        "in",
+                                                        "exists",
+                                                        "prefix",
+                                                        "regex"
+                                                    ],
+                                                    "type": "string"
+                                                },
+                                                "qprop": {
+                                                    "description": "Qualified property to compare, for example 'asset.assetID'",
+                                                    "type": "string"
+                                                },
+                                                "value": {
+                                                    "description": "Value to be compared",
+                                                    "type": "string"
+                                                },
+                                                "values": {
+                                                    "description": "Values to be compared by the in operator",
+                                                    "items": {
+                                                        "type": "string"
+                                                    },
+                                                    "type": "array"
+                                                }
+                                            },
+                                            "type": "object"
+                                        },
+                                        "type": "array"
+                                    }
+                                },
+                                "type": "object"
+                            },
+                            "pagesize": {
+                                "description": "maximum number of results to return in one page, results are returned in a paged envelope when pagesize or bookmark is passed",
+                                "minimum": 0,
                                 "type": "integer"
+                            },
+                            "since": {
+                                "description": "return only states written at or after this time",
+                                "format": "date-time",
+                                "type": "string"
                             }
                         },
                         "type": "object"
                     },
-                    "maxItems": 0,
+                    "maxItems": 1,
                     "minItems": 0,
                     "type": "array"
                 },
@@ -1378,6 +3597,70 @@ var schemas = `
                                 "description": "This asset has no active alerts",
                                 "type": "boolean"
                             },
+                            "deleted": {
+                                "description": "Marks a history state that records the deletion of the asset, native history only",
+                                "type": "boolean"
+                            },
+                            "diff": {
+                                "description": "The properties that an asset state added, removed and changed, values of private properties are never recorded",
+                                "properties": {
+                                    "added": {
+                                        "items": {
+                                            "properties": {
+                                                "new": {
+                                                    "description": "the new value, absent when removed or private"
+                                                },
+                                                "old": {
+                                                    "description": "the prior value, absent when added or private"
+                                                },
+                                                "path": {
+                                                    "description": "property path relative to asset state",
+                                                    "type": "string"
+                                                }
+                                            },
+                                            "type": "object"
+                                        },
+                                        "type": "array"
+                                    },
+                                    "changed": {
+                                        "items": {
+                                            "properties": {
+                                                "new": {
+                                                    "description": "the new value, absent when removed or private"
+                                                },
+                                                "old": {
+                                                    "description": "the prior value, absent when added or private"
+                                                },
+                                                "path": {
+                                                    "description": "property path relative to asset state",
+                                                    "type": "string"
+                                                }
+                                            },
+                                            "type": "object"
+                                        },
+                                        "type": "array"
+                                    },
+                                    "removed": {
+                                        "items": {
+                                            "properties": {
+                                                "new": {
+                                                    "description": "the new value, absent when removed or private"
+                                                },
+                                                "old": {
+                                                    "description": "the prior value, absent when added or private"
+                                                },
+                                                "path": {
+                                                    "description": "property path relative to asset state",
+                                                    "type": "string"
+                                                }
+                                            },
+                                            "type": "object"
+                                        },
+                                        "type": "array"
+                                    }
+                                },
+                                "type": "object"
+                            },
                             "eventin": {
                                 "description": "The contract event that created this state, for example updateAsset",
                                 "properties": {
@@ -1455,9 +3738,8 @@ var schemas = `
                                         "properties": {
                                             "name": {
                                                 "default": "EVT.IOTCP.INVOKE.RESULT",
-                                                "enum": [
-                                                    "EVT.IOTCP.INVOKE.RESULT"
-                                                ],
+                                                "description": "EVT.IOTCP.INVOKE.RESULT, followed by the declared names of the invoke's events separated by colons when the contract turns the event name suffix on, e.g. EVT.IOTCP.INVOKE.RESULT:KIT.OUTOFAREA",
+                                                "pattern": "^EVT\\.IOTCP\\.INVOKE\\.RESULT(:.+)?$",
                                                 "type": "string"
                                             },
                                             "payload": {
@@ -1489,6 +3771,152 @@ var schemas = `
                                                             },
                                                             "type": "array"
                                                         },
+                                                        "events": {
+                                                            "items": {
+                                                                "description": "A typed event raised by an invoke, one per asset written or deleted and one per alert raised or cleared",
+                                                                "properties": {
+                                                                    "alertsCleared": {
+                                                                        "description": "An array of alert names",
+                                                                        "items": {
+                                                                            "description": "An alert name",
+                                                                            "type": "string"
+                                                                        },
+                                                                        "type": "array"
+                                                                    },
+                                                                    "alertsRaised": {
+                                                                        "description": "An array of alert names",
+                                                                        "items": {
+                                                                            "description": "An alert name",
+                                                                            "type": "string"
+                                                                        },
+                                                                        "type": "array"
+                                                                    },
+                                                                    "assetkey": {
+                                                                        "asset": {
+                                                                            "properties": {
+                                                                                "assetID": {
+                                                                                    "description": "An asset's unique ID, e.g. barcode, VIN, etc.",
+                                                                                    "type": "string"
+                                                                                }
+                                                                            },
+                                                                            "type": "object"
+                                                                        }
+                                                                    },
+                                                                    "changed": {
+                                                                        "description": "property paths of the asset state that the write changed",
+                                                                        "items": {
+                                                                            "type": "string"
+                                                                        },
+                                                                        "type": "array"
+                                                                    },
+                                                                    "class": {
+                                                                        "type": "string"
+                                                                    },
+                                                                    "diff": {
+                                                                        "description": "The properties that an asset state added, removed and changed, values of private properties are never recorded",
+                                                                        "properties": {
+                                                                            "added": {
+                                                                                "items": {
+                                                                                    "properties": {
+                                                                                        "new": {
+                                                                                            "description": "the new value, absent when removed or private"
+                                                                                        },
+                                                                                        "old": {
+                                                                                            "description": "the prior value, absent when added or private"
+                                                                                        },
+                                                                                        "path": {
+                                                                                            "description": "property path relative to asset state",
+                                                                                            "type": "string"
+                                                                                        }
+                                                                                    },
+                                                                                    "type": "object"
+                                                                                },
+                                                                                "type": "array"
+                                                                            },
+                                                                            "changed": {
+                                                                                "items": {
+                                                                                    "properties": {
+                                                                                        "new": {
+                                                                                            "description": "the new value, absent when removed or private"
+                                                                                        },
+                                                                                        "old": {
+                                                                                            "description": "the prior value, absent when added or private"
+                                                                                        },
+                                                                                        "path": {
+                                                                                            "description": "property path relative to asset state",
+                                                                                            "type": "string"
+                                                                                        }
+                                                                                    },
+                                                                                    "type": "object"
+                                                                                },
+                                                                                "type": "array"
+                                                                            },
+                                                                            "removed": {
+                                                                                "items": {
+                                                                                    "properties": {
+                                                                                        "new": {
+                                                                                            "description": "the new value, absent when removed or private"
+                                                                                        },
+                                                                                        "old": {
+                                                                                            "description": "the prior value, absent when added or private"
+                                                                                        },
+                                                                                        "path": {
+                                                                                            "description": "property path relative to asset state",
+                                                                                            "type": "string"
+                                                                                        }
+                                                                                    },
+                                                                                    "type": "object"
+                                                                                },
+                                                                                "type": "array"
+                                                                            }
+                                                                        },
+                                                                        "type": "object"
+                                                                    },
+                                                                    "name": {
+                                                                        "description": "the event name declared by the contract for the class or alert, also appended to the chaincode event name",
+                                                                        "type": "string"
+                                                                    },
+                                                                    "transition": {
+                                                                        "description": "The lifecycle transition made by an asset state",
+                                                                        "properties": {
+                                                                            "from": {
+                                                                                "type": "string"
+                                                                            },
+                                                                            "to": {
+                                                                                "type": "string"
+                                                                            },
+                                                                            "trigger": {
+                                                                                "description": "The function that made the transition",
+                                                                                "type": "string"
+                                                                            }
+                                                                        },
+                                                                        "type": "object"
+                                                                    },
+                                                                    "txnid": {
+                                                                        "type": "string"
+                                                                    },
+                                                                    "type": {
+                                                                        "enum": [
+                                                                            "asset.created",
+                                                                            "asset.updated",
+                                                                            "asset.deleted",
+                                                                            "alert.raised",
+                                                                            "alert.cleared",
+                                                                            "zone.entered",
+                                                                            "zone.exited",
+                                                                            "history.archived"
+                                                                        ],
+                                                                        "type": "string"
+                                                                    },
+                                                                    "zone": {
+                                                                        "description": "the zone of a zone event",
+                                                                        "type": "string"
+                                                                    }
+                                                                },
+                                                                "type": "object"
+                                                            },
+                                                            "type": "array"
+                                                        },
                                                         "invokeresult": {
                                                             "description": "status: OK==txn succeeded, ERROR==txn failed",
                                                             "properties": {
@@ -1504,6 +3932,17 @@ var schemas = `
                                                                 }
                                                             },
                                                             "type": "object"
+                                                        },
+                                                        "names": {
+                                                            "description": "the distinct declared names of the events, sorted",
+                                                            "items": {
+                                                                "type": "string"
+                                                            },
+                                                            "type": "array"
+                                                        },
+                                                        "txnid": {
+                                                            "description": "the transaction that emitted the event",
+                                                            "type": "string"
                                                         }
                                                     },
                                                     "type": "object"
@@ -1516,6 +3955,10 @@ var schemas = `
                                 },
                                 "type": "object"
                             },
+                            "rulesversion": {
+                                "description": "Version of the declarative rule set that judged this state",
+                                "type": "integer"
+                            },
                             "state": {
                                 "description": "Properties that have been received or calculated for this asset",
                                 "properties": {
@@ -1585,6 +4028,22 @@ var schemas = `
                                 },
                                 "type": "object"
                             },
+                            "transition": {
+                                "description": "The lifecycle transition made by an asset state",
+                                "properties": {
+                                    "from": {
+                                        "type": "string"
+                                    },
+                                    "to": {
+                                        "type": "string"
+                                    },
+                                    "trigger": {
+                                        "description": "The function that made the transition",
+                                        "type": "string"
+                                    }
+                                },
+                                "type": "object"
+                            },
                             "txnid": {
                                 "description": "Transaction UUID matching the blockchain",
                                 "type": "string"
@@ -1592,6 +4051,40 @@ var schemas = `
                             "txnts": {
                                 "description": "Transaction timestamp matching the blockchain",
                                 "type": "string"
+                            },
+                            "zones": {
+                                "description": "The attached zones that hold an asset's location, and those that its move from the prior location entered and exited",
+                                "properties": {
+                                    "alerts": {
+                                        "description": "zone alerts that this state holds raised, cleared on the next write once their zones are detached or deleted",
+                                        "items": {
+                                            "type": "string"
+                                        },
+                                        "type": "array"
+                                    },
+                                    "entered": {
+                                        "description": "zones entered by this write",
+                                        "items": {
+                                            "type": "string"
+                                        },
+                                        "type": "array"
+                                    },
+                                    "exited": {
+                                        "description": "zones exited by this write",
+                                        "items": {
+                                            "type": "string"
+                                        },
+                                        "type": "array"
+                                    },
+                                    "inside": {
+                                        "description": "zones holding the location",
+                                        "items": {
+                                            "type": "string"
+                                        },
+                                        "type": "array"
+                                    }
+                                },
+                                "type": "object"
                             }
                         },
                         "type": "object"
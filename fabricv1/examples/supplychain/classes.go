@@ -0,0 +1,69 @@
+// Code generated by processSchema.go from generate.json. DO NOT EDIT.
+// Change the classes section of generate.json and run go generate instead.
+
+package main
+
+import (
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	iot "github.com/ibm-watson-iot/blockchain-samples/fabricv1/platform"
+)
+
+// ContainerClass is the asset class for container, its asset keys start with CON
+var ContainerClass = iot.AssetClass{
+	Name:        "container",
+	Prefix:      "CON",
+	AssetIDPath: "container.barcode",
+}
+
+var createAssetContainer iot.ChaincodeFunc = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	return ContainerClass.CreateAsset(stub, args, "createAssetContainer", []iot.QPropNV{})
+}
+
+var replaceAssetContainer iot.ChaincodeFunc = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	return ContainerClass.ReplaceAsset(stub, args, "replaceAssetContainer", []iot.QPropNV{})
+}
+
+var updateAssetContainer iot.ChaincodeFunc = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	return ContainerClass.UpdateAsset(stub, args, "updateAssetContainer", []iot.QPropNV{})
+}
+
+var deleteAssetContainer iot.ChaincodeFunc = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	return ContainerClass.DeleteAsset(stub, args)
+}
+
+var deleteAssetStateHistoryContainer iot.ChaincodeFunc = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	return ContainerClass.DeleteAssetStateHistory(stub, args)
+}
+
+var deleteAllAssetsContainer iot.ChaincodeFunc = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	return ContainerClass.DeleteAllAssets(stub, args)
+}
+
+var deletePropertiesFromAssetContainer iot.ChaincodeFunc = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	return ContainerClass.DeletePropertiesFromAsset(stub, args, "deletePropertiesFromAssetContainer", []iot.QPropNV{})
+}
+
+var readAssetContainer iot.ChaincodeFunc = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	return ContainerClass.ReadAsset(stub, args)
+}
+
+var readAssetStateHistoryContainer iot.ChaincodeFunc = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	return ContainerClass.ReadAssetStateHistory(stub, args)
+}
+
+var readAllAssetsContainer iot.ChaincodeFunc = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	return ContainerClass.ReadAllAssets(stub, args)
+}
+
+func init() {
+	iot.AddRoute("createAssetContainer", "invoke", ContainerClass, createAssetContainer)
+	iot.AddRoute("replaceAssetContainer", "invoke", ContainerClass, replaceAssetContainer)
+	iot.AddRoute("updateAssetContainer", "invoke", ContainerClass, updateAssetContainer)
+	iot.AddRoute("deleteAssetContainer", "invoke", ContainerClass, deleteAssetContainer)
+	iot.AddRoute("deleteAssetStateHistoryContainer", "invoke", ContainerClass, deleteAssetStateHistoryContainer)
+	iot.AddRoute("deleteAllAssetsContainer", "invoke", ContainerClass, deleteAllAssetsContainer)
+	iot.AddRoute("deletePropertiesFromAssetContainer", "invoke", ContainerClass, deletePropertiesFromAssetContainer)
+	iot.AddRoute("readAssetContainer", "query", ContainerClass, readAssetContainer)
+	iot.AddRoute("readAssetStateHistoryContainer", "query", ContainerClass, readAssetStateHistoryContainer)
+	iot.AddRoute("readAllAssetsContainer", "query", ContainerClass, readAllAssetsContainer)
+}
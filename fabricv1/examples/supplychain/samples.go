@@ -3,7 +3,7 @@ package main
 
 	import (
 		"github.com/hyperledger/fabric/core/chaincode/shim"
-		iot "github.com/ibm-watson-iot/blockchain-samples/contracts/platform/iotcontractplatform"
+		iot "github.com/ibm-watson-iot/blockchain-samples/fabricv1/platform"
 )
 
 var samples = `
@@ -3,7 +3,7 @@ package main
 
 	import (
 		"github.com/hyperledger/fabric/core/chaincode/shim"
-		iot "github.com/ibm-watson-iot/blockchain-samples/contracts/platform/iotcontractplatform"
+		iot "github.com/ibm-watson-iot/blockchain-samples/fabricv1/platform"
 )
 
 var schemas = `
@@ -105,13 +105,23 @@ var schemas = `
                             "filter": {
                                 "description": "Filter asset states",
                                 "properties": {
+                                    "groups": {
+                                        "description": "Nested filters with the same shape as this filter, combined with the select terms according to match",
+                                        "items": {
+                                            "type": "object"
+                                        },
+                                        "type": "array"
+                                    },
                                     "match": {
-                                        "description": "Defines how to match properties, missing property always fails match",
+                                        "description": "Defines how to combine the select terms and groups, and, or and not are aliases for all, any and none; missing property always fails match",
                                         "enum": [
                                             "n/a",
                                             "all",
                                             "any",
-                                            "none"
+                                            "none",
+                                            "and",
+                                            "or",
+                                            "not"
                                         ],
                                         "type": "string"
                                     },
@@ -119,6 +129,22 @@ var schemas = `
                                         "description": "Qualified property names and values match",
                                         "items": {
                                             "properties": {
+                                                "op": {
+                                                    "description": "Comparison operator, defaults to equality; numbers compare numerically and strings lexically; exists with value 'false' matches a missing property",
+                                                    "enum": [
+                                                        "eq",
+                                                        "ne",
+                                                        "gt",
+                                                        "gte",
+                                                        "lt",
+                                                        "lte",
+                                                        "in",
+                                                        "exists",
+                                                        "prefix",
+                                                        "regex"
+                                                    ],
+                                                    "type": "string"
+                                                },
                                                 "qprop": {
                                                     "description": "Qualified property to compare, for example 'asset.assetID'",
                                                     "type": "string"
@@ -126,6 +152,13 @@ var schemas = `
                                                 "value": {
                                                     "description": "Value to be compared",
                                                     "type": "string"
+                                                },
+                                                "values": {
+                                                    "description": "Values to be compared by the in operator",
+                                                    "items": {
+                                                        "type": "string"
+                                                    },
+                                                    "type": "array"
                                                 }
                                             },
                                             "type": "object"
@@ -275,11 +308,47 @@ var schemas = `
             "type": "object"
         },
         "initContract": {
-            "description": "Sets contract version and nickname",
+            "description": "Sets contract version and nickname, and the admin access policy",
             "properties": {
                 "args": {
                     "items": {
                         "properties": {
+                            "admin": {
+                                "description": "What a caller needs in order to call a route; each list that is present must be satisfied by one entry, and every attribute must be present with the given value or with any value when the value is empty",
+                                "properties": {
+                                    "attributes": {
+                                        "description": "Certificate attributes and their required values",
+                                        "patternProperties": {
+                                            "^.*$": {
+                                                "type": "string"
+                                            }
+                                        },
+                                        "type": "object"
+                                    },
+                                    "mspids": {
+                                        "description": "MSP IDs allowed to call the route",
+                                        "items": {
+                                            "type": "string"
+                                        },
+                                        "type": "array"
+                                    },
+                                    "ous": {
+                                        "description": "Organizational units from the caller's certificate subject",
+                                        "items": {
+                                            "type": "string"
+                                        },
+                                        "type": "array"
+                                    },
+                                    "roles": {
+                                        "description": "Matched against the caller's role attribute or OUs",
+                                        "items": {
+                                            "type": "string"
+                                        },
+                                        "type": "array"
+                                    }
+                                },
+                                "type": "object"
+                            },
                             "nickname": {
                                 "default": "IOT Contract Platform",
                                 "description": "The nickname of the current contract instance",
@@ -312,16 +381,30 @@ var schemas = `
                 "args": {
                     "items": {
                         "properties": {
+                            "bookmark": {
+                                "description": "opaque bookmark returned with the previous page, pass it back unchanged to read the next page; an empty bookmark means there are no more results",
+                                "type": "string"
+                            },
                             "filter": {
                                 "description": "Filter asset states",
                                 "properties": {
+                                    "groups": {
+                                        "description": "Nested filters with the same shape as this filter, combined with the select terms according to match",
+                                        "items": {
+                                            "type": "object"
+                                        },
+                                        "type": "array"
+                                    },
                                     "match": {
-                                        "description": "Defines how to match properties, missing property always fails match",
+                                        "description": "Defines how to combine the select terms and groups, and, or and not are aliases for all, any and none; missing property always fails match",
                                         "enum": [
                                             "n/a",
                                             "all",
                                             "any",
-                                            "none"
+                                            "none",
+                                            "and",
+                                            "or",
+                                            "not"
                                         ],
                                         "type": "string"
                                     },
@@ -329,6 +412,22 @@ var schemas = `
                                         "description": "Qualified property names and values match",
                                         "items": {
                                             "properties": {
+                                                "op": {
+                                                    "description": "Comparison operator, defaults to equality; numbers compare numerically and strings lexically; exists with value 'false' matches a missing property",
+                                                    "enum": [
+                                                        "eq",
+                                                        "ne",
+                                                        "gt",
+                                                        "gte",
+                                                        "lt",
+                                                        "lte",
+                                                        "in",
+                                                        "exists",
+                                                        "prefix",
+                                                        "regex"
+                                                    ],
+                                                    "type": "string"
+                                                },
                                                 "qprop": {
                                                     "description": "Qualified property to compare, for example 'asset.assetID'",
                                                     "type": "string"
@@ -336,6 +435,13 @@ var schemas = `
                                                 "value": {
                                                     "description": "Value to be compared",
                                                     "type": "string"
+                                                },
+                                                "values": {
+                                                    "description": "Values to be compared by the in operator",
+                                                    "items": {
+                                                        "type": "string"
+                                                    },
+                                                    "type": "array"
                                                 }
                                             },
                                             "type": "object"
@@ -344,6 +450,11 @@ var schemas = `
                                     }
                                 },
                                 "type": "object"
+                            },
+                            "pagesize": {
+                                "description": "maximum number of results to return in one page, results are returned in a paged envelope when pagesize or bookmark is passed",
+                                "minimum": 0,
+                                "type": "integer"
                             }
                         },
                         "type": "object"
@@ -359,12 +470,842 @@ var schemas = `
                     "type": "string"
                 },
                 "method": "query",
+                "pagedresult": {
+                    "description": "returned instead of result when pagesize or bookmark is passed",
+                    "properties": {
+                        "bookmark": {
+                            "description": "opaque bookmark returned with the previous page, pass it back unchanged to read the next page; an empty bookmark means there are no more results",
+                            "type": "string"
+                        },
+                        "count": {
+                            "description": "number of results in this page",
+                            "type": "integer"
+                        },
+                        "results": {
+                            "description": "Array of container states, can mix asset classes",
+                            "items": {
+                                "patternProperties": {
+                                    "^CON": {
+                                        "description": "A container's complete state",
+                                        "properties": {
+                                            "AssetKey": {
+                                                "description": "This container's world state container ID",
+                                                "type": "string"
+                                            },
+                                            "alerts": {
+                                                "description": "An array of alert names",
+                                                "items": {
+                                                    "description": "An alert name",
+                                                    "type": "string"
+                                                },
+                                                "type": "array"
+                                            },
+                                            "assetIDpath": {
+                                                "description": "Qualified property path to the container's ID, declared in the contract code",
+                                                "type": "string"
+                                            },
+                                            "class": {
+                                                "description": "The container's asset class",
+                                                "type": "string"
+                                            },
+                                            "compliant": {
+                                                "description": "This container has no active alerts",
+                                                "type": "boolean"
+                                            },
+                                            "eventin": {
+                                                "description": "The contract event that created this state, for example updateAssetContainer",
+                                                "properties": {
+                                                    "container": {
+                                                        "description": "The changeable properties for a container, also considered its 'event' as a partial state",
+                                                        "properties": {
+                                                            "barcode": {
+                                                                "description": "A container's ID",
+                                                                "type": "string"
+                                                            },
+                                                            "carrier": {
+                                                                "description": "The carrier in possession of this container",
+                                                                "type": "string"
+                                                            },
+                                                            "common": {
+                                                                "description": "Common properties for all assets",
+                                                                "properties": {
+                                                                    "appdata": {
+                                                                        "description": "Application managed information as an array of key:value pairs",
+                                                                        "items": {
+                                                                            "properties": {
+                                                                                "K": {
+                                                                                    "type": "string"
+                                                                                },
+                                                                                "V": {
+                                                                                    "type": "string"
+                                                                                }
+                                                                            },
+                                                                            "type": "object"
+                                                                        },
+                                                                        "minItems": 0,
+                                                                        "type": "array"
+                                                                    },
+                                                                    "deviceID": {
+                                                                        "description": "A unique identifier for the device that sent the current event",
+                                                                        "type": "string"
+                                                                    },
+                                                                    "devicetimestamp": {
+                                                                        "description": "A timestamp recoded by the device that sent the current event",
+                                                                        "type": "string"
+                                                                    },
+                                                                    "location": {
+                                                                        "description": "A geographical coordinate",
+                                                                        "properties": {
+                                                                            "latitude": {
+                                                                                "type": "number"
+                                                                            },
+                                                                            "longitude": {
+                                                                                "type": "number"
+                                                                            }
+                                                                        },
+                                                                        "type": "object"
+                                                                    }
+                                                                },
+                                                                "type": "object"
+                                                            },
+                                                            "temperature": {
+                                                                "description": "Temperature of a container's contents in degrees Celsuis",
+                                                                "type": "number"
+                                                            }
+                                                        },
+                                                        "required": [
+                                                            "barcode"
+                                                        ],
+                                                        "type": "object"
+                                                    }
+                                                },
+                                                "type": "object"
+                                            },
+                                            "eventout": {
+                                                "description": "The chaincode event emitted on invoke exit, if any",
+                                                "properties": {
+                                                    "container": {
+                                                        "description": "A chaincode event defining the standard platform-generated result event for a contract invoke, contains an array of contributed results",
+                                                        "properties": {
+                                                            "name": {
+                                                                "default": "EVT.IOTCP.INVOKE.RESULT",
+                                                                "description": "EVT.IOTCP.INVOKE.RESULT, followed by the declared names of the invoke's events separated by colons when the contract turns the event name suffix on, e.g. EVT.IOTCP.INVOKE.RESULT:KIT.OUTOFAREA",
+                                                                "pattern": "^EVT\\.IOTCP\\.INVOKE\\.RESULT(:.+)?$",
+                                                                "type": "string"
+                                                            },
+                                                            "payload": {
+                                                                "description": "A map of contributed results",
+                                                                "properties": {
+                                                                    "description": "the overall status of the invoke result, defined by err",
+                                                                    "properties": {
+                                                                        "activeAlerts": {
+                                                                            "description": "An array of alert names",
+                                                                            "items": {
+                                                                                "description": "An alert name",
+                                                                                "type": "string"
+                                                                            },
+                                                                            "type": "array"
+                                                                        },
+                                                                        "alertsCleared": {
+                                                                            "description": "An array of alert names",
+                                                                            "items": {
+                                                                                "description": "An alert name",
+                                                                                "type": "string"
+                                                                            },
+                                                                            "type": "array"
+                                                                        },
+                                                                        "alertsRaised": {
+                                                                            "description": "An array of alert names",
+                                                                            "items": {
+                                                                                "description": "An alert name",
+                                                                                "type": "string"
+                                                                            },
+                                                                            "type": "array"
+                                                                        },
+                                                                        "events": {
+                                                                            "items": {
+                                                                                "description": "A typed event raised by an invoke, one per asset written or deleted and one per alert raised or cleared",
+                                                                                "properties": {
+                                                                                    "alertsCleared": {
+                                                                                        "description": "An array of alert names",
+                                                                                        "items": {
+                                                                                            "description": "An alert name",
+                                                                                            "type": "string"
+                                                                                        },
+                                                                                        "type": "array"
+                                                                                    },
+                                                                                    "alertsRaised": {
+                                                                                        "description": "An array of alert names",
+                                                                                        "items": {
+                                                                                            "description": "An alert name",
+                                                                                            "type": "string"
+                                                                                        },
+                                                                                        "type": "array"
+                                                                                    },
+                                                                                    "assetkey": {
+                                                                                        "asset": {
+                                                                                            "properties": {
+                                                                                                "assetID": {
+                                                                                                    "description": "An asset's unique ID, e.g. barcode, VIN, etc.",
+                                                                                                    "type": "string"
+                                                                                                }
+                                                                                            },
+                                                                                            "type": "object"
+                                                                                        }
+                                                                                    },
+                                                                                    "changed": {
+                                                                                        "description": "property paths of the asset state that the write changed",
+                                                                                        "items": {
+                                                                                            "type": "string"
+                                                                                        },
+                                                                                        "type": "array"
+                                                                                    },
+                                                                                    "class": {
+                                                                                        "type": "string"
+                                                                                    },
+                                                                                    "diff": {
+                                                                                        "description": "The properties that an asset state added, removed and changed, values of private properties are never recorded",
+                                                                                        "properties": {
+                                                                                            "added": {
+                                                                                                "items": {
+                                                                                                    "properties": {
+                                                                                                        "new": {
+                                                                                                            "description": "the new value, absent when removed or private"
+                                                                                                        },
+                                                                                                        "old": {
+                                                                                                            "description": "the prior value, absent when added or private"
+                                                                                                        },
+                                                                                                        "path": {
+                                                                                                            "description": "property path relative to asset state",
+                                                                                                            "type": "string"
+                                                                                                        }
+                                                                                                    },
+                                                                                                    "type": "object"
+                                                                                                },
+                                                                                                "type": "array"
+                                                                                            },
+                                                                                            "changed": {
+                                                                                                "items": {
+                                                                                                    "properties": {
+                                                                                                        "new": {
+                                                                                                            "description": "the new value, absent when removed or private"
+                                                                                                        },
+                                                                                                        "old": {
+                                                                                                            "description": "the prior value, absent when added or private"
+                                                                                                        },
+                                                                                                        "path": {
+                                                                                                            "description": "property path relative to asset state",
+                                                                                                            "type": "string"
+                                                                                                        }
+                                                                                                    },
+                                                                                                    "type": "object"
+                                                                                                },
+                                                                                                "type": "array"
+                                                                                            },
+                                                                                            "removed": {
+                                                                                                "items": {
+                                                                                                    "properties": {
+                                                                                                        "new": {
+                                                                                                            "description": "the new value, absent when removed or private"
+                                                                                                        },
+                                                                                                        "old": {
+                                                                                                            "description": "the prior value, absent when added or private"
+                                                                                                        },
+                                                                                                        "path": {
+                                                                                                            "description": "property path relative to asset state",
+                                                                                                            "type": "string"
+                                                                                                        }
+                                                                                                    },
+                                                                                                    "type": "object"
+                                                                                                },
+                                                                                                "type": "array"
+                                                                                            }
+                                                                                        },
+                                                                                        "type": "object"
+                                                                                    },
+                                                                                    "name": {
+                                                                                        "description": "the event name declared by the contract for the class or alert, also appended to the chaincode event name",
+                                                                                        "type": "string"
+                                                                                    },
+                                                                                    "transition": {
+                                                                                        "description": "The lifecycle transition made by an asset state",
+                                                                                        "properties": {
+                                                                                            "from": {
+                                                                                                "type": "string"
+                                                                                            },
+                                                                                            "to": {
+                                                                                                "type": "string"
+                                                                                            },
+                                                                                            "trigger": {
+                                                                                                "description": "The function that made the transition",
+                                                                                                "type": "string"
+                                                                                            }
+                                                                                        },
+                                                                                        "type": "object"
+                                                                                    },
+                                                                                    "txnid": {
+                                                                                        "type": "string"
+                                                                                    },
+                                                                                    "type": {
+                                                                                        "enum": [
+                                                                                            "asset.created",
+                                                                                            "asset.updated",
+                                                                                            "asset.deleted",
+                                                                                            "alert.raised",
+                                                                                            "alert.cleared",
+                                                                                            "zone.entered",
+                                                                                            "zone.exited",
+                                                                                            "history.archived"
+                                                                                        ],
+                                                                                        "type": "string"
+                                                                                    },
+                                                                                    "zone": {
+                                                                                        "description": "the zone of a zone event",
+                                                                                        "type": "string"
+                                                                                    }
+                                                                                },
+                                                                                "type": "object"
+                                                                            },
+                                                                            "type": "array"
+                                                                        },
+                                                                        "invokeresult": {
+                                                                            "description": "status: OK==txn succeeded, ERROR==txn failed",
+                                                                            "properties": {
+                                                                                "message": {
+                                                                                    "type": "string"
+                                                                                },
+                                                                                "status": {
+                                                                                    "enum": [
+                                                                                        "OK",
+                                                                                        "ERROR"
+                                                                                    ],
+                                                                                    "type": "string"
+                                                                                }
+                                                                            },
+                                                                            "type": "object"
+                                                                        },
+                                                                        "names": {
+                                                                            "description": "the distinct declared names of the events, sorted",
+                                                                            "items": {
+                                                                                "type": "string"
+                                                                            },
+                                                                            "type": "array"
+                                                                        },
+                                                                        "txnid": {
+                                                                            "description": "the transaction that emitted the event",
+                                                                            "type": "string"
+                                                                        }
+                                                                    },
+                                                                    "type": "object"
+                                                                },
+                                                                "type": "object"
+                                                            }
+                                                        },
+                                                        "type": "object"
+                                                    }
+                                                },
+                                                "type": "object"
+                                            },
+                                            "prefix": {
+                                                "description": "The container's asset class prefix in world state",
+                                                "type": "string"
+                                            },
+                                            "state": {
+                                                "description": "Properties that have been received or calculated for this container",
+                                                "properties": {
+                                                    "container": {
+                                                        "description": "The changeable properties for a container, also considered its 'event' as a partial state",
+                                                        "properties": {
+                                                            "barcode": {
+                                                                "description": "A container's ID",
+                                                                "type": "string"
+                                                            },
+                                                            "carrier": {
+                                                                "description": "The carrier in possession of this container",
+                                                                "type": "string"
+                                                            },
+                                                            "common": {
+                                                                "description": "Common properties for all assets",
+                                                                "properties": {
+                                                                    "appdata": {
+                                                                        "description": "Application managed information as an array of key:value pairs",
+                                                                        "items": {
+                                                                            "properties": {
+                                                                                "K": {
+                                                                                    "type": "string"
+                                                                                },
+                                                                                "V": {
+                                                                                    "type": "string"
+                                                                                }
+                                                                            },
+                                                                            "type": "object"
+                                                                        },
+                                                                        "minItems": 0,
+                                                                        "type": "array"
+                                                                    },
+                                                                    "deviceID": {
+                                                                        "description": "A unique identifier for the device that sent the current event",
+                                                                        "type": "string"
+                                                                    },
+                                                                    "devicetimestamp": {
+                                                                        "description": "A timestamp recoded by the device that sent the current event",
+                                                                        "type": "string"
+                                                                    },
+                                                                    "location": {
+                                                                        "description": "A geographical coordinate",
+                                                                        "properties": {
+                                                                            "latitude": {
+                                                                                "type": "number"
+                                                                            },
+                                                                            "longitude": {
+                                                                                "type": "number"
+                                                                            }
+                                                                        },
+                                                                        "type": "object"
+                                                                    }
+                                                                },
+                                                                "type": "object"
+                                                            },
+                                                            "temperature": {
+                                                                "description": "Temperature of a container's contents in degrees Celsuis",
+                                                                "type": "number"
+                                                            }
+                                                        },
+                                                        "required": [
+                                                            "barcode"
+                                                        ],
+                                                        "type": "object"
+                                                    }
+                                                },
+                                                "type": "object"
+                                            },
+                                            "txnid": {
+                                                "description": "Transaction UUID matching the blockchain",
+                                                "type": "string"
+                                            },
+                                            "txnts": {
+                                                "description": "Transaction timestamp matching the blockchain",
+                                                "type": "string"
+                                            }
+                                        },
+                                        "type": "object"
+                                    }
+                                },
+                                "type": "object"
+                            },
+                            "minItems": 0,
+                            "type": "array"
+                        }
+                    },
+                    "type": "object"
+                },
                 "result": {
                     "description": "Array of container states, can mix asset classes",
                     "items": {
                         "patternProperties": {
                             "^CON": {
-                                "description": "The external state of one container asset, named by its world state ID",
+                                "description": "A container's complete state",
+                                "properties": {
+                                    "AssetKey": {
+                                        "description": "This container's world state container ID",
+                                        "type": "string"
+                                    },
+                                    "alerts": {
+                                        "description": "An array of alert names",
+                                        "items": {
+                                            "description": "An alert name",
+                                            "type": "string"
+                                        },
+                                        "type": "array"
+                                    },
+                                    "assetIDpath": {
+                                        "description": "Qualified property path to the container's ID, declared in the contract code",
+                                        "type": "string"
+                                    },
+                                    "class": {
+                                        "description": "The container's asset class",
+                                        "type": "string"
+                                    },
+                                    "compliant": {
+                                        "description": "This container has no active alerts",
+                                        "type": "boolean"
+                                    },
+                                    "eventin": {
+                                        "description": "The contract event that created this state, for example updateAssetContainer",
+                                        "properties": {
+                                            "container": {
+                                                "description": "The changeable properties for a container, also considered its 'event' as a partial state",
+                                                "properties": {
+                                                    "barcode": {
+                                                        "description": "A container's ID",
+                                                        "type": "string"
+                                                    },
+                                                    "carrier": {
+                                                        "description": "The carrier in possession of this container",
+                                                        "type": "string"
+                                                    },
+                                                    "common": {
+                                                        "description": "Common properties for all assets",
+                                                        "properties": {
+                                                            "appdata": {
+                                                                "description": "Application managed information as an array of key:value pairs",
+                                                                "items": {
+                                                                    "properties": {
+                                                                        "K": {
+                                                                            "type": "string"
+                                                                        },
+                                                                        "V": {
+                                                                            "type": "string"
+                                                                        }
+                                                                    },
+                                                                    "type": "object"
+                                                                },
+                                                                "minItems": 0,
+                                                                "type": "array"
+                                                            },
+                                                            "deviceID": {
+                                                                "description": "A unique identifier for the device that sent the current event",
+                                                                "type": "string"
+                                                            },
+                                                            "devicetimestamp": {
+                                                                "description": "A timestamp recoded by the device that sent the current event",
+                                                                "type": "string"
+                                                            },
+                                                            "location": {
+                                                                "description": "A geographical coordinate",
+                                                                "properties": {
+                                                                    "latitude": {
+                                                                        "type": "number"
+                                                                    },
+                                                                    "longitude": {
+                                                                        "type": "number"
+                                                                    }
+                                                                },
+                                                                "type": "object"
+                                                            }
+                                                        },
+                                                        "type": "object"
+                                                    },
+                                                    "temperature": {
+                                                        "description": "Temperature of a container's contents in degrees Celsuis",
+                                                        "type": "number"
+                                                    }
+                                                },
+                                                "required": [
+                                                    "barcode"
+                                                ],
+                                                "type": "object"
+                                            }
+                                        },
+                                        "type": "object"
+                                    },
+                                    "eventout": {
+                                        "description": "The chaincode event emitted on invoke exit, if any",
+                                        "properties": {
+                                            "container": {
+                                                "description": "A chaincode event defining the standard platform-generated result event for a contract invoke, contains an array of contributed results",
+                                                "properties": {
+                                                    "name": {
+                                                        "default": "EVT.IOTCP.INVOKE.RESULT",
+                                                        "description": "EVT.IOTCP.INVOKE.RESULT, followed by the declared names of the invoke's events separated by colons when the contract turns the event name suffix on, e.g. EVT.IOTCP.INVOKE.RESULT:KIT.OUTOFAREA",
+                                                        "pattern": "^EVT\\.IOTCP\\.INVOKE\\.RESULT(:.+)?$",
+                                                        "type": "string"
+                                                    },
+                                                    "payload": {
+                                                        "description": "A map of contributed results",
+                                                        "properties": {
+                                                            "description": "the overall status of the invoke result, defined by err",
+                                                            "properties": {
+                                                                "activeAlerts": {
+                                                                    "description": "An array of alert names",
+                                                                    "items": {
+                                                                        "description": "An alert name",
+                                                                        "type": "string"
+                                                                    },
+                                                                    "type": "array"
+                                                                },
+                                                                "alertsCleared": {
+                                                                    "description": "An array of alert names",
+                                                                    "items": {
+                                                                        "description": "An alert name",
+                                                                        "type": "string"
+                                                                    },
+                                                                    "type": "array"
+                                                                },
+                                                                "alertsRaised": {
+                                                                    "description": "An array of alert names",
+                                                                    "items": {
+                                                                        "description": "An alert name",
+                                                                        "type": "string"
+                                                                    },
+                                                                    "type": "array"
+                                                                },
+                                                                "events": {
+                                                                    "items": {
+                                                                        "description": "A typed event raised by an invoke, one per asset written or deleted and one per alert raised or cleared",
+                                                                        "properties": {
+                                                                            "alertsCleared": {
+                                                                                "description": "An array of alert names",
+                                                                                "items": {
+                                                                                    "description": "An alert name",
+                                                                                    "type": "string"
+                                                                                },
+                                                                                "type": "array"
+                                                                            },
+                                                                            "alertsRaised": {
+                                                                                "description": "An array of alert names",
+                                                                                "items": {
+                                                                                    "description": "An alert name",
+                                                                                    "type": "string"
+                                                                                },
+                                                                                "type": "array"
+                                                                            },
+                                                                            "assetkey": {
+                                                                                "asset": {
+                                                                                    "properties": {
+                                                                                        "assetID": {
+                                                                                            "description": "An asset's unique ID, e.g. barcode, VIN, etc.",
+                                                                                            "type": "string"
+                                                                                        }
+                                                                                    },
+                                                                                    "type": "object"
+                                                                                }
+                                                                            },
+                                                                            "changed": {
+                                                                                "description": "property paths of the asset state that the write changed",
+                                                                                "items": {
+                                                                                    "type": "string"
+                                                                                },
+                                                                                "type": "array"
+                                                                            },
+                                                                            "class": {
+                                                                                "type": "string"
+                                                                            },
+                                                                            "diff": {
+                                                                                "description": "The properties that an asset state added, removed and changed, values of private properties are never recorded",
+                                                                                "properties": {
+                                                                                    "added": {
+                                                                                        "items": {
+                                                                                            "properties": {
+                                                                                                "new": {
+                                                                                                    "description": "the new value, absent when removed or private"
+                                                                                                },
+                                                                                                "old": {
+                                                                                                    "description": "the prior value, absent when added or private"
+                                                                                                },
+                                                                                                "path": {
+                                                                                                    "description": "property path relative to asset state",
+                                                                                                    "type": "string"
+                                                                                                }
+                                                                                            },
+                                                                                            "type": "object"
+                                                                                        },
+                                                                                        "type": "array"
+                                                                                    },
+                                                                                    "changed": {
+                                                                                        "items": {
+                                                                                            "properties": {
+                                                                                                "new": {
+                                                                                                    "description": "the new value, absent when removed or private"
+                                                                                                },
+                                                                                                "old": {
+                                                                                                    "description": "the prior value, absent when added or private"
+                                                                                                },
+                                                                                                "path": {
+                                                                                                    "description": "property path relative to asset state",
+                                                                                                    "type": "string"
+                                                                                                }
+                                                                                            },
+                                                                                            "type": "object"
+                                                                                        },
+                                                                                        "type": "array"
+                                                                                    },
+                                                                                    "removed": {
+                                                                                        "items": {
+                                                                                            "properties": {
+                                                                                                "new": {
+                                                                                                    "description": "the new value, absent when removed or private"
+                                                                                                },
+                                                                                                "old": {
+                                                                                                    "description": "the prior value, absent when added or private"
+                                                                                                },
+                                                                                                "path": {
+                                                                                                    "description": "property path relative to asset state",
+                                                                                                    "type": "string"
+                                                                                                }
+                                                                                            },
+                                                                                            "type": "object"
+                                                                                        },
+                                                                                        "type": "array"
+                                                                                    }
+                                                                                },
+                                                                                "type": "object"
+                                                                            },
+                                                                            "name": {
+                                                                                "description": "the event name declared by the contract for the class or alert, also appended to the chaincode event name",
+                                                                                "type": "string"
+                                                                            },
+                                                                            "transition": {
+                                                                                "description": "The lifecycle transition made by an asset state",
+                                                                                "properties": {
+                                                                                    "from": {
+                                                                                        "type": "string"
+                                                                                    },
+                                                                                    "to": {
+                                                                                        "type": "string"
+                                                                                    },
+                                                                                    "trigger": {
+                                                                                        "description": "The function that made the transition",
+                                                                                        "type": "string"
+                                                                                    }
+                                                                                },
+                                                                                "type": "object"
+                                                                            },
+                                                                            "txnid": {
+                                                                                "type": "string"
+                                                                            },
+                                                                            "type": {
+                                                                                "enum": [
+                                                                                    "asset.created",
+                                                                                    "asset.updated",
+                                                                                    "asset.deleted",
+                                                                                    "alert.raised",
+                                                                                    "alert.cleared",
+                                                                                    "zone.entered",
+                                                                                    "zone.exited",
+                                                                                    "history.archived"
+                                                                                ],
+                                                                                "type": "string"
+                                                                            },
+                                                                            "zone": {
+                                                                                "description": "the zone of a zone event",
+                                                                                "type": "string"
+                                                                            }
+                                                                        },
+                                                                        "type": "object"
+                                                                    },
+                                                                    "type": "array"
+                                                                },
+                                                                "invokeresult": {
+                                                                    "description": "status: OK==txn succeeded, ERROR==txn failed",
+                                                                    "properties": {
+                                                                        "message": {
+                                                                            "type": "string"
+                                                                        },
+                                                                        "status": {
+                                                                            "enum": [
+                                                                                "OK",
+                                                                                "ERROR"
+                                                                            ],
+                                                                            "type": "string"
+                                                                        }
+                                                                    },
+                                                                    "type": "object"
+                                                                },
+                                                                "names": {
+                                                                    "description": "the distinct declared names of the events, sorted",
+                                                                    "items": {
+                                                                        "type": "string"
+                                                                    },
+                                                                    "type": "array"
+                                                                },
+                                                                "txnid": {
+                                                                    "description": "the transaction that emitted the event",
+                                                                    "type": "string"
+                                                                }
+                                                            },
+                                                            "type": "object"
+                                                        },
+                                                        "type": "object"
+                                                    }
+                                                },
+                                                "type": "object"
+                                            }
+                                        },
+                                        "type": "object"
+                                    },
+                                    "prefix": {
+                                        "description": "The container's asset class prefix in world state",
+                                        "type": "string"
+                                    },
+                                    "state": {
+                                        "description": "Properties that have been received or calculated for this container",
+                                        "properties": {
+                                            "container": {
+                                                "description": "The changeable properties for a container, also considered its 'event' as a partial state",
+                                                "properties": {
+                                                    "barcode": {
+                                                        "description": "A container's ID",
+                                                        "type": "string"
+                                                    },
+                                                    "carrier": {
+                                                        "description": "The carrier in possession of this container",
+                                                        "type": "string"
+                                                    },
+                                                    "common": {
+                                                        "description": "Common properties for all assets",
+                                                        "properties": {
+                                                            "appdata": {
+                                                                "description": "Application managed information as an array of key:value pairs",
+                                                                "items": {
+                                                                    "properties": {
+                                                                        "K": {
+                                                                            "type": "string"
+                                                                        },
+                                                                        "V": {
+                                                                            "type": "string"
+                                                                        }
+                                                                    },
+                                                                    "type": "object"
+                                                                },
+                                                                "minItems": 0,
+                                                                "type": "array"
+                                                            },
+                                                            "deviceID": {
+                                                                "description": "A unique identifier for the device that sent the current event",
+                                                                "type": "string"
+                                                            },
+                                                            "devicetimestamp": {
+                                                                "description": "A timestamp recoded by the device that sent the current event",
+                                                                "type": "string"
+                                                            },
+                                                            "location": {
+                                                                "description": "A geographical coordinate",
+                                                                "properties": {
+                                                                    "latitude": {
+                                                                        "type": "number"
+                                                                    },
+                                                                    "longitude": {
+                                                                        "type": "number"
+                                                                    }
+                                                                },
+                                                                "type": "object"
+                                                            }
+                                                        },
+                                                        "type": "object"
+                                                    },
+                                                    "temperature": {
+                                                        "description": "Temperature of a container's contents in degrees Celsuis",
+                                                        "type": "number"
+                                                    }
+                                                },
+                                                "required": [
+                                                    "barcode"
+                                                ],
+                                                "type": "object"
+                                            }
+                                        },
+                                        "type": "object"
+                                    },
+                                    "txnid": {
+                                        "description": "Transaction UUID matching the blockchain",
+                                        "type": "string"
+                                    },
+                                    "txnts": {
+                                        "description": "Transaction timestamp matching the blockchain",
+                                        "type": "string"
+                                    }
+                                },
                                 "type": "object"
                             }
                         },
@@ -554,9 +1495,8 @@ var schemas = `
                                     "properties": {
                                         "name": {
                                             "default": "EVT.IOTCP.INVOKE.RESULT",
-                                            "enum": [
-                                                "EVT.IOTCP.INVOKE.RESULT"
-                                            ],
+                                            "description": "EVT.IOTCP.INVOKE.RESULT, followed by the declared names of the invoke's events separated by colons when the contract turns the event name suffix on, e.g. EVT.IOTCP.INVOKE.RESULT:KIT.OUTOFAREA",
+                                            "pattern": "^EVT\\.IOTCP\\.INVOKE\\.RESULT(:.+)?$",
                                             "type": "string"
                                         },
                                         "payload": {
@@ -588,6 +1528,152 @@ var schemas = `
                                                         },
                                                         "type": "array"
                                                     },
+                                                    "events": {
+                                                        "items": {
+                                                            "description": "A typed event raised by an invoke, one per asset written or deleted and one per alert raised or cleared",
+                                                            "properties": {
+                                                                "alertsCleared": {
+                                                                    "description": "An array of alert names",
+                                                                    "items": {
+                                                                        "description": "An alert name",
+                                                                        "type": "string"
+                                                                    },
+                                                                    "type": "array"
+                                                                },
+                                                                "alertsRaised": {
+                                                                    "description": "An array of alert names",
+                                                                    "items": {
+                                                                        "description": "An alert name",
+                                                                        "type": "string"
+                                                                    },
+                                                                    "type": "array"
+                                                                },
+                                                                "assetkey": {
+                                                                    "asset": {
+                                                                        "properties": {
+                                                                            "assetID": {
+                                                                                "description": "An asset's unique ID, e.g. barcode, VIN, etc.",
+                                                                                "type": "string"
+                                                                            }
+                                                                        },
+                                                                        "type": "object"
+                                                                    }
+                                                                },
+                                                                "changed": {
+                                                                    "description": "property paths of the asset state that the write changed",
+                                                                    "items": {
+                                                                        "type": "string"
+                                                                    },
+                                                                    "type": "array"
+                                                                },
+                                                                "class": {
+                                                                    "type": "string"
+                                                                },
+                                                                "diff": {
+                                                                    "description": "The properties that an asset state added, removed and changed, values of private properties are never recorded",
+                                                                    "properties": {
+                                                                        "added": {
+                                                                            "items": {
+                                                                                "properties": {
+                                                                                    "new": {
+                                                                                        "description": "the new value, absent when removed or private"
+                                                                                    },
+                                                                                    "old": {
+                                                                                        "description": "the prior value, absent when added or private"
+                                                                                    },
+                                                                                    "path": {
+                                                                                        "description": "property path relative to asset state",
+                                                                                        "type": "string"
+                                                                                    }
+                                                                                },
+                                                                                "type": "object"
+                                                                            },
+                                                                            "type": "array"
+                                                                        },
+                                                                        "changed": {
+                                                                            "items": {
+                                                                                "properties": {
+                                                                                    "new": {
+                                                                                        "description": "the new value, absent when removed or private"
+                                                                                    },
+                                                                                    "old": {
+                                                                                        "description": "the prior value, absent when added or private"
+                                                                                    },
+                                                                                    "path": {
+                                                                                        "description": "property path relative to asset state",
+                                                                                        "type": "string"
+                                                                                    }
+                                                                                },
+                                                                                "type": "object"
+                                                                            },
+                                                                            "type": "array"
+                                                                        },
+                                                                        "removed": {
+                                                                            "items": {
+                                                                                "properties": {
+                                                                                    "new": {
+                                                                                        "description": "the new value, absent when removed or private"
+                                                                                    },
+                                                                                    "old": {
+                                                                                        "description": "the prior value, absent when added or private"
+                                                                                    },
+                                                                                    "path": {
+                                                                                        "description": "property path relative to asset state",
+                                                                                        "type": "string"
+                                                                                    }
+                                                                                },
+                                                                                "type": "object"
+                                                                            },
+                                                                            "type": "array"
+                                                                        }
+                                                                    },
+                                                                    "type": "object"
+                                                                },
+                                                                "name": {
+                                                                    "description": "the event name declared by the contract for the class or alert, also appended to the chaincode event name",
+                                                                    "type": "string"
+                                                                },
+                                                                "transition": {
+                                                                    "description": "The lifecycle transition made by an asset state",
+                                                                    "properties": {
+                                                                        "from": {
+                                                                            "type": "string"
+                                                                        },
+                                                                        "to": {
+                                                                            "type": "string"
+                                                                        },
+                                                                        "trigger": {
+                                                                            "description": "The function that made the transition",
+                                                                            "type": "string"
+                                                                        }
+                                                                    },
+                                                                    "type": "object"
+                                                                },
+                                                                "txnid": {
+                                                                    "type": "string"
+                                                                },
+                                                                "type": {
+                                                                    "enum": [
+                                                                        "asset.created",
+                                                                        "asset.updated",
+                                                                        "asset.deleted",
+                                                                        "alert.raised",
+                                                                        "alert.cleared",
+                                                                        "zone.entered",
+                                                                        "zone.exited",
+                                                                        "history.archived"
+                                                                    ],
+                                                                    "type": "string"
+                                                                },
+                                                                "zone": {
+                                                                    "description": "the zone of a zone event",
+                                                                    "type": "string"
+                                                                }
+                                                            },
+                                                            "type": "object"
+                                                        },
+                                                        "type": "array"
+                                                    },
                                                     "invokeresult": {
                                                         "description": "status: OK==txn succeeded, ERROR==txn failed",
                                                         "properties": {
@@ -603,6 +1689,17 @@ var schemas = `
                                                             }
                                                         },
                                                         "type": "object"
+                                                    },
+                                                    "names": {
+                                                        "description": "the distinct declared names of the events, sorted",
+                                                        "items": {
+                                                            "type": "string"
+                                                        },
+                                                        "type": "array"
+                                                    },
+                                                    "txnid": {
+                                                        "description": "the transaction that emitted the event",
+                                                        "type": "string"
                                                     }
                                                 },
                                                 "type": "object"
@@ -708,6 +1805,10 @@ var schemas = `
                 "args": {
                     "items": {
                         "properties": {
+                            "bookmark": {
+                                "description": "opaque bookmark returned with the previous page, pass it back unchanged to read the next page; an empty bookmark means there are no more results",
+                                "type": "string"
+                            },
                             "container": {
                                 "properties": {
                                     "barcode": {
@@ -738,13 +1839,23 @@ var schemas = `
                             "filter": {
                                 "description": "Filter asset states",
                                 "properties": {
+                                    "groups": {
+                                        "description": "Nested filters with the same shape as this filter, combined with the select terms according to match",
+                                        "items": {
+                                            "type": "object"
+                                        },
+                                        "type": "array"
+                                    },
                                     "match": {
-                                        "description": "Defines how to match properties, missing property always fails match",
+                                        "description": "Defines how to combine the select terms and groups, and, or and not are aliases for all, any and none; missing property always fails match",
                                         "enum": [
                                             "n/a",
                                             "all",
                                             "any",
-                                            "none"
+                                            "none",
+                                            "and",
+                                            "or",
+                                            "not"
                                         ],
                                         "type": "string"
                                     },
@@ -752,6 +1863,22 @@ var schemas = `
                                         "description": "Qualified property names and values match",
                                         "items": {
                                             "properties": {
+                                                "op": {
+                                                    "description": "Comparison operator, defaults to equality; numbers compare numerically and strings lexically; exists with value 'false' matches a missing property",
+                                                    "enum": [
+                                                        "eq",
+                                                        "ne",
+                                                        "gt",
+                                                        "gte",
+                                                        "lt",
+                                                        "lte",
+                                                        "in",
+                                                        "exists",
+                                                        "prefix",
+                                                        "regex"
+                                                    ],
+                                                    "type": "string"
+                                                },
                                                 "qprop": {
                                                     "description": "Qualified property to compare, for example 'asset.assetID'",
                                                     "type": "string"
@@ -759,6 +1886,13 @@ var schemas = `
                                                 "value": {
                                                     "description": "Value to be compared",
                                                     "type": "string"
+                                                },
+                                                "values": {
+                                                    "description": "Values to be compared by the in operator",
+                                                    "items": {
+                                                        "type": "string"
+                                                    },
+                                                    "type": "array"
                                                 }
                                             },
                                             "type": "object"
@@ -767,6 +1901,11 @@ var schemas = `
                                     }
                                 },
                                 "type": "object"
+                            },
+                            "pagesize": {
+                                "description": "maximum number of results to return in one page, results are returned in a paged envelope when pagesize or bookmark is passed",
+                                "minimum": 0,
+                                "type": "integer"
                             }
                         },
                         "required": [
@@ -785,12 +1924,842 @@ var schemas = `
                     "type": "string"
                 },
                 "method": "query",
+                "pagedresult": {
+                    "description": "returned instead of result when pagesize or bookmark is passed",
+                    "properties": {
+                        "bookmark": {
+                            "description": "opaque bookmark returned with the previous page, pass it back unchanged to read the next page; an empty bookmark means there are no more results",
+                            "type": "string"
+                        },
+                        "count": {
+                            "description": "number of results in this page",
+                            "type": "integer"
+                        },
+                        "results": {
+                            "description": "Array of container states, can mix asset classes",
+                            "items": {
+                                "patternProperties": {
+                                    "^CON": {
+                                        "description": "A container's complete state",
+                                        "properties": {
+                                            "AssetKey": {
+                                                "description": "This container's world state container ID",
+                                                "type": "string"
+                                            },
+                                            "alerts": {
+                                                "description": "An array of alert names",
+                                                "items": {
+                                                    "description": "An alert name",
+                                                    "type": "string"
+                                                },
+                                                "type": "array"
+                                            },
+                                            "assetIDpath": {
+                                                "description": "Qualified property path to the container's ID, declared in the contract code",
+                                                "type": "string"
+                                            },
+                                            "class": {
+                                                "description": "The container's asset class",
+                                                "type": "string"
+                                            },
+                                            "compliant": {
+                                                "description": "This container has no active alerts",
+                                                "type": "boolean"
+                                            },
+                                            "eventin": {
+                                                "description": "The contract event that created this state, for example updateAssetContainer",
+                                                "properties": {
+                                                    "container": {
+                                                        "description": "The changeable properties for a container, also considered its 'event' as a partial state",
+                                                        "properties": {
+                                                            "barcode": {
+                                                                "description": "A container's ID",
+                                                                "type": "string"
+                                                            },
+                                                            "carrier": {
+                                                                "description": "The carrier in possession of this container",
+                                                                "type": "string"
+                                                            },
+                                                            "common": {
+                                                                "description": "Common properties for all assets",
+                                                                "properties": {
+                                                                    "appdata": {
+                                                                        "description": "Application managed information as an array of key:value pairs",
+                                                                        "items": {
+                                                                            "properties": {
+                                                                                "K": {
+                                                                                    "type": "string"
+                                                                                },
+                                                                                "V": {
+                                                                                    "type": "string"
+                                                                                }
+                                                                            },
+                                                                            "type": "object"
+                                                                        },
+                                                                        "minItems": 0,
+                                                                        "type": "array"
+                                                                    },
+                                                                    "deviceID": {
+                                                                        "description": "A unique identifier for the device that sent the current event",
+                                                                        "type": "string"
+                                                                    },
+                                                                    "devicetimestamp": {
+                                                                        "description": "A timestamp recoded by the device that sent the current event",
+                                                                        "type": "string"
+                                                                    },
+                                                                    "location": {
+                                                                        "description": "A geographical coordinate",
+                                                                        "properties": {
+                                                                            "latitude": {
+                                                                                "type": "number"
+                                                                            },
+                                                                            "longitude": {
+                                                                                "type": "number"
+                                                                            }
+                                                                        },
+                                                                        "type": "object"
+                                                                    }
+                                                                },
+                                                                "type": "object"
+                                                            },
+                                                            "temperature": {
+                                                                "description": "Temperature of a container's contents in degrees Celsuis",
+                                                                "type": "number"
+                                                            }
+                                                        },
+                                                        "required": [
+                                                            "barcode"
+                                                        ],
+                                                        "type": "object"
+                                                    }
+                                                },
+                                                "type": "object"
+                                            },
+                                            "eventout": {
+                                                "description": "The chaincode event emitted on invoke exit, if any",
+                                                "properties": {
+                                                    "container": {
+                                                        "description": "A chaincode event defining the standard platform-generated result event for a contract invoke, contains an array of contributed results",
+                                                        "properties": {
+                                                            "name": {
+                                                                "default": "EVT.IOTCP.INVOKE.RESULT",
+                                                                "description": "EVT.IOTCP.INVOKE.RESULT, followed by the declared names of the invoke's events separated by colons when the contract turns the event name suffix on, e.g. EVT.IOTCP.INVOKE.RESULT:KIT.OUTOFAREA",
+                                                                "pattern": "^EVT\\.IOTCP\\.INVOKE\\.RESULT(:.+)?$",
+                                                                "type": "string"
+                                                            },
+                                                            "payload": {
+                                                                "description": "A map of contributed results",
+                                                                "properties": {
+                                                                    "description": "the overall status of the invoke result, defined by err",
+                                                                    "properties": {
+                                                                        "activeAlerts": {
+                                                                            "description": "An array of alert names",
+                                                                            "items": {
+                                                                                "description": "An alert name",
+                                                                                "type": "string"
+                                                                            },
+                                                                            "type": "array"
+                                                                        },
+                                                                        "alertsCleared": {
+                                                                            "description": "An array of alert names",
+                                                                            "items": {
+                                                                                "description": "An alert name",
+                                                                                "type": "string"
+                                                                            },
+                                                                            "type": "array"
+                                                                        },
+                                                                        "alertsRaised": {
+                                                                            "description": "An array of alert names",
+                                                                            "items": {
+                                                                                "description": "An alert name",
+                                                                                "type": "string"
+                                                                            },
+                                                                            "type": "array"
+                                                                        },
+                                                                        "events": {
+                                                                            "items": {
+                                                                                "description": "A typed event raised by an invoke, one per asset written or deleted and one per alert raised or cleared",
+                                                                                "properties": {
+                                                                                    "alertsCleared": {
+                                                                                        "description": "An array of alert names",
+                                                                                        "items": {
+                                                                                            "description": "An alert name",
+                                                                                            "type": "string"
+                                                                                        },
+                                                                                        "type": "array"
+                                                                                    },
+                                                                                    "alertsRaised": {
+                                                                                        "description": "An array of alert names",
+                                                                                        "items": {
+                                                                                            "description": "An alert name",
+                                                                                            "type": "string"
+                                                                                        },
+                                                                                        "type": "array"
+                                                                                    },
+                                                                                    "assetkey": {
+                                                                                        "asset": {
+                                                                                            "properties": {
+                                                                                                "assetID": {
+                                                                                                    "description": "An asset's unique ID, e.g. barcode, VIN, etc.",
+                                                                                                    "type": "string"
+                                                                                                }
+                                                                                            },
+                                                                                            "type": "object"
+                                                                                        }
+                                                                                    },
+                                                                                    "changed": {
+                                                                                        "description": "property paths of the asset state that the write changed",
+                                                                                        "items": {
+                                                                                            "type": "string"
+                                                                                        },
+                                                                                        "type": "array"
+                                                                                    },
+                                                                                    "class": {
+                                                                                        "type": "string"
+                                                                                    },
+                                                                                    "diff": {
+                                                                                        "description": "The properties that an asset state added, removed and changed, values of private properties are never recorded",
+                                                                                        "properties": {
+                                                                                            "added": {
+                                                                                                "items": {
+                                                                                                    "properties": {
+                                                                                                        "new": {
+                                                                                                            "description": "the new value, absent when removed or private"
+                                                                                                        },
+                                                                                                        "old": {
+                                                                                                            "description": "the prior value, absent when added or private"
+                                                                                                        },
+                                                                                                        "path": {
+                                                                                                            "description": "property path relative to asset state",
+                                                                                                            "type": "string"
+                                                                                                        }
+                                                                                                    },
+                                                                                                    "type": "object"
+                                                                                                },
+                                                                                                "type": "array"
+                                                                                            },
+                                                                                            "changed": {
+                                                                                                "items": {
+                                                                                                    "properties": {
+                                                                                                        "new": {
+                                                                                                            "description": "the new value, absent when removed or private"
+                                                                                                        },
+                                                                                                        "old": {
+                                                                                                            "description": "the prior value, absent when added or private"
+                                                                                                        },
+                                                                                                        "path": {
+                                                                                                            "description": "property path relative to asset state",
+                                                                                                            "type": "string"
+                                                                                                        }
+                                                                                                    },
+                                                                                                    "type": "object"
+                                                                                                },
+                                                                                                "type": "array"
+                                                                                            },
+                                                                                            "removed": {
+                                                                                                "items": {
+                                                                                                    "properties": {
+                                                                                                        "new": {
+                                                                                                            "description": "the new value, absent when removed or private"
+                                                                                                        },
+                                                                                                        "old": {
+                                                                                                            "description": "the prior value, absent when added or private"
+                                                                                                        },
+                                                                                                        "path": {
+                                                                                                            "description": "property path relative to asset state",
+                                                                                                            "type": "string"
+                                                                                                        }
+                                                                                                    },
+                                                                                                    "type": "object"
+                                                                                                },
+                                                                                                "type": "array"
+                                                                                            }
+                                                                                        },
+                                                                                        "type": "object"
+                                                                                    },
+                                                                                    "name": {
+                                                                                        "description": "the event name declared by the contract for the class or alert, also appended to the chaincode event name",
+                                                                                        "type": "string"
+                                                                                    },
+                                                                                    "transition": {
+                                                                                        "description": "The lifecycle transition made by an asset state",
+                                                                                        "properties": {
+                                                                                            "from": {
+                                                                                                "type": "string"
+                                                                                            },
+                                                                                            "to": {
+                                                                                                "type": "string"
+                                                                                            },
+                                                                                            "trigger": {
+                                                                                                "description": "The function that made the transition",
+                                                                                                "type": "string"
+                                                                                            }
+                                                                                        },
+                                                                                        "type": "object"
+                                                                                    },
+                                                                                    "txnid": {
+                                                                                        "type": "string"
+                                                                                    },
+                                                                                    "type": {
+                                                                                        "enum": [
+                                                                                            "asset.created",
+                                                                                            "asset.updated",
+                                                                                            "asset.deleted",
+                                                                                            "alert.raised",
+                                                                                            "alert.cleared",
+                                                                                            "zone.entered",
+                                                                                            "zone.exited",
+                                                                                            "history.archived"
+                                                                                        ],
+                                                                                        "type": "string"
+                                                                                    },
+                                                                                    "zone": {
+                                                                                        "description": "the zone of a zone event",
+                                                                                        "type": "string"
+                                                                                    }
+                                                                                },
+                                                                                "type": "object"
+                                                                            },
+                                                                            "type": "array"
+                                                                        },
+                                                                        "invokeresult": {
+                                                                            "description": "status: OK==txn succeeded, ERROR==txn failed",
+                                                                            "properties": {
+                                                                                "message": {
+                                                                                    "type": "string"
+                                                                                },
+                                                                                "status": {
+                                                                                    "enum": [
+                                                                                        "OK",
+                                                                                        "ERROR"
+                                                                                    ],
+                                                                                    "type": "string"
+                                                                                }
+                                                                            },
+                                                                            "type": "object"
+                                                                        },
+                                                                        "names": {
+                                                                            "description": "the distinct declared names of the events, sorted",
+                                                                            "items": {
+                                                                                "type": "string"
+                                                                            },
+                                                                            "type": "array"
+                                                                        },
+                                                                        "txnid": {
+                                                                            "description": "the transaction that emitted the event",
+                                                                            "type": "string"
+                                                                        }
+                                                                    },
+                                                                    "type": "object"
+                                                                },
+                                                                "type": "object"
+                                                            }
+                                                        },
+                                                        "type": "object"
+                                                    }
+                                                },
+                                                "type": "object"
+                                            },
+                                            "prefix": {
+                                                "description": "The container's asset class prefix in world state",
+                                                "type": "string"
+                                            },
+                                            "state": {
+                                                "description": "Properties that have been received or calculated for this container",
+                                                "properties": {
+                                                    "container": {
+                                                        "description": "The changeable properties for a container, also considered its 'event' as a partial state",
+                                                        "properties": {
+                                                            "barcode": {
+                                                                "description": "A container's ID",
+                                                                "type": "string"
+                                                            },
+                                                            "carrier": {
+                                                                "description": "The carrier in possession of this container",
+                                                                "type": "string"
+                                                            },
+                                                            "common": {
+                                                                "description": "Common properties for all assets",
+                                                                "properties": {
+                                                                    "appdata": {
+                                                                        "description": "Application managed information as an array of key:value pairs",
+                                                                        "items": {
+                                                                            "properties": {
+                                                                                "K": {
+                                                                                    "type": "string"
+                                                                                },
+                                                                                "V": {
+                                                                                    "type": "string"
+                                                                                }
+                                                                            },
+                                                                            "type": "object"
+                                                                        },
+                                                                        "minItems": 0,
+                                                                        "type": "array"
+                                                                    },
+                                                                    "deviceID": {
+                                                                        "description": "A unique identifier for the device that sent the current event",
+                                                                        "type": "string"
+                                                                    },
+                                                                    "devicetimestamp": {
+                                                                        "description": "A timestamp recoded by the device that sent the current event",
+                                                                        "type": "string"
+                                                                    },
+                                                                    "location": {
+                                                                        "description": "A geographical coordinate",
+                                                                        "properties": {
+                                                                            "latitude": {
+                                                                                "type": "number"
+                                                                            },
+                                                                            "longitude": {
+                                                                                "type": "number"
+                                                                            }
+                                                                        },
+                                                                        "type": "object"
+                                                                    }
+                                                                },
+                                                                "type": "object"
+                                                            },
+                                                            "temperature": {
+                                                                "description": "Temperature of a container's contents in degrees Celsuis",
+                                                                "type": "number"
+                                                            }
+                                                        },
+                                                        "required": [
+                                                            "barcode"
+                                                        ],
+                                                        "type": "object"
+                                                    }
+                                                },
+                                                "type": "object"
+                                            },
+                                            "txnid": {
+                                                "description": "Transaction UUID matching the blockchain",
+                                                "type": "string"
+                                            },
+                                            "txnts": {
+                                                "description": "Transaction timestamp matching the blockchain",
+                                                "type": "string"
+                                            }
+                                        },
+                                        "type": "object"
+                                    }
+                                },
+                                "type": "object"
+                            },
+                            "minItems": 0,
+                            "type": "array"
+                        }
+                    },
+                    "type": "object"
+                },
                 "result": {
                     "description": "Array of container states, can mix asset classes",
                     "items": {
                         "patternProperties": {
                             "^CON": {
-                                "description": "The external state of one container asset, named by its world state ID",
+                                "description": "A container's complete state",
+                                "properties": {
+                                    "AssetKey": {
+                                        "description": "This container's world state container ID",
+                                        "type": "string"
+                                    },
+                                    "alerts": {
+                                        "description": "An array of alert names",
+                                        "items": {
+                                            "description": "An alert name",
+                                            "type": "string"
+                                        },
+                                        "type": "array"
+                                    },
+                                    "assetIDpath": {
+                                        "description": "Qualified property path to the container's ID, declared in the contract code",
+                                        "type": "string"
+                                    },
+                                    "class": {
+                                        "description": "The container's asset class",
+                                        "type": "string"
+                                    },
+                                    "compliant": {
+                                        "description": "This container has no active alerts",
+                                        "type": "boolean"
+                                    },
+                                    "eventin": {
+                                        "description": "The contract event that created this state, for example updateAssetContainer",
+                                        "properties": {
+                                            "container": {
+                                                "description": "The changeable properties for a container, also considered its 'event' as a partial state",
+                                                "properties": {
+                                                    "barcode": {
+                                                        "description": "A container's ID",
+                                                        "type": "string"
+                                                    },
+                                                    "carrier": {
+                                                        "description": "The carrier in possession of this container",
+                                                        "type": "string"
+                                                    },
+                                                    "common": {
+                                                        "description": "Common properties for all assets",
+                                                        "properties": {
+                                                            "appdata": {
+                                                                "description": "Application managed information as an array of key:value pairs",
+                                                                "items": {
+                                                                    "properties": {
+                                                                        "K": {
+                                                                            "type": "string"
+                                                                        },
+                                                                        "V": {
+                                                                            "type": "string"
+                                                                        }
+                                                                    },
+                                                                    "type": "object"
+                                                                },
+                                                                "minItems": 0,
+                                                                "type": "array"
+                                                            },
+                                                            "deviceID": {
+                                                                "description": "A unique identifier for the device that sent the current event",
+                                                                "type": "string"
+                                                            },
+                                                            "devicetimestamp": {
+                                                                "description": "A timestamp recoded by the device that sent the current event",
+                                                                "type": "string"
+                                                            },
+                                                            "location": {
+                                                                "description": "A geographical coordinate",
+                                                                "properties": {
+                                                                    "latitude": {
+                                                                        "type": "number"
+                                                                    },
+                                                                    "longitude": {
+                                                                        "type": "number"
+                                                                    }
+                                                                },
+                                                                "type": "object"
+                                                            }
+                                                        },
+                                                        "type": "object"
+                                                    },
+                                                    "temperature": {
+                                                        "description": "Temperature of a container's contents in degrees Celsuis",
+                                                        "type": "number"
+                                                    }
+                                                },
+                                                "required": [
+                                                    "barcode"
+                                                ],
+                                                "type": "object"
+                                            }
+                                        },
+                                        "type": "object"
+                                    },
+                                    "eventout": {
+                                        "description": "The chaincode event emitted on invoke exit, if any",
+                                        "properties": {
+                                            "container": {
+                                                "description": "A chaincode event defining the standard platform-generated result event for a contract invoke, contains an array of contributed results",
+                                                "properties": {
+                                                    "name": {
+                                                        "default": "EVT.IOTCP.INVOKE.RESULT",
+                                                        "description": "EVT.IOTCP.INVOKE.RESULT, followed by the declared names of the invoke's events separated by colons when the contract turns the event name suffix on, e.g. EVT.IOTCP.INVOKE.RESULT:KIT.OUTOFAREA",
+                                                        "pattern": "^EVT\\.IOTCP\\.INVOKE\\.RESULT(:.+)?$",
+                                                        "type": "string"
+                                                    },
+                                                    "payload": {
+                                                        "description": "A map of contributed results",
+                                                        "properties": {
+                                                            "description": "the overall status of the invoke result, defined by err",
+                                                            "properties": {
+                                                                "activeAlerts": {
+                                                                    "description": "An array of alert names",
+                                                                    "items": {
+                                                                        "description": "An alert name",
+                                                                        "type": "string"
+                                                                    },
+                                                                    "type": "array"
+                                                                },
+                                                                "alertsCleared": {
+                                                                    "description": "An array of alert names",
+                                                                    "items": {
+                                                                        "description": "An alert name",
+                                                                        "type": "string"
+                                                                    },
+                                                                    "type": "array"
+                                                                },
+                                                                "alertsRaised": {
+                                                                    "description": "An array of alert names",
+                                                                    "items": {
+                                                                        "description": "An alert name",
+                                                                        "type": "string"
+                                                                    },
+                                                                    "type": "array"
+                                                                },
+                                                                "events": {
+                                                                    "items": {
+                                                                        "description": "A typed event raised by an invoke, one per asset written or deleted and one per alert raised or cleared",
+                                                                        "properties": {
+                                                                            "alertsCleared": {
+                                                                                "description": "An array of alert names",
+                                                                                "items": {
+                                                                                    "description": "An alert name",
+                                                                                    "type": "string"
+                                                                                },
+                                                                                "type": "array"
+                                                                            },
+                                                                            "alertsRaised": {
+                                                                                "description": "An array of alert names",
+                                                                                "items": {
+                                                                                    "description": "An alert name",
+                                                                                    "type": "string"
+                                                                                },
+                                                                                "type": "array"
+                                                                            },
+                                                                            "assetkey": {
+                                                                                "asset": {
+                                                                                    "properties": {
+                                                                                        "assetID": {
+                                                                                            "description": "An asset's unique ID, e.g. barcode, VIN, etc.",
+                                                                                            "type": "string"
+                                                                                        }
+                                                                                    },
+                                                                                    "type": "object"
+                                                                                }
+                                                                            },
+                                                                            "changed": {
+                                                                                "description": "property paths of the asset state that the write changed",
+                                                                                "items": {
+                                                                                    "type": "string"
+                                                                                },
+                                                                                "type": "array"
+                                                                            },
+                                                                            "class": {
+                                                                                "type": "string"
+                                                                            },
+                                                                            "diff": {
+                                                                                "description": "The properties that an asset state added, removed and changed, values of private properties are never recorded",
+                                                                                "properties": {
+                                                                                    "added": {
+                                                                                        "items": {
+                                                                                            "properties": {
+                                                                                                "new": {
+                                                                                                    "description": "the new value, absent when removed or private"
+                                                                                                },
+                                                                                                "old": {
+                                                                                                    "description": "the prior value, absent when added or private"
+                                                                                                },
+                                                                                                "path": {
+                                                                                                    "description": "property path relative to asset state",
+                                                                                                    "type": "string"
+                                                                                                }
+                                                                                            },
+                                                                                            "type": "object"
+                                                                                        },
+                                                                                        "type": "array"
+                                                                                    },
+                                                                                    "changed": {
+                                                                                        "items": {
+                                                                                            "properties": {
+                                                                                                "new": {
+                                                                                                    "description": "the new value, absent when removed or private"
+                                                                                                },
+                                                                                                "old": {
+                                                                                                    "description": "the prior value, absent when added or private"
+                                                                                                },
+                                                                                                "path": {
+                                                                                                    "description": "property path relative to asset state",
+                                                                                                    "type": "string"
+                                                                                                }
+                                                                                            },
+                                                                                            "type": "object"
+                                                                                        },
+                                                                                        "type": "array"
+                                                                                    },
+                                                                                    "removed": {
+                                                                                        "items": {
+                                                                                            "properties": {
+                                                                                                "new": {
+                                                                                                    "description": "the new value, absent when removed or private"
+                                                                                                },
+                                                                                                "old": {
+                                                                                                    "description": "the prior value, absent when added or private"
+                                                                                                },
+                                                                                                "path": {
+                                                                                                    "description": "property path relative to asset state",
+                                                                                                    "type": "string"
+                                                                                                }
+                                                                                            },
+                                                                                            "type": "object"
+                                                                                        },
+                                                                                        "type": "array"
+                                                                                    }
+                                                                                },
+                                                                                "type": "object"
+                                                                            },
+                                                                            "name": {
+                                                                                "description": "the event name declared by the contract for the class or alert, also appended to the chaincode event name",
+                                                                                "type": "string"
+                                                                            },
+                                                                            "transition": {
+                                                                                "description": "The lifecycle transition made by an asset state",
+                                                                                "properties": {
+                                                                                    "from": {
+                                                                                        "type": "string"
+                                                                                    },
+                                                                                    "to": {
+                                                                                        "type": "string"
+                                                                                    },
+                                                                                    "trigger": {
+                                                                                        "description": "The function that made the transition",
+                                                                                        "type": "string"
+                                                                                    }
+                                                                                },
+                                                                                "type": "object"
+                                                                            },
+                                                                            "txnid": {
+                                                                                "type": "string"
+                                                                            },
+                                                                            "type": {
+                                                                                "enum": [
+                                                                                    "asset.created",
+                                                                                    "asset.updated",
+                                                                                    "asset.deleted",
+                                                                                    "alert.raised",
+                                                                                    "alert.cleared",
+                                                                                    "zone.entered",
+                                                                                    "zone.exited",
+                                                                                    "history.archived"
+                                                                                ],
+                                                                                "type": "string"
+                                                                            },
+                                                                            "zone": {
+                                                                                "description": "the zone of a zone event",
+                                                                                "type": "string"
+                                                                            }
+                                                                        },
+                                                                        "type": "object"
+                                                                    },
+                                                                    "type": "array"
+                                                                },
+                                                                "invokeresult": {
+                                                                    "description": "status: OK==txn succeeded, ERROR==txn failed",
+                                                                    "properties": {
+                                                                        "message": {
+                                                                            "type": "string"
+                                                                        },
+                                                                        "status": {
+                                                                            "enum": [
+                                                                                "OK",
+                                                                                "ERROR"
+                                                                            ],
+                                                                            "type": "string"
+                                                                        }
+                                                                    },
+                                                                    "type": "object"
+                                                                },
+                                                                "names": {
+                                                                    "description": "the distinct declared names of the events, sorted",
+                                                                    "items": {
+                                                                        "type": "string"
+                                                                    },
+                                                                    "type": "array"
+                                                                },
+                                                                "txnid": {
+                                                                    "description": "the transaction that emitted the event",
+                                                                    "type": "string"
+                                                                }
+                                                            },
+                                                            "type": "object"
+                                                        },
+                                                        "type": "object"
+                                                    }
+                                                },
+                                                "type": "object"
+                                            }
+                                        },
+                                        "type": "object"
+                                    },
+                                    "prefix": {
+                                        "description": "The container's asset class prefix in world state",
+                                        "type": "string"
+                                    },
+                                    "state": {
+                                        "description": "Properties that have been received or calculated for this container",
+                                        "properties": {
+                                            "container": {
+                                                "description": "The changeable properties for a container, also considered its 'event' as a partial state",
+                                                "properties": {
+                                                    "barcode": {
+                                                        "description": "A container's ID",
+                                                        "type": "string"
+                                                    },
+                                                    "carrier": {
+                                                        "description": "The carrier in possession of this container",
+                                                        "type": "string"
+                                                    },
+                                                    "common": {
+                                                        "description": "Common properties for all assets",
+                                                        "properties": {
+                                                            "appdata": {
+                                                                "description": "Application managed information as an array of key:value pairs",
+                                                                "items": {
+                                                                    "properties": {
+                                                                        "K": {
+                                                                            "type": "string"
+                                                                        },
+                                                                        "V": {
+                                                                            "type": "string"
+                                                                        }
+                                                                    },
+                                                                    "type": "object"
+                                                                },
+                                                                "minItems": 0,
+                                                                "type": "array"
+                                                            },
+                                                            "deviceID": {
+                                                                "description": "A unique identifier for the device that sent the current event",
+                                                                "type": "string"
+                                                            },
+                                                            "devicetimestamp": {
+                                                                "description": "A timestamp recoded by the device that sent the current event",
+                                                                "type": "string"
+                                                            },
+                                                            "location": {
+                                                                "description": "A geographical coordinate",
+                                                                "properties": {
+                                                                    "latitude": {
+                                                                        "type": "number"
+                                                                    },
+                                                                    "longitude": {
+                                                                        "type": "number"
+                                                                    }
+                                                                },
+                                                                "type": "object"
+                                                            }
+                                                        },
+                                                        "type": "object"
+                                                    },
+                                                    "temperature": {
+                                                        "description": "Temperature of a container's contents in degrees Celsuis",
+                                                        "type": "number"
+                                                    }
+                                                },
+                                                "required": [
+                                                    "barcode"
+                                                ],
+                                                "type": "object"
+                                            }
+                                        },
+                                        "type": "object"
+                                    },
+                                    "txnid": {
+                                        "description": "Transaction UUID matching the blockchain",
+                                        "type": "string"
+                                    },
+                                    "txnts": {
+                                        "description": "Transaction timestamp matching the blockchain",
+                                        "type": "string"
+                                    }
+                                },
                                 "type": "object"
                             }
                         },
@@ -803,23 +2772,107 @@ var schemas = `
             "type": "object"
         },
         "readRecentStates": {
-            "description": "Returns the state of recently updated assets",
+            "description": "Returns recently written asset states, newest first, within the retention window; supports class, since and state filters and pagination",
             "properties": {
                 "args": {
                     "items": {
                         "properties": {
                             "begin": {
-                                "description": "zero based beginning of range",
+                                "description": "zero based beginning of range, ignored when paginated",
                                 "type": "integer"
                             },
+                            "bookmark": {
+                                "description": "opaque bookmark returned with the previous page, pass it back unchanged to read the next page; an empty bookmark means there are no more results",
+                                "type": "string"
+                            },
+                            "classname": {
+                                "description": "return only states of this asset class",
+                                "type": "string"
+                            },
                             "end": {
-                                "description": "zero based end of range, absence means to end",
+                                "description": "zero based end of range, absence means the newest 40 states, ignored when paginated",
+                                "type": "integer"
+                            },
+                            "filter": {
+                                "description": "Filter asset states",
+                                "properties": {
+                                    "groups": {
+                                        "description": "Nested filters with the same shape as this filter, combined with the select terms according to match",
+                                        "items": {
+                                            "type": "object"
+                                        },
+                                        "type": "array"
+                                    },
+                                    "match": {
+                                        "description": "Defines how to combine the select terms and groups, and, or and not are aliases for all, any and none; missing property always fails match",
+                                        "enum": [
+                                            "n/a",
+                                            "all",
+                                            "any",
+                                            "none",
+                                            "and",
+                                            "or",
+                                            "not"
+                                        ],
+                                        "type": "string"
+                                    },
+                                    "select": {
+                                        "description": "Qualified property names and values match",
+                                        "items": {
+                                            "properties": {
+                                                "op": {
+                                                    "description": "Comparison operator, defaults to equality; numbers compare numerically and strings lexically; exists with value 'false' matches a missing property",
+                                                    "enum": [
+                                                        "eq",
+                                                        "ne",
+                                                        "gt",
+                                                        "gte",
+                                                        "lt",
+                                                        "lte",
+                                                        "in",
+                                                        "exists",
+                                                        "prefix",
+                                                        "regex"
+                                                    ],
+                                                    "type": "string"
+                                                },
+                                                "qprop": {
+                                                    "description": "Qualified property to compare, for example 'asset.assetID'",
+                                                    "type": "string"
+                                                },
+                                                "value": {
+                                                    "description": "Value to be compared",
+                                                    "type": "string"
+                                                },
+                                                "values": {
+                                                    "description": "Values to be compared by the in operator",
+                                                    "items": {
+                                                        "type": "string"
+                                                    },
+                                                    "type": "array"
+                                                }
+                                            },
+                                            "type": "object"
+                                        },
+                                        "type": "array"
+                                    }
+                                },
+                                "type": "object"
+                            },
+                            "pagesize": {
+                                "description": "maximum number of results to return in one page, results are returned in a paged envelope when pagesize or bookmark is passed",
+                                "minimum": 0,
                                 "type": "integer"
+                            },
+                            "since": {
+                                "description": "return only states written at or after this time",
+                                "format": "date-time",
+                                "type": "string"
                             }
                         },
                         "type": "object"
                     },
-                    "maxItems": 0,
+                    "maxItems": 1,
                     "minItems": 0,
                     "type": "array"
                 },
@@ -860,6 +2913,70 @@ var schemas = `
                                 "description": "This asset has no active alerts",
                                 "type": "boolean"
                             },
+                            "deleted": {
+                                "description": "Marks a history state that records the deletion of the asset, native history only",
+                                "type": "boolean"
+                            },
+                            "diff": {
+                                "description": "The properties that an asset state added, removed and changed, values of private properties are never recorded",
+                                "properties": {
+                                    "added": {
+                                        "items": {
+                                            "properties": {
+                                                "new": {
+                                                    "description": "the new value, absent when removed or private"
+                                                },
+                                                "old": {
+                                                    "description": "the prior value, absent when added or private"
+                                                },
+                                                "path": {
+                                                    "description": "property path relative to asset state",
+                                                    "type": "string"
+                                                }
+                                            },
+                                            "type": "object"
+                                        },
+                                        "type": "array"
+                                    },
+                                    "changed": {
+                                        "items": {
+                                            "properties": {
+                                                "new": {
+                                                    "description": "the new value, absent when removed or private"
+                                                },
+                                                "old": {
+                                                    "description": "the prior value, absent when added or private"
+                                                },
+                                                "path": {
+                                                    "description": "property path relative to asset state",
+                                                    "type": "string"
+                                                }
+                                            },
+                                            "type": "object"
+                                        },
+                                        "type": "array"
+                                    },
+                                    "removed": {
+                                        "items": {
+                                            "properties": {
+                                                "new": {
+                                                    "description": "the new value, absent when removed or private"
+                                                },
+                                                "old": {
+                                                    "description": "the prior value, absent when added or private"
+                                                },
+                                                "path": {
+                                                    "description": "property path relative to asset state",
+                                                    "type": "string"
+                                                }
+                                            },
+                                            "type": "object"
+                                        },
+                                        "type": "array"
+                                    }
+                                },
+                                "type": "object"
+                            },
                             "eventin": {
                                 "description": "The contract event that created this state, for example updateAsset",
                                 "properties": {
@@ -937,9 +3054,8 @@ var schemas = `
                                         "properties": {
                                             "name": {
                                                 "default": "EVT.IOTCP.INVOKE.RESULT",
-                                                "enum": [
-                                                    "EVT.IOTCP.INVOKE.RESULT"
-                                                ],
+                                                "description": "EVT.IOTCP.INVOKE.RESULT, followed by the declared names of the invoke's events separated by colons when the contract turns the event name suffix on, e.g. EVT.IOTCP.INVOKE.RESULT:KIT.OUTOFAREA",
+                                                "pattern": "^EVT\\.IOTCP\\.INVOKE\\.RESULT(:.+)?$",
                                                 "type": "string"
                                             },
                                             "payload": {
@@ -971,6 +3087,152 @@ var schemas = `
                                                             },
                                                             "type": "array"
                                                         },
+                                                        "events": {
+                                                            "items": {
+                                                                "description": "A typed event raised by an invoke, one per asset written or deleted and one per alert raised or cleared",
+                                                                "properties": {
+                                                                    "alertsCleared": {
+                                                                        "description": "An array of alert names",
+                                                                        "items": {
+                                                                            "description": "An alert name",
+                                                                            "type": "string"
+                                                                        },
+                                                                        "type": "array"
+                                                                    },
+                                                                    "alertsRaised": {
+                                                                        "description": "An array of alert names",
+                                                                        "items": {
+                                                                            "description": "An alert name",
+                                                                            "type": "string"
+                                                                        },
+                                                                        "type": "array"
+                                                                    },
+                                                                    "assetkey": {
+                                                                        "asset": {
+                                                                            "properties": {
+                                                                                "assetID": {
+                                                                                    "description": "An asset's unique ID, e.g. barcode, VIN, etc.",
+                                                                                    "type": "string"
+                                                                                }
+                                                                            },
+                                                                            "type": "object"
+                                                                        }
+                                                                    },
+                                                                    "changed": {
+                                                                        "description": "property paths of the asset state that the write changed",
+                                                                        "items": {
+                                                                            "type": "string"
+                                                                        },
+                                                                        "type": "array"
+                                                                    },
+                                                                    "class": {
+                                                                        "type": "string"
+                                                                    },
+                                                                    "diff": {
+                                                                        "description": "The properties that an asset state added, removed and changed, values of private properties are never recorded",
+                                                                        "properties": {
+                                                                            "added": {
+                                                                                "items": {
+                                                                                    "properties": {
+                                                                                        "new": {
+                                                                                            "description": "the new value, absent when removed or private"
+                                                                                        },
+                                                                                        "old": {
+                                                                                            "description": "the prior value, absent when added or private"
+                                                                                        },
+                                                                                        "path": {
+                                                                                            "description": "property path relative to asset state",
+                                                                                            "type": "string"
+                                                                                        }
+                                                                                    },
+                                                                                    "type": "object"
+                                                                                },
+                                                                                "type": "array"
+                                                                            },
+                                                                            "changed": {
+                                                                                "items": {
+                                                                                    "properties": {
+                                                                                        "new": {
+                                                                                            "description": "the new value, absent when removed or private"
+                                                                                        },
+                                                                                        "old": {
+                                                                                            "description": "the prior value, absent when added or private"
+                                                                                        },
+                                                                                        "path": {
+                                                                                            "description": "property path relative to asset state",
+                                                                                            "type": "string"
+                                                                                        }
+                                                                                    },
+                                                                                    "type": "object"
+                                                                                },
+                                                                                "type": "array"
+                                                                            },
+                                                                            "removed": {
+                                                                                "items": {
+                                                                                    "properties": {
+                                                                                        "new": {
+                                                                                            "description": "the new value, absent when removed or private"
+                                                                                        },
+                                                                                        "old": {
+                                                                                            "description": "the prior value, absent when added or private"
+                                                                                        },
+                                                                                        "path": {
+                                                                                            "description": "property path relative to asset state",
+                                                                                            "type": "string"
+                                                                                        }
+                                                                                    },
+                                                                                    "type": "object"
+                                                                                },
+                                                                                "type": "array"
+                                                                            }
+                                                                        },
+                                                                        "type": "object"
+                                                                    },
+                                                                    "name": {
+                                                                        "description": "the event name declared by the contract for the class or alert, also appended to the chaincode event name",
+                                                                        "type": "string"
+                                                                    },
+                                                                    "transition": {
+                                                                        "description": "The lifecycle transition made by an asset state",
+                                                                        "properties": {
+                                                                            "from": {
+                                                                                "type": "string"
+                                                                            },
+                                                                            "to": {
+                                                                                "type": "string"
+                                                                            },
+                                                                            "trigger": {
+                                                                                "description": "The function that made the transition",
+                                                                                "type": "string"
+                                                                            }
+                                                                        },
+                                                                        "type": "object"
+                                                                    },
+                                                                    "txnid": {
+                                                                        "type": "string"
+                                                                    },
+                                                                    "type": {
+                                                                        "enum": [
+                                                                            "asset.created",
+                                                                            "asset.updated",
+                                                                            "asset.deleted",
+                                                                            "alert.raised",
+                                                                            "alert.cleared",
+                                                                            "zone.entered",
+                                                                            "zone.exited",
+                                                                            "history.archived"
+                                                                        ],
+                                                                        "type": "string"
+                                                                    },
+                                                                    "zone": {
+                                                                        "description": "the zone of a zone event",
+                                                                        "type": "string"
+                                                                    }
+                                                                },
+                                                                "type": "object"
+                                                            },
+                                                            "type": "array"
+                                                        },
                                                         "invokeresult": {
                                                             "description": "status: OK==txn succeeded, ERROR==txn failed",
                                                             "properties": {
@@ -986,6 +3248,17 @@ var schemas = `
                                                                 }
                                                             },
                                                             "type": "object"
+                                                        },
+                                                        "names": {
+                                                            "description": "the distinct declared names of the events, sorted",
+                                                            "items": {
+                                                                "type": "string"
+                                                            },
+                                                            "type": "array"
+                                                        },
+                                                        "txnid": {
+                                                            "description": "the transaction that emitted the event",
+                                                            "type": "string"
                                                         }
                                                     },
                                                     "type": "object"
@@ -998,6 +3271,10 @@ var schemas = `
                                 },
                                 "type": "object"
                             },
+                            "rulesversion": {
+                                "description": "Version of the declarative rule set that judged this state",
+                                "type": "integer"
+                            },
                             "state": {
                                 "description": "Properties that have been received or calculated for this asset",
                                 "properties": {
@@ -1067,6 +3344,22 @@ var schemas = `
                                 },
                                 "type": "object"
                             },
+                            "transition": {
+                                "description": "The lifecycle transition made by an asset state",
+                                "properties": {
+                                    "from": {
+                                        "type": "string"
+                                    },
+                                    "to": {
+                                        "type": "string"
+                                    },
+                                    "trigger": {
+                                        "description": "The function that made the transition",
+                                        "type": "string"
+                                    }
+                                },
+                                "type": "object"
+                            },
                             "txnid": {
                                 "description": "Transaction UUID matching the blockchain",
                                 "type": "string"
@@ -1074,6 +3367,40 @@ var schemas = `
                             "txnts": {
                                 "description": "Transaction timestamp matching the blockchain",
                                 "type": "string"
+                            },
+                            "zones": {
+                                "description": "The attached zones that hold an asset's location, and those that its move from the prior location entered and exited",
+                                "properties": {
+                                    "alerts": {
+                                        "description": "zone alerts that this state holds raised, cleared on the next write once their zones are detached or deleted",
+                                        "items": {
+                                            "type": "string"
+                                        },
+                                        "type": "array"
+                                    },
+                                    "entered": {
+                                        "description": "zones entered by this write",
+                                        "items": {
+                                            "type": "string"
+                                        },
+                                        "type": "array"
+                                    },
+                                    "exited": {
+                                        "description": "zones exited by this write",
+                                        "items": {
+                                            "type": "string"
+                                        },
+                                        "type": "array"
+                                    },
+                                    "inside": {
+                                        "description": "zones holding the location",
+                                        "items": {
+                                            "type": "string"
+                                        },
+                                        "type": "array"
+                                    }
+                                },
+                                "type": "object"
                             }
                         },
                         "type": "object"
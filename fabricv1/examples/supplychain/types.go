@@ -0,0 +1,49 @@
+// Code generated by processSchema.go from container.json. DO NOT EDIT.
+// Change the Model in the schema or the types section of generate.json and run go generate instead.
+
+package main
+
+import iot "github.com/ibm-watson-iot/blockchain-samples/fabricv1/platform"
+
+// Container is generated from Model/container, The changeable properties for a container, also considered its 'event' as a partial state
+type Container struct {
+	Barcode     string          `json:"barcode"`
+	Carrier     *string         `json:"carrier,omitempty"` // The carrier in possession of this container
+	Common      *Ioteventcommon `json:"common,omitempty"`
+	Temperature *float64        `json:"temperature,omitempty"` // Temperature of a container's contents in degrees Celsuis
+}
+
+// Ioteventcommon is generated from Model/ioteventcommon, Common properties for all assets
+type Ioteventcommon struct {
+	Appdata         []IoteventcommonAppdataItem `json:"appdata,omitempty"`         // Application managed information as an array of key:value pairs
+	DeviceID        *string                     `json:"deviceID,omitempty"`        // A unique identifier for the device that sent the current event
+	Devicetimestamp *string                     `json:"devicetimestamp,omitempty"` // A timestamp recoded by the device that sent the current event
+	Location        *Geo                        `json:"location,omitempty"`
+}
+
+// IoteventcommonAppdataItem is a nested object
+type IoteventcommonAppdataItem struct {
+	K *string `json:"K,omitempty"`
+	V *string `json:"V,omitempty"`
+}
+
+// Geo is generated from Model/geo, A geographical coordinate
+type Geo struct {
+	Latitude  *float64 `json:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty"`
+}
+
+// ContainerFromState reads the Container at qname in an asset's state, or the whole state when qname is empty
+func ContainerFromState(state *map[string]interface{}, qname string) (*Container, bool, error) {
+	var x Container
+	found, err := iot.GetObjectAsStruct(state, qname, &x)
+	if !found || err != nil {
+		return nil, found, err
+	}
+	return &x, true, nil
+}
+
+// MergeIntoState deep merges the properties of the Container that are set into an asset's state at qname
+func (x *Container) MergeIntoState(state *map[string]interface{}, qname string) error {
+	return iot.MergeStruct(state, qname, x)
+}
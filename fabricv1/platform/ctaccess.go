@@ -0,0 +1,442 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+// v0.1 KL -- attribute based access control on routes, using the submitter's certificate
+
+package iotcontractplatform
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/msp"
+)
+
+// ACCESSPOLICYKEY holds the access policies that were set through setAccessPolicy
+const ACCESSPOLICYKEY string = "IOTCP:AccessPolicy"
+
+// AccessPolicyDefault is the function name of the policy that applies to every route
+// without a policy of its own
+const AccessPolicyDefault string = "*"
+
+// AccessPolicyAdmin is the function name of the policy that applies to every admin route
+// without a policy of its own. It is set when the contract is deployed, and admin routes
+// are closed to everyone while it is missing.
+const AccessPolicyAdmin string = "admin"
+
+// ROLEATTRIBUTE is the certificate attribute that holds a caller's role
+const ROLEATTRIBUTE string = "role"
+
+// attrOID is the X.509 extension in which the fabric CA stores attributes as
+// {"attrs":{"name":"value"}}
+var attrOID = asn1.ObjectIdentifier{1, 2, 3, 4, 5, 6, 7, 8, 1}
+
+// AccessPolicy declares what a caller needs in order to call a route. Each list that
+// is present must be satisfied by at least one entry; every attribute must be present
+// with the given value, or with any value when the value is empty. Roles match the
+// caller's role attribute or one of the caller's OUs.
+type AccessPolicy struct {
+	MSPIDs     []string          `json:"mspids,omitempty"`
+	OUs        []string          `json:"ous,omitempty"`
+	Roles      []string          `json:"roles,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// CallerIdentity is the part of the submitter's certificate that policies can test
+type CallerIdentity struct {
+	MSPID      string            `json:"mspid"`
+	CommonName string            `json:"cn"`
+	OUs        []string          `json:"ous,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// AccessError is returned when a caller does not satisfy a route's policy, its text is
+// JSON so that clients can pick out the reason from the invoke result event
+type AccessError struct {
+	Function string `json:"function"`
+	MSPID    string `json:"mspid,omitempty"`
+	Caller   string `json:"caller,omitempty"`
+	Reason   string `json:"reason"`
+}
+
+func (e *AccessError) Error() string {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Sprintf("access denied to %s: %s", e.Function, e.Reason)
+	}
+	return string(b)
+}
+
+var routepolicies = make(map[string]AccessPolicy, 0)
+
+var adminroutes = make(map[string]bool, 0)
+
+// AddAdminRoute marks a route as an admin route, which is governed by the admin policy
+// instead of the default policy and is closed when the route has no policy at all
+func AddAdminRoute(functionName string) error {
+	if adminroutes[functionName] {
+		err := fmt.Errorf("AddAdminRoute: function %s is already an admin route", functionName)
+		log.Error(err)
+		return err
+	}
+	adminroutes[functionName] = true
+	log.Debugf("Function %s added as an admin route", functionName)
+	return nil
+}
+
+// AddRoutePolicy allows a contract to declare the policy for a route in code, a policy
+// set through setAccessPolicy for the same function replaces it
+func AddRoutePolicy(functionName string, policy AccessPolicy) error {
+	if _, found := routepolicies[functionName]; found {
+		err := fmt.Errorf("AddRoutePolicy: function %s already has a policy", functionName)
+		log.Error(err)
+		return err
+	}
+	routepolicies[functionName] = policy
+	log.Debugf("Function %s added access policy %+v", functionName, policy)
+	return nil
+}
+
+func (p AccessPolicy) isEmpty() bool {
+	return len(p.MSPIDs) == 0 && len(p.OUs) == 0 && len(p.Roles) == 0 && len(p.Attributes) == 0
+}
+
+func containsString(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+// check returns an empty string when the identity satisfies the policy, or the reason
+// that it does not
+func (p AccessPolicy) check(id CallerIdentity) string {
+	if len(p.MSPIDs) > 0 && !containsString(p.MSPIDs, id.MSPID) {
+		return fmt.Sprintf("MSP %s is not one of %v", id.MSPID, p.MSPIDs)
+	}
+	if len(p.OUs) > 0 {
+		found := false
+		for _, ou := range id.OUs {
+			if containsString(p.OUs, ou) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Sprintf("caller OUs %v include none of %v", id.OUs, p.OUs)
+		}
+	}
+	if len(p.Roles) > 0 {
+		role, hasRole := id.Attributes[ROLEATTRIBUTE]
+		found := hasRole && containsString(p.Roles, role)
+		for _, ou := range id.OUs {
+			if containsString(p.Roles, ou) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Sprintf("caller has none of the roles %v", p.Roles)
+		}
+	}
+	for k, v := range p.Attributes {
+		actual, found := id.Attributes[k]
+		if !found {
+			return fmt.Sprintf("caller does not have attribute %s", k)
+		}
+		if v != "" && actual != v {
+			return fmt.Sprintf("caller attribute %s is %s, expected %s", k, actual, v)
+		}
+	}
+	return ""
+}
+
+// getCallerMSPID reads the MSP ID of the submitter
+func getCallerMSPID(stub shim.ChaincodeStubInterface) (string, error) {
+	creator, err := stub.GetCreator()
+	if err != nil {
+		return "", err
+	}
+	var sid msp.SerializedIdentity
+	if err = proto.Unmarshal(creator, &sid); err != nil {
+		return "", err
+	}
+	return sid.Mspid, nil
+}
+
+// getCallerIdentity reads the MSP ID, OUs and attributes from the submitter's certificate
+func getCallerIdentity(stub shim.ChaincodeStubInterface) (CallerIdentity, error) {
+	var id = CallerIdentity{Attributes: make(map[string]string)}
+	creator, err := stub.GetCreator()
+	if err != nil {
+		err = fmt.Errorf("getCallerIdentity GetCreator failed: %s", err)
+		log.Error(err)
+		return id, err
+	}
+	var sid msp.SerializedIdentity
+	if err = proto.Unmarshal(creator, &sid); err != nil {
+		err = fmt.Errorf("getCallerIdentity failed to unmarshal the serialized identity: %s", err)
+		log.Error(err)
+		return id, err
+	}
+	id.MSPID = sid.Mspid
+	block, _ := pem.Decode(sid.IdBytes)
+	if block == nil {
+		err = errors.New("getCallerIdentity found no PEM certificate in the identity")
+		log.Error(err)
+		return id, err
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		err = fmt.Errorf("getCallerIdentity failed to parse the certificate: %s", err)
+		log.Error(err)
+		return id, err
+	}
+	id.CommonName = cert.Subject.CommonName
+	id.OUs = cert.Subject.OrganizationalUnit
+	for _, ext := range cert.Extensions {
+		if !ext.Id.Equal(attrOID) {
+			continue
+		}
+		var attrs struct {
+			Attrs map[string]string `json:"attrs"`
+		}
+		if err = json.Unmarshal(ext.Value, &attrs); err != nil {
+			log.Warningf("getCallerIdentity ignored malformed attribute extension: %s", err)
+			continue
+		}
+		for k, v := range attrs.Attrs {
+			id.Attributes[k] = v
+		}
+	}
+	return id, nil
+}
+
+func getAccessPolicies(stub shim.ChaincodeStubInterface) (map[string]AccessPolicy, error) {
+	var policies = make(map[string]AccessPolicy)
+	pBytes, err := stub.GetState(ACCESSPOLICYKEY)
+	if err != nil {
+		err = fmt.Errorf("getAccessPolicies GetState failed: %s", err)
+		log.Error(err)
+		return nil, err
+	}
+	if len(pBytes) == 0 {
+		return policies, nil
+	}
+	if err = json.Unmarshal(pBytes, &policies); err != nil {
+		err = fmt.Errorf("getAccessPolicies unmarshal failed: %s", err)
+		log.Error(err)
+		return nil, err
+	}
+	return policies, nil
+}
+
+// effectivePolicy chooses, in order, the policy set for the function in world state, the
+// policy declared in code, and the admin policy for admin routes or the default policy
+// for all others
+func effectivePolicy(policies map[string]AccessPolicy, function string) (AccessPolicy, bool) {
+	if p, found := policies[function]; found {
+		return p, true
+	}
+	if p, found := routepolicies[function]; found {
+		return p, true
+	}
+	if adminroutes[function] {
+		p, found := policies[AccessPolicyAdmin]
+		return p, found
+	}
+	p, found := policies[AccessPolicyDefault]
+	return p, found
+}
+
+// checkAccess returns an AccessError when the submitter is not allowed to call the function.
+// Admin routes without a policy are closed, other routes without a policy remain open to
+// everyone.
+func checkAccess(stub shim.ChaincodeStubInterface, function string) error {
+	policies, err := getAccessPolicies(stub)
+	if err != nil {
+		return &AccessError{Function: function, Reason: err.Error()}
+	}
+	policy, found := effectivePolicy(policies, function)
+	if !found && adminroutes[function] {
+		err := &AccessError{Function: function, Reason: "admin route has no policy, the admin policy is set when the contract is deployed"}
+		log.Error(err)
+		return err
+	}
+	if !found || policy.isEmpty() {
+		return nil
+	}
+	id, err := getCallerIdentity(stub)
+	if err != nil {
+		return &AccessError{Function: function, Reason: err.Error()}
+	}
+	if reason := policy.check(id); reason != "" {
+		err := &AccessError{function, id.MSPID, id.CommonName, reason}
+		log.Error(err)
+		return err
+	}
+	return nil
+}
+
+// setAccessPolicy replaces the policy for one function, {"function": "deleteWorldState",
+// "policy": {...}}; an empty policy removes the entry so that the function falls back to
+// its declared, admin or default policy. setAccessPolicy is an admin route, and the admin
+// policy itself can be replaced but not removed.
+var setAccessPolicy ChaincodeFunc = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	type PolicyArg struct {
+		Function string       `json:"function"`
+		Policy   AccessPolicy `json:"policy"`
+	}
+	var arg PolicyArg
+	if len(args) != 1 {
+		err := errors.New("setAccessPolicy expects a JSON object with function and policy")
+		log.Error(err)
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(args[0]), &arg); err != nil {
+		err = fmt.Errorf("setAccessPolicy failed to unmarshal arg: %s", err)
+		log.Error(err)
+		return nil, err
+	}
+	if arg.Function == AccessPolicyAdmin && arg.Policy.isEmpty() {
+		err := errors.New("setAccessPolicy: the admin policy cannot be removed")
+		log.Error(err)
+		return nil, err
+	}
+	if _, found := router[arg.Function]; !found && arg.Function != AccessPolicyDefault && arg.Function != AccessPolicyAdmin {
+		err := fmt.Errorf("setAccessPolicy: function %s is not a registered route", arg.Function)
+		log.Error(err)
+		return nil, err
+	}
+	policies, err := getAccessPolicies(stub)
+	if err != nil {
+		return nil, err
+	}
+	if arg.Policy.isEmpty() {
+		delete(policies, arg.Function)
+	} else {
+		policies[arg.Function] = arg.Policy
+	}
+	if err = putAccessPolicies(stub, policies); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func putAccessPolicies(stub shim.ChaincodeStubInterface, policies map[string]AccessPolicy) error {
+	pBytes, err := json.Marshal(policies)
+	if err != nil {
+		err = fmt.Errorf("putAccessPolicies marshal failed: %s", err)
+		log.Error(err)
+		return err
+	}
+	if err = stub.PutState(ACCESSPOLICYKEY, pBytes); err != nil {
+		err = fmt.Errorf("putAccessPolicies failed to PUT policies: %s", err)
+		log.Error(err)
+		return err
+	}
+	return nil
+}
+
+// initAccessPolicy sets the admin policy when the contract is deployed, from "admin" in the
+// deploy argument. Without one, a contract that has no admin policy yet gives it to the
+// members of the deployer's MSP, so that no later caller can take control of access.
+var initAccessPolicy = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	type AdminArg struct {
+		Admin *AccessPolicy `json:"admin"`
+	}
+	var arg AdminArg
+	if len(args) == 0 {
+		err := errors.New("initAccessPolicy expects the deploy argument")
+		log.Error(err)
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(args[0]), &arg); err != nil {
+		err = fmt.Errorf("initAccessPolicy failed to unmarshal the deploy argument: %s", err)
+		log.Error(err)
+		return nil, err
+	}
+	policies, err := getAccessPolicies(stub)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case arg.Admin != nil:
+		if arg.Admin.isEmpty() {
+			err := errors.New("initAccessPolicy: the admin policy must not be empty")
+			log.Error(err)
+			return nil, err
+		}
+		policies[AccessPolicyAdmin] = *arg.Admin
+	case policies[AccessPolicyAdmin].isEmpty():
+		mspid, err := getCallerMSPID(stub)
+		if err != nil || mspid == "" {
+			log.Warningf("initAccessPolicy: the deployer's MSP is unknown, admin routes stay closed until the contract is deployed with an admin policy")
+			return nil, nil
+		}
+		policies[AccessPolicyAdmin] = AccessPolicy{MSPIDs: []string{mspid}}
+	default:
+		return nil, nil
+	}
+	if err = putAccessPolicies(stub, policies); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// readAccessPolicies shows the effective policy of every route that has one
+var readAccessPolicies ChaincodeFunc = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	policies, err := getAccessPolicies(stub)
+	if err != nil {
+		return nil, err
+	}
+	var out = make(map[string]AccessPolicy)
+	for f := range router {
+		if p, found := effectivePolicy(policies, f); found && !p.isEmpty() {
+			out[f] = p
+		}
+	}
+	for _, name := range []string{AccessPolicyDefault, AccessPolicyAdmin} {
+		if p, found := policies[name]; found {
+			out[name] = p
+		}
+	}
+	return json.Marshal(out)
+}
+
+func init() {
+	AddRoute("setAccessPolicy", "invoke", SystemClass, setAccessPolicy)
+	AddRoute("readAccessPolicies", "query", SystemClass, readAccessPolicies)
+	AddRoute("initAccessPolicy", "deploy", SystemClass, initAccessPolicy)
+
+	// the platform routes that change configuration or remove data
+	for _, f := range []string{
+		"setAccessPolicy", "deleteWorldState", "setLoggingLevel", "setCreateOnFirstUpdate",
+		"setValidationMode", "setRecentStatesRetention", "pruneRecentStates",
+		"pruneAssetStateHistory", "pruneLegacyHistory", "runMigration", "rebuildIndexes",
+		"createZone", "updateZone", "deleteZone", "createRule", "updateRule", "deleteRule",
+	} {
+		AddAdminRoute(f)
+	}
+}
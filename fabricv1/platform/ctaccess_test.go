@@ -0,0 +1,134 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+package iotcontractplatform
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/ibm-watson-iot/blockchain-samples/fabricv1/platform/cttest"
+)
+
+func TestAccessPolicyCheck(t *testing.T) {
+	id := CallerIdentity{
+		MSPID:      "Org1MSP",
+		CommonName: "gateway1",
+		OUs:        []string{"client", "logistics"},
+		Attributes: map[string]string{"role": "operator", "region": "emea"},
+	}
+	var tests = []struct {
+		policy AccessPolicy
+		want   bool
+	}{
+		{AccessPolicy{}, true},
+		{AccessPolicy{MSPIDs: []string{"Org1MSP", "Org2MSP"}}, true},
+		{AccessPolicy{MSPIDs: []string{"Org2MSP"}}, false},
+		{AccessPolicy{OUs: []string{"logistics"}}, true},
+		{AccessPolicy{OUs: []string{"admin"}}, false},
+		{AccessPolicy{Roles: []string{"operator"}}, true},
+		{AccessPolicy{Roles: []string{"client"}}, true},
+		{AccessPolicy{Roles: []string{"admin"}}, false},
+		{AccessPolicy{Attributes: map[string]string{"region": "emea"}}, true},
+		{AccessPolicy{Attributes: map[string]string{"region": ""}}, true},
+		{AccessPolicy{Attributes: map[string]string{"region": "apac"}}, false},
+		{AccessPolicy{Attributes: map[string]string{"clearance": ""}}, false},
+		{AccessPolicy{MSPIDs: []string{"Org1MSP"}, Roles: []string{"admin"}}, false},
+	}
+	for _, test := range tests {
+		if got := test.policy.check(id) == ""; got != test.want {
+			t.Fail()
+			fmt.Printf("*** policy %+v returned %t, expected %t\n", test.policy, got, test.want)
+		}
+	}
+}
+
+func TestEffectivePolicy(t *testing.T) {
+	routepolicies["testRoutePolicy"] = AccessPolicy{Roles: []string{"declared"}}
+	defer delete(routepolicies, "testRoutePolicy")
+	policies := map[string]AccessPolicy{
+		AccessPolicyDefault: {Roles: []string{"default"}},
+	}
+	if p, found := effectivePolicy(policies, "testRoutePolicy"); !found || p.Roles[0] != "declared" {
+		t.Fail()
+		fmt.Printf("*** declared policy should win over the default: %+v\n", p)
+	}
+	if p, found := effectivePolicy(policies, "readAllRoutes"); !found || p.Roles[0] != "default" {
+		t.Fail()
+		fmt.Printf("*** default policy should apply: %+v\n", p)
+	}
+	policies["testRoutePolicy"] = AccessPolicy{Roles: []string{"stored"}}
+	if p, found := effectivePolicy(policies, "testRoutePolicy"); !found || p.Roles[0] != "stored" {
+		t.Fail()
+		fmt.Printf("*** stored policy should win over the declared policy: %+v\n", p)
+	}
+}
+
+func TestAdminPolicy(t *testing.T) {
+	org2, err := cttest.NewCertificate("org2user", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock, _ := cttest.NewCertificate("mockuser", nil, nil)
+	setAdmin := `{"function": "admin", "policy": {"mspids": ["Org2MSP"]}}`
+
+	// the deployer's MSP is given the admin routes
+	stub := cttest.NewMockStub("admin", scenarioChaincode{})
+	stub.MockInit("init", []string{`{"version": "1.0"}`})
+	stub.SetCreator("Org2MSP", org2)
+	if resp := stub.MockInvoke("setAccessPolicy", []string{setAdmin}); resp.Status == shim.OK || !strings.Contains(resp.Message, "MSP Org2MSP is not one of [MockMSP]") {
+		t.Fail()
+		fmt.Printf("*** another MSP was allowed to set the admin policy: %s\n", resp.Message)
+	}
+	if resp := stub.MockInvoke("createAsset", []string{`{"asset": {"assetID": "AP1"}}`}); resp.Status != shim.OK {
+		t.Fail()
+		fmt.Printf("*** routes without a policy should stay open: %s\n", resp.Message)
+	}
+	stub.SetCreator(cttest.DefaultMSPID, mock)
+	if resp := stub.MockInvoke("setAccessPolicy", []string{`{"function": "admin", "policy": {}}`}); resp.Status == shim.OK {
+		t.Fail()
+		fmt.Printf("*** the admin policy was removed\n")
+	}
+	if resp := stub.MockInvoke("setAccessPolicy", []string{setAdmin}); resp.Status != shim.OK {
+		t.Fatalf("setAccessPolicy failed: %s", resp.Message)
+	}
+	stub.SetCreator("Org2MSP", org2)
+	if resp := stub.MockInvoke("rebuildIndexes", []string{`{"classname": "idxtest"}`}); resp.Status != shim.OK {
+		t.Fail()
+		fmt.Printf("*** the new admin was denied: %s\n", resp.Message)
+	}
+
+	// without a known deployer or an admin argument, admin routes are closed to everyone
+	stub = cttest.NewMockStub("closed", scenarioChaincode{})
+	stub.SetCreator("", nil)
+	stub.MockInit("init", []string{`{"version": "1.0"}`})
+	stub.SetCreator(cttest.DefaultMSPID, mock)
+	if resp := stub.MockInvoke("setAccessPolicy", []string{setAdmin}); resp.Status == shim.OK {
+		t.Fail()
+		fmt.Printf("*** setAccessPolicy was open without an admin policy\n")
+	}
+
+	// an admin policy passed at deploy is used as is
+	stub = cttest.NewMockStub("declared", scenarioChaincode{})
+	stub.MockInit("init", []string{`{"version": "1.0", "admin": {"mspids": ["Org2MSP"]}}`})
+	stub.SetCreator("Org2MSP", org2)
+	if resp := stub.MockInvoke("setAccessPolicy", []string{setAdmin}); resp.Status != shim.OK {
+		t.Fail()
+		fmt.Printf("*** the declared admin was denied: %s\n", resp.Message)
+	}
+}
@@ -0,0 +1,377 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+// v0.1 KL -- rolling aggregates of numeric sensor readings, kept beside the asset so that
+//            rules can judge a window of readings and not only the latest
+// v0.2 KL -- aggregates start at the oldest reading kept, and configs that cannot keep a
+//            window of readings at their interval are rejected
+
+package iotcontractplatform
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// AGGREGATEKEY prefixes the readings of one sensor of one asset, + assetKey + "." + qprop
+const AGGREGATEKEY string = "IOTCP.AGG."
+
+// DefaultMaxSamples bounds the readings kept for one sensor when the class sets no bound
+const DefaultMaxSamples int = 2000
+
+// mktActivationRatio is the activation energy over the gas constant, in Kelvin, that the
+// mean kinetic temperature uses for pharmaceuticals, 83.144 kJ/mol / 8.3144 J/mol/K
+const mktActivationRatio float64 = 10000
+
+// SensorAggregation declares a numeric property, relative to asset state, whose readings
+// are aggregated. Threshold adds the time spent above it, and MKT the mean kinetic
+// temperature of readings in degrees Celsius.
+type SensorAggregation struct {
+	QProp     string   `json:"qprop"`
+	Threshold *float64 `json:"threshold,omitempty"`
+	MKT       bool     `json:"mkt,omitempty"`
+}
+
+// AggregationConfig declares the sensors of a class and the windows over which they are
+// aggregated. Readings are kept for the longest window, up to MaxSamples per sensor.
+// Interval is the shortest expected time between readings, and when it is set the
+// longest window must hold no more than MaxSamples readings at that interval.
+type AggregationConfig struct {
+	Sensors    []SensorAggregation `json:"sensors"`
+	Windows    []time.Duration     `json:"windows"`
+	MaxSamples int                 `json:"maxsamples,omitempty"`
+	Interval   time.Duration       `json:"interval,omitempty"`
+}
+
+// Sample is one reading
+type Sample struct {
+	T time.Time `json:"t"`
+	V float64   `json:"v"`
+}
+
+// sensorSeries is stored under the aggregate key, readings are oldest first. Since is the
+// time of the oldest reading kept once older readings have been dropped.
+type sensorSeries struct {
+	QProp   string     `json:"qprop"`
+	Samples []Sample   `json:"samples"`
+	Since   *time.Time `json:"since,omitempty"`
+}
+
+// sensorReadings holds the series that a write updated, by qprop
+type sensorReadings map[string]*sensorSeries
+
+// Aggregate summarizes the readings of a sensor from From to To. From is the start of the
+// window, or the oldest reading kept when the window reaches further back. The mean is of
+// the readings. Each reading holds until the next, so SecondsAbove counts the time that the
+// readings were above the threshold, including the time since the last reading.
+type Aggregate struct {
+	QProp        string    `json:"qprop"`
+	Window       string    `json:"window"`
+	From         time.Time `json:"from"`
+	To           time.Time `json:"to"`
+	Count        int       `json:"count"`
+	Min          float64   `json:"min"`
+	Max          float64   `json:"max"`
+	Mean         float64   `json:"mean"`
+	Last         float64   `json:"last"`
+	SecondsAbove *float64  `json:"secondsabove,omitempty"`
+	MKT          *float64  `json:"mkt,omitempty"`
+}
+
+var aggregationrouter = make(map[AssetClass]AggregationConfig, 0)
+
+// AddAggregation declares the sensors of a class whose readings are aggregated
+func AddAggregation(class AssetClass, cfg AggregationConfig) error {
+	if len(cfg.Sensors) == 0 || len(cfg.Windows) == 0 {
+		err := fmt.Errorf("AddAggregation: class %s needs sensors and windows", class.Name)
+		log.Error(err)
+		return err
+	}
+	for _, w := range cfg.Windows {
+		if w <= 0 {
+			err := fmt.Errorf("AddAggregation: class %s window %s is not positive", class.Name, w)
+			log.Error(err)
+			return err
+		}
+	}
+	if cfg.MaxSamples <= 0 {
+		cfg.MaxSamples = DefaultMaxSamples
+	}
+	if cfg.Interval < 0 {
+		err := fmt.Errorf("AddAggregation: class %s interval %s is negative", class.Name, cfg.Interval)
+		log.Error(err)
+		return err
+	}
+	if cfg.Interval > 0 && int64(cfg.longestWindow()/cfg.Interval) > int64(cfg.MaxSamples) {
+		err := fmt.Errorf("AddAggregation: class %s window %s holds %d readings at interval %s, more than the %d that are kept", class.Name, cfg.longestWindow(), cfg.longestWindow()/cfg.Interval, cfg.Interval, cfg.MaxSamples)
+		log.Error(err)
+		return err
+	}
+	aggregationrouter[class] = cfg
+	log.Debugf("Class %s added aggregation of %d sensors over %v", class.Name, len(cfg.Sensors), cfg.Windows)
+	return nil
+}
+
+func (cfg AggregationConfig) longestWindow() time.Duration {
+	var longest time.Duration
+	for _, w := range cfg.Windows {
+		if w > longest {
+			longest = w
+		}
+	}
+	return longest
+}
+
+func (cfg AggregationConfig) sensor(qprop string) (SensorAggregation, bool) {
+	for _, s := range cfg.Sensors {
+		if s.QProp == qprop {
+			return s, true
+		}
+	}
+	return SensorAggregation{}, false
+}
+
+func aggregateKey(assetKey string, qprop string) string {
+	return AGGREGATEKEY + assetKey + "." + qprop
+}
+
+func getSensorSeries(stub shim.ChaincodeStubInterface, assetKey string, qprop string) (*sensorSeries, error) {
+	var series = &sensorSeries{QProp: qprop, Samples: make([]Sample, 0)}
+	sBytes, err := stub.GetState(aggregateKey(assetKey, qprop))
+	if err != nil {
+		return nil, fmt.Errorf("GetState for %s readings of %s failed: %s", qprop, assetKey, err)
+	}
+	if len(sBytes) > 0 {
+		if err = json.Unmarshal(sBytes, series); err != nil {
+			return nil, fmt.Errorf("unmarshal of %s readings of %s failed: %s", qprop, assetKey, err)
+		}
+	}
+	return series, nil
+}
+
+// trim drops the readings that no window can see. The newest reading before the longest
+// window is kept, as it holds into the window. When readings are dropped, Since moves to
+// the oldest reading kept.
+func (s *sensorSeries) trim(now time.Time, keep time.Duration, maxSamples int) {
+	from := now.Add(-keep)
+	first := 0
+	for i := range s.Samples {
+		if s.Samples[i].T.After(from) {
+			break
+		}
+		first = i
+	}
+	if len(s.Samples)-first > maxSamples {
+		first = len(s.Samples) - maxSamples
+	}
+	if first > 0 {
+		since := s.Samples[first].T
+		s.Since = &since
+	}
+	s.Samples = s.Samples[first:]
+}
+
+// updateAggregates records the readings that the event carries, the updated series are
+// kept on the asset so that rules see the current reading
+func (a *Asset) updateAggregates(stub shim.ChaincodeStubInterface) error {
+	a.series = nil
+	cfg, found := aggregationrouter[a.Class]
+	if !found || a.EventIn == nil || a.TXNTS == nil {
+		return nil
+	}
+	for _, sensor := range cfg.Sensors {
+		v, found := GetObjectAsNumber(a.EventIn, sensor.QProp)
+		if !found {
+			continue
+		}
+		series, err := getSensorSeries(stub, a.AssetKey, sensor.QProp)
+		if err != nil {
+			return err
+		}
+		series.Samples = append(series.Samples, Sample{*a.TXNTS, v})
+		series.trim(*a.TXNTS, cfg.longestWindow(), cfg.MaxSamples)
+		sBytes, err := json.Marshal(series)
+		if err != nil {
+			return err
+		}
+		if err = stub.PutState(aggregateKey(a.AssetKey, sensor.QProp), sBytes); err != nil {
+			return fmt.Errorf("PutState for %s readings of %s failed: %s", sensor.QProp, a.AssetKey, err)
+		}
+		if a.series == nil {
+			a.series = make(sensorReadings)
+		}
+		a.series[sensor.QProp] = series
+	}
+	return nil
+}
+
+// removeAggregates deletes the readings of an asset that is being deleted
+func (a *Asset) removeAggregates(stub shim.ChaincodeStubInterface) error {
+	cfg, found := aggregationrouter[a.Class]
+	if !found {
+		return nil
+	}
+	for _, sensor := range cfg.Sensors {
+		if err := stub.DelState(aggregateKey(a.AssetKey, sensor.QProp)); err != nil {
+			return fmt.Errorf("DelState for %s readings of %s failed: %s", sensor.QProp, a.AssetKey, err)
+		}
+	}
+	return nil
+}
+
+// aggregate summarizes the readings in the window that ends at now
+func (s *sensorSeries) aggregate(sensor SensorAggregation, window time.Duration, now time.Time) Aggregate {
+	var agg = Aggregate{QProp: s.QProp, Window: window.String(), From: now.Add(-window), To: now}
+	if s.Since != nil && s.Since.After(agg.From) {
+		agg.From = *s.Since
+	}
+	var sum, arrhenius, above float64
+	for i, sample := range s.Samples {
+		if sample.T.After(now) {
+			break
+		}
+		// the time that this reading held within the window
+		end := now
+		if i+1 < len(s.Samples) && s.Samples[i+1].T.Before(now) {
+			end = s.Samples[i+1].T
+		}
+		start := sample.T
+		if start.Before(agg.From) {
+			start = agg.From
+		}
+		if sensor.Threshold != nil && sample.V > *sensor.Threshold && end.After(start) {
+			above += end.Sub(start).Seconds()
+		}
+		if !sample.T.After(agg.From) {
+			continue
+		}
+		if agg.Count == 0 || sample.V < agg.Min {
+			agg.Min = sample.V
+		}
+		if agg.Count == 0 || sample.V > agg.Max {
+			agg.Max = sample.V
+		}
+		agg.Count++
+		agg.Last = sample.V
+		sum += sample.V
+		arrhenius += math.Exp(-mktActivationRatio / (sample.V + 273.15))
+	}
+	if sensor.Threshold != nil {
+		agg.SecondsAbove = &above
+	}
+	if agg.Count == 0 {
+		return agg
+	}
+	agg.Mean = sum / float64(agg.Count)
+	if sensor.MKT {
+		mkt := -mktActivationRatio/math.Log(arrhenius/float64(agg.Count)) - 273.15
+		agg.MKT = &mkt
+	}
+	return agg
+}
+
+// Aggregate summarizes the readings of a sensor over the window that ends at the asset's
+// transaction time, including the reading that the asset is being written with, e.g. to
+// raise an alert when the mean temperature of the last hour is above 8. It returns false
+// when the window holds no readings. Windows longer than the class's longest window see
+// only the readings kept for it, and start at the oldest of them.
+func (a *Asset) Aggregate(stub shim.ChaincodeStubInterface, qprop string, window time.Duration) (Aggregate, bool, error) {
+	cfg, found := aggregationrouter[a.Class]
+	if !found {
+		return Aggregate{}, false, fmt.Errorf("class %s has no aggregation", a.Class.Name)
+	}
+	sensor, found := cfg.sensor(qprop)
+	if !found {
+		return Aggregate{}, false, fmt.Errorf("class %s does not aggregate %s", a.Class.Name, qprop)
+	}
+	series, found := a.series[qprop]
+	if !found {
+		var err error
+		if series, err = getSensorSeries(stub, a.AssetKey, qprop); err != nil {
+			return Aggregate{}, false, err
+		}
+	}
+	var now time.Time
+	if a.TXNTS != nil {
+		now = *a.TXNTS
+	}
+	agg := series.aggregate(sensor, window, now)
+	return agg, agg.Count > 0, nil
+}
+
+// ReadAssetAggregates returns the aggregates of an asset's sensors over each window of
+// the class, ending at the transaction time, e.g. {"asset": {"assetID": "C1"}}. Pass
+// {"qprop": "..."} to read one sensor.
+func (c *AssetClass) ReadAssetAggregates(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	type QPropArg struct {
+		QProp string `json:"qprop"`
+	}
+	var arg = c.NewAsset()
+	if err := arg.unmarshallEventIn(stub, args); err != nil {
+		err = fmt.Errorf("ReadAssetAggregates for class %s could not unmarshall, err is %s", c.Name, err)
+		log.Error(err)
+		return nil, err
+	}
+	assetKey, err := arg.getAssetKey()
+	if err != nil {
+		err = fmt.Errorf("ReadAssetAggregates for class %s could not find id at %s, err is %s", c.Name, c.AssetIDPath, err)
+		log.Error(err)
+		return nil, err
+	}
+	var qarg QPropArg
+	if err = json.Unmarshal([]byte(args[0]), &qarg); err != nil {
+		err = fmt.Errorf("ReadAssetAggregates for class %s failed to unmarshal qprop: %s", c.Name, err)
+		log.Error(err)
+		return nil, err
+	}
+	cfg, found := aggregationrouter[*c]
+	if !found {
+		err = fmt.Errorf("ReadAssetAggregates: class %s has no aggregation", c.Name)
+		log.Error(err)
+		return nil, err
+	}
+	if err = assetExists(stub, *c, assetKey); err != nil {
+		err = fmt.Errorf("ReadAssetAggregates: %s", err)
+		log.Error(err)
+		return nil, err
+	}
+	now, err := getTxTime(stub)
+	if err != nil {
+		err = fmt.Errorf("ReadAssetAggregates failed to get the transaction time: %s", err)
+		log.Error(err)
+		return nil, err
+	}
+	var aggs = make([]Aggregate, 0, len(cfg.Sensors)*len(cfg.Windows))
+	for _, sensor := range cfg.Sensors {
+		if qarg.QProp != "" && qarg.QProp != sensor.QProp {
+			continue
+		}
+		series, err := getSensorSeries(stub, assetKey, sensor.QProp)
+		if err != nil {
+			err = fmt.Errorf("ReadAssetAggregates for class %s: %s", c.Name, err)
+			log.Error(err)
+			return nil, err
+		}
+		for _, w := range cfg.Windows {
+			aggs = append(aggs, series.aggregate(sensor, w, now))
+		}
+	}
+	return json.Marshal(aggs)
+}
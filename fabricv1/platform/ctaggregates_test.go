@@ -0,0 +1,137 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+package iotcontractplatform
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/ibm-watson-iot/blockchain-samples/fabricv1/platform/cttest"
+)
+
+func TestSensorAggregate(t *testing.T) {
+	start := time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+	series := &sensorSeries{QProp: "box.temperature"}
+	for i, v := range []float64{2, 5, 9, 12, 7} {
+		series.Samples = append(series.Samples, Sample{start.Add(time.Duration(i) * 10 * time.Minute), v})
+	}
+	threshold := 8.0
+	sensor := SensorAggregation{QProp: "box.temperature", Threshold: &threshold, MKT: true}
+	now := start.Add(45 * time.Minute)
+
+	agg := series.aggregate(sensor, 30*time.Minute, now)
+	if agg.Count != 3 || agg.Min != 7 || agg.Max != 12 || agg.Mean != 28.0/3 || agg.Last != 7 {
+		t.Fail()
+		fmt.Printf("*** 30 minute aggregate is %+v\n", agg)
+	}
+	// 9 from 20 to 30 and 12 from 30 to 40 minutes
+	if agg.SecondsAbove == nil || *agg.SecondsAbove != 1200 {
+		t.Fail()
+		fmt.Printf("*** seconds above %v, expected 1200\n", agg.SecondsAbove)
+	}
+	// the mean kinetic temperature favours the warm readings
+	if agg.MKT == nil || *agg.MKT <= agg.Mean || *agg.MKT >= agg.Max || math.Abs(*agg.MKT-9.59) > 0.01 {
+		t.Fail()
+		fmt.Printf("*** mkt %v, expected about 9.59\n", agg.MKT)
+	}
+
+	series.trim(now, 20*time.Minute, DefaultMaxSamples)
+	if len(series.Samples) != 3 || series.Samples[0].V != 9 {
+		t.Fail()
+		fmt.Printf("*** trim kept %v, expected the readings from 20 minutes\n", series.Samples)
+	}
+	series.trim(now, time.Hour, 2)
+	if len(series.Samples) != 2 || series.Samples[0].V != 12 {
+		t.Fail()
+		fmt.Printf("*** trim kept %v, expected the newest 2 readings\n", series.Samples)
+	}
+	// the window reaches past the readings that were dropped, so it starts at the oldest kept
+	agg = series.aggregate(sensor, 30*time.Minute, now)
+	if !agg.From.Equal(start.Add(30*time.Minute)) || agg.Count != 1 {
+		t.Fail()
+		fmt.Printf("*** aggregate after the trim is from %s with %d readings, expected from %s with 1\n", agg.From, agg.Count, start.Add(30*time.Minute))
+	}
+}
+
+func TestAddAggregationInterval(t *testing.T) {
+	class := AssetClass{Name: "aggintervaltest", Prefix: "AGI", AssetIDPath: "box.id"}
+	defer delete(aggregationrouter, class)
+	cfg := AggregationConfig{
+		Sensors:    []SensorAggregation{{QProp: "box.temperature"}},
+		Windows:    []time.Duration{time.Minute, time.Hour},
+		MaxSamples: 100,
+		Interval:   time.Second,
+	}
+	if err := AddAggregation(class, cfg); err == nil {
+		t.Fail()
+		fmt.Printf("*** an hour of readings a second was accepted with %d samples\n", cfg.MaxSamples)
+	}
+	cfg.Interval = time.Minute
+	if err := AddAggregation(class, cfg); err != nil {
+		t.Fail()
+		fmt.Printf("*** an hour of readings a minute was rejected: %s\n", err)
+	}
+}
+
+var aggTestClass = AssetClass{Name: "aggtest", Prefix: "AGG", AssetIDPath: "box.id"}
+
+func init() {
+	AddAggregation(aggTestClass, AggregationConfig{
+		Sensors: []SensorAggregation{{QProp: "box.temperature"}},
+		Windows: []time.Duration{3 * time.Second},
+	})
+	AddRule("Mean Temperature", aggTestClass, []AlertName{"MEANHIGH"}, func(stub shim.ChaincodeStubInterface, a *Asset) error {
+		agg, found, err := a.Aggregate(stub, "box.temperature", 3*time.Second)
+		if err != nil {
+			return err
+		}
+		if found && agg.Mean > 8 {
+			RaiseAlert(a, "MEANHIGH")
+		} else {
+			ClearAlert(a, "MEANHIGH")
+		}
+		return nil
+	})
+	AddRoute("updateAggTest", "invoke", aggTestClass, func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+		return aggTestClass.UpdateAsset(stub, args, "updateAggTest", []QPropNV{})
+	})
+}
+
+// TestAggregateRule judges the mean of the readings in a rule, the current reading included
+func TestAggregateRule(t *testing.T) {
+	stub := cttest.NewMockStub("aggregate", scenarioChaincode{})
+	stub.MockInit("init", []string{`{"version": "1.0"}`})
+	stub.MockInvoke("setCreateOnFirstUpdate", []string{`{"setCreateOnFirstUpdate": true}`})
+	for _, test := range []struct {
+		temperature float64
+		alert       bool
+	}{{6, false}, {12, true}, {7, true}, {4, false}} {
+		arg := fmt.Sprintf(`{"box": {"id": "B1", "temperature": %v}}`, test.temperature)
+		if resp := stub.MockInvoke("updateAggTest", []string{arg}); resp.Status != shim.OK {
+			t.Fail()
+			fmt.Printf("*** updateAggTest failed: %s\n", resp.Message)
+		}
+		a, _, err := GetAssetFromLedger(stub, "AGGB1")
+		if err != nil || Contains(a.AlertsActive, AlertName("MEANHIGH")) != test.alert {
+			t.Fail()
+			fmt.Printf("*** after %v the alerts are %v, expected MEANHIGH %t\n", test.temperature, a.AlertsActive, test.alert)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+// v0.1 KL -- point in time reads, the state of an asset or a class in effect at a
+//            moment, reconstructed from the state history
+
+package iotcontractplatform
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// AsOf selects the moment to read, as {"asof": {"timestamp": "...", "txnid": "..."}}.
+// The state in effect is the newest state written at or before the timestamp. A txnid
+// selects the state that the transaction wrote, and without a timestamp the moment is
+// that transaction's time.
+type AsOf struct {
+	AsOf struct {
+		Timestamp string `json:"timestamp"`
+		TXNID     string `json:"txnid"`
+	} `json:"asof"`
+}
+
+// asOfPoint is a parsed AsOf
+type asOfPoint struct {
+	ts    time.Time
+	hasTS bool
+	txnid string
+}
+
+func getUnmarshalledAsOf(args []string) (asOfPoint, error) {
+	var p asOfPoint
+	var arg AsOf
+	if len(args) == 0 {
+		return p, fmt.Errorf("expecting an asof timestamp or txnid in args[0]")
+	}
+	if err := json.Unmarshal([]byte(args[0]), &arg); err != nil {
+		return p, fmt.Errorf("failed to unmarshal %s as an asof: %s", args[0], err)
+	}
+	if arg.AsOf.Timestamp == "" && arg.AsOf.TXNID == "" {
+		return p, fmt.Errorf("expecting an asof timestamp or txnid in args[0]")
+	}
+	if arg.AsOf.Timestamp != "" {
+		ts, err := time.Parse(time.RFC3339Nano, arg.AsOf.Timestamp)
+		if err != nil {
+			return p, fmt.Errorf("asof timestamp %s is not RFC3339: %s", arg.AsOf.Timestamp, err)
+		}
+		p.ts, p.hasTS = ts, true
+	}
+	p.txnid = arg.AsOf.TXNID
+	return p, nil
+}
+
+// asOfSelector picks the state in effect from an asset's history, offered in any order
+type asOfSelector struct {
+	point asOfPoint
+	state *Asset
+	exact bool
+}
+
+func (s *asOfSelector) offer(a *Asset) {
+	if s.exact || a.TXNTS == nil {
+		return
+	}
+	if s.point.txnid != "" && a.TXNID == s.point.txnid {
+		s.state, s.exact = a, true
+		return
+	}
+	if !s.point.hasTS || a.TXNTS.After(s.point.ts) {
+		return
+	}
+	if s.state == nil || !a.TXNTS.Before(*s.state.TXNTS) {
+		s.state = a
+	}
+}
+
+// deletedAsOf is true when the ledger shows that the asset was deleted after the state
+// was written and no later than the moment. Peers without a history database cannot
+// answer, and then the state stands.
+func deletedAsOf(stub shim.ChaincodeStubInterface, a *Asset, point asOfPoint) bool {
+	if !point.hasTS {
+		return false
+	}
+	iter, err := stub.GetHistoryForKey(a.AssetKey)
+	if err != nil {
+		log.Warningf("deletedAsOf could not read the ledger history of %s: %s", a.AssetKey, err)
+		return false
+	}
+	defer iter.Close()
+	for iter.HasNext() {
+		km, err := iter.Next()
+		if err != nil {
+			log.Warningf("deletedAsOf could not read the ledger history of %s: %s", a.AssetKey, err)
+			return false
+		}
+		if !km.IsDelete || km.Timestamp == nil {
+			continue
+		}
+		deleted := time.Unix(km.Timestamp.Seconds, int64(km.Timestamp.Nanos))
+		if deleted.After(*a.TXNTS) && !deleted.After(point.ts) {
+			return true
+		}
+	}
+	return false
+}
+
+// readStateAsOf returns the state of one asset in effect at the moment, or nil when the
+// asset did not exist then
+func (c *AssetClass) readStateAsOf(stub shim.ChaincodeStubInterface, assetKey string, point asOfPoint) (*Asset, error) {
+	var sel = asOfSelector{point: point}
+	// history keys carry the write time in the zone of the peer that wrote them, so the
+	// states are selected by their timestamps alone
+	err := classHistoryBackend(*c).ForEachAssetState(stub, *c, assetKey, "", "}", func(key string, state *Asset) error {
+		sel.offer(state)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = checkHistoryPrunedBefore(stub, assetKey, point, sel.exact); err != nil {
+		return nil, err
+	}
+	if sel.state == nil || sel.state.Deleted || deletedAsOf(stub, sel.state, point) {
+		return nil, nil
+	}
+	return sel.state, nil
+}
+
+// checkHistoryPrunedBefore fails when the moment falls before the asset's pruned history,
+// where the state in effect may be gone. A state found by its transaction stands.
+func checkHistoryPrunedBefore(stub shim.ChaincodeStubInterface, assetKey string, point asOfPoint, exact bool) error {
+	before, err := getHistoryPrunedBefore(stub, assetKey)
+	if err != nil || before == nil || exact {
+		return err
+	}
+	if !point.hasTS {
+		return fmt.Errorf("transaction %s is not in the history of %s, which was pruned before %s", point.txnid, assetKey, before.Format(time.RFC3339Nano))
+	}
+	if point.ts.Before(*before) {
+		return fmt.Errorf("the history of %s was pruned before %s, so %s cannot be read", assetKey, before.Format(time.RFC3339Nano), point.ts.Format(time.RFC3339Nano))
+	}
+	return nil
+}
+
+// ReadAssetAsOf returns the state of an asset in effect at a moment, e.g.
+// {"asset": {"assetID": "A1"}, "asof": {"timestamp": "2017-06-01T14:05:00Z"}}. Private
+// values are returned as the markers that the state held, as collections keep only the
+// current values.
+func (c *AssetClass) ReadAssetAsOf(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	var arg = c.NewAsset()
+	if err := arg.unmarshallEventIn(stub, args); err != nil {
+		err = fmt.Errorf("ReadAssetAsOf for class %s could not unmarshall, err is %s", c.Name, err)
+		log.Error(err)
+		return nil, err
+	}
+	assetKey, err := arg.getAssetKey()
+	if err != nil {
+		err = fmt.Errorf("ReadAssetAsOf for class %s could not find id at %s, err is %s", c.Name, c.AssetIDPath, err)
+		log.Error(err)
+		return nil, err
+	}
+	point, err := getUnmarshalledAsOf(args)
+	if err != nil {
+		err = fmt.Errorf("ReadAssetAsOf for class %s, asset %s: %s", c.Name, assetKey, err)
+		log.Error(err)
+		return nil, err
+	}
+	state, err := c.readStateAsOf(stub, assetKey, point)
+	if err != nil {
+		err = fmt.Errorf("ReadAssetAsOf for class %s, asset %s: %s", c.Name, assetKey, err)
+		log.Error(err)
+		return nil, err
+	}
+	if state == nil {
+		if point.hasTS {
+			err = fmt.Errorf("ReadAssetAsOf for class %s, asset %s did not exist at %s", c.Name, assetKey, point.ts.Format(time.RFC3339Nano))
+		} else {
+			err = fmt.Errorf("ReadAssetAsOf for class %s, asset %s was not written by transaction %s, pass a timestamp", c.Name, assetKey, point.txnid)
+		}
+		log.Error(err)
+		return nil, err
+	}
+	return json.Marshal(state)
+}
+
+// ReadAllAssetsAsOf returns the states in effect at a moment of all assets of the class
+// that pass the filter, in key order. It reads the whole history of the class, and so
+// returns an error for classes with NativeHistory.
+func (c *AssetClass) ReadAllAssetsAsOf(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	point, err := getUnmarshalledAsOf(args)
+	if err != nil {
+		err = fmt.Errorf("ReadAllAssetsAsOf for class %s: %s", c.Name, err)
+		log.Error(err)
+		return nil, err
+	}
+	filter, err := getUnmarshalledStateFilter(args)
+	if err != nil {
+		err = fmt.Errorf("ReadAllAssetsAsOf for class %s failed to get a filter: %s", c.Name, err)
+		log.Error(err)
+		return nil, err
+	}
+
+	// a transaction alone is resolved to its time, which applies to the assets it did not write
+	if !point.hasTS {
+		err = c.forEachHistoryState(stub, func(state *Asset) {
+			if state.TXNID == point.txnid && state.TXNTS != nil {
+				point.ts, point.hasTS = *state.TXNTS, true
+			}
+		})
+		if err == nil && !point.hasTS {
+			err = fmt.Errorf("transaction %s wrote no %s asset, pass a timestamp", point.txnid, c.Name)
+		}
+	}
+	var selectors = make(map[string]*asOfSelector)
+	if err == nil {
+		err = c.forEachHistoryState(stub, func(state *Asset) {
+			sel, found := selectors[state.AssetKey]
+			if !found {
+				sel = &asOfSelector{point: point}
+				selectors[state.AssetKey] = sel
+			}
+			sel.offer(state)
+		})
+	}
+	if err != nil {
+		err = fmt.Errorf("ReadAllAssetsAsOf for class %s: %s", c.Name, err)
+		log.Error(err)
+		return nil, err
+	}
+
+	var assets = make(AssetArray, 0, len(selectors))
+	for assetKey, sel := range selectors {
+		if err = checkHistoryPrunedBefore(stub, assetKey, point, sel.exact); err != nil {
+			err = fmt.Errorf("ReadAllAssetsAsOf for class %s: %s", c.Name, err)
+			log.Error(err)
+			return nil, err
+		}
+		if sel.state == nil || sel.state.Deleted || !sel.state.Filter(filter) || deletedAsOf(stub, sel.state, point) {
+			continue
+		}
+		assets = append(assets, *sel.state)
+	}
+	sort.Sort(assets)
+	return json.Marshal(assets)
+}
+
+// forEachHistoryState calls f with every history state of the class
+func (c *AssetClass) forEachHistoryState(stub shim.ChaincodeStubInterface, f func(*Asset)) error {
+	return classHistoryBackend(*c).ForEachClassState(stub, *c, func(key string, state *Asset) error {
+		f(state)
+		return nil
+	})
+}
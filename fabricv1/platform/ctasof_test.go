@@ -0,0 +1,70 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+package iotcontractplatform
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/ibm-watson-iot/blockchain-samples/fabricv1/platform/cttest"
+)
+
+func TestAsOfAcrossZones(t *testing.T) {
+	local := time.Local
+	defer func() { time.Local = local }()
+
+	// the history is written by a peer east of UTC
+	time.Local = time.FixedZone("east", 9*3600)
+	stub := cttest.NewMockStub("asofzone", scenarioChaincode{})
+	stub.MockInit("init", []string{`{"version": "1.0"}`})
+	for _, temp := range []int{-5, 4} {
+		resp := stub.MockInvoke("updateAsset", []string{fmt.Sprintf(`{"asset": {"assetID": "Z1", "temperature": %d}}`, temp)})
+		if resp.Status != shim.OK {
+			t.Fatalf("updateAsset failed: %s", resp.Message)
+		}
+	}
+
+	// and read by a peer west of UTC
+	time.Local = time.FixedZone("west", -5*3600)
+	for _, test := range []struct {
+		asof string
+		temp float64
+	}{
+		{"2017-01-01T00:00:01.5Z", -5},
+		{"2017-01-01T00:00:02Z", 4},
+		{"2016-12-31T19:00:01.5-05:00", -5},
+		{"2017-01-01T09:00:03+09:00", 4},
+	} {
+		resp := stub.MockQuery("readAssetAsOf", []string{fmt.Sprintf(`{"asset": {"assetID": "Z1"}, "asof": {"timestamp": "%s"}}`, test.asof)})
+		if resp.Status != shim.OK {
+			t.Fail()
+			fmt.Printf("*** readAssetAsOf %s failed: %s\n", test.asof, resp.Message)
+			continue
+		}
+		var a Asset
+		if err := json.Unmarshal(resp.Payload, &a); err != nil {
+			t.Fatal(err)
+		}
+		if temp, _ := GetObjectAsNumber(a.State, "asset.temperature"); temp != test.temp {
+			t.Fail()
+			fmt.Printf("*** readAssetAsOf %s returned temperature %v, expected %v\n", test.asof, temp, test.temp)
+		}
+	}
+}
@@ -52,7 +52,7 @@ type AssetClass struct {
 // NewAsset create an instance of an asset class
 func (c AssetClass) NewAsset() Asset {
 	var a = Asset{
-		c, "", nil, nil, "", "", nil, &InvokeResultEvent{"EVT.IOTCP.INVOKE.RESULT", make(map[string]interface{}, 0)}, AlertNameArray(make([]AlertName, 0)), true,
+		c, "", nil, nil, "", "", nil, &InvokeResultEvent{"EVT.IOTCP.INVOKE.RESULT", make(map[string]interface{}, 0)}, AlertNameArray(make([]AlertName, 0)), true, 0, nil, nil, false, nil, nil,
 	}
 	return a
 }
@@ -67,16 +67,22 @@ func (c AssetClass) String() string {
 // Asset is a type that holds all information about an asset, including its name,
 // its world state prefix, and the qualified property name that is its assetID
 type Asset struct {
-	Class        AssetClass              `json:"assetclass"`         // asset's classifier with metadata
-	AssetKey     string                  `json:"assetkey"`           // asset's world state key
-	State        *map[string]interface{} `json:"assetstate"`         // asset's current state
-	EventIn      *map[string]interface{} `json:"eventpayload"`       // most recent event body
-	FunctionIn   string                  `json:"eventfunction"`      // most recent event function
-	TXNID        string                  `json:"txnid"`              // transaction UUID matching blockchain
-	TXNTS        *time.Time              `json:"txnts,omitempty"`    // transaction timestamp matching blockchain
-	EventOut     *InvokeResultEvent      `json:"eventout,omitempty"` // event emitted upon exit from an invoke
-	AlertsActive AlertNameArray          `json:"alerts,omitempty"`   // array of active alerts
-	Compliant    bool                    `json:"compliant"`          // true if the asset complies with the contract terms
+	Class        AssetClass              `json:"assetclass"`             // asset's classifier with metadata
+	AssetKey     string                  `json:"assetkey"`               // asset's world state key
+	State        *map[string]interface{} `json:"assetstate"`             // asset's current state
+	EventIn      *map[string]interface{} `json:"eventpayload"`           // most recent event body
+	FunctionIn   string                  `json:"eventfunction"`          // most recent event function
+	TXNID        string                  `json:"txnid"`                  // transaction UUID matching blockchain
+	TXNTS        *time.Time              `json:"txnts,omitempty"`        // transaction timestamp matching blockchain
+	EventOut     *InvokeResultEvent      `json:"eventout,omitempty"`     // event emitted upon exit from an invoke
+	AlertsActive AlertNameArray          `json:"alerts,omitempty"`       // array of active alerts
+	Compliant    bool                    `json:"compliant"`              // true if the asset complies with the contract terms
+	RulesVersion int                     `json:"rulesversion,omitempty"` // version of the declarative rule set that judged this state
+	Transition   *StateTransition        `json:"transition,omitempty"`   // lifecycle transition made by this state
+	Diff         *StateDiff              `json:"diff,omitempty"`         // properties added, removed and changed by this state
+	Deleted      bool                    `json:"deleted,omitempty"`      // true for a history state that records a deletion
+	Zones        *ZoneChange             `json:"zones,omitempty"`        // attached zones holding this state's location
+	series       sensorReadings          `json:"-"`                      // sensor readings updated by this write, for rules
 }
 
 // AssetArray is an array of assets, used by read all, recent states, history, etc.
@@ -109,13 +115,43 @@ func (a *Asset) PUTAsset(stub shim.ChaincodeStubInterface, caller string, inject
 		}
 	}
 
+	prior, err := a.getPriorAsset(stub)
+	if err != nil {
+		err = fmt.Errorf("PUTAsset for class %s failed to read the prior state of %s, err is %s", a.Class.Name, a.AssetKey, err)
+		log.Error(err)
+		return nil, err
+	}
+	var priorState *map[string]interface{}
+	if prior != nil {
+		priorState = prior.State
+	}
+
+	// rules see what the event changed, the zones that the asset moved through and the
+	// aggregated sensor readings, the stored diff also has what the rules changed
+	a.Diff = computeStateDiff(priorState, a.State)
+	if err := a.updateAggregates(stub); err != nil {
+		err = fmt.Errorf("PUTAsset for class %s failed to aggregate the readings of %s, err is %s", a.Class.Name, a.AssetKey, err)
+		log.Error(err)
+		return nil, err
+	}
+	if err := a.applyZones(stub, prior); err != nil {
+		err = fmt.Errorf("PUTAsset for class %s failed to check the zones of %s, err is %s", a.Class.Name, a.AssetKey, err)
+		log.Error(err)
+		return nil, err
+	}
 	if err := a.ExecuteRules(stub); err != nil {
 		err = fmt.Errorf("PUTAsset for class %s failed in rules engine for %s, err is %s", a.Class.Name, a.AssetKey, err)
 		log.Errorf(err.Error())
 		return nil, err
 	}
+	a.Diff = a.redactDiff(computeStateDiff(priorState, a.State))
 
+	// the typed events travel with the alert deltas into the invoke result event
 	alertsDeltas := GetAlertsAndDeltas(alertsIn, a.AlertsActive)
+	if alertsDeltas == nil {
+		alertsDeltas = make(map[string]interface{})
+	}
+	alertsDeltas["events"] = a.writeEvents(prior, alertsIn)
 	alertsDeltasBytes, err := json.Marshal(alertsDeltas)
 	if err != nil {
 		err = fmt.Errorf("PUTAsset for class %s failed to marshall alert deltas for %s[%+v], err is %s", a.Class.Name, a.AssetKey, alertsDeltas, err)
@@ -142,6 +178,9 @@ func (c *AssetClass) CreateAsset(stub shim.ChaincodeStubInterface, args []string
 		log.Errorf(err.Error())
 		return nil, err
 	}
+	if err := c.validateEvent(stub, caller, a.EventIn, false); err != nil {
+		return nil, err
+	}
 	assetKey, err := a.getAssetKey()
 	if err != nil {
 		err = fmt.Errorf("CreateAsset for class %s could not find id at %s, err is %s", c.Name, c.AssetIDPath, err)
@@ -168,6 +207,9 @@ func (c *AssetClass) CreateAsset(stub shim.ChaincodeStubInterface, args []string
 		log.Errorf(err.Error())
 		return nil, err
 	}
+	if err := a.applyStateMachine(stub, "", caller); err != nil {
+		return nil, err
+	}
 
 	return a.PUTAsset(stub, caller, inject)
 }
@@ -182,13 +224,16 @@ func (c *AssetClass) ReplaceAsset(stub shim.ChaincodeStubInterface, args []strin
 		log.Errorf(err.Error())
 		return nil, err
 	}
+	if err := c.validateEvent(stub, caller, a.EventIn, false); err != nil {
+		return nil, err
+	}
 	assetKey, err := a.getAssetKey()
 	if err != nil {
 		err = fmt.Errorf("ReplaceAsset for class %s could not find id at %s, err is %s", c.Name, c.AssetIDPath, err)
 		log.Errorf(err.Error())
 		return nil, err
 	}
-	_, exists, err := c.getAssetFromWorldState(stub, assetKey)
+	assetBytes, exists, err := c.getAssetFromWorldState(stub, assetKey)
 	if err != nil {
 		err := fmt.Errorf("ReplaceAsset for class %s asset %s read from world state returned error %s", c.Name, a.AssetKey, err)
 		log.Errorf(err.Error())
@@ -199,6 +244,12 @@ func (c *AssetClass) ReplaceAsset(stub shim.ChaincodeStubInterface, args []strin
 		log.Errorf(err.Error())
 		return nil, err
 	}
+	var prior = c.NewAsset()
+	if err := json.Unmarshal(assetBytes, &prior); err != nil {
+		err = fmt.Errorf("ReplaceAsset for class %s asset %s Unmarshal failed with err %s", c.Name, assetKey, err)
+		log.Errorf(err.Error())
+		return nil, err
+	}
 
 	// copy the event into a new state
 	astate := DeepCopyMap(*a.EventIn)
@@ -208,6 +259,9 @@ func (c *AssetClass) ReplaceAsset(stub shim.ChaincodeStubInterface, args []strin
 		log.Errorf(err.Error())
 		return nil, err
 	}
+	if err := a.applyStateMachine(stub, prior.priorStatus(), caller); err != nil {
+		return nil, err
+	}
 
 	return a.PUTAsset(stub, caller, inject)
 }
@@ -243,15 +297,25 @@ func (c *AssetClass) UpdateAsset(stub shim.ChaincodeStubInterface, args []string
 		log.Errorf(err.Error())
 		return nil, err
 	}
+	if err := c.validateEvent(stub, caller, arg.EventIn, true); err != nil {
+		return nil, err
+	}
 	err = json.Unmarshal(assetBytes, &a)
 	if err != nil {
 		err := fmt.Errorf("UpdateAsset for class %s asset %s Unmarshal failed with err %s", c.Name, assetKey, err)
 		log.Errorf(err.Error())
 		return nil, err
 	}
+	// rules must see the private values
+	if err := a.mergePrivateState(stub, nil); err != nil {
+		err = fmt.Errorf("UpdateAsset for class %s asset %s failed to merge private state, err is %s", c.Name, assetKey, err)
+		log.Errorf(err.Error())
+		return nil, err
+	}
 	// save the incoming EventIn
 	a.EventIn = arg.EventIn
 	a.FunctionIn = arg.FunctionIn
+	prior := a.priorStatus()
 
 	// merge the event into the state
 	astate := DeepMergeMap(*a.EventIn, *a.State)
@@ -262,6 +326,9 @@ func (c *AssetClass) UpdateAsset(stub shim.ChaincodeStubInterface, args []string
 		log.Errorf(err.Error())
 		return nil, err
 	}
+	if err := a.applyStateMachine(stub, prior, caller); err != nil {
+		return nil, err
+	}
 
 	return a.PUTAsset(stub, caller, inject)
 }
@@ -281,13 +348,23 @@ func (c *AssetClass) DeleteAsset(stub shim.ChaincodeStubInterface, args []string
 		log.Errorf(err.Error())
 		return nil, err
 	}
+	prior, err := arg.getPriorAsset(stub)
+	if err != nil {
+		err = fmt.Errorf("DeleteAsset for class %s could not read asset %s, err is %s", c.Name, assetKey, err)
+		log.Errorf(err.Error())
+		return nil, err
+	}
+	if prior == nil {
+		prior = &arg
+	}
 	err = arg.removeOneAssetFromWorldState(stub)
 	if err != nil {
 		err := fmt.Errorf("DeleteAsset: removeOneAssetFromWorldState class %s, asset %s, returned error: %s", c.Name, assetKey, err)
 		log.Errorf(err.Error())
 		return nil, err
 	}
-	return nil, nil
+	prior.Class = *c
+	return json.Marshal(map[string]interface{}{"events": prior.deleteEvents(stub.GetTxID())})
 }
 
 // DeleteAllAssets reletes all asstes of a specific asset class from world state
@@ -300,6 +377,23 @@ func (c *AssetClass) DeleteAllAssets(stub shim.ChaincodeStubInterface, args []st
 		log.Error(err)
 		return nil, err
 	}
+	if qprop, sel, found := c.indexedSelect(filter); found {
+		assets, _, err := c.readAssetsFromIndex(stub, qprop, sel.Value, filter, PageRequest{})
+		if err != nil {
+			err = fmt.Errorf("DeleteAllAssets failed to read from index on %s: %s", qprop, err)
+			log.Errorf(err.Error())
+			return nil, err
+		}
+		for _, state := range assets {
+			err = state.removeOneAssetFromWorldState(stub)
+			if err != nil {
+				err = fmt.Errorf("DeleteAllAssets removeOneAssetFromWorldState for asset %s failed: %s", state.AssetKey, err)
+				log.Errorf(err.Error())
+				return nil, err
+			}
+		}
+		return nil, nil
+	}
 	iter, err := stub.GetStateByRange(c.Prefix, c.Prefix+"}")
 	if err != nil {
 		err = fmt.Errorf("DeleteAllAssets failed to get a range query iterator: %s", err)
@@ -368,9 +462,15 @@ func (c *AssetClass) DeletePropertiesFromAsset(stub shim.ChaincodeStubInterface,
 		log.Errorf(err.Error())
 		return nil, err
 	}
+	if err := a.mergePrivateState(stub, nil); err != nil {
+		err = fmt.Errorf("DeletePropertiesFromAsset for class %s asset %s failed to merge private state, err is %s", c.Name, a.AssetKey, err)
+		log.Errorf(err.Error())
+		return nil, err
+	}
 	// save the incoming EventIn
 	a.EventIn = arg.EventIn
 	a.FunctionIn = arg.FunctionIn
+	prior := a.priorStatus()
 
 	var qprops []string
 	qprops, found := GetObjectAsStringArray(arg.EventIn, "qprops")
@@ -396,31 +496,13 @@ func (c *AssetClass) DeletePropertiesFromAsset(stub shim.ChaincodeStubInterface,
 		log.Errorf(err.Error())
 		return nil, err
 	}
-
-	// save original asset function
-	a.FunctionIn = caller
-
-	if len(inject) > 0 {
-		err := a.injectProps(inject)
-		if err != nil {
-			err = fmt.Errorf("deletePropertiesFromAsset for class %s failed to inject properties %+v for %s, err is %s", c.Name, inject, a.AssetKey, err)
-			log.Errorf(err.Error())
-			return nil, err
-		}
-	}
-	if err := a.ExecuteRules(stub); err != nil {
-		err = fmt.Errorf("CreateAsset for class %s failed in rules engine for %s, err is %s", c.Name, a.AssetKey, err)
-		log.Errorf(err.Error())
-		return nil, err
-	}
-	jsonBytes, err := a.putMarshalledState(stub)
-	if err != nil {
-		err = fmt.Errorf("CreateAsset for class %s failed to marshall for %s, err is %s", c.Name, a.AssetKey, err)
-		log.Errorf(err.Error())
+	if err := a.applyStateMachine(stub, prior, caller); err != nil {
 		return nil, err
 	}
 
-	return jsonBytes, nil
+	// the removed paths reach the diff, events, zones, aggregates and rules like any
+	// other write
+	return a.PUTAsset(stub, caller, inject)
 }
 
 // ReadAsset returns an asset from world state, intended to be returned directly to a client
@@ -449,16 +531,32 @@ func (c *AssetClass) ReadAsset(stub shim.ChaincodeStubInterface, args []string)
 		log.Errorf(err.Error())
 		return nil, err
 	}
+	if len(classPrivateCollections(*c)) > 0 {
+		return c.readPrivateAsset(stub, assetBytes)
+	}
 	return assetBytes, nil
 }
 
-// ReadAllAssets returns all assets of a specific class from world state as an array
+// ReadAllAssets returns all assets of a specific class from world state as an array, or
+// one page of them in a PagedResults envelope when a pagesize or bookmark is passed
 func (c AssetClass) ReadAllAssets(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
-	results, err := c.ReadAllAssetsUnmarshalled(stub, args)
+	page, err := getUnmarshalledPageRequest(args)
+	if err != nil {
+		err = fmt.Errorf("readAllAssets failed to get the page request: %s", err)
+		log.Errorf(err.Error())
+		return nil, err
+	}
+	filter, err := getUnmarshalledStateFilter(args)
+	if err != nil {
+		err = fmt.Errorf("readAllAssets failed to get a filter: %s", err)
+		log.Errorf(err.Error())
+		return nil, err
+	}
+	results, lastKey, err := c.readAssetsPage(stub, filter, page)
 	if err != nil {
 		return nil, err
 	}
-	resultsBytes, err := json.Marshal(&results)
+	resultsBytes, err := marshalPage(page, results, lastKey)
 	if err != nil {
 		err = fmt.Errorf("readAllAssets failed to marshal assets structure: %s", err)
 		log.Errorf(err.Error())
@@ -469,50 +567,89 @@ func (c AssetClass) ReadAllAssets(stub shim.ChaincodeStubInterface, args []strin
 
 // ReadAllAssetsUnmarshalled returns all assets of a specific class from world state as an object, intended for internal use
 func (c AssetClass) ReadAllAssetsUnmarshalled(stub shim.ChaincodeStubInterface, args []string) (AssetArray, error) {
-	var assets AssetArray
-	var err error
-	var filter StateFilter
-
-	filter, err = getUnmarshalledStateFilter(args)
+	filter, err := getUnmarshalledStateFilter(args)
 	if err != nil {
 		err = fmt.Errorf("readAllAssetsUnmarshalled failed to get a filter: %s", err)
 		log.Errorf(err.Error())
 		return nil, err
 	}
+	assets, _, err := c.readAssetsPage(stub, filter, PageRequest{})
+	return assets, err
+}
 
-	iter, err := stub.GetStateByRange(c.Prefix, c.Prefix+"}")
+// readAssetsPage returns the assets of a class that pass the filter, in key order. When
+// the page request is active, reading stops once the page is full and the last key read
+// is returned so that the next page can start after it.
+func (c AssetClass) readAssetsPage(stub shim.ChaincodeStubInterface, filter StateFilter, page PageRequest) (AssetArray, string, error) {
+	var assets AssetArray
+	var lastKey string
+	var err error
+
+	if qprop, sel, found := c.indexedSelect(filter); found {
+		assets, lastKey, err = c.readAssetsFromIndex(stub, qprop, sel.Value, filter, page)
+		if err != nil {
+			err = fmt.Errorf("readAllAssetsUnmarshalled failed to read from index on %s: %s", qprop, err)
+			log.Errorf(err.Error())
+			return nil, "", err
+		}
+		sort.Sort(assets)
+		return assets, lastKey, nil
+	}
+
+	start, err := decodeBookmark(page.Bookmark, c.Prefix)
+	if err != nil {
+		return nil, "", err
+	}
+	after := start
+	if start == "" {
+		start = c.Prefix
+	}
+
+	iter, err := stub.GetStateByRange(start, c.Prefix+"}")
 	if err != nil {
 		err = fmt.Errorf("readAllAssetsUnmarshalled failed to get a range query iterator: %s", err)
 		log.Errorf(err.Error())
-		return nil, err
+		return nil, "", err
 	}
 	defer iter.Close()
 	for iter.HasNext() {
+		if page.full(len(assets)) {
+			break
+		}
 		key, assetBytes, err := iter.Next()
 		if err != nil {
 			err = fmt.Errorf("readAllAssetsUnmarshalled iter.Next() failed: %s", err)
 			log.Errorf(err.Error())
-			return nil, err
+			return nil, "", err
 		}
+		if key == after {
+			// the bookmark is the last key of the previous page
+			continue
+		}
+		lastKey = key
 		var state = new(Asset)
 		err = json.Unmarshal(assetBytes, state)
 		if err != nil {
 			err = fmt.Errorf("readAllAssetsUnmarshalled unmarshal %s failed: %s", key, err)
 			log.Errorf(err.Error())
-			return nil, err
+			return nil, "", err
 		}
 		if state.Filter(filter) {
 			assets = append(assets, *state)
 		}
 	}
+	if !page.full(len(assets)) || !iter.HasNext() {
+		// nothing left to read
+		lastKey = ""
+	}
 
 	if len(assets) == 0 {
-		return make(AssetArray, 0), nil
+		return make(AssetArray, 0), "", nil
 	}
 
 	sort.Sort(assets)
 
-	return assets, nil
+	return assets, lastKey, nil
 }
 
 //********** default API ***********
@@ -564,6 +701,18 @@ var readAssetStateHistoryDefault = func(stub shim.ChaincodeStubInterface, args [
 	return DefaultClass.ReadAssetStateHistory(stub, args)
 }
 
+var readAssetAsOfDefault ChaincodeFunc = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	return DefaultClass.ReadAssetAsOf(stub, args)
+}
+
+var readAssetAggregatesDefault ChaincodeFunc = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	return DefaultClass.ReadAssetAggregates(stub, args)
+}
+
+var readAllAssetsAsOfDefault ChaincodeFunc = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	return DefaultClass.ReadAllAssetsAsOf(stub, args)
+}
+
 // RegisterDefaultRoutes registers the basic crud API for the simplest possible contract
 func RegisterDefaultRoutes() {
 	AddRoute("createAsset", "invoke", DefaultClass, createAssetDefault)
@@ -576,6 +725,9 @@ func RegisterDefaultRoutes() {
 	AddRoute("readAsset", "query", DefaultClass, readAssetDefault)
 	AddRoute("readAssetStateHistory", "query", DefaultClass, readAssetStateHistoryDefault)
 	AddRoute("readAllAssets", "query", DefaultClass, readAllAssetsDefault)
+	AddRoute("readAssetAsOf", "query", DefaultClass, readAssetAsOfDefault)
+	AddRoute("readAllAssetsAsOf", "query", DefaultClass, readAllAssetsAsOfDefault)
+	AddRoute("readAssetAggregates", "query", DefaultClass, readAssetAggregatesDefault)
 
 	AddRule("Over Temperature Alert", DefaultClass, []AlertName{overtempAlert}, overtempRule)
 }
@@ -0,0 +1,441 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+// v0.1 KL -- batch invoke, so that a gateway can send buffered events in one transaction
+
+package iotcontractplatform
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// Batch modes, allornothing fails the transaction on the first failed entry, besteffort
+// commits the entries that succeed
+const (
+	BatchAllOrNothing = "allornothing"
+	BatchBestEffort   = "besteffort"
+)
+
+// MaxBatchEntries bounds the size of one batch
+const MaxBatchEntries int = 500
+
+// BatchEntry is one invoke in a batch, args may be JSON objects or strings
+type BatchEntry struct {
+	Function string            `json:"function"`
+	Args     []json.RawMessage `json:"args"`
+}
+
+// BatchRequest is the argument of batchInvoke
+type BatchRequest struct {
+	Mode    string       `json:"mode"`
+	Entries []BatchEntry `json:"entries"`
+}
+
+// BatchEntryResult reports the outcome of one entry
+type BatchEntryResult struct {
+	Index    int                    `json:"index"`
+	Function string                 `json:"function"`
+	AssetKey string                 `json:"assetkey,omitempty"`
+	Status   string                 `json:"status"`
+	Message  string                 `json:"message,omitempty"`
+	Result   map[string]interface{} `json:"result,omitempty"`
+}
+
+// BatchResult is returned by batchInvoke and so becomes the invoke result event. Alert
+// deltas are listed per asset key, merged across the entries for that asset. The typed
+// events of the successful entries are gathered in entry order.
+type BatchResult struct {
+	Mode        string                            `json:"mode"`
+	Succeeded   int                               `json:"succeeded"`
+	Failed      int                               `json:"failed"`
+	Results     []BatchEntryResult                `json:"results"`
+	AssetAlerts map[string]map[string]interface{} `json:"assetalerts"`
+	Events      []interface{}                     `json:"events"`
+}
+
+// batchStub lets each entry read the writes of the entries before it, which the peer does
+// not do within one transaction, and holds an entry's writes until the entry succeeds so
+// that a failed entry leaves nothing behind. A nil value is a deleted key. Private data
+// is held the same way, keyed by collection and key. Each entry sees the transaction
+// time plus its index in nanoseconds, so that several writes to one asset get their own
+// history and recent state keys, in entry order.
+type batchStub struct {
+	shim.ChaincodeStubInterface
+	committed     map[string][]byte
+	pending       map[string][]byte
+	privCommitted map[string][]byte
+	privPending   map[string][]byte
+	seq           int32
+}
+
+func newBatchStub(stub shim.ChaincodeStubInterface) *batchStub {
+	return &batchStub{stub, make(map[string][]byte), make(map[string][]byte), make(map[string][]byte), make(map[string][]byte), 0}
+}
+
+func (b *batchStub) GetTxTimestamp() (*timestamp.Timestamp, error) {
+	ts, err := b.ChaincodeStubInterface.GetTxTimestamp()
+	if err != nil {
+		return nil, err
+	}
+	var seq = timestamp.Timestamp{Seconds: ts.Seconds, Nanos: ts.Nanos + b.seq}
+	if seq.Nanos >= 1e9 {
+		seq.Seconds++
+		seq.Nanos -= 1e9
+	}
+	return &seq, nil
+}
+
+func privateBatchKey(collection string, key string) string {
+	return collection + "\x00" + key
+}
+
+func (b *batchStub) lookup(key string) ([]byte, bool) {
+	if v, found := b.pending[key]; found {
+		return v, true
+	}
+	v, found := b.committed[key]
+	return v, found
+}
+
+func (b *batchStub) GetState(key string) ([]byte, error) {
+	if v, found := b.lookup(key); found {
+		return v, nil
+	}
+	return b.ChaincodeStubInterface.GetState(key)
+}
+
+func (b *batchStub) PutState(key string, value []byte) error {
+	if value == nil {
+		value = []byte{}
+	}
+	b.pending[key] = value
+	return nil
+}
+
+func (b *batchStub) DelState(key string) error {
+	b.pending[key] = nil
+	return nil
+}
+
+func (b *batchStub) GetPrivateData(collection string, key string) ([]byte, error) {
+	pk := privateBatchKey(collection, key)
+	if v, found := b.privPending[pk]; found {
+		return v, nil
+	}
+	if v, found := b.privCommitted[pk]; found {
+		return v, nil
+	}
+	return b.ChaincodeStubInterface.GetPrivateData(collection, key)
+}
+
+func (b *batchStub) PutPrivateData(collection string, key string, value []byte) error {
+	if value == nil {
+		value = []byte{}
+	}
+	b.privPending[privateBatchKey(collection, key)] = value
+	return nil
+}
+
+func (b *batchStub) DelPrivateData(collection string, key string) error {
+	b.privPending[privateBatchKey(collection, key)] = nil
+	return nil
+}
+
+// commit writes the entry's changes through to the transaction
+func (b *batchStub) commit() error {
+	for k, v := range b.pending {
+		var err error
+		if v == nil {
+			err = b.ChaincodeStubInterface.DelState(k)
+		} else {
+			err = b.ChaincodeStubInterface.PutState(k, v)
+		}
+		if err != nil {
+			return err
+		}
+		b.committed[k] = v
+	}
+	for pk, v := range b.privPending {
+		var err error
+		parts := strings.SplitN(pk, "\x00", 2)
+		if v == nil {
+			err = b.ChaincodeStubInterface.DelPrivateData(parts[0], parts[1])
+		} else {
+			err = b.ChaincodeStubInterface.PutPrivateData(parts[0], parts[1], v)
+		}
+		if err != nil {
+			return err
+		}
+		b.privCommitted[pk] = v
+	}
+	b.rollback()
+	return nil
+}
+
+func (b *batchStub) rollback() {
+	b.pending = make(map[string][]byte)
+	b.privPending = make(map[string][]byte)
+}
+
+func (b *batchStub) GetStateByRange(startKey string, endKey string) (shim.StateQueryIteratorInterface, error) {
+	iter, err := b.ChaincodeStubInterface.GetStateByRange(startKey, endKey)
+	if err != nil {
+		return nil, err
+	}
+	if startKey == "" {
+		// as on the peer, an open range begins after the composite keys
+		startKey = "\x01"
+	}
+	return b.overlay(iter, func(k string) bool {
+		return k >= startKey && (endKey == "" || k < endKey)
+	})
+}
+
+func (b *batchStub) GetStateByPartialCompositeKey(objectType string, keys []string) (shim.StateQueryIteratorInterface, error) {
+	iter, err := b.ChaincodeStubInterface.GetStateByPartialCompositeKey(objectType, keys)
+	if err != nil {
+		return nil, err
+	}
+	prefix, err := b.CreateCompositeKey(objectType, keys)
+	if err != nil {
+		return nil, err
+	}
+	return b.overlay(iter, func(k string) bool {
+		return strings.HasPrefix(k, prefix)
+	})
+}
+
+// overlay merges the batch's writes that fall in a query into its results
+func (b *batchStub) overlay(iter shim.StateQueryIteratorInterface, inRange func(string) bool) (shim.StateQueryIteratorInterface, error) {
+	defer iter.Close()
+	var kvs = make(map[string][]byte)
+	for iter.HasNext() {
+		k, v, err := iter.Next()
+		if err != nil {
+			return nil, err
+		}
+		kvs[k] = v
+	}
+	for _, m := range []map[string][]byte{b.committed, b.pending} {
+		for k, v := range m {
+			if !inRange(k) {
+				continue
+			}
+			if v == nil {
+				delete(kvs, k)
+			} else {
+				kvs[k] = v
+			}
+		}
+	}
+	var keys = make([]string, 0, len(kvs))
+	for k := range kvs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return &sliceIterator{keys, kvs, 0}, nil
+}
+
+// sliceIterator returns merged query results in key order
+type sliceIterator struct {
+	keys []string
+	kvs  map[string][]byte
+	posn int
+}
+
+func (s *sliceIterator) HasNext() bool {
+	return s.posn < len(s.keys)
+}
+
+func (s *sliceIterator) Next() (string, []byte, error) {
+	if !s.HasNext() {
+		return "", nil, fmt.Errorf("sliceIterator: no more results")
+	}
+	k := s.keys[s.posn]
+	s.posn++
+	return k, s.kvs[k], nil
+}
+
+func (s *sliceIterator) Close() error {
+	return nil
+}
+
+// batchArgs turns raw JSON args into the strings that routes expect, a JSON string is
+// passed as its content and anything else as its JSON text
+func batchArgs(raw []json.RawMessage) []string {
+	var args = make([]string, 0, len(raw))
+	for _, r := range raw {
+		var s string
+		if err := json.Unmarshal(r, &s); err == nil {
+			args = append(args, s)
+			continue
+		}
+		args = append(args, string(r))
+	}
+	return args
+}
+
+// batchAssetKey finds the key of the asset that an entry addresses, if any
+func batchAssetKey(r ChaincodeRoute, args []string) string {
+	if len(args) == 0 || r.Class.AssetIDPath == "" {
+		return ""
+	}
+	var event map[string]interface{}
+	if err := json.Unmarshal([]byte(args[0]), &event); err != nil {
+		return ""
+	}
+	id, found := GetObjectAsString(&event, r.Class.AssetIDPath)
+	if !found {
+		return ""
+	}
+	return r.Class.Prefix + id
+}
+
+// mergeAlertDeltas accumulates the alert deltas of several entries for one asset, the
+// active alerts are those after the last entry
+func mergeAlertDeltas(into map[string]interface{}, deltas map[string]interface{}) {
+	for _, k := range []string{"alertsRaised", "alertsCleared"} {
+		if v, found := deltas[k]; found {
+			if prior, ok := into[k].([]interface{}); ok {
+				if more, ok := v.([]interface{}); ok {
+					into[k] = append(prior, more...)
+					continue
+				}
+			}
+			into[k] = v
+		}
+	}
+	if v, found := deltas["activeAlerts"]; found {
+		into["activeAlerts"] = v
+	} else {
+		delete(into, "activeAlerts")
+	}
+}
+
+// runBatchEntry checks and dispatches one entry through the router
+func runBatchEntry(stub *batchStub, e BatchEntry) (BatchEntryResult, map[string]interface{}, error) {
+	var result = BatchEntryResult{Function: e.Function}
+	r, found := router[e.Function]
+	if !found {
+		return result, nil, fmt.Errorf("function %s is not registered", e.Function)
+	}
+	if r.Method != "invoke" || e.Function == "batchInvoke" {
+		return result, nil, fmt.Errorf("function %s cannot be batched", e.Function)
+	}
+	if err := checkAccess(stub, e.Function); err != nil {
+		return result, nil, err
+	}
+	if err := checkMigration(stub, r.Class); err != nil {
+		return result, nil, err
+	}
+	args := batchArgs(e.Args)
+	result.AssetKey = batchAssetKey(r, args)
+	outBytes, err := r.Function(stub, args)
+	if err != nil {
+		return result, nil, err
+	}
+	var out map[string]interface{}
+	if len(outBytes) > 0 {
+		if err = json.Unmarshal(outBytes, &out); err != nil {
+			return result, nil, fmt.Errorf("function %s returned an event that is not a map: %s", e.Function, err)
+		}
+	}
+	return result, out, nil
+}
+
+// batchInvoke dispatches an array of invokes in one transaction,
+// {"mode": "besteffort", "entries": [{"function": "updateAsset...", "args": [{...}]}]}
+var batchInvoke ChaincodeFunc = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	var req BatchRequest
+	if len(args) != 1 {
+		err := fmt.Errorf("batchInvoke expects one argument, got %d", len(args))
+		log.Error(err)
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(args[0]), &req); err != nil {
+		err = fmt.Errorf("batchInvoke failed to unmarshal arg: %s", err)
+		log.Error(err)
+		return nil, err
+	}
+	if req.Mode == "" {
+		req.Mode = BatchAllOrNothing
+	}
+	if req.Mode != BatchAllOrNothing && req.Mode != BatchBestEffort {
+		err := fmt.Errorf("batchInvoke: unknown mode %s", req.Mode)
+		log.Error(err)
+		return nil, err
+	}
+	if len(req.Entries) == 0 || len(req.Entries) > MaxBatchEntries {
+		err := fmt.Errorf("batchInvoke: a batch must hold between 1 and %d entries, got %d", MaxBatchEntries, len(req.Entries))
+		log.Error(err)
+		return nil, err
+	}
+
+	var br = BatchResult{
+		Mode:        req.Mode,
+		Results:     make([]BatchEntryResult, 0, len(req.Entries)),
+		AssetAlerts: make(map[string]map[string]interface{}),
+		Events:      make([]interface{}, 0),
+	}
+	bs := newBatchStub(stub)
+	for i, e := range req.Entries {
+		bs.seq = int32(i)
+		result, out, err := runBatchEntry(bs, e)
+		result.Index = i
+		if err == nil {
+			err = bs.commit()
+		}
+		if err != nil {
+			bs.rollback()
+			if req.Mode == BatchAllOrNothing {
+				err = fmt.Errorf("batchInvoke entry %d (%s) failed, no entries were committed: %s", i, e.Function, err)
+				log.Error(err)
+				return nil, err
+			}
+			log.Warningf("batchInvoke entry %d (%s) failed: %s", i, e.Function, err)
+			result.Status = "ERROR"
+			result.Message = err.Error()
+			br.Failed++
+			br.Results = append(br.Results, result)
+			continue
+		}
+		if events, ok := out["events"].([]interface{}); ok {
+			br.Events = append(br.Events, events...)
+			delete(out, "events")
+		}
+		result.Status = "OK"
+		result.Result = out
+		br.Succeeded++
+		br.Results = append(br.Results, result)
+		if result.AssetKey != "" && out != nil {
+			if br.AssetAlerts[result.AssetKey] == nil {
+				br.AssetAlerts[result.AssetKey] = make(map[string]interface{})
+			}
+			mergeAlertDeltas(br.AssetAlerts[result.AssetKey], out)
+		}
+	}
+	return json.Marshal(br)
+}
+
+func init() {
+	AddRoute("batchInvoke", "invoke", SystemClass, batchInvoke)
+}
@@ -0,0 +1,102 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+package iotcontractplatform
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/ibm-watson-iot/blockchain-samples/fabricv1/platform/cttest"
+)
+
+func TestBatchArgs(t *testing.T) {
+	var raw []json.RawMessage
+	if err := json.Unmarshal([]byte(`[{"kit":{"id":"K1"}}, "{\"kit\":{\"id\":\"K2\"}}", 3]`), &raw); err != nil {
+		t.Fatal(err)
+	}
+	got := batchArgs(raw)
+	want := []string{`{"kit":{"id":"K1"}}`, `{"kit":{"id":"K2"}}`, `3`}
+	if !reflect.DeepEqual(got, want) {
+		t.Fail()
+		fmt.Printf("*** batchArgs returned %v, expected %v\n", got, want)
+	}
+	r := ChaincodeRoute{Class: AssetClass{Name: "kit", Prefix: "KIT", AssetIDPath: "kit.id"}}
+	if key := batchAssetKey(r, got[1:]); key != "KITK2" {
+		t.Fail()
+		fmt.Printf("*** batchAssetKey returned %s\n", key)
+	}
+}
+
+func TestMergeAlertDeltas(t *testing.T) {
+	var merged = make(map[string]interface{})
+	for _, d := range []string{
+		`{"alertsRaised":["OVERTEMP"],"activeAlerts":["OVERTEMP"]}`,
+		`{"alertsRaised":["TILT"],"activeAlerts":["OVERTEMP","TILT"]}`,
+		`{"alertsCleared":["OVERTEMP","TILT"]}`,
+	} {
+		var deltas map[string]interface{}
+		if err := json.Unmarshal([]byte(d), &deltas); err != nil {
+			t.Fatal(err)
+		}
+		mergeAlertDeltas(merged, deltas)
+	}
+	got, _ := json.Marshal(merged)
+	if string(got) != `{"alertsCleared":["OVERTEMP","TILT"],"alertsRaised":["OVERTEMP","TILT"]}` {
+		t.Fail()
+		fmt.Printf("*** merged alert deltas %s\n", got)
+	}
+}
+
+func TestBatchRepeatedWrites(t *testing.T) {
+	stub := cttest.NewMockStub("batchseq", scenarioChaincode{})
+	stub.MockInit("init", []string{`{"version": "1.0"}`})
+	resp := stub.MockInvoke("batchInvoke", []string{`{"entries": [
+		{"function": "createAsset", "args": [{"asset": {"assetID": "S1", "temperature": 1}}]},
+		{"function": "updateAsset", "args": [{"asset": {"assetID": "S1", "temperature": 2}}]},
+		{"function": "updateAsset", "args": [{"asset": {"assetID": "S1", "temperature": 3}}]}
+	]}`})
+	if resp.Status != shim.OK {
+		t.Fatalf("batchInvoke failed: %s", resp.Message)
+	}
+
+	// every entry keeps its own history state and recent state, in entry order
+	if keys := stub.Keys(STATEHISTORYKEY + "DEFS1."); len(keys) != 3 {
+		t.Fail()
+		fmt.Printf("*** batch wrote history keys %q\n", keys)
+	}
+	var history AssetArray
+	resp = stub.MockQuery("readAssetStateHistory", []string{`{"asset": {"assetID": "S1"}}`})
+	if err := json.Unmarshal(resp.Payload, &history); err != nil {
+		t.Fatal(err)
+	}
+	var temps []float64
+	for _, a := range history {
+		n, _ := GetObjectAsNumber(a.State, "asset.temperature")
+		temps = append(temps, n)
+	}
+	if fmt.Sprint(temps) != "[3 2 1]" {
+		t.Fail()
+		fmt.Printf("*** batch history has temperatures %v\n", temps)
+	}
+	if keys := stub.Keys(RECENTSTATESKEY); len(keys) != 3 {
+		t.Fail()
+		fmt.Printf("*** batch wrote recent state keys %q\n", keys)
+	}
+}
@@ -107,6 +107,20 @@ var deleteWorldState ChaincodeFunc = func(stub shim.ChaincodeStubInterface, args
 			return nil, err
 		}
 	}
+	// range queries do not return composite keys, so the index, relationship and zone
+	// keys are cleared by object type
+	for _, objectType := range []string{INDEXOBJECTTYPE, RELCHILDOBJECTTYPE, RELPARENTOBJECTTYPE, ZONEASSETOBJECTTYPE, ZONEZONEOBJECTTYPE} {
+		keys, err := getKeysByPartialCompositeKey(stub, objectType, []string{})
+		if err != nil {
+			return nil, err
+		}
+		for key := range keys {
+			if err = stub.DelState(key); err != nil {
+				log.Errorf("deleteWorldState composite key %q failed DELSTATE", key)
+				return nil, err
+			}
+		}
+	}
 	log.Debugf("\n\n********** WORLD STATE CLEARED *************\n\n")
 	if len(args) > 0 && args[0] == "reinit" {
 		time.Sleep(300)
@@ -32,8 +32,9 @@ const CONTRACTSTATEKEY string = "IOTCP:ContractState"
 // ContractState struct defines contract state. Unlike the main contract maps, structs work fine
 // for this fixed structure.
 type ContractState struct {
-	Version  string `json:"version"`
-	Nickname string `json:"nickname"`
+	Version   string           `json:"version"`
+	Nickname  string           `json:"nickname"`
+	Migration *MigrationStatus `json:"migration,omitempty"`
 }
 
 // GETContractStateFromLedger retrieves state from ledger and returns to caller
@@ -80,7 +81,8 @@ func PUTContractStateToLedger(stub shim.ChaincodeStubInterface, state ContractSt
 	return nil
 }
 
-// InitializeContractState sets version and nickname back to defaults
+// InitializeContractState sets version and nickname back to defaults, an upgrade to a new
+// version starts the migrations registered for it
 func InitializeContractState(stub shim.ChaincodeStubInterface, contractversion string, nicknamearg string, versionarg string) error {
 	var state ContractState
 	var err error
@@ -99,7 +101,11 @@ func InitializeContractState(stub shim.ChaincodeStubInterface, contractversion s
 		log.Noticef("Deployed contract version %s appears to be redeployed", versionarg)
 	}
 	if contractversion != state.Version {
-		log.Noticef("Deployed contract version has changed from %s to %s", versionarg, contractversion)
+		log.Noticef("Deployed contract version has changed from %s to %s", state.Version, contractversion)
+		err = upgradeContractState(stub, &state, contractversion)
+		if err != nil {
+			return err
+		}
 	}
 	return PUTContractStateToLedger(stub, state)
 }
@@ -152,7 +152,7 @@ func (a *Asset) unmarshallEventIn(stub shim.ChaincodeStubInterface, args []strin
 	}
 	a.EventIn = &amap
 
-	return nil
+	return a.mergePrivateTransient(stub)
 }
 
 // // Returns the world state represented by prefix + assetID unmarshalled.
@@ -185,6 +185,14 @@ func (a *Asset) unmarshallEventIn(stub shim.ChaincodeStubInterface, args []strin
 
 // Pushes state to the ledger using assetID, which is expected to be prefixed.
 func (a *Asset) putMarshalledState(stub shim.ChaincodeStubInterface) ([]byte, error) {
+	// private values leave the state before anything is written
+	err := a.putPrivateState(stub)
+	if err != nil {
+		err = fmt.Errorf("putMarshalledState: assetID %s private state update failed: %s", a.AssetKey, err)
+		log.Error(err)
+		return nil, err
+	}
+
 	// Write the new state to the ledger
 	stateJSON, err := json.Marshal(a)
 	if err != nil {
@@ -193,6 +201,14 @@ func (a *Asset) putMarshalledState(stub shim.ChaincodeStubInterface) ([]byte, er
 		return nil, err
 	}
 
+	// index entries are computed against the prior state, so must precede the write
+	err = a.putIndexEntries(stub)
+	if err != nil {
+		err = fmt.Errorf("putMarshalledState: assetID %s index update failed: %s", a.AssetKey, err)
+		log.Error(err)
+		return nil, err
+	}
+
 	err = stub.PutState(a.AssetKey, []byte(stateJSON))
 	if err != nil {
 		err = fmt.Errorf("putMarshalledState: PUTSTATE for assetID %s failed: %s", a.AssetKey, err)
@@ -218,15 +234,47 @@ func (a *Asset) putMarshalledState(stub shim.ChaincodeStubInterface) ([]byte, er
 
 // RemoveOneAssetFromWorldState remove the asset from world state
 func (a *Asset) removeOneAssetFromWorldState(stub shim.ChaincodeStubInterface) error {
-	err := stub.DelState(a.AssetKey)
+	return a.removeAssetFromWorldState(stub, make(map[string]bool))
+}
+
+// removeAssetFromWorldState removes the asset and, through cascading relationships, its
+// children, which are listed in removed so that each is removed once
+func (a *Asset) removeAssetFromWorldState(stub shim.ChaincodeStubInterface, removed map[string]bool) error {
+	// relationship policies can refuse the delete, so they come first
+	err := a.removeRelations(stub, removed)
+	if err != nil {
+		err = fmt.Errorf("removeOneAssetFromWorldState: asset %s relationships prevent removal: %s", a.AssetKey, err)
+		log.Error(err)
+		return err
+	}
+	err = a.removeIndexEntries(stub)
+	if err != nil {
+		err = fmt.Errorf("removeOneAssetFromWorldState: asset %s index entries could not be removed: %s", a.AssetKey, err)
+		log.Error(err)
+		return err
+	}
+	err = a.removeZoneAttachments(stub)
+	if err != nil {
+		err = fmt.Errorf("removeOneAssetFromWorldState: asset %s zones could not be detached: %s", a.AssetKey, err)
+		log.Error(err)
+		return err
+	}
+	err = a.removeAggregates(stub)
+	if err != nil {
+		err = fmt.Errorf("removeOneAssetFromWorldState: asset %s sensor readings could not be removed: %s", a.AssetKey, err)
+		log.Error(err)
+		return err
+	}
+	err = stub.DelState(a.AssetKey)
 	if err != nil {
 		err = fmt.Errorf("removeOneAssetFromWorldState: asset %s failed", a.AssetKey)
 		log.Error(err)
 		return err
 	}
-	err = a.RemoveAssetFromRecentStates(stub)
+	// recent states entries age out, readRecentStates skips deleted assets
+	err = a.removePrivateState(stub)
 	if err != nil {
-		err = fmt.Errorf("removeOneAssetFromWorldState: asset %s could not be removed from recent states: %s", a.AssetKey, err)
+		err = fmt.Errorf("removeOneAssetFromWorldState: asset %s private state could not be removed: %s", a.AssetKey, err)
 		log.Error(err)
 		return err
 	}
@@ -0,0 +1,199 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+// v0.1 KL -- the properties added, removed and changed by each asset write, so that
+//            rules can fire on change and consumers can see what happened
+
+package iotcontractplatform
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// PropertyChange is one leaf of asset state that a write added, removed or changed. Old
+// is absent for added properties and New for removed ones. Private values are never
+// recorded, only their paths.
+type PropertyChange struct {
+	Path string      `json:"path"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// StateDiff is recorded in the asset state that a write produced, and so in that state's
+// history. Paths are relative to asset state and each list is in path order. Arrays are
+// compared as a whole.
+type StateDiff struct {
+	Added   []PropertyChange `json:"added,omitempty"`
+	Removed []PropertyChange `json:"removed,omitempty"`
+	Changed []PropertyChange `json:"changed,omitempty"`
+}
+
+// computeStateDiff compares two states, a nil prior is a new asset
+func computeStateDiff(prior *map[string]interface{}, current *map[string]interface{}) *StateDiff {
+	var d = &StateDiff{}
+	var p, c map[string]interface{}
+	if prior != nil {
+		p = *prior
+	}
+	if current != nil {
+		c = *current
+	}
+	d.collect("", p, c)
+	sort.Sort(byPath(d.Added))
+	sort.Sort(byPath(d.Removed))
+	sort.Sort(byPath(d.Changed))
+	return d
+}
+
+func (d *StateDiff) collect(prefix string, prior map[string]interface{}, current map[string]interface{}) {
+	var seen = make(map[string]bool)
+	for _, m := range []map[string]interface{}{prior, current} {
+		for k := range m {
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+			path := k
+			if prefix != "" {
+				path = prefix + "." + k
+			}
+			pv, pfound := prior[k]
+			cv, cfound := current[k]
+			pm, pIsMap := pv.(map[string]interface{})
+			cm, cIsMap := cv.(map[string]interface{})
+			switch {
+			case pIsMap && cIsMap:
+				d.collect(path, pm, cm)
+			case pIsMap && !cfound:
+				d.collect(path, pm, nil)
+			case cIsMap && !pfound:
+				d.collect(path, nil, cm)
+			case !pfound:
+				d.Added = append(d.Added, PropertyChange{Path: path, New: normalizeJSON(cv)})
+			case !cfound:
+				d.Removed = append(d.Removed, PropertyChange{Path: path, Old: normalizeJSON(pv)})
+			default:
+				pn, cn := normalizeJSON(pv), normalizeJSON(cv)
+				if !reflect.DeepEqual(pn, cn) {
+					d.Changed = append(d.Changed, PropertyChange{Path: path, Old: pn, New: cn})
+				}
+			}
+		}
+	}
+}
+
+type byPath []PropertyChange
+
+func (b byPath) Len() int           { return len(b) }
+func (b byPath) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+func (b byPath) Less(i, j int) bool { return b[i].Path < b[j].Path }
+
+// normalizeJSON makes values that came from different sources comparable, e.g. an int
+// in memory and a float64 that was read from the ledger
+func normalizeJSON(v interface{}) interface{} {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out interface{}
+	if err = json.Unmarshal(b, &out); err != nil {
+		return v
+	}
+	return out
+}
+
+// Paths returns every path in the diff, in order
+func (d *StateDiff) Paths() []string {
+	var paths = make([]string, 0)
+	if d == nil {
+		return paths
+	}
+	for _, l := range [][]PropertyChange{d.Added, d.Removed, d.Changed} {
+		for _, pc := range l {
+			paths = append(paths, pc.Path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Empty is true when the write changed nothing
+func (d *StateDiff) Empty() bool {
+	return d == nil || len(d.Added)+len(d.Removed)+len(d.Changed) == 0
+}
+
+// Find returns the change at a path, if the path was added, removed or changed
+func (d *StateDiff) Find(qprop string) (PropertyChange, bool) {
+	if d == nil {
+		return PropertyChange{}, false
+	}
+	for _, l := range [][]PropertyChange{d.Added, d.Removed, d.Changed} {
+		for _, pc := range l {
+			if pc.Path == qprop {
+				return pc, true
+			}
+		}
+	}
+	return PropertyChange{}, false
+}
+
+// PropertyChanged lets a rule fire on change rather than on value, e.g. a status that
+// changed from "transit" to "hospital". Old or new is nil when the property was added or
+// removed. The asset's diff is available to rules while the asset is written.
+func (a *Asset) PropertyChanged(qprop string) (old interface{}, new interface{}, changed bool) {
+	pc, found := a.Diff.Find(qprop)
+	if !found {
+		return nil, nil, false
+	}
+	return pc.Old, pc.New, true
+}
+
+// isPrivatePath is true when the path is, contains or is inside a private property
+func isPrivatePath(c AssetClass, path string) bool {
+	for _, pc := range classPrivateCollections(c) {
+		for _, qprop := range pc.QProps {
+			if path == qprop || strings.HasPrefix(path, qprop+".") || strings.HasPrefix(qprop, path+".") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// redactDiff returns a copy of the diff without the values of private properties, which
+// is what is stored and emitted
+func (a *Asset) redactDiff(d *StateDiff) *StateDiff {
+	if d == nil {
+		return nil
+	}
+	redact := func(l []PropertyChange) []PropertyChange {
+		var out = make([]PropertyChange, 0, len(l))
+		for _, pc := range l {
+			if isPrivatePath(a.Class, pc.Path) {
+				pc = PropertyChange{Path: pc.Path}
+			}
+			out = append(out, pc)
+		}
+		if len(out) == 0 {
+			return nil
+		}
+		return out
+	}
+	return &StateDiff{redact(d.Added), redact(d.Removed), redact(d.Changed)}
+}
@@ -0,0 +1,75 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+package iotcontractplatform
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestComputeStateDiff(t *testing.T) {
+	var prior map[string]interface{}
+	if err := json.Unmarshal([]byte(`{"kit":{"id":"K1","temp":3,"status":"transit","loc":{"lat":1,"lon":2},"tags":["a"]}}`), &prior); err != nil {
+		t.Fatal(err)
+	}
+	current := map[string]interface{}{
+		"kit": map[string]interface{}{
+			"id":     "K1",
+			"temp":   3,
+			"status": "hospital",
+			"loc":    map[string]interface{}{"lat": 1.5},
+			"tags":   []string{"a", "b"},
+			"door":   false,
+		},
+	}
+	d := computeStateDiff(&prior, &current)
+	got, _ := json.Marshal(d)
+	want := `{"added":[{"path":"kit.door","new":false}],"removed":[{"path":"kit.loc.lon","old":2}],"changed":[{"path":"kit.loc.lat","old":1,"new":1.5},{"path":"kit.status","old":"transit","new":"hospital"},{"path":"kit.tags","old":["a"],"new":["a","b"]}]}`
+	if string(got) != want {
+		t.Fail()
+		fmt.Printf("*** computeStateDiff returned %s\n", got)
+	}
+	a := Asset{Diff: d}
+	if old, new, changed := a.PropertyChanged("kit.status"); !changed || old != "transit" || new != "hospital" {
+		t.Fail()
+		fmt.Printf("*** PropertyChanged returned %v %v %v\n", old, new, changed)
+	}
+	if _, _, changed := a.PropertyChanged("kit.temp"); changed {
+		t.Fail()
+		fmt.Printf("*** PropertyChanged reported an unchanged property\n")
+	}
+	if paths := computeStateDiff(nil, &prior).Paths(); !reflect.DeepEqual(paths, []string{"kit.id", "kit.loc.lat", "kit.loc.lon", "kit.status", "kit.tags", "kit.temp"}) {
+		t.Fail()
+		fmt.Printf("*** the diff of a new state has paths %v\n", paths)
+	}
+}
+
+func TestRedactDiff(t *testing.T) {
+	c := AssetClass{Name: "redact", Prefix: "RDT", AssetIDPath: "kit.id"}
+	if err := AddPrivateCollection(c, "org1", []string{"kit.price"}, AccessPolicy{MSPIDs: []string{"Org1MSP"}}); err != nil {
+		t.Fatal(err)
+	}
+	a := c.NewAsset()
+	d := a.redactDiff(&StateDiff{Changed: []PropertyChange{{"kit.price", 10, 12}, {"kit.temp", 1, 2}}})
+	got, _ := json.Marshal(d)
+	if string(got) != `{"changed":[{"path":"kit.price"},{"path":"kit.temp","old":1,"new":2}]}` {
+		t.Fail()
+		fmt.Printf("*** redactDiff returned %s\n", got)
+	}
+}
@@ -0,0 +1,283 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+// v0.1 KL -- typed events for asset writes, alerts and transitions, aggregated into the
+//            one chaincode event that Fabric allows per transaction
+
+package iotcontractplatform
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// Event types
+const (
+	EventAssetCreated = "asset.created"
+	EventAssetUpdated = "asset.updated"
+	EventAssetDeleted = "asset.deleted"
+	EventAlertRaised  = "alert.raised"
+	EventAlertCleared = "alert.cleared"
+	EventZoneEntered  = "zone.entered"
+	EventZoneExited   = "zone.exited"
+
+	EventHistoryArchived = "history.archived"
+)
+
+// EVENTNAMESEPARATOR separates the declared event names that are appended to the
+// chaincode event name when SetEventNameSuffix is on, so that a consumer can subscribe
+// with a pattern such as ".*:KIT\.OUTOFAREA(:|$)"
+const EVENTNAMESEPARATOR string = ":"
+
+// eventNameSuffix is off by default so that subscribers to EVT.IOTCP.INVOKE.RESULT keep
+// receiving every invoke result
+var eventNameSuffix = false
+
+// SetEventNameSuffix allows a contract to append the declared names of an invoke's events
+// to the chaincode event name. Subscribers that register for the exact name
+// EVT.IOTCP.INVOKE.RESULT then receive only the events that declare no names, so they
+// must register with a pattern instead.
+func SetEventNameSuffix(on bool) {
+	eventNameSuffix = on
+}
+
+// IOTEvent is one logical event. Name is the name that the contract declared for the
+// class or alert, if any. Changed lists the property paths of the asset state that the
+// write changed, and Diff their old and new values. Zone names the zone of a zone event.
+type IOTEvent struct {
+	Type          string           `json:"type"`
+	Name          string           `json:"name,omitempty"`
+	Class         string           `json:"class"`
+	AssetKey      string           `json:"assetkey"`
+	TXNID         string           `json:"txnid"`
+	AlertsRaised  AlertNameArray   `json:"alertsRaised,omitempty"`
+	AlertsCleared AlertNameArray   `json:"alertsCleared,omitempty"`
+	Changed       []string         `json:"changed,omitempty"`
+	Diff          *StateDiff       `json:"diff,omitempty"`
+	Transition    *StateTransition `json:"transition,omitempty"`
+	Zone          string           `json:"zone,omitempty"`
+}
+
+var classeventrouter = make(map[AssetClass]string, 0)
+var alerteventrouter = make(map[AssetClass]map[AlertName]string, 0)
+
+func validEventName(name string) bool {
+	return name != "" && !strings.Contains(name, EVENTNAMESEPARATOR)
+}
+
+// AddClassEvent declares the event name emitted when an asset of the class is written
+// or deleted
+func AddClassEvent(class AssetClass, name string) error {
+	if !validEventName(name) {
+		err := fmt.Errorf("AddClassEvent: class %s event name '%s' must be non-empty and contain no '%s'", class.Name, name, EVENTNAMESEPARATOR)
+		log.Error(err)
+		return err
+	}
+	if n, found := classeventrouter[class]; found {
+		err := fmt.Errorf("AddClassEvent: class %s already emits event %s", class.Name, n)
+		log.Error(err)
+		return err
+	}
+	classeventrouter[class] = name
+	log.Debugf("Class %s added event %s", class.Name, name)
+	return nil
+}
+
+// AddAlertEvent declares the event name emitted when the alert is raised or cleared on
+// an asset of the class
+func AddAlertEvent(class AssetClass, alert AlertName, name string) error {
+	if !validEventName(name) {
+		err := fmt.Errorf("AddAlertEvent: class %s alert %s event name '%s' must be non-empty and contain no '%s'", class.Name, alert, name, EVENTNAMESEPARATOR)
+		log.Error(err)
+		return err
+	}
+	if alerteventrouter[class] == nil {
+		alerteventrouter[class] = make(map[AlertName]string)
+	}
+	if n, found := alerteventrouter[class][alert]; found {
+		err := fmt.Errorf("AddAlertEvent: class %s alert %s already emits event %s", class.Name, alert, n)
+		log.Error(err)
+		return err
+	}
+	alerteventrouter[class][alert] = name
+	log.Debugf("Class %s alert %s added event %s", class.Name, alert, name)
+	return nil
+}
+
+// getPriorAsset returns the stored asset with its private values, or nil when the asset
+// is new
+func (a *Asset) getPriorAsset(stub shim.ChaincodeStubInterface) (*Asset, error) {
+	priorBytes, err := stub.GetState(a.AssetKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(priorBytes) == 0 {
+		return nil, nil
+	}
+	var prior Asset
+	if err = json.Unmarshal(priorBytes, &prior); err != nil {
+		return nil, err
+	}
+	if err = prior.mergePrivateState(stub, nil); err != nil {
+		return nil, err
+	}
+	return &prior, nil
+}
+
+// writeEvents returns the events for an asset that is about to be written, the asset's
+// diff must already be redacted
+func (a *Asset) writeEvents(prior *Asset, alertsIn AlertNameArray) []IOTEvent {
+	var events = make([]IOTEvent, 0, 1)
+	var e = IOTEvent{
+		Type:       EventAssetUpdated,
+		Name:       classeventrouter[a.Class],
+		Class:      a.Class.Name,
+		AssetKey:   a.AssetKey,
+		TXNID:      a.TXNID,
+		Transition: a.Transition,
+	}
+	if prior == nil {
+		e.Type = EventAssetCreated
+	}
+	if !a.Diff.Empty() {
+		e.Changed = a.Diff.Paths()
+		e.Diff = a.Diff
+	}
+	for _, alert := range a.AlertsActive {
+		if !Contains(alertsIn, alert) {
+			e.AlertsRaised = append(e.AlertsRaised, alert)
+		}
+	}
+	for _, alert := range alertsIn {
+		if !Contains(a.AlertsActive, alert) {
+			e.AlertsCleared = append(e.AlertsCleared, alert)
+		}
+	}
+	events = append(events, e)
+	for _, alert := range e.AlertsRaised {
+		events = append(events, a.alertEvent(EventAlertRaised, alert))
+	}
+	for _, alert := range e.AlertsCleared {
+		events = append(events, a.alertEvent(EventAlertCleared, alert))
+	}
+	if a.Zones != nil {
+		for _, z := range a.Zones.Entered {
+			events = append(events, a.zoneEvent(EventZoneEntered, z))
+		}
+		for _, z := range a.Zones.Exited {
+			events = append(events, a.zoneEvent(EventZoneExited, z))
+		}
+	}
+	return events
+}
+
+func (a *Asset) zoneEvent(eventType string, zone string) IOTEvent {
+	return IOTEvent{
+		Type:     eventType,
+		Class:    a.Class.Name,
+		AssetKey: a.AssetKey,
+		TXNID:    a.TXNID,
+		Zone:     zone,
+	}
+}
+
+func (a *Asset) alertEvent(eventType string, alert AlertName) IOTEvent {
+	e := IOTEvent{
+		Type:     eventType,
+		Name:     alerteventrouter[a.Class][alert],
+		Class:    a.Class.Name,
+		AssetKey: a.AssetKey,
+		TXNID:    a.TXNID,
+	}
+	if eventType == EventAlertRaised {
+		e.AlertsRaised = AlertNameArray{alert}
+	} else {
+		e.AlertsCleared = AlertNameArray{alert}
+	}
+	return e
+}
+
+// deleteEvents returns the events for an asset that has been deleted, its active alerts
+// are cleared
+func (a *Asset) deleteEvents(txid string) []IOTEvent {
+	a.TXNID = txid
+	var events = []IOTEvent{{
+		Type:          EventAssetDeleted,
+		Name:          classeventrouter[a.Class],
+		Class:         a.Class.Name,
+		AssetKey:      a.AssetKey,
+		TXNID:         txid,
+		AlertsCleared: a.AlertsActive,
+	}}
+	for _, alert := range a.AlertsActive {
+		events = append(events, a.alertEvent(EventAlertCleared, alert))
+	}
+	return events
+}
+
+// eventNames returns the distinct declared names of the events in an invoke result
+func eventNames(events interface{}) []string {
+	var names = make([]string, 0)
+	var seen = make(map[string]bool)
+	list, _ := events.([]interface{})
+	for _, e := range list {
+		em, _ := e.(map[string]interface{})
+		if n, ok := em["name"].(string); ok && n != "" && !seen[n] {
+			seen[n] = true
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// chaincodeEventName appends the declared names to the invoke result event name when the
+// contract has turned the suffix on
+func chaincodeEventName(names []string) string {
+	if len(names) == 0 || !eventNameSuffix {
+		return EVTCCINVRESULT
+	}
+	return EVTCCINVRESULT + EVENTNAMESEPARATOR + strings.Join(names, EVENTNAMESEPARATOR)
+}
+
+// readEventNames returns the declared event names by class, so that consumers know what
+// to subscribe to
+var readEventNames ChaincodeFunc = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	type ClassEvents struct {
+		Event  string               `json:"event,omitempty"`
+		Alerts map[AlertName]string `json:"alerts,omitempty"`
+	}
+	var out = make(map[string]ClassEvents)
+	for c, n := range classeventrouter {
+		ce := out[c.Name]
+		ce.Event = n
+		out[c.Name] = ce
+	}
+	for c, alerts := range alerteventrouter {
+		ce := out[c.Name]
+		ce.Alerts = alerts
+		out[c.Name] = ce
+	}
+	return json.Marshal(out)
+}
+
+func init() {
+	AddRoute("readEventNames", "query", SystemClass, readEventNames)
+}
@@ -0,0 +1,49 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+package iotcontractplatform
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestEventNames(t *testing.T) {
+	var events interface{}
+	if err := json.Unmarshal([]byte(`[{"type":"asset.updated","name":"KIT"},{"type":"alert.raised","name":"KIT.HOT"},{"type":"alert.raised"},{"name":"KIT"}]`), &events); err != nil {
+		t.Fatal(err)
+	}
+	names := eventNames(events)
+	if name := chaincodeEventName(names); name != EVTCCINVRESULT {
+		t.Fail()
+		fmt.Printf("*** chaincodeEventName without the suffix returned %s\n", name)
+	}
+	SetEventNameSuffix(true)
+	defer SetEventNameSuffix(false)
+	if name := chaincodeEventName(names); name != "EVT.IOTCP.INVOKE.RESULT:KIT:KIT.HOT" {
+		t.Fail()
+		fmt.Printf("*** chaincodeEventName returned %s\n", name)
+	}
+	if name := chaincodeEventName(eventNames(nil)); name != EVTCCINVRESULT {
+		t.Fail()
+		fmt.Printf("*** chaincodeEventName with no names returned %s\n", name)
+	}
+	if err := AddClassEvent(AssetClass{Name: "kit"}, "KIT:UPDATED"); err == nil {
+		t.Fail()
+		fmt.Printf("*** AddClassEvent accepted a name containing the separator\n")
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
 )
@@ -63,16 +64,59 @@ func (x MatchType) String() string {
 	return MatchName[int(x)]
 }
 
-// QPropNV is a name : value pair to be matched
+// Operators supported by QPropNV, the empty operator means equality for backward
+// compatibility with filters that predate operators
+const (
+	OpEqual        = "eq"
+	OpNotEqual     = "ne"
+	OpGreater      = "gt"
+	OpGreaterEqual = "gte"
+	OpLess         = "lt"
+	OpLessEqual    = "lte"
+	OpIn           = "in"
+	OpExists       = "exists"
+	OpPrefix       = "prefix"
+	OpRegex        = "regex"
+)
+
+// OpAlias is a map of accepted operator spellings to the canonical operator
+var OpAlias = map[string]string{
+	"":       OpEqual,
+	"=":      OpEqual,
+	"==":     OpEqual,
+	"eq":     OpEqual,
+	"!=":     OpNotEqual,
+	"ne":     OpNotEqual,
+	">":      OpGreater,
+	"gt":     OpGreater,
+	">=":     OpGreaterEqual,
+	"gte":    OpGreaterEqual,
+	"<":      OpLess,
+	"lt":     OpLess,
+	"<=":     OpLessEqual,
+	"lte":    OpLessEqual,
+	"in":     OpIn,
+	"exists": OpExists,
+	"prefix": OpPrefix,
+	"regex":  OpRegex,
+}
+
+// QPropNV is a name : value pair to be matched, with an optional operator. The
+// in operator matches against Values, all others use Value.
 type QPropNV struct {
-	QProp string `json:"qprop"`
-	Value string `json:"value"`
+	QProp  string   `json:"qprop"`
+	Value  string   `json:"value"`
+	Op     string   `json:"op,omitempty"`
+	Values []string `json:"values,omitempty"`
 }
 
-// StateFilter is a complete filter for a state
+// StateFilter is a complete filter for a state. Match combines the results of the
+// select terms and the nested groups, so that all, any and none act as and, or and
+// not respectively, which allows arbitrary boolean trees.
 type StateFilter struct {
-	Match  string    `json:"match"`
-	Select []QPropNV `json:"select"`
+	Match  string        `json:"match"`
+	Select []QPropNV     `json:"select"`
+	Groups []StateFilter `json:"groups,omitempty"`
 }
 
 // TaggedFilter is a complete filter for a state, inside a "filter" object"
@@ -80,15 +124,39 @@ type TaggedFilter struct {
 	Filter StateFilter `json:"filter"`
 }
 
-var emptyStateFilter = StateFilter{"", make([]QPropNV, 0)}
-var emptyTaggedFilter = TaggedFilter{StateFilter{"", make([]QPropNV, 0)}}
+var emptyStateFilter = StateFilter{Match: "", Select: make([]QPropNV, 0)}
+var emptyTaggedFilter = TaggedFilter{emptyStateFilter}
+
+// canonicalMatch maps the boolean aliases onto the original match names
+func canonicalMatch(m string) string {
+	switch m {
+	case "and":
+		return "all"
+	case "or":
+		return "any"
+	case "not":
+		return "none"
+	}
+	return m
+}
+
+// isActive returns true if the filter has a match type and something to match
+func (filter StateFilter) isActive() bool {
+	m := canonicalMatch(filter.Match)
+	return m != "" && m != "n/a" && (len(filter.Select) > 0 || len(filter.Groups) > 0)
+}
+
+// isEquality returns true if the term is a plain equality match
+func (prop QPropNV) isEquality() bool {
+	return OpAlias[prop.Op] == OpEqual
+}
 
 // Filter returns true if the filter's conditions are all met
 func (a *Asset) Filter(filter StateFilter) bool {
-	if len(filter.Select) == 0 {
+	if len(filter.Select) == 0 && len(filter.Groups) == 0 {
 		return true
 	}
-	switch filter.Match {
+	switch canonicalMatch(filter.Match) {
 	case "n/a", "":
 		return true
 	case "all":
@@ -103,6 +171,14 @@ func (a *Asset) Filter(filter StateFilter) bool {
 	}
 }
 
+// groups that are inactive are ignored so that an empty group cannot change the result
+func (a *Asset) matchGroup(g StateFilter) (bool, bool) {
+	if !g.isActive() {
+		return false, false
+	}
+	return a.Filter(g), true
+}
+
 func (a *Asset) matchAll(filter StateFilter) bool {
 	for _, f := range filter.Select {
 		if !a.performOneMatch(f) {
@@ -110,6 +186,11 @@ func (a *Asset) matchAll(filter StateFilter) bool {
 			return false
 		}
 	}
+	for _, g := range filter.Groups {
+		if m, active := a.matchGroup(g); active && !m {
+			return false
+		}
+	}
 	// success
 	return true
 }
@@ -121,6 +202,11 @@ func (a *Asset) matchAny(filter StateFilter) bool {
 			return true
 		}
 	}
+	for _, g := range filter.Groups {
+		if m, active := a.matchGroup(g); active && m {
+			return true
+		}
+	}
 	// fail
 	return false
 }
@@ -132,6 +218,11 @@ func (a *Asset) matchNone(filter StateFilter) bool {
 			return false
 		}
 	}
+	for _, g := range filter.Groups {
+		if m, active := a.matchGroup(g); active && m {
+			return false
+		}
+	}
 	// success, none matched
 	return true
 }
@@ -158,76 +249,214 @@ func dump(s string, i interface{}, j interface{}, k interface{}, l interface{})
 
 func (a *Asset) performOneMatch(prop QPropNV) bool {
 	dump("performOneMatch", a, prop, nil, nil)
+	op, found := OpAlias[prop.Op]
+	if !found {
+		err := fmt.Errorf("Unknown operator %s in filter for property %s", prop.Op, prop.QProp)
+		log.Error(err)
+		return false
+	}
+	o, found := a.findFilterProperty(prop.QProp)
+	if op == OpExists {
+		// exists with value false matches a missing property
+		return found == (prop.Value != "false")
+	}
+	if !found {
+		// missing property always fails match
+		return false
+	}
+	return compareOne(o, op, prop)
+}
+
+// findFilterProperty returns the leaf value at a qualified property, which is addressed
+// from the root of the asset so that it can reach properties outside of asset state,
+// for example "alerts" or "assetstate.container.carrier"
+func (a *Asset) findFilterProperty(qprop string) (interface{}, bool) {
 	var levels []string
 	var found = false
 	var kind reflect.Kind
 	var v reflect.Value
 	var o interface{}
-	if prop.QProp == "" {
-		return false
+	if qprop == "" {
+		return nil, false
 	}
-	levels = strings.SplitAfterN(prop.QProp, ".", 2)
+	levels = strings.SplitAfterN(qprop, ".", 2)
 	ar := reflect.ValueOf(a).Elem()
 	v, o, kind, found = findJSONPropInStruct(strings.TrimSuffix(levels[0], ","), ar)
 	dump("JSON prop in struct returned", v, o, kind, found)
+	if !found {
+		return nil, false
+	}
+	if len(levels) == 2 {
+		omap, found := o.(*map[string]interface{})
+		if found {
+			return GetObject(omap, levels[1])
+		}
+		if kind == reflect.Struct {
+			_, o, _, found = findJSONPropInStruct(levels[1], v)
+			return o, found
+		}
+		return nil, false
+	}
+	return o, true
+}
 
-	if found {
-		if len(levels) == 2 {
-			omap, found := o.(*map[string]interface{})
-			if found {
-				o, found = GetObject(omap, levels[1])
-				if !found {
-					return false
-				}
-			} else if kind == reflect.Struct {
-				v, o, kind, found = findJSONPropInStruct(levels[1], v)
-				if !found {
-					return false
-				}
-			} else {
-				return false
-			}
-		} else if kind == reflect.Slice {
-			return Contains(o, prop.Value)
+// compareOne applies one operator to a leaf value, slices match when any element matches
+// except for not equal, which requires that no element is equal
+func compareOne(o interface{}, op string, prop QPropNV) bool {
+	switch t := o.(type) {
+	case AlertNameArray:
+		arr := make([]interface{}, 0, len(t))
+		for _, alert := range t {
+			arr = append(arr, string(alert))
 		}
-		// ** at this point, we have a leaf node interface{} value "o" to compare
-		switch t := o.(type) {
-		case []interface{}:
-			return Contains(o, prop.Value)
-		case string:
-			return o.(string) == prop.Value
-		case float64:
-			f, err := strconv.ParseFloat(prop.Value, 64)
-			if err == nil {
-				return o.(float64) == f
-			}
-			err = fmt.Errorf("Cannot convert %s to float64 in filter when comparing to object %s %+v", prop.Value, prop.QProp, a)
-			log.Error(err)
-			return false
-		case int:
-			i, err := strconv.Atoi(prop.Value)
-			if err == nil {
-				return o.(int) == i
-			}
-			err = fmt.Errorf("Cannot convert %s to int in filter when comparing to object %s %+v", prop.Value, prop.QProp, a)
-			log.Error(err)
-			return false
-		case bool:
-			if b, err := strconv.ParseBool(prop.Value); err == nil {
-				return b == o.(bool)
+		return compareSlice(arr, op, prop)
+	case []string:
+		arr := make([]interface{}, 0, len(t))
+		for _, e := range t {
+			arr = append(arr, e)
+		}
+		return compareSlice(arr, op, prop)
+	case []interface{}:
+		return compareSlice(t, op, prop)
+	}
+	switch op {
+	case OpEqual:
+		return equalsValue(o, prop.Value, prop.QProp)
+	case OpNotEqual:
+		return !equalsValue(o, prop.Value, prop.QProp)
+	case OpIn:
+		for _, v := range inValues(prop) {
+			if equalsValue(o, v, prop.QProp) {
+				return true
 			}
-			err := fmt.Errorf("Cannot convert %s to bool in filter when comparing to object %s %+v", prop.Value, prop.QProp, a)
-			log.Error(err)
+		}
+		return false
+	case OpGreater, OpGreaterEqual, OpLess, OpLessEqual:
+		c, ok := orderValue(o, prop.Value, prop.QProp)
+		if !ok {
 			return false
+		}
+		switch op {
+		case OpGreater:
+			return c > 0
+		case OpGreaterEqual:
+			return c >= 0
+		case OpLess:
+			return c < 0
 		default:
-			err := fmt.Errorf("Unexpected property to compare type: %T %s", prop.Value, t)
+			return c <= 0
+		}
+	case OpPrefix:
+		s, ok := o.(string)
+		return ok && strings.HasPrefix(s, prop.Value)
+	case OpRegex:
+		s, ok := o.(string)
+		if !ok {
+			return false
+		}
+		matched, err := regexp.MatchString(prop.Value, s)
+		if err != nil {
+			err = fmt.Errorf("Invalid regular expression %s in filter for property %s: %s", prop.Value, prop.QProp, err)
 			log.Error(err)
 			return false
 		}
+		return matched
+	}
+	return false
+}
+
+func compareSlice(arr []interface{}, op string, prop QPropNV) bool {
+	if op == OpNotEqual {
+		return !compareSlice(arr, OpEqual, prop)
+	}
+	for _, e := range arr {
+		if compareOne(e, op, prop) {
+			return true
+		}
 	}
 	return false
 }
 
+// inValues returns the list for the in operator, a comma separated value is accepted
+// when values is not present
+func inValues(prop QPropNV) []string {
+	if len(prop.Values) > 0 {
+		return prop.Values
+	}
+	if prop.Value == "" {
+		return []string{}
+	}
+	return strings.Split(prop.Value, ",")
+}
+
+// equalsValue compares a leaf value to a filter value by converting the filter value
+// to the leaf's type
+func equalsValue(o interface{}, value string, qprop string) bool {
+	switch t := o.(type) {
+	case string:
+		return t == value
+	case float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err == nil {
+			return t == f
+		}
+		err = fmt.Errorf("Cannot convert %s to float64 in filter when comparing to object %s", value, qprop)
+		log.Error(err)
+		return false
+	case int:
+		i, err := strconv.Atoi(value)
+		if err == nil {
+			return t == i
+		}
+		err = fmt.Errorf("Cannot convert %s to int in filter when comparing to object %s", value, qprop)
+		log.Error(err)
+		return false
+	case bool:
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b == t
+		}
+		err := fmt.Errorf("Cannot convert %s to bool in filter when comparing to object %s", value, qprop)
+		log.Error(err)
+		return false
+	default:
+		err := fmt.Errorf("Unexpected property to compare type: %T %s", o, qprop)
+		log.Error(err)
+		return false
+	}
+}
+
+// orderValue returns -1, 0 or 1 as the leaf value is less than, equal to or greater
+// than the filter value; numbers compare numerically and strings lexically, which
+// suits RFC3339 timestamps
+func orderValue(o interface{}, value string, qprop string) (int, bool) {
+	var f float64
+	switch t := o.(type) {
+	case string:
+		return strings.Compare(t, value), true
+	case float64:
+		f = t
+	case int:
+		f = float64(t)
+	default:
+		err := fmt.Errorf("Property %s of type %T cannot be ordered in filter", qprop, o)
+		log.Error(err)
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		err = fmt.Errorf("Cannot convert %s to float64 in filter when comparing to object %s", value, qprop)
+		log.Error(err)
+		return 0, false
+	}
+	switch {
+	case f < v:
+		return -1, true
+	case f > v:
+		return 1, true
+	}
+	return 0, true
+}
+
 // Returns a filter found in the json object in args[0]
 func getUnmarshalledStateFilter(args []string) (StateFilter, error) {
 	var filter StateFilter
@@ -239,11 +468,11 @@ func getUnmarshalledStateFilter(args []string) (StateFilter, error) {
 	}
 
 	filter, err = getCanonicalFilterFromEventIn(args)
-	if err == nil && filter.Match != "" && filter.Match != "n/a" && len(filter.Select) > 0 {
+	if err == nil && filter.isActive() {
 		return filter, nil
 	}
 	filter, err = getMapFormatFilterFromEventIn(args)
-	if err == nil && filter.Match != "" && filter.Match != "n/a" && len(filter.Select) > 0 {
+	if err == nil && filter.isActive() {
 		return filter, nil
 	}
 	return emptyStateFilter, nil
@@ -255,11 +484,11 @@ func getCanonicalFilterFromEventIn(args []string) (StateFilter, error) {
 
 	fBytes := []byte(args[0])
 	errtagged := json.Unmarshal(fBytes, &taggedFilter)
-	if errtagged == nil && taggedFilter.Filter.Match != "" && taggedFilter.Filter.Match != "n/a" {
+	if errtagged == nil && taggedFilter.Filter.isActive() {
 		return taggedFilter.Filter, nil
 	}
 	erruntagged := json.Unmarshal(fBytes, &filter)
-	if erruntagged == nil && filter.Match != "" && filter.Match != "n/a" {
+	if erruntagged == nil && filter.isActive() {
 		return filter, nil
 	}
 	// log.Debugf("getCanonicalFilterFromEventIn failed to unmarshal %+v\n        tagged error: %s,\n        untagged error: %s\n", args[0], errtagged, erruntagged)
@@ -267,7 +496,6 @@ func getCanonicalFilterFromEventIn(args []string) (StateFilter, error) {
 }
 
 func getMapFormatFilterFromEventIn(args []string) (StateFilter, error) {
-	var filter = StateFilter{"", make([]QPropNV, 0)}
 	var f interface{}
 	var err error
 
@@ -290,17 +518,32 @@ func getMapFormatFilterFromEventIn(args []string) (StateFilter, error) {
 		fobj = amap
 	}
 
+	filter, ok := getMapFormatFilter(fobj)
+	if !ok {
+		return emptyStateFilter, err
+	}
+
+	// log.Debugf("getMapFormatFilterFromEventIn returning filter %+v\n", filter)
+	return filter, nil
+}
+
+// getMapFormatFilter reads one level of a filter whose arrays may have been sent as
+// objects keyed by position, and recurses into its groups
+func getMapFormatFilter(fobj map[string]interface{}) (StateFilter, bool) {
+	var filter = StateFilter{Match: "", Select: make([]QPropNV, 0)}
+
 	m, mfound := GetObjectAsString(&fobj, "match")
-	sel, selfound := GetObjectAsMap(&fobj, "select")
+	sel, selfound := getObjectAsElements(&fobj, "select")
+	groups, groupsfound := getObjectAsElements(&fobj, "groups")
 	if !mfound {
-		if selfound {
+		if selfound || groupsfound {
 			log.Warningf("getMapFormatFilterFromEventIn incorrect filter format 'match' found: %t 'select' found %t\n", mfound, selfound)
-			return emptyStateFilter, err
+			return emptyStateFilter, false
 		}
 	} else {
-		if !selfound {
+		if !selfound && !groupsfound {
 			log.Warningf("getMapFormatFilterFromEventIn incorrect filter format 'match' found: %t 'select' found %t\n", mfound, selfound)
-			return emptyStateFilter, err
+			return emptyStateFilter, false
 		}
 	}
 
@@ -310,18 +553,59 @@ func getMapFormatFilterFromEventIn(args []string) (StateFilter, error) {
 		emap, found := AsMap(e)
 		if !found {
 			log.Warningf("getMapFormatFilterFromEventIn prop:value not a map shape: %+v\n", e)
-			return emptyStateFilter, nil
+			return emptyStateFilter, false
 		}
 		k, kfound := GetObjectAsString(&emap, "qprop")
 		v, vfound := GetObjectAsString(&emap, "value")
-		if !kfound || !vfound {
+		op, _ := GetObjectAsString(&emap, "op")
+		values, valuesfound := getObjectAsElements(&emap, "values")
+		if !kfound || (!vfound && !valuesfound && OpAlias[op] != OpExists) {
 			log.Warningf("getMapFormatFilterFromEventIn prop or value not found: prop %t value %t\n", kfound, vfound)
-			return emptyStateFilter, err
+			return emptyStateFilter, false
 		}
-		qprops = append(qprops, QPropNV{k, v})
+		qp := QPropNV{QProp: k, Value: v, Op: op}
+		for _, value := range values {
+			if s, ok := value.(string); ok {
+				qp.Values = append(qp.Values, s)
+			}
+		}
+		qprops = append(qprops, qp)
 	}
 	filter.Select = qprops
 
-	// log.Debugf("getMapFormatFilterFromEventIn returning filter %+v\n", filter)
-	return filter, nil
+	for _, g := range groups {
+		gmap, found := AsMap(g)
+		if !found {
+			log.Warningf("getMapFormatFilterFromEventIn group not a map shape: %+v\n", g)
+			return emptyStateFilter, false
+		}
+		group, ok := getMapFormatFilter(gmap)
+		if !ok {
+			return emptyStateFilter, false
+		}
+		filter.Groups = append(filter.Groups, group)
+	}
+
+	return filter, true
+}
+
+// getObjectAsElements returns the elements of an array that may have been sent as an
+// array or as an object keyed by position; order is not significant to a filter
+func getObjectAsElements(objIn *map[string]interface{}, qname string) ([]interface{}, bool) {
+	o, found := GetObject(objIn, qname)
+	if !found {
+		return nil, false
+	}
+	if arr, ok := o.([]interface{}); ok {
+		return arr, true
+	}
+	omap, ok := o.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	arr := make([]interface{}, 0, len(omap))
+	for _, e := range omap {
+		arr = append(arr, e)
+	}
+	return arr, true
 }
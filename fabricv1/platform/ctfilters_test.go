@@ -120,3 +120,102 @@ func TestGetUnmarshalledStateFilterUntaggedObject(t *testing.T) {
 		fmt.Printf("*** getUnmarshalledStateFilter untagged object: [%+v]==>[%+v] : err [%+v]\n", f4, filter4, err)
 	}
 }
+
+var f5 = "{\"filter\":{\"match\":\"all\",\"select\":[{\"qprop\":\"assetstate.kit.maxgforce\",\"op\":\">\",\"value\":\"1.5\"}],\"groups\":[{\"match\":\"any\",\"select\":[{\"qprop\":\"assetstate.kit.status\",\"op\":\"in\",\"values\":[\"transit\",\"hospital\"]},{\"qprop\":\"alerts\",\"value\":\"EXCESSTILT\"}]}]}}"
+var f6 = "{\"filter\":{\"match\":\"all\",\"select\":{\"0\":{\"qprop\":\"assetstate.kit.maxgforce\",\"op\":\"gte\",\"value\":\"2\"}},\"groups\":{\"0\":{\"match\":\"not\",\"select\":{\"0\":{\"qprop\":\"assetstate.kit.carrier\",\"op\":\"exists\"}}}}}}"
+
+func newFilterTestAsset(force float64, status string, alerts ...AlertName) *Asset {
+	state := map[string]interface{}{
+		"kit": map[string]interface{}{
+			"maxgforce": force,
+			"status":    status,
+			"skitID":    "SK001",
+		},
+	}
+	a := DefaultClass.NewAsset()
+	a.State = &state
+	a.AlertsActive = alerts
+	return &a
+}
+
+func TestFilterOperators(t *testing.T) {
+	a := newFilterTestAsset(2, "transit")
+	var tests = []struct {
+		prop QPropNV
+		want bool
+	}{
+		{QPropNV{QProp: "assetstate.kit.maxgforce", Op: ">", Value: "1.5"}, true},
+		{QPropNV{QProp: "assetstate.kit.maxgforce", Op: "gt", Value: "2"}, false},
+		{QPropNV{QProp: "assetstate.kit.maxgforce", Op: ">=", Value: "2"}, true},
+		{QPropNV{QProp: "assetstate.kit.maxgforce", Op: "<", Value: "2.5"}, true},
+		{QPropNV{QProp: "assetstate.kit.maxgforce", Op: "lte", Value: "1"}, false},
+		{QPropNV{QProp: "assetstate.kit.maxgforce", Op: "!=", Value: "2"}, false},
+		{QPropNV{QProp: "assetstate.kit.maxgforce", Value: "2"}, true},
+		{QPropNV{QProp: "assetstate.kit.status", Op: "in", Values: []string{"hospital", "transit"}}, true},
+		{QPropNV{QProp: "assetstate.kit.status", Op: "in", Value: "hospital,inventory"}, false},
+		{QPropNV{QProp: "assetstate.kit.status", Op: "prefix", Value: "tran"}, true},
+		{QPropNV{QProp: "assetstate.kit.skitID", Op: "regex", Value: "^SK[0-9]+$"}, true},
+		{QPropNV{QProp: "assetstate.kit.skitID", Op: "regex", Value: "("}, false},
+		{QPropNV{QProp: "assetstate.kit.carrier", Op: "exists"}, false},
+		{QPropNV{QProp: "assetstate.kit.carrier", Op: "exists", Value: "false"}, true},
+		{QPropNV{QProp: "assetstate.kit.status", Op: "exists"}, true},
+		{QPropNV{QProp: "assetstate.kit.carrier", Op: "!=", Value: "UPS"}, false},
+		{QPropNV{QProp: "assetstate.kit.status", Op: "bogus", Value: "transit"}, false},
+	}
+	for _, test := range tests {
+		if got := a.performOneMatch(test.prop); got != test.want {
+			t.Fail()
+			fmt.Printf("*** operator match %+v returned %t, expected %t\n", test.prop, got, test.want)
+		}
+	}
+}
+
+func TestFilterAlertsArray(t *testing.T) {
+	a := newFilterTestAsset(2, "transit", "EXCESSFORCE", "EXCESSTILT")
+	if !a.performOneMatch(QPropNV{QProp: "alerts", Value: "EXCESSTILT"}) {
+		t.Fail()
+		fmt.Printf("*** alerts should contain EXCESSTILT: %+v\n", a.AlertsActive)
+	}
+	if a.performOneMatch(QPropNV{QProp: "alerts", Op: "ne", Value: "EXCESSTILT"}) {
+		t.Fail()
+		fmt.Printf("*** alerts ne should fail when EXCESSTILT is present: %+v\n", a.AlertsActive)
+	}
+}
+
+func TestFilterGroups(t *testing.T) {
+	filter, err := getUnmarshalledStateFilter([]string{f5})
+	if err != nil || len(filter.Groups) != 1 {
+		t.Fail()
+		fmt.Printf("*** nested filter did not unmarshal: [%+v]==>[%+v] : err [%+v]\n", f5, filter, err)
+		return
+	}
+	if !newFilterTestAsset(2, "hospital").Filter(filter) {
+		t.Fail()
+		fmt.Printf("*** nested filter should match on force and status\n")
+	}
+	if newFilterTestAsset(2, "inventory").Filter(filter) {
+		t.Fail()
+		fmt.Printf("*** nested filter should fail on status\n")
+	}
+	if !newFilterTestAsset(2, "inventory", "EXCESSTILT").Filter(filter) {
+		t.Fail()
+		fmt.Printf("*** nested filter should match on alert\n")
+	}
+	if newFilterTestAsset(1, "hospital").Filter(filter) {
+		t.Fail()
+		fmt.Printf("*** nested filter should fail on force\n")
+	}
+}
+
+func TestFilterGroupsMapFormat(t *testing.T) {
+	filter, err := getUnmarshalledStateFilter([]string{f6})
+	if err != nil || len(filter.Groups) != 1 || filter.Select[0].Op != "gte" {
+		t.Fail()
+		fmt.Printf("*** map format nested filter did not unmarshal: [%+v]==>[%+v] : err [%+v]\n", f6, filter, err)
+		return
+	}
+	if !newFilterTestAsset(2, "transit").Filter(filter) {
+		t.Fail()
+		fmt.Printf("*** map format nested filter should match when carrier is missing\n")
+	}
+}
@@ -0,0 +1,84 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+// v0.1 KL -- chaincode clients for the REST gateway
+
+// Package ctgateway serves a platform contract over REST, so that applications can create
+// and read assets without building chaincode argument arrays.
+package ctgateway
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/ibm-watson-iot/blockchain-samples/fabricv1/platform/cttest"
+)
+
+// Client sends one function call to a contract. Invoke returns the payload of the
+// invoke's result event and Query returns the query's result. Implementations wrap a
+// peer SDK or, for local use, a MockStub.
+type Client interface {
+	Invoke(function string, args []string) ([]byte, error)
+	Query(function string, args []string) ([]byte, error)
+}
+
+// ChaincodeError is returned by a Client when the contract rejects a call, as opposed
+// to failing to deliver it
+type ChaincodeError struct {
+	Function string
+	Message  string
+}
+
+func (e *ChaincodeError) Error() string {
+	return fmt.Sprintf("%s failed: %s", e.Function, e.Message)
+}
+
+// MockClient runs calls in process against a MockStub, one at a time
+type MockClient struct {
+	stub *cttest.MockStub
+	mux  sync.Mutex
+}
+
+// NewMockClient returns a client for a MockStub on which the contract has been initialized
+func NewMockClient(stub *cttest.MockStub) *MockClient {
+	return &MockClient{stub: stub}
+}
+
+// Invoke runs an invoke transaction
+func (c *MockClient) Invoke(function string, args []string) ([]byte, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	resp := c.stub.MockInvoke(function, args)
+	if resp.Status != shim.OK {
+		return nil, &ChaincodeError{function, resp.Message}
+	}
+	if c.stub.Event == nil {
+		return resp.Payload, nil
+	}
+	return c.stub.Event.Payload, nil
+}
+
+// Query runs a query, which commits nothing
+func (c *MockClient) Query(function string, args []string) ([]byte, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	resp := c.stub.MockQuery(function, args)
+	if resp.Status != shim.OK {
+		return nil, &ChaincodeError{function, resp.Message}
+	}
+	return resp.Payload, nil
+}
@@ -0,0 +1,245 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+// v0.1 KL -- OpenAPI documents from the resolved contract schema
+
+package ctgateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// OpenAPI turns the schema that a contract serves from readAssetSchemas into an OpenAPI 3
+// document describing the gateway's routes. Each API function is a POST on /invoke or
+// /query, and classes with a createAsset function also get their /assets routes.
+func OpenAPI(schemas []byte, title string, version string) ([]byte, error) {
+	var schema map[string]interface{}
+	if err := json.Unmarshal(schemas, &schema); err != nil {
+		return nil, fmt.Errorf("OpenAPI could not unmarshal the schema: %s", err)
+	}
+	api, found := schema["API"].(map[string]interface{})
+	if !found {
+		return nil, fmt.Errorf("OpenAPI found no API in the schema")
+	}
+	var names = make([]string, 0, len(api))
+	for name := range api {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var paths = make(map[string]interface{})
+	for _, name := range names {
+		f, _ := api[name].(map[string]interface{})
+		props, _ := f["properties"].(map[string]interface{})
+		method, _ := props["method"].(string)
+		if method != "invoke" && method != "query" {
+			continue
+		}
+		op := operation(name, f, []string{method})
+		if body, required, found := requestSchema(props); found {
+			op["requestBody"] = map[string]interface{}{
+				"required": required,
+				"content":  jsonContent(body),
+			}
+		}
+		paths["/"+method+"/"+name] = map[string]interface{}{"post": op}
+	}
+
+	// the REST routes, classes are named by the suffix of their functions
+	for _, name := range names {
+		if !strings.HasPrefix(name, "createAsset") {
+			continue
+		}
+		suffix := strings.TrimPrefix(name, "createAsset")
+		class := suffix
+		if class == "" {
+			class = "default"
+		}
+		create := api[name].(map[string]interface{})
+		op := operation(name, create, []string{class})
+		op["operationId"] = "create" + goSuffix(class)
+		if body, _, found := requestSchema(create["properties"].(map[string]interface{})); found {
+			op["requestBody"] = map[string]interface{}{"required": true, "content": jsonContent(body)}
+		}
+		op["responses"].(map[string]interface{})["201"] = op["responses"].(map[string]interface{})["200"]
+		delete(op["responses"].(map[string]interface{}), "200")
+		classPath := map[string]interface{}{"post": op}
+		if all, found := api["readAllAssets"+suffix].(map[string]interface{}); found {
+			op := operation("readAllAssets"+suffix, all, []string{class})
+			op["operationId"] = "readAll" + goSuffix(class)
+			classPath["get"] = op
+		}
+		paths["/assets/"+class] = classPath
+		if read, found := api["readAsset"+suffix].(map[string]interface{}); found {
+			op := operation("readAsset"+suffix, read, []string{class})
+			op["operationId"] = "read" + goSuffix(class)
+			op["parameters"] = []interface{}{map[string]interface{}{
+				"name":        "id",
+				"in":          "path",
+				"required":    true,
+				"description": "the asset's id, without its class prefix",
+				"schema":      map[string]interface{}{"type": "string"},
+			}}
+			op["responses"].(map[string]interface{})["404"] = map[string]interface{}{
+				"description": "the asset does not exist",
+				"content":     jsonContent(map[string]interface{}{"$ref": "#/components/schemas/error"}),
+			}
+			paths["/assets/"+class+"/{id}"] = map[string]interface{}{"get": op}
+		}
+	}
+
+	doc := map[string]interface{}{
+		"openapi": "3.0.0",
+		"info":    map[string]interface{}{"title": title, "version": version},
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"schemas": map[string]interface{}{
+				"error": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"status":  map[string]interface{}{"type": "string", "enum": []interface{}{"ERROR"}},
+						"message": map[string]interface{}{"type": "string"},
+					},
+				},
+				"invokeResult": map[string]interface{}{
+					"type":        "object",
+					"description": "the payload of the invoke's result event",
+					"properties": map[string]interface{}{
+						"status": map[string]interface{}{"type": "string", "enum": []interface{}{"OK"}},
+						"txnid":  map[string]interface{}{"type": "string"},
+						"names":  map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+						"events": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "object"}},
+					},
+				},
+			},
+		},
+	}
+	return json.MarshalIndent(doc, "", "    ")
+}
+
+func goSuffix(class string) string {
+	return strings.ToUpper(class[:1]) + class[1:]
+}
+
+// an operation for a function, queries respond with their result and invokes with their
+// result event
+func operation(name string, f map[string]interface{}, tags []string) map[string]interface{} {
+	props, _ := f["properties"].(map[string]interface{})
+	var result interface{} = map[string]interface{}{"$ref": "#/components/schemas/invokeResult"}
+	if method, _ := props["method"].(string); method == "query" {
+		result = map[string]interface{}{}
+		if r, found := props["result"]; found {
+			result = toSchema(r)
+		}
+	}
+	op := map[string]interface{}{
+		"operationId": name,
+		"tags":        tags,
+		"responses": map[string]interface{}{
+			"200": map[string]interface{}{
+				"description": "success",
+				"content":     jsonContent(result),
+			},
+			"default": map[string]interface{}{
+				"description": "the contract rejected the call",
+				"content":     jsonContent(map[string]interface{}{"$ref": "#/components/schemas/error"}),
+			},
+		},
+	}
+	if desc, found := f["description"].(string); found {
+		op["summary"] = desc
+	}
+	return op
+}
+
+// the body is a function's single argument, or the array of its arguments
+func requestSchema(props map[string]interface{}) (interface{}, bool, bool) {
+	args, found := props["args"].(map[string]interface{})
+	if !found {
+		return nil, false, false
+	}
+	max, hasMax := args["maxItems"].(float64)
+	min, _ := args["minItems"].(float64)
+	if hasMax && max == 0 {
+		return nil, false, false
+	}
+	if hasMax && max == 1 {
+		return toSchema(args["items"]), min > 0, true
+	}
+	return toSchema(args), min > 0, true
+}
+
+func jsonContent(schema interface{}) map[string]interface{} {
+	return map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}}
+}
+
+// toSchema converts a JSON schema to the subset that OpenAPI 3 accepts
+func toSchema(in interface{}) interface{} {
+	switch v := in.(type) {
+	case []interface{}:
+		var out = make([]interface{}, 0, len(v))
+		for _, e := range v {
+			out = append(out, toSchema(e))
+		}
+		return out
+	case map[string]interface{}:
+		var out = make(map[string]interface{}, len(v))
+		for k, e := range v {
+			switch k {
+			case "id", "$schema", "definitions":
+			case "patternProperties":
+				// maps keep one value schema for any key
+				out["additionalProperties"] = true
+				if pp, found := e.(map[string]interface{}); found && len(pp) == 1 {
+					for _, ps := range pp {
+						out["additionalProperties"] = toSchema(ps)
+					}
+				}
+			case "properties":
+				props, _ := e.(map[string]interface{})
+				var outProps = make(map[string]interface{}, len(props))
+				for name, p := range props {
+					if _, found := p.(map[string]interface{}); found {
+						outProps[name] = toSchema(p)
+					} else {
+						outProps[name] = map[string]interface{}{"description": fmt.Sprint(p)}
+					}
+				}
+				out[k] = outProps
+			case "type":
+				if types, found := e.([]interface{}); found {
+					// a list of types becomes the first type that is not null
+					for _, t := range types {
+						if t == "null" {
+							out["nullable"] = true
+						} else if _, found := out["type"]; !found {
+							out["type"] = t
+						}
+					}
+				} else {
+					out[k] = e
+				}
+			default:
+				out[k] = toSchema(e)
+			}
+		}
+		return out
+	}
+	return in
+}
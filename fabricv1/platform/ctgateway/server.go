@@ -0,0 +1,268 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+// v0.1 KL -- REST gateway for platform contracts
+
+package ctgateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	iot "github.com/ibm-watson-iot/blockchain-samples/fabricv1/platform"
+	"github.com/ibm-watson-iot/blockchain-samples/fabricv1/platform/cttest"
+)
+
+// Server maps REST requests onto contract functions:
+//
+//	POST /assets/{class}           createAsset for the class, the body is the asset event
+//	GET  /assets/{class}           readAllAssets for the class
+//	GET  /assets/{class}/{id}      readAsset for the class
+//	POST /invoke/{function}        any invoke, the body is its argument or an array of them
+//	POST /query/{function}         any query, as above
+//	GET  /openapi.json             the contract's API as an OpenAPI document
+//
+// A class's functions are found through readAllRoutes, they are named as for the default
+// class with the class name appended, e.g. createAssetSurgicalKit.
+type Server struct {
+	Title    string // OpenAPI title
+	Version  string // OpenAPI version of the contract
+	Document []byte // OpenAPI document to serve, e.g. the one processOpenAPI.go writes, instead of one built from readAssetSchemas
+
+	client Client
+	mux    sync.Mutex
+	routes map[string]route
+}
+
+type route struct {
+	FunctionName string         `json:"functionname"`
+	Method       string         `json:"method"`
+	Class        iot.AssetClass `json:"class"`
+}
+
+// NewServer returns a gateway that calls the contract through client
+func NewServer(client Client) *Server {
+	return &Server{
+		Title:   "IoT Contract",
+		Version: "1.0",
+		client:  client,
+	}
+}
+
+// ServeMock initializes a contract on a MockStub with initArgs and serves it on addr,
+// for local use without a peer
+func ServeMock(addr string, cc shim.Chaincode, initArgs []string) error {
+	stub := cttest.NewMockStub("gateway", cc)
+	if resp := stub.MockInit("init", initArgs); resp.Status != shim.OK {
+		return fmt.Errorf("ServeMock could not initialize the contract: %s", resp.Message)
+	}
+	return http.ListenAndServe(addr, NewServer(NewMockClient(stub)))
+}
+
+// loads the routes once, they do not change while a contract is deployed
+func (s *Server) getRoutes() (map[string]route, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.routes != nil {
+		return s.routes, nil
+	}
+	routesBytes, err := s.client.Query("readAllRoutes", []string{})
+	if err != nil {
+		return nil, err
+	}
+	var routes []route
+	if err = json.Unmarshal(routesBytes, &routes); err != nil {
+		return nil, fmt.Errorf("readAllRoutes returned unexpected routes: %s", err)
+	}
+	s.routes = make(map[string]route, len(routes))
+	for _, r := range routes {
+		s.routes[r.FunctionName] = r
+	}
+	return s.routes, nil
+}
+
+// finds a class's function, named as for the default class or with the class name appended,
+// which generated routes capitalise, e.g. createAssetContainer for class container
+func (s *Server) classRoute(class string, function string) (route, bool, error) {
+	routes, err := s.getRoutes()
+	if err != nil {
+		return route{}, false, err
+	}
+	for _, r := range routes {
+		if r.Class.Name == class && (r.FunctionName == function || strings.EqualFold(r.FunctionName, function+r.Class.Name)) {
+			return r, true, nil
+		}
+	}
+	return route{}, false, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case r.Method == "GET" && len(parts) == 1 && parts[0] == "openapi.json":
+		s.openAPI(w)
+	case r.Method == "POST" && len(parts) == 2 && parts[0] == "assets":
+		s.classCall(w, r, parts[1], "createAsset", nil)
+	case r.Method == "GET" && len(parts) == 2 && parts[0] == "assets":
+		s.classCall(w, r, parts[1], "readAllAssets", nil)
+	case r.Method == "GET" && len(parts) == 3 && parts[0] == "assets":
+		s.classCall(w, r, parts[1], "readAsset", &parts[2])
+	case r.Method == "POST" && len(parts) == 2 && (parts[0] == "invoke" || parts[0] == "query"):
+		s.functionCall(w, r, parts[0], parts[1])
+	default:
+		writeError(w, http.StatusNotFound, fmt.Sprintf("no route for %s %s", r.Method, r.URL.Path))
+	}
+}
+
+func (s *Server) classCall(w http.ResponseWriter, r *http.Request, class string, function string, id *string) {
+	cr, found, err := s.classRoute(class, function)
+	if err != nil {
+		writeClientError(w, err)
+		return
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("class %s has no %s function", class, function))
+		return
+	}
+	var args []string
+	if id != nil {
+		// the id goes where the class keeps it, e.g. {"asset": {"assetID": "A1"}}
+		var arg = make(map[string]interface{})
+		iot.PutObject(&arg, cr.Class.AssetIDPath, *id)
+		argBytes, _ := json.Marshal(arg)
+		args = []string{string(argBytes)}
+	} else if args, err = readArgs(r); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.call(w, cr, args, id != nil)
+}
+
+func (s *Server) functionCall(w http.ResponseWriter, r *http.Request, method string, function string) {
+	routes, err := s.getRoutes()
+	if err != nil {
+		writeClientError(w, err)
+		return
+	}
+	fr, found := routes[function]
+	if !found || fr.Method != method {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("contract has no %s function %s", method, function))
+		return
+	}
+	args, err := readArgs(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.call(w, fr, args, false)
+}
+
+func (s *Server) call(w http.ResponseWriter, fr route, args []string, read bool) {
+	var result []byte
+	var err error
+	if fr.Method == "invoke" {
+		result, err = s.client.Invoke(fr.FunctionName, args)
+	} else {
+		result, err = s.client.Query(fr.FunctionName, args)
+	}
+	if err != nil {
+		if ce, found := err.(*ChaincodeError); found && read && strings.Contains(ce.Message, "does not exist") {
+			writeError(w, http.StatusNotFound, ce.Message)
+			return
+		}
+		writeClientError(w, err)
+		return
+	}
+	if fr.Method == "invoke" && strings.HasPrefix(fr.FunctionName, "createAsset") {
+		writeJSON(w, http.StatusCreated, result)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) openAPI(w http.ResponseWriter) {
+	if len(s.Document) > 0 {
+		writeJSON(w, http.StatusOK, s.Document)
+		return
+	}
+	schemas, err := s.client.Query("readAssetSchemas", []string{})
+	if err != nil {
+		writeClientError(w, err)
+		return
+	}
+	doc, err := OpenAPI(schemas, s.Title, s.Version)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, doc)
+}
+
+// a body holds one argument, or an array of them
+func readArgs(r *http.Request) ([]string, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	body = []byte(strings.TrimSpace(string(body)))
+	if len(body) == 0 {
+		return []string{}, nil
+	}
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return nil, fmt.Errorf("request body is not JSON: %s", err)
+	}
+	if body[0] != '[' {
+		return []string{string(body)}, nil
+	}
+	var raw []json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, err
+	}
+	var args = make([]string, 0, len(raw))
+	for _, a := range raw {
+		args = append(args, string(a))
+	}
+	return args, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, body []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if len(body) == 0 {
+		body = []byte("{}")
+	}
+	w.Write(body)
+}
+
+// errors take the shape of a failed invoke's result event
+func writeError(w http.ResponseWriter, status int, message string) {
+	body, _ := json.Marshal(map[string]string{"status": "ERROR", "message": message})
+	writeJSON(w, status, body)
+}
+
+func writeClientError(w http.ResponseWriter, err error) {
+	if ce, found := err.(*ChaincodeError); found {
+		writeError(w, http.StatusBadRequest, ce.Error())
+		return
+	}
+	writeError(w, http.StatusBadGateway, err.Error())
+}
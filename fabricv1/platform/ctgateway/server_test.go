@@ -0,0 +1,157 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+package ctgateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	iot "github.com/ibm-watson-iot/blockchain-samples/fabricv1/platform"
+	"github.com/ibm-watson-iot/blockchain-samples/fabricv1/platform/cttest"
+)
+
+type gatewayChaincode struct{}
+
+func (gatewayChaincode) Init(stub shim.ChaincodeStubInterface) pb.Response {
+	return iot.Init(stub, "1.0")
+}
+
+func (gatewayChaincode) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
+	return iot.Invoke(stub)
+}
+
+// a resolved schema as served by a generated contract, cut down to the asset functions
+var gatewaySchemas = `{"API": {
+	"createAsset": {"description": "Creates a new asset by class", "properties": {"method": "invoke",
+		"args": {"type": "array", "items": {"type": "object", "properties": {"asset": {"type": "object"}}}, "minItems": 1, "maxItems": 1}}},
+	"readAsset": {"description": "Returns the state an asset", "properties": {"method": "query",
+		"args": {"type": "array", "items": {"type": "object"}, "minItems": 1, "maxItems": 1},
+		"result": {"type": "object", "properties": {"assetID": {"type": "string"}, "class": {"type": "object", "properties": {"name": "An asset's class name"}}}}}},
+	"readAllAssets": {"description": "Returns the state of all assets", "properties": {"method": "query",
+		"args": {"type": "array", "items": {"type": "object"}, "minItems": 0, "maxItems": 1},
+		"result": {"type": "array", "items": {"type": "object", "patternProperties": {"^.*$": {"type": "string"}}}}}}
+}}`
+
+// a class whose routes are named as processSchema.go generates them
+var containerClass = iot.AssetClass{Name: "container", Prefix: "CON", AssetIDPath: "container.barcode"}
+
+func init() {
+	iot.RegisterDefaultRoutes()
+	iot.AddRoute("createAssetContainer", "invoke", containerClass, func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+		return containerClass.CreateAsset(stub, args, "createAssetContainer", []iot.QPropNV{})
+	})
+	iot.AddRoute("readAssetContainer", "query", containerClass, func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+		return containerClass.ReadAsset(stub, args)
+	})
+	iot.AddRoute("readAssetSchemas", "query", iot.SystemClass, func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+		return []byte(gatewaySchemas), nil
+	})
+}
+
+func TestGateway(t *testing.T) {
+	stub := cttest.NewMockStub("gateway", gatewayChaincode{})
+	stub.MockInit("init", []string{`{"version": "1.0"}`})
+	server := httptest.NewServer(NewServer(NewMockClient(stub)))
+	defer server.Close()
+
+	var tests = []struct {
+		method string
+		path   string
+		body   string
+		status int
+		expect string
+	}{
+		{"POST", "/assets/default", `{"asset": {"assetID": "A1", "temperature": 3}}`, http.StatusCreated, `"status":"OK"`},
+		{"POST", "/assets/default", `{"asset": {"assetID": "A1"}}`, http.StatusBadRequest, `already exists`},
+		{"GET", "/assets/default/A1", "", http.StatusOK, `"temperature":3`},
+		{"GET", "/assets/default/A2", "", http.StatusNotFound, `does not exist`},
+		{"GET", "/assets/default", "", http.StatusOK, `"assetID":"A1"`},
+		{"GET", "/assets/nosuchclass/A1", "", http.StatusNotFound, `has no readAsset`},
+		{"POST", "/assets/container", `{"container": {"barcode": "C1", "carrier": "ship"}}`, http.StatusCreated, `"status":"OK"`},
+		{"GET", "/assets/container/C1", "", http.StatusOK, `"carrier":"ship"`},
+		{"GET", "/assets/container", "", http.StatusNotFound, `has no readAllAssets`},
+		{"POST", "/invoke/updateAsset", `[{"asset": {"assetID": "A1", "temperature": 4}}]`, http.StatusOK, `"status":"OK"`},
+		{"POST", "/query/readAsset", `{"asset": {"assetID": "A1"}}`, http.StatusOK, `"temperature":4`},
+		{"POST", "/invoke/readAsset", `{"asset": {"assetID": "A1"}}`, http.StatusNotFound, `no invoke function`},
+		{"POST", "/invoke/updateAsset", `{"asset": `, http.StatusBadRequest, `not JSON`},
+		{"GET", "/openapi.json", "", http.StatusOK, `"/assets/default/{id}"`},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest(test.method, server.URL+test.path, strings.NewReader(test.body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != test.status || !strings.Contains(string(body), test.expect) {
+			t.Fail()
+			fmt.Printf("*** %s %s returned %d %s, expected %d containing %s\n", test.method, test.path, resp.StatusCode, body, test.status, test.expect)
+		}
+	}
+
+	// a prebuilt document is served as it is
+	prebuilt := NewServer(NewMockClient(stub))
+	prebuilt.Document = []byte(`{"openapi": "3.0.0", "info": {"title": "prebuilt"}}`)
+	rec := httptest.NewRecorder()
+	prebuilt.ServeHTTP(rec, httptest.NewRequest("GET", "/openapi.json", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != string(prebuilt.Document) {
+		t.Fail()
+		fmt.Printf("*** GET /openapi.json with a document returned %d %s\n", rec.Code, rec.Body)
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
+	docBytes, err := OpenAPI([]byte(gatewaySchemas), "test", "1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		OpenAPI string                                       `json:"openapi"`
+		Paths   map[string]map[string]map[string]interface{} `json:"paths"`
+	}
+	json.Unmarshal(docBytes, &doc)
+	for path, method := range map[string]string{
+		"/invoke/createAsset":  "post",
+		"/query/readAsset":     "post",
+		"/assets/default":      "get",
+		"/assets/default/{id}": "get",
+	} {
+		if _, found := doc.Paths[path][method]; !found {
+			t.Fail()
+			fmt.Printf("*** OpenAPI has no %s %s\n", method, path)
+		}
+	}
+	if _, found := doc.Paths["/query/readAllAssets"]["post"]["requestBody"].(map[string]interface{})["required"]; !found {
+		t.Fail()
+		fmt.Println("*** readAllAssets body is not optional")
+	}
+	// malformed properties become descriptions and maps lose their patterns
+	for _, bad := range []string{`"name": "An asset's class name"`, `patternProperties`} {
+		if strings.Contains(string(docBytes), bad) {
+			t.Fail()
+			fmt.Printf("*** OpenAPI contains %s\n", bad)
+		}
+	}
+}
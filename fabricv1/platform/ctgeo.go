@@ -15,6 +15,7 @@ Kim Letkeman - Initial Contribution
 */
 
 // v0.1 KL -- created to handle geo calculations
+// v0.2 KL -- points, point in polygon and distance to edges and routes for geofencing
 
 package iotcontractplatform
 
@@ -41,3 +42,63 @@ func Distance(lat1, lon1, lat2, lon2 float64) float64 {
 	c := 2 * math.Asin(math.Sqrt(a))
 	return rEarth * c
 }
+
+// Point is a geo coordinate in degrees
+type Point struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// PointInPolygon returns true when the point is inside the ring, which is closed from its
+// last point back to its first. Coordinates are treated as planar, so rings must not
+// cross the antimeridian or enclose a pole.
+func PointInPolygon(p Point, ring []Point) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if (a.Latitude > p.Latitude) != (b.Latitude > p.Latitude) &&
+			p.Longitude < (b.Longitude-a.Longitude)*(p.Latitude-a.Latitude)/(b.Latitude-a.Latitude)+a.Longitude {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// DistanceToSegment returns the distance in km from a point to the nearest point of the
+// segment from a to b, on a plane tangent at the point, which is accurate for segments
+// of up to a few hundred km
+func DistanceToSegment(p Point, a Point, b Point) float64 {
+	project := func(q Point) (float64, float64) {
+		return Rad(q.Longitude-p.Longitude) * math.Cos(Rad(p.Latitude)) * rEarth, Rad(q.Latitude-p.Latitude) * rEarth
+	}
+	ax, ay := project(a)
+	bx, by := project(b)
+	dx, dy := bx-ax, by-ay
+	t := 0.0
+	if l := dx*dx + dy*dy; l > 0 {
+		t = math.Max(0, math.Min(1, -(ax*dx+ay*dy)/l))
+	}
+	return math.Hypot(ax+t*dx, ay+t*dy)
+}
+
+// DistanceToPath returns the distance in km from a point to the nearest segment of the
+// path, which is open
+func DistanceToPath(p Point, path []Point) float64 {
+	if len(path) == 1 {
+		return Distance(p.Latitude, p.Longitude, path[0].Latitude, path[0].Longitude)
+	}
+	d := math.Inf(1)
+	for i := 1; i < len(path); i++ {
+		d = math.Min(d, DistanceToSegment(p, path[i-1], path[i]))
+	}
+	return d
+}
+
+// DistanceToEdge returns the distance in km from a point to the nearest edge of the ring,
+// whether the point is inside or outside
+func DistanceToEdge(p Point, ring []Point) float64 {
+	if len(ring) == 0 {
+		return math.Inf(1)
+	}
+	return DistanceToPath(p, append(append([]Point{}, ring...), ring[0]))
+}
@@ -0,0 +1,53 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+package iotcontractplatform
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestZoneContains(t *testing.T) {
+	// a square of about 11 km a side, with a notch cut into its east side
+	ring := []Point{{0, 0}, {0, 0.1}, {0.04, 0.1}, {0.05, 0.05}, {0.06, 0.1}, {0.1, 0.1}, {0.1, 0}}
+	var tests = []struct {
+		name   string
+		zone   Zone
+		p      Point
+		inside bool
+		edge   float64 // meters
+	}{
+		{"inside polygon", Zone{Type: ZonePolygon, Polygon: ring}, Point{0.02, 0.05}, true, 2224},
+		{"in the notch", Zone{Type: ZonePolygon, Polygon: ring}, Point{0.05, 0.08}, false, 0},
+		{"second polygon", Zone{Type: ZoneMultiPolygon, Polygons: [][]Point{ring, {{1, 1}, {1, 2}, {2, 2}}}}, Point{1.2, 1.5}, true, 0},
+		{"inside circle", Zone{Type: ZoneCircle, Center: &Point{0, 0}, Radius: 1000}, Point{0, 0.005}, true, 444},
+		{"outside circle", Zone{Type: ZoneCircle, Center: &Point{0, 0}, Radius: 1000}, Point{0, 0.01}, false, 112},
+		{"beside a corridor", Zone{Type: ZoneCorridor, Route: []Point{{0, 0}, {0, 1}}, Width: 500}, Point{0.004, 0.5}, true, 55},
+		{"beyond a corridor's end", Zone{Type: ZoneCorridor, Route: []Point{{0, 0}, {0, 1}}, Width: 500}, Point{0, 1.005}, false, 56},
+	}
+	for _, test := range tests {
+		if test.zone.Contains(test.p) != test.inside {
+			t.Fail()
+			fmt.Printf("*** %s: Contains returned %t\n", test.name, !test.inside)
+		}
+		if d := test.zone.EdgeDistance(test.p); test.edge > 0 && math.Abs(d-test.edge) > 2 {
+			t.Fail()
+			fmt.Printf("*** %s: EdgeDistance returned %f, expected %f\n", test.name, d, test.edge)
+		}
+	}
+}
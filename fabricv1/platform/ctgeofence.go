@@ -0,0 +1,616 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+// v0.1 KL -- named zones in world state, attached to assets, with enter and exit detection
+//            and alerts as assets move
+// v0.2 KL -- zones sharing an alert name raise it together, and the alerts of detached or
+//            deleted zones are cleared on the next write
+
+package iotcontractplatform
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// ZONEKEY prefixes the world state key of a zone, + zone name
+const ZONEKEY string = "IOTCP.GEO.ZONE."
+
+// ZONEASSETOBJECTTYPE composite keys list the zones attached to an asset, [assetKey, zone]
+const ZONEASSETOBJECTTYPE string = "IOTCP.GEO.A"
+
+// ZONEZONEOBJECTTYPE composite keys list the assets attached to a zone, [zone, assetKey]
+const ZONEZONEOBJECTTYPE string = "IOTCP.GEO.Z"
+
+// Zone types
+const (
+	ZoneCircle       = "circle"
+	ZonePolygon      = "polygon"
+	ZoneMultiPolygon = "multipolygon"
+	ZoneCorridor     = "corridor"
+)
+
+// A zone's alert is active while an attached asset is outside the zone, or inside it
+const (
+	ZoneAlertOutside = "outside"
+	ZoneAlertInside  = "inside"
+)
+
+// Zone is a named area. A circle has a center and a radius, a polygon one ring, a
+// multipolygon several rings, and a corridor holds the points within width of its route.
+// Distances are in meters. When Alert is set, the alert is raised on an attached asset
+// while it is outside the zone, or inside it when AlertWhen is "inside".
+type Zone struct {
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	Center    *Point    `json:"center,omitempty"`
+	Radius    float64   `json:"radius,omitempty"`
+	Polygon   []Point   `json:"polygon,omitempty"`
+	Polygons  [][]Point `json:"polygons,omitempty"`
+	Route     []Point   `json:"route,omitempty"`
+	Width     float64   `json:"width,omitempty"`
+	Alert     AlertName `json:"alert,omitempty"`
+	AlertWhen string    `json:"alertwhen,omitempty"`
+}
+
+// ZoneChange is recorded in the asset state that a write produced when the asset has
+// zones attached, and so in that state's history. Inside lists the zones that hold the
+// asset's location, Entered and Exited the zones that the move from the prior location
+// entered and left. Alerts lists the zone alerts that the state holds raised, so that the
+// next write can clear those whose zones are no longer attached.
+type ZoneChange struct {
+	Inside  []string    `json:"inside"`
+	Entered []string    `json:"entered,omitempty"`
+	Exited  []string    `json:"exited,omitempty"`
+	Alerts  []AlertName `json:"alerts,omitempty"`
+}
+
+// GeoLocation names the latitude and longitude properties of a class, relative to
+// asset state, e.g. "kit.location.latitude"
+type GeoLocation struct {
+	Latitude  string `json:"latitude"`
+	Longitude string `json:"longitude"`
+}
+
+var geolocationrouter = make(map[AssetClass]GeoLocation, 0)
+
+// AddGeoLocation declares where the assets of a class hold their location, which allows
+// zones to be attached to them
+func AddGeoLocation(class AssetClass, loc GeoLocation) error {
+	if loc.Latitude == "" || loc.Longitude == "" {
+		err := fmt.Errorf("AddGeoLocation: class %s needs latitude and longitude properties", class.Name)
+		log.Error(err)
+		return err
+	}
+	geolocationrouter[class] = loc
+	log.Debugf("Class %s added location %s, %s", class.Name, loc.Latitude, loc.Longitude)
+	return nil
+}
+
+func getGeoLocationClass(name string) (AssetClass, bool) {
+	for c := range geolocationrouter {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return AssetClass{}, false
+}
+
+// location returns the asset's location for its class
+func (a *Asset) location(loc GeoLocation) (Point, bool) {
+	if a == nil || a.State == nil {
+		return Point{}, false
+	}
+	lat, found := GetObjectAsNumber(a.State, loc.Latitude)
+	if !found {
+		return Point{}, false
+	}
+	lon, found := GetObjectAsNumber(a.State, loc.Longitude)
+	if !found {
+		return Point{}, false
+	}
+	return Point{lat, lon}, true
+}
+
+func validRing(ring []Point) bool {
+	return len(ring) >= 3
+}
+
+func (z Zone) validate() error {
+	if z.Name == "" {
+		return errors.New("zone has no name")
+	}
+	if z.AlertWhen != "" && z.AlertWhen != ZoneAlertOutside && z.AlertWhen != ZoneAlertInside {
+		return fmt.Errorf("zone %s alertwhen must be %s or %s", z.Name, ZoneAlertOutside, ZoneAlertInside)
+	}
+	switch z.Type {
+	case ZoneCircle:
+		if z.Center == nil || z.Radius <= 0 {
+			return fmt.Errorf("circle zone %s needs a center and a positive radius", z.Name)
+		}
+	case ZonePolygon:
+		if !validRing(z.Polygon) {
+			return fmt.Errorf("polygon zone %s needs at least 3 points", z.Name)
+		}
+	case ZoneMultiPolygon:
+		if len(z.Polygons) == 0 {
+			return fmt.Errorf("multipolygon zone %s has no polygons", z.Name)
+		}
+		for _, ring := range z.Polygons {
+			if !validRing(ring) {
+				return fmt.Errorf("multipolygon zone %s needs at least 3 points in each polygon", z.Name)
+			}
+		}
+	case ZoneCorridor:
+		if len(z.Route) == 0 || z.Width <= 0 {
+			return fmt.Errorf("corridor zone %s needs a route and a positive width", z.Name)
+		}
+	default:
+		return fmt.Errorf("zone %s has unknown type %s", z.Name, z.Type)
+	}
+	return nil
+}
+
+// Contains returns true when the point is inside the zone
+func (z Zone) Contains(p Point) bool {
+	switch z.Type {
+	case ZoneCircle:
+		return Distance(p.Latitude, p.Longitude, z.Center.Latitude, z.Center.Longitude)*1000 <= z.Radius
+	case ZonePolygon:
+		return PointInPolygon(p, z.Polygon)
+	case ZoneMultiPolygon:
+		for _, ring := range z.Polygons {
+			if PointInPolygon(p, ring) {
+				return true
+			}
+		}
+	case ZoneCorridor:
+		return DistanceToPath(p, z.Route)*1000 <= z.Width
+	}
+	return false
+}
+
+// EdgeDistance returns the distance in meters from the point to the zone's boundary,
+// whether the point is inside or outside
+func (z Zone) EdgeDistance(p Point) float64 {
+	switch z.Type {
+	case ZoneCircle:
+		return math.Abs(Distance(p.Latitude, p.Longitude, z.Center.Latitude, z.Center.Longitude)*1000 - z.Radius)
+	case ZonePolygon:
+		return DistanceToEdge(p, z.Polygon) * 1000
+	case ZoneMultiPolygon:
+		d := math.Inf(1)
+		for _, ring := range z.Polygons {
+			d = math.Min(d, DistanceToEdge(p, ring)*1000)
+		}
+		return d
+	case ZoneCorridor:
+		return math.Abs(DistanceToPath(p, z.Route)*1000 - z.Width)
+	}
+	return math.Inf(1)
+}
+
+func (z Zone) alertActive(inside bool) bool {
+	if z.AlertWhen == ZoneAlertInside {
+		return inside
+	}
+	return !inside
+}
+
+func getZone(stub shim.ChaincodeStubInterface, name string) (Zone, bool, error) {
+	var z Zone
+	zBytes, err := stub.GetState(ZONEKEY + name)
+	if err != nil {
+		return z, false, fmt.Errorf("GetState for zone %s failed: %s", name, err)
+	}
+	if len(zBytes) == 0 {
+		return z, false, nil
+	}
+	if err = json.Unmarshal(zBytes, &z); err != nil {
+		return z, false, fmt.Errorf("unmarshal of zone %s failed: %s", name, err)
+	}
+	return z, true, nil
+}
+
+func putZone(stub shim.ChaincodeStubInterface, z Zone) error {
+	zBytes, err := json.Marshal(z)
+	if err != nil {
+		return err
+	}
+	return stub.PutState(ZONEKEY+z.Name, zBytes)
+}
+
+// getAttachedKeys returns the second attribute of the composite keys that start with
+// the first, the zones of an asset or the assets of a zone
+func getAttachedKeys(stub shim.ChaincodeStubInterface, objectType string, key string) ([]string, error) {
+	var keys = make([]string, 0)
+	iter, err := stub.GetStateByPartialCompositeKey(objectType, []string{key})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get a partial composite key iterator for %s: %s", key, err)
+	}
+	defer iter.Close()
+	for iter.HasNext() {
+		ck, _, err := iter.Next()
+		if err != nil {
+			return nil, fmt.Errorf("iter.Next() failed: %s", err)
+		}
+		_, attrs, err := stub.SplitCompositeKey(ck)
+		if err != nil || len(attrs) != 2 {
+			return nil, fmt.Errorf("zone attachment key %s is malformed: %v", ck, err)
+		}
+		keys = append(keys, attrs[1])
+	}
+	return keys, nil
+}
+
+func attachmentKeys(stub shim.ChaincodeStubInterface, zone string, assetKey string) (string, string, error) {
+	ak, err := stub.CreateCompositeKey(ZONEASSETOBJECTTYPE, []string{assetKey, zone})
+	if err != nil {
+		return "", "", err
+	}
+	zk, err := stub.CreateCompositeKey(ZONEZONEOBJECTTYPE, []string{zone, assetKey})
+	if err != nil {
+		return "", "", err
+	}
+	return ak, zk, nil
+}
+
+func removeAttachment(stub shim.ChaincodeStubInterface, zone string, assetKey string) error {
+	ak, zk, err := attachmentKeys(stub, zone, assetKey)
+	if err != nil {
+		return err
+	}
+	if err = stub.DelState(ak); err != nil {
+		return err
+	}
+	return stub.DelState(zk)
+}
+
+// getAssetZones returns the zones attached to an asset, in name order
+func getAssetZones(stub shim.ChaincodeStubInterface, assetKey string) ([]Zone, error) {
+	names, err := getAttachedKeys(stub, ZONEASSETOBJECTTYPE, assetKey)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	var zones = make([]Zone, 0, len(names))
+	for _, n := range names {
+		z, found, err := getZone(stub, n)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			zones = append(zones, z)
+		}
+	}
+	return zones, nil
+}
+
+// applyZones finds the attached zones that the asset entered and exited on its way from
+// the prior location, and raises or clears their alerts. An alert is raised while any
+// zone that names it has it active, and the alerts that the prior state held for zones
+// that have since been detached or deleted are cleared. An asset without a location is
+// left alone, a new asset enters the zones that it starts in.
+func (a *Asset) applyZones(stub shim.ChaincodeStubInterface, prior *Asset) error {
+	a.Zones = nil
+	loc, found := geolocationrouter[a.Class]
+	if !found {
+		return nil
+	}
+	current, found := a.location(loc)
+	if !found {
+		return nil
+	}
+	zones, err := getAssetZones(stub, a.AssetKey)
+	if err != nil {
+		return err
+	}
+	previous, hadLocation := prior.location(loc)
+	var zc = &ZoneChange{Inside: make([]string, 0)}
+	var active = make(map[AlertName]bool)
+	for _, z := range zones {
+		inside := z.Contains(current)
+		was := hadLocation && z.Contains(previous)
+		if inside {
+			zc.Inside = append(zc.Inside, z.Name)
+		}
+		if inside && !was {
+			zc.Entered = append(zc.Entered, z.Name)
+		}
+		if was && !inside {
+			zc.Exited = append(zc.Exited, z.Name)
+		}
+		if z.Alert != "" {
+			active[z.Alert] = active[z.Alert] || z.alertActive(inside)
+		}
+	}
+	if prior != nil && prior.Zones != nil {
+		for _, alert := range prior.Zones.Alerts {
+			if _, found := active[alert]; !found {
+				ClearAlert(a, alert)
+			}
+		}
+	}
+	if len(zones) == 0 {
+		return nil
+	}
+	var alerts = make(AlertNameArray, 0, len(active))
+	for alert := range active {
+		alerts = append(alerts, alert)
+	}
+	sort.Sort(alerts)
+	for _, alert := range alerts {
+		if active[alert] {
+			RaiseAlert(a, alert)
+			zc.Alerts = append(zc.Alerts, alert)
+		} else {
+			ClearAlert(a, alert)
+		}
+	}
+	a.Zones = zc
+	return nil
+}
+
+// removeZoneAttachments detaches all zones from an asset that is being deleted
+func (a *Asset) removeZoneAttachments(stub shim.ChaincodeStubInterface) error {
+	names, err := getAttachedKeys(stub, ZONEASSETOBJECTTYPE, a.AssetKey)
+	if err != nil {
+		return err
+	}
+	for _, n := range names {
+		if err = removeAttachment(stub, n, a.AssetKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ZoneArg identifies a zone and, for attachments, an asset by class name and asset ID
+type ZoneArg struct {
+	Zone      string `json:"zone"`
+	ClassName string `json:"classname"`
+	AssetID   string `json:"assetid"`
+}
+
+func getUnmarshalledZoneArg(caller string, args []string) (ZoneArg, error) {
+	var arg ZoneArg
+	if len(args) != 1 {
+		err := fmt.Errorf("%s expects a JSON object with a zone name", caller)
+		log.Error(err)
+		return arg, err
+	}
+	if err := json.Unmarshal([]byte(args[0]), &arg); err != nil {
+		err = fmt.Errorf("%s failed to unmarshal arg: %s", caller, err)
+		log.Error(err)
+		return arg, err
+	}
+	return arg, nil
+}
+
+// assetKey returns the key of the asset named in the arg, whose class must have a location
+func (arg ZoneArg) assetKey(stub shim.ChaincodeStubInterface) (string, error) {
+	class, found := getGeoLocationClass(arg.ClassName)
+	if !found {
+		return "", fmt.Errorf("class %s has no location", arg.ClassName)
+	}
+	assetKey := class.Prefix + arg.AssetID
+	if err := assetExists(stub, class, assetKey); err != nil {
+		return "", err
+	}
+	return assetKey, nil
+}
+
+func putZoneArg(caller string, stub shim.ChaincodeStubInterface, args []string, create bool) ([]byte, error) {
+	var z Zone
+	if len(args) != 1 {
+		err := fmt.Errorf("%s expects a JSON zone", caller)
+		log.Error(err)
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(args[0]), &z); err != nil {
+		err = fmt.Errorf("%s failed to unmarshal zone: %s", caller, err)
+		log.Error(err)
+		return nil, err
+	}
+	if err := z.validate(); err != nil {
+		err = fmt.Errorf("%s: %s", caller, err)
+		log.Error(err)
+		return nil, err
+	}
+	_, exists, err := getZone(stub, z.Name)
+	if err == nil && exists && create {
+		err = fmt.Errorf("zone %s already exists", z.Name)
+	}
+	if err == nil && !exists && !create {
+		err = fmt.Errorf("zone %s does not exist", z.Name)
+	}
+	if err == nil {
+		err = putZone(stub, z)
+	}
+	if err != nil {
+		err = fmt.Errorf("%s failed: %s", caller, err)
+		log.Error(err)
+		return nil, err
+	}
+	return nil, nil
+}
+
+// createZone stores a new zone, e.g. {"name": "H1", "type": "circle", "center":
+// {"latitude": 42.36, "longitude": -71.06}, "radius": 500, "alert": "OUTOFAREA"}
+var createZone ChaincodeFunc = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	return putZoneArg("createZone", stub, args, true)
+}
+
+// updateZone replaces a zone, attached assets see the new shape on their next write
+var updateZone ChaincodeFunc = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	return putZoneArg("updateZone", stub, args, false)
+}
+
+// deleteZone deletes a zone and detaches it from its assets, {"zone": "H1"}. An alert
+// that the zone raised on an asset is cleared on the asset's next write.
+var deleteZone ChaincodeFunc = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	arg, err := getUnmarshalledZoneArg("deleteZone", args)
+	if err != nil {
+		return nil, err
+	}
+	assetKeys, err := getAttachedKeys(stub, ZONEZONEOBJECTTYPE, arg.Zone)
+	for _, k := range assetKeys {
+		if err = removeAttachment(stub, arg.Zone, k); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = stub.DelState(ZONEKEY + arg.Zone)
+	}
+	if err != nil {
+		err = fmt.Errorf("deleteZone %s failed: %s", arg.Zone, err)
+		log.Error(err)
+		return nil, err
+	}
+	return nil, nil
+}
+
+var readZone ChaincodeFunc = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	arg, err := getUnmarshalledZoneArg("readZone", args)
+	if err != nil {
+		return nil, err
+	}
+	z, found, err := getZone(stub, arg.Zone)
+	if err == nil && !found {
+		err = fmt.Errorf("zone %s does not exist", arg.Zone)
+	}
+	if err != nil {
+		err = fmt.Errorf("readZone failed: %s", err)
+		log.Error(err)
+		return nil, err
+	}
+	return json.Marshal(z)
+}
+
+// readAllZones returns all zones in name order
+var readAllZones ChaincodeFunc = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	iter, err := stub.GetStateByRange(ZONEKEY, ZONEKEY+"}")
+	if err != nil {
+		err = fmt.Errorf("readAllZones failed to get a range query iterator: %s", err)
+		log.Error(err)
+		return nil, err
+	}
+	defer iter.Close()
+	var zones = make([]Zone, 0)
+	for iter.HasNext() {
+		key, zBytes, err := iter.Next()
+		if err != nil {
+			err = fmt.Errorf("readAllZones iter.Next() failed: %s", err)
+			log.Error(err)
+			return nil, err
+		}
+		var z Zone
+		if err = json.Unmarshal(zBytes, &z); err != nil {
+			err = fmt.Errorf("readAllZones unmarshal of %s failed: %s", key, err)
+			log.Error(err)
+			return nil, err
+		}
+		zones = append(zones, z)
+	}
+	return json.Marshal(zones)
+}
+
+// attachZone attaches a zone to an asset, {"zone": "H1", "classname": "kit", "assetid":
+// "K1"}. The asset's next write detects whether it is inside.
+var attachZone ChaincodeFunc = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	arg, err := getUnmarshalledZoneArg("attachZone", args)
+	if err != nil {
+		return nil, err
+	}
+	assetKey, err := arg.assetKey(stub)
+	if err == nil {
+		var found bool
+		_, found, err = getZone(stub, arg.Zone)
+		if err == nil && !found {
+			err = fmt.Errorf("zone %s does not exist", arg.Zone)
+		}
+	}
+	var ak, zk string
+	if err == nil {
+		ak, zk, err = attachmentKeys(stub, arg.Zone, assetKey)
+	}
+	if err == nil {
+		err = stub.PutState(ak, indexValueMarker)
+	}
+	if err == nil {
+		err = stub.PutState(zk, indexValueMarker)
+	}
+	if err != nil {
+		err = fmt.Errorf("attachZone failed: %s", err)
+		log.Error(err)
+		return nil, err
+	}
+	return nil, nil
+}
+
+// detachZone detaches a zone from an asset, an alert that the zone raised is cleared on
+// the asset's next write
+var detachZone ChaincodeFunc = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	arg, err := getUnmarshalledZoneArg("detachZone", args)
+	if err != nil {
+		return nil, err
+	}
+	assetKey, err := arg.assetKey(stub)
+	if err == nil {
+		err = removeAttachment(stub, arg.Zone, assetKey)
+	}
+	if err != nil {
+		err = fmt.Errorf("detachZone failed: %s", err)
+		log.Error(err)
+		return nil, err
+	}
+	return nil, nil
+}
+
+// readAssetZones returns the zones attached to an asset, {"classname": "kit", "assetid": "K1"}
+var readAssetZones ChaincodeFunc = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	arg, err := getUnmarshalledZoneArg("readAssetZones", args)
+	if err != nil {
+		return nil, err
+	}
+	assetKey, err := arg.assetKey(stub)
+	var zones []Zone
+	if err == nil {
+		zones, err = getAssetZones(stub, assetKey)
+	}
+	if err != nil {
+		err = fmt.Errorf("readAssetZones failed: %s", err)
+		log.Error(err)
+		return nil, err
+	}
+	return json.Marshal(zones)
+}
+
+func init() {
+	AddRoute("createZone", "invoke", SystemClass, createZone)
+	AddRoute("updateZone", "invoke", SystemClass, updateZone)
+	AddRoute("deleteZone", "invoke", SystemClass, deleteZone)
+	AddRoute("attachZone", "invoke", SystemClass, attachZone)
+	AddRoute("detachZone", "invoke", SystemClass, detachZone)
+	AddRoute("readZone", "query", SystemClass, readZone)
+	AddRoute("readAllZones", "query", SystemClass, readAllZones)
+	AddRoute("readAssetZones", "query", SystemClass, readAssetZones)
+}
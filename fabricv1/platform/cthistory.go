@@ -26,8 +26,6 @@ import (
 	"encoding/json"
 	"fmt"
 
-	"time"
-
 	"sort"
 
 	"github.com/hyperledger/fabric/core/chaincode/shim"
@@ -57,25 +55,18 @@ type DateRange struct {
 	} `json:"daterange"`
 }
 
-// PUTAssetStateHistory write an Asset state with history key
+// PUTAssetStateHistory records an Asset state in the history backend of its class
 func (a *Asset) PUTAssetStateHistory(stub shim.ChaincodeStubInterface) error {
-	historyKey := STATEHISTORYKEY + a.AssetKey + "." + a.TXNTS.Format(time.RFC3339Nano)
-	assetBytes, err := json.Marshal(a)
+	err := classHistoryBackend(a.Class).PutAssetState(stub, a)
 	if err != nil {
-		err = fmt.Errorf("Failed to marshal Asset for history: %s", err)
-		log.Error(err)
-		return err
-	}
-	err = stub.PutState(historyKey, assetBytes)
-	if err != nil {
-		err = fmt.Errorf("Failed to PUT Asset history: %s", err)
 		log.Error(err)
 		return err
 	}
 	return nil
 }
 
-// DeleteAssetStateHistory deletes all history for an asset
+// DeleteAssetStateHistory deletes all history for an asset. Classes with NativeHistory
+// return an error, as the ledger's history cannot be deleted.
 func (c *AssetClass) DeleteAssetStateHistory(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
 	var err error
 	var arg = c.NewAsset()
@@ -92,42 +83,31 @@ func (c *AssetClass) DeleteAssetStateHistory(stub shim.ChaincodeStubInterface, a
 		return nil, err
 	}
 
-	var historyKey = STATEHISTORYKEY + assetKey + "."
-
-	iter, err := stub.GetStateByRange(historyKey, historyKey+"}")
+	err = classHistoryBackend(*c).DeleteAssetStates(stub, assetKey)
 	if err != nil {
-		err = fmt.Errorf("DeleteAssetStateHistory failed to get a range query iterator: %s", err)
+		err = fmt.Errorf("DeleteAssetStateHistory for class %s failed: %s", c.Name, err)
 		log.Errorf(err.Error())
 		return nil, err
 	}
-	defer iter.Close()
-	for iter.HasNext() {
-		key, _, err := iter.Next()
-		if err != nil {
-			err = fmt.Errorf("DeleteAssetStateHistory iter.Next() failed: %s", err)
-			log.Errorf(err.Error())
-			return nil, err
-		}
-		err = stub.DelState(key)
-		if err != nil {
-			err = fmt.Errorf("DeleteAssetStateHistory DelState for asset %s failed: %s ", key, err)
-			log.Errorf(err.Error())
-			return nil, err
-		}
-	}
 
 	return nil, nil
 }
 
-// ReadAssetStateHistory gets the state history for an asset.
+// ReadAssetStateHistory gets the state history for an asset, newest first. When a pagesize
+// or bookmark is passed, states are returned oldest first in PagedResults envelopes, as
+// the range is read forward from the bookmark and stops once the page is full. Classes
+// with native history also return deletions, as states marked deleted.
 func (c *AssetClass) ReadAssetStateHistory(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
 	var assets = make(AssetArray, 0)
+	var keys = make([]string, 0)
 	var err error
 	var filter StateFilter
 	var dr DateRange
+	var page PageRequest
 	var arg = c.NewAsset()
 	var begin string
 	var end string
+	var more bool
 
 	if err = arg.unmarshallEventIn(stub, args); err != nil {
 		err := fmt.Errorf("ReadAssetStateHistory for class %s could not unmarshall, err is %s", c.Name, err)
@@ -155,6 +135,13 @@ func (c *AssetClass) ReadAssetStateHistory(stub shim.ChaincodeStubInterface, arg
 		return nil, err
 	}
 
+	page, err = getUnmarshalledPageRequest(args)
+	if err != nil {
+		err = fmt.Errorf("ReadAssetStateHistory failed while getting page request for %s %s, err is %s", c.Name, assetKey, err)
+		log.Error(err)
+		return nil, err
+	}
+
 	if dr == EmptyDateRange {
 		begin = ""
 		end = "}"
@@ -165,36 +152,45 @@ func (c *AssetClass) ReadAssetStateHistory(stub shim.ChaincodeStubInterface, arg
 
 	var historyKey = STATEHISTORYKEY + assetKey + "."
 
-	iter, err := stub.GetStateByRange(historyKey+begin, historyKey+end)
+	// pages run from oldest to newest, so the bookmark is the exclusive beginning of the range
+	bookmark, err := decodeBookmark(page.Bookmark, historyKey)
 	if err != nil {
-		err = fmt.Errorf("ReadAssetStateHistory failed to get a range query iterator: %s", err)
-		log.Errorf(err.Error())
 		return nil, err
 	}
-	defer iter.Close()
-	for iter.HasNext() {
-		key, assetBytes, err := iter.Next()
-		if err != nil {
-			err = fmt.Errorf("ReadAssetStateHistory iter.Next() failed: %s", err)
-			log.Errorf(err.Error())
-			return nil, err
-		}
-		var state = new(Asset)
-		err = json.Unmarshal(assetBytes, state)
-		if err != nil {
-			err = fmt.Errorf("ReadAssetStateHistory unmarshal %s failed: %s", key, err)
-			log.Errorf(err.Error())
-			return nil, err
-		}
+	if after := bookmark + "\x00"; bookmark != "" && after > historyKey+begin {
+		begin = after[len(historyKey):]
+	}
+
+	errStop := fmt.Errorf("page full")
+	err = classHistoryBackend(*c).ForEachAssetState(stub, *c, assetKey, begin, end, func(key string, state *Asset) error {
 		if state.Filter(filter) {
+			if page.full(len(assets)) {
+				more = true
+				return errStop
+			}
 			assets = append(assets, *state)
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if err != nil && err != errStop {
+		err = fmt.Errorf("ReadAssetStateHistory for class %s, asset %s: %s", c.Name, assetKey, err)
+		log.Errorf(err.Error())
+		return nil, err
+	}
+
+	if page.isPaged() {
+		var lastKey string
+		if more {
+			lastKey = keys[len(keys)-1]
 		}
+		return marshalPage(page, assets, lastKey)
 	}
 
 	// return history, newest first
 	sort.Sort(sort.Reverse(ByTimestamp(assets)))
 
-	return json.Marshal(assets)
+	return marshalPage(page, assets, "")
 }
 
 // Returns a date range found in the json object in args[0]
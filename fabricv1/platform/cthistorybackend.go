@@ -0,0 +1,430 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+// v0.1 KL -- pluggable history per class, either the platform's own copies of each state
+//            under IOTCP.HIST. or the ledger's native key history
+// v0.2 KL -- legacy history keys are compared with the ledger's history by a query, and
+//            the prune deletes only the keys that it is passed
+
+package iotcontractplatform
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// HistoryStateFunc receives one history state and its history key
+type HistoryStateFunc func(key string, a *Asset) error
+
+// HistoryBackend stores and reads the state history of a class. Every state has a history
+// key, STATEHISTORYKEY + assetKey + "." + the state's RFC3339Nano timestamp, whether or
+// not the backend stores it, so that date ranges and bookmarks work the same way for all
+// backends. Begin and end bound the part of the key after the asset key and the dot, from
+// begin inclusive to end exclusive.
+type HistoryBackend interface {
+	// PutAssetState records a state as the asset is written
+	PutAssetState(stub shim.ChaincodeStubInterface, a *Asset) error
+	// ForEachAssetState calls f with the states of one asset in history key order
+	ForEachAssetState(stub shim.ChaincodeStubInterface, class AssetClass, assetKey string, begin string, end string, f HistoryStateFunc) error
+	// ForEachClassState calls f with the states of all assets of a class, one asset at a
+	// time and each in history key order, or fails when the backend cannot list them all
+	ForEachClassState(stub shim.ChaincodeStubInterface, class AssetClass, f HistoryStateFunc) error
+	// DeleteAssetStates removes the history of one asset, or fails when the backend cannot
+	// delete it
+	DeleteAssetStates(stub shim.ChaincodeStubInterface, assetKey string) error
+}
+
+// KeyHistory is the default backend, which writes a copy of every state under its
+// history key
+var KeyHistory HistoryBackend = keyHistory{}
+
+// NativeHistory reads the ledger's own history of the asset keys, which needs the peer's
+// history database. It writes nothing, records deletions as states marked deleted, and
+// cannot delete history. History keys that the class wrote before it switched are still
+// read, until pruneLegacyHistory removes those that readPrunableLegacyHistory finds the
+// ledger also holds. Class wide reads fail, as the ledger cannot list the keys of deleted
+// assets.
+var NativeHistory HistoryBackend = nativeHistory{}
+
+var historybackendrouter = make(map[AssetClass]HistoryBackend, 0)
+
+// AddHistoryBackend selects the history backend of a class, KeyHistory by default
+func AddHistoryBackend(class AssetClass, backend HistoryBackend) error {
+	if backend == nil {
+		err := fmt.Errorf("AddHistoryBackend: class %s backend is nil", class.Name)
+		log.Error(err)
+		return err
+	}
+	historybackendrouter[class] = backend
+	log.Debugf("Class %s added history backend %T", class.Name, backend)
+	return nil
+}
+
+func classHistoryBackend(c AssetClass) HistoryBackend {
+	if b, found := historybackendrouter[c]; found {
+		return b
+	}
+	return KeyHistory
+}
+
+func historyKey(assetKey string, ts time.Time) string {
+	return STATEHISTORYKEY + assetKey + "." + ts.Format(time.RFC3339Nano)
+}
+
+// forEachHistoryKey calls f with the stored states in a range of history keys
+func forEachHistoryKey(stub shim.ChaincodeStubInterface, start string, end string, f HistoryStateFunc) error {
+	iter, err := stub.GetStateByRange(start, end)
+	if err != nil {
+		return fmt.Errorf("failed to get a range query iterator: %s", err)
+	}
+	defer iter.Close()
+	for iter.HasNext() {
+		key, assetBytes, err := iter.Next()
+		if err != nil {
+			return fmt.Errorf("iter.Next() failed: %s", err)
+		}
+		var state = new(Asset)
+		if err = json.Unmarshal(assetBytes, state); err != nil {
+			return fmt.Errorf("unmarshal %s failed: %s", key, err)
+		}
+		if err = f(key, state); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//********** copy on write history keys
+
+type keyHistory struct{}
+
+func (keyHistory) PutAssetState(stub shim.ChaincodeStubInterface, a *Asset) error {
+	assetBytes, err := json.Marshal(a)
+	if err != nil {
+		return fmt.Errorf("Failed to marshal Asset for history: %s", err)
+	}
+	if err = stub.PutState(historyKey(a.AssetKey, *a.TXNTS), assetBytes); err != nil {
+		return fmt.Errorf("Failed to PUT Asset history: %s", err)
+	}
+	return nil
+}
+
+func (keyHistory) ForEachAssetState(stub shim.ChaincodeStubInterface, class AssetClass, assetKey string, begin string, end string, f HistoryStateFunc) error {
+	var prefix = STATEHISTORYKEY + assetKey + "."
+	return forEachHistoryKey(stub, prefix+begin, prefix+end, f)
+}
+
+func (keyHistory) ForEachClassState(stub shim.ChaincodeStubInterface, class AssetClass, f HistoryStateFunc) error {
+	var prefix = STATEHISTORYKEY + class.Prefix
+	return forEachHistoryKey(stub, prefix, prefix+"}", func(key string, a *Asset) error {
+		// another class's prefix can start with this one
+		if a.Class.Name != class.Name {
+			return nil
+		}
+		return f(key, a)
+	})
+}
+
+func (keyHistory) DeleteAssetStates(stub shim.ChaincodeStubInterface, assetKey string) error {
+	var prefix = STATEHISTORYKEY + assetKey + "."
+	iter, err := stub.GetStateByRange(prefix, prefix+"}")
+	if err != nil {
+		return fmt.Errorf("failed to get a range query iterator: %s", err)
+	}
+	defer iter.Close()
+	for iter.HasNext() {
+		key, _, err := iter.Next()
+		if err != nil {
+			return fmt.Errorf("iter.Next() failed: %s", err)
+		}
+		if err = stub.DelState(key); err != nil {
+			return fmt.Errorf("DelState for asset %s failed: %s ", key, err)
+		}
+	}
+	return nil
+}
+
+//********** native ledger history
+
+type nativeHistory struct{}
+
+func (nativeHistory) PutAssetState(stub shim.ChaincodeStubInterface, a *Asset) error {
+	// the ledger records the write of the asset itself
+	return nil
+}
+
+// nativeStates returns the ledger's history of an asset merged with any legacy history
+// keys, keyed by history key. The ledger's state wins where both hold a key.
+func nativeStates(stub shim.ChaincodeStubInterface, class AssetClass, assetKey string) (map[string]*Asset, error) {
+	var states = make(map[string]*Asset)
+	var prefix = STATEHISTORYKEY + assetKey + "."
+	err := forEachHistoryKey(stub, prefix, prefix+"}", func(key string, a *Asset) error {
+		states[key] = a
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	iter, err := stub.GetHistoryForKey(assetKey)
+	if err != nil {
+		return nil, fmt.Errorf("GetHistoryForKey %s failed: %s", assetKey, err)
+	}
+	defer iter.Close()
+	for iter.HasNext() {
+		km, err := iter.Next()
+		if err != nil {
+			return nil, fmt.Errorf("GetHistoryForKey %s next failed: %s", assetKey, err)
+		}
+		if km.Timestamp == nil {
+			continue
+		}
+		ts := time.Unix(km.Timestamp.Seconds, int64(km.Timestamp.Nanos))
+		var state = new(Asset)
+		if km.IsDelete {
+			*state = class.NewAsset()
+			state.AssetKey = assetKey
+			state.TXNID = km.TxId
+			state.TXNTS = &ts
+			state.EventOut = nil
+			state.Deleted = true
+		} else if err = json.Unmarshal(km.Value, state); err != nil {
+			return nil, fmt.Errorf("unmarshal of %s in transaction %s failed: %s", assetKey, km.TxId, err)
+		}
+		if state.TXNTS == nil {
+			state.TXNTS = &ts
+		}
+		states[historyKey(assetKey, *state.TXNTS)] = state
+	}
+	return states, nil
+}
+
+func (nativeHistory) ForEachAssetState(stub shim.ChaincodeStubInterface, class AssetClass, assetKey string, begin string, end string, f HistoryStateFunc) error {
+	states, err := nativeStates(stub, class, assetKey)
+	if err != nil {
+		return err
+	}
+	var prefix = STATEHISTORYKEY + assetKey + "."
+	var keys = make([]string, 0, len(states))
+	for k := range states {
+		if k >= prefix+begin && k < prefix+end {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err = f(k, states[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ForEachClassState fails, as neither world state nor the history keys list the assets
+// that were written after the switch and then deleted, and the ledger can only be asked
+// for the history of a known key
+func (nativeHistory) ForEachClassState(stub shim.ChaincodeStubInterface, class AssetClass, f HistoryStateFunc) error {
+	return fmt.Errorf("the ledger's history of class %s cannot be read class wide, as it does not list deleted assets, read the assets one at a time", class.Name)
+}
+
+func (nativeHistory) DeleteAssetStates(stub shim.ChaincodeStubInterface, assetKey string) error {
+	return fmt.Errorf("the ledger's history of %s cannot be deleted", assetKey)
+}
+
+// readPrunableLegacyHistory lists, in bounded batches, the history keys of classes that
+// use NativeHistory where the ledger holds the same state, {"limit": 1000, "bookmark":
+// "..."}. History queries must not drive updates, so the comparison is a query and the
+// listed keys are passed to pruneLegacyHistory. States that only the history keys hold are
+// counted as kept. Pass the returned bookmark until more is false.
+var readPrunableLegacyHistory ChaincodeFunc = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	type PrunableArg struct {
+		Limit    int    `json:"limit"`
+		Bookmark string `json:"bookmark"`
+	}
+	type PrunableResult struct {
+		Keys     []string `json:"keys"`
+		Kept     int      `json:"kept"`
+		More     bool     `json:"more"`
+		Bookmark string   `json:"bookmark,omitempty"`
+	}
+	var arg PrunableArg
+	var result = PrunableResult{Keys: make([]string, 0)}
+	if len(args) > 0 {
+		if err := json.Unmarshal([]byte(args[0]), &arg); err != nil {
+			err = fmt.Errorf("readPrunableLegacyHistory failed to unmarshal arg: %s", err)
+			log.Error(err)
+			return nil, err
+		}
+	}
+	if arg.Limit <= 0 {
+		arg.Limit = DefaultPruneLimit
+	}
+	after, err := decodeBookmark(arg.Bookmark, STATEHISTORYKEY)
+	if err != nil {
+		return nil, err
+	}
+
+	// the ledger's history of the asset being compared
+	var ledgerAsset string
+	var ledgerTxns map[string]bool
+	errStop := fmt.Errorf("limit reached")
+	for _, class := range nativeHistoryClasses() {
+		var prefix = STATEHISTORYKEY + class.Prefix
+		var start = prefix
+		if after != "" {
+			if after >= prefix+"}" {
+				continue
+			}
+			if strings.HasPrefix(after, prefix) {
+				start = after + "\x00"
+			}
+		}
+		err = forEachHistoryKey(stub, start, prefix+"}", func(key string, a *Asset) error {
+			if a.Class.Name != class.Name {
+				return nil
+			}
+			if len(result.Keys) >= arg.Limit {
+				return errStop
+			}
+			if a.AssetKey != ledgerAsset {
+				ledgerAsset, ledgerTxns = a.AssetKey, make(map[string]bool)
+				iter, err := stub.GetHistoryForKey(a.AssetKey)
+				if err != nil {
+					return fmt.Errorf("GetHistoryForKey %s failed: %s", a.AssetKey, err)
+				}
+				for iter.HasNext() {
+					km, err := iter.Next()
+					if err != nil {
+						iter.Close()
+						return fmt.Errorf("GetHistoryForKey %s next failed: %s", a.AssetKey, err)
+					}
+					if !km.IsDelete {
+						ledgerTxns[km.TxId] = true
+					}
+				}
+				iter.Close()
+			}
+			result.Bookmark = encodeBookmark(key)
+			if !ledgerTxns[a.TXNID] {
+				result.Kept++
+				return nil
+			}
+			result.Keys = append(result.Keys, key)
+			return nil
+		})
+		if err == errStop {
+			result.More = true
+			break
+		}
+		if err != nil {
+			err = fmt.Errorf("readPrunableLegacyHistory class %s: %s", class.Name, err)
+			log.Error(err)
+			return nil, err
+		}
+	}
+	if !result.More {
+		result.Bookmark = ""
+	}
+	return json.Marshal(result)
+}
+
+// pruneLegacyHistory deletes the history keys that readPrunableLegacyHistory listed,
+// {"keys": ["..."]}, at most DefaultPruneLimit at a time. It does not read the ledger's
+// history, and deletes a key only when world state still holds there a state of a class
+// that uses NativeHistory, stored under its own history key. Other keys are skipped.
+var pruneLegacyHistory ChaincodeFunc = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	type PruneArg struct {
+		Keys []string `json:"keys"`
+	}
+	type PruneResult struct {
+		Pruned  int `json:"pruned"`
+		Skipped int `json:"skipped"`
+	}
+	var arg PruneArg
+	var result PruneResult
+	if len(args) != 1 {
+		err := fmt.Errorf("pruneLegacyHistory expects a JSON object with the keys from readPrunableLegacyHistory")
+		log.Error(err)
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(args[0]), &arg); err != nil {
+		err = fmt.Errorf("pruneLegacyHistory failed to unmarshal arg: %s", err)
+		log.Error(err)
+		return nil, err
+	}
+	if len(arg.Keys) > DefaultPruneLimit {
+		err := fmt.Errorf("pruneLegacyHistory was passed %d keys, the limit is %d", len(arg.Keys), DefaultPruneLimit)
+		log.Error(err)
+		return nil, err
+	}
+	var native = make(map[string]bool)
+	for _, class := range nativeHistoryClasses() {
+		native[class.Name] = true
+	}
+	for _, key := range arg.Keys {
+		if !strings.HasPrefix(key, STATEHISTORYKEY) {
+			result.Skipped++
+			continue
+		}
+		assetBytes, err := stub.GetState(key)
+		if err != nil {
+			err = fmt.Errorf("pruneLegacyHistory GetState for %s failed: %s", key, err)
+			log.Error(err)
+			return nil, err
+		}
+		var a Asset
+		if len(assetBytes) == 0 || json.Unmarshal(assetBytes, &a) != nil ||
+			!native[a.Class.Name] || a.TXNTS == nil || historyKey(a.AssetKey, *a.TXNTS) != key {
+			result.Skipped++
+			continue
+		}
+		if err = stub.DelState(key); err != nil {
+			err = fmt.Errorf("pruneLegacyHistory DelState for %s failed: %s", key, err)
+			log.Error(err)
+			return nil, err
+		}
+		result.Pruned++
+	}
+	log.Noticef("pruneLegacyHistory pruned %d and skipped %d history keys", result.Pruned, result.Skipped)
+	return json.Marshal(result)
+}
+
+// nativeHistoryClasses returns the classes that use NativeHistory in prefix order
+func nativeHistoryClasses() []AssetClass {
+	var classes = make([]AssetClass, 0)
+	for c, b := range historybackendrouter {
+		if _, native := b.(nativeHistory); native {
+			classes = append(classes, c)
+		}
+	}
+	sort.Sort(byPrefix(classes))
+	return classes
+}
+
+type byPrefix []AssetClass
+
+func (b byPrefix) Len() int           { return len(b) }
+func (b byPrefix) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+func (b byPrefix) Less(i, j int) bool { return b[i].Prefix < b[j].Prefix }
+
+func init() {
+	AddRoute("readPrunableLegacyHistory", "query", SystemClass, readPrunableLegacyHistory)
+	AddRoute("pruneLegacyHistory", "invoke", SystemClass, pruneLegacyHistory)
+}
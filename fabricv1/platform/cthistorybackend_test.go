@@ -0,0 +1,120 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+package iotcontractplatform
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/ibm-watson-iot/blockchain-samples/fabricv1/platform/cttest"
+)
+
+var nativeTestClass = AssetClass{Name: "nativetest", Prefix: "NAT", AssetIDPath: "nat.id"}
+
+func init() {
+	AddRoute("createNativeTest", "invoke", nativeTestClass, func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+		return nativeTestClass.CreateAsset(stub, args, "createNativeTest", []QPropNV{})
+	})
+	AddRoute("updateNativeTest", "invoke", nativeTestClass, func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+		return nativeTestClass.UpdateAsset(stub, args, "updateNativeTest", []QPropNV{})
+	})
+	AddRoute("deleteNativeTest", "invoke", nativeTestClass, func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+		return nativeTestClass.DeleteAsset(stub, args)
+	})
+	AddRoute("readNativeTestHistory", "query", nativeTestClass, func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+		return nativeTestClass.ReadAssetStateHistory(stub, args)
+	})
+	AddRoute("readAllNativeTestAsOf", "query", nativeTestClass, func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+		return nativeTestClass.ReadAllAssetsAsOf(stub, args)
+	})
+	AddRoute("deleteNativeTestHistory", "invoke", nativeTestClass, func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+		return nativeTestClass.DeleteAssetStateHistory(stub, args)
+	})
+}
+
+// TestNativeHistory switches a class from history keys to native history part way
+// through, then lists and prunes the legacy keys
+func TestNativeHistory(t *testing.T) {
+	defer delete(historybackendrouter, nativeTestClass)
+	stub := cttest.NewMockStub("native", scenarioChaincode{})
+	stub.MockInit("init", []string{`{"version": "1.0"}`})
+
+	invoke := func(function string, arg string) {
+		if resp := stub.MockInvoke(function, []string{arg}); resp.Status != shim.OK {
+			t.Fail()
+			fmt.Printf("*** %s failed: %s\n", function, resp.Message)
+		}
+	}
+	history := func() AssetArray {
+		var states AssetArray
+		resp := stub.MockQuery("readNativeTestHistory", []string{`{"nat": {"id": "N1"}}`})
+		if err := json.Unmarshal(resp.Payload, &states); err != nil {
+			t.Fail()
+			fmt.Printf("*** readNativeTestHistory returned %s: %s\n", resp.Message, err)
+		}
+		return states
+	}
+
+	invoke("createNativeTest", `{"nat": {"id": "N1", "level": 1}}`)
+	AddHistoryBackend(nativeTestClass, NativeHistory)
+	invoke("updateNativeTest", `{"nat": {"id": "N1", "level": 2}}`)
+	invoke("deleteNativeTest", `{"nat": {"id": "N1"}}`)
+
+	check := func(when string, legacy int) {
+		states := history()
+		if len(states) != 3 || !states[0].Deleted || states[1].Deleted || states[2].Deleted {
+			t.Fail()
+			fmt.Printf("*** %s: expected a deletion and two states, newest first, got %s\n", when, states)
+		}
+		if keys := stub.Keys(STATEHISTORYKEY + "NAT"); len(keys) != legacy {
+			t.Fail()
+			fmt.Printf("*** %s: expected %d legacy history keys, got %v\n", when, legacy, keys)
+		}
+	}
+	check("before pruning", 1)
+
+	// the query lists the keys that the ledger also holds, the invoke re-checks them
+	var prunable struct {
+		Keys []string `json:"keys"`
+		More bool     `json:"more"`
+	}
+	resp := stub.MockQuery("readPrunableLegacyHistory", []string{`{}`})
+	if err := json.Unmarshal(resp.Payload, &prunable); err != nil || len(prunable.Keys) != 1 || prunable.More {
+		t.Fail()
+		fmt.Printf("*** readPrunableLegacyHistory returned %s %s\n", resp.Payload, resp.Message)
+	}
+	keys, _ := json.Marshal(append(prunable.Keys, STATEHISTORYKEY+"NATN9.2017-01-01T00:00:00Z", "NATN1"))
+	invoke("pruneLegacyHistory", `{"keys": `+string(keys)+`}`)
+	var result map[string]interface{}
+	if err := json.Unmarshal(stub.Event.Payload, &result); err != nil || result["pruned"] != 1.0 || result["skipped"] != 2.0 {
+		t.Fail()
+		fmt.Printf("*** pruneLegacyHistory event was %s\n", stub.Event.Payload)
+	}
+	check("after pruning", 0)
+
+	// the deleted asset is not in world state, so a class wide read would leave it out
+	if resp := stub.MockQuery("readAllNativeTestAsOf", []string{`{"asof": {"timestamp": "2017-01-01T00:00:02Z"}}`}); resp.Status == shim.OK {
+		t.Fail()
+		fmt.Printf("*** readAllNativeTestAsOf returned %s\n", resp.Payload)
+	}
+	if resp := stub.MockInvoke("deleteNativeTestHistory", []string{`{"nat": {"id": "N1"}}`}); resp.Status == shim.OK {
+		t.Fail()
+		fmt.Printf("*** deleteNativeTestHistory deleted the ledger's history\n")
+	}
+}
@@ -0,0 +1,457 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+// v0.1 KL -- secondary indexes stored as composite keys so that filtered reads
+//            do not have to scan and unmarshal an entire asset class
+
+package iotcontractplatform
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// INDEXOBJECTTYPE is the composite key object type for all secondary index entries,
+// the attributes are class name, qualified property, value and asset key
+const INDEXOBJECTTYPE string = "IOTCP.IDX"
+
+// indexValueMarker is stored as the value of every index entry, as the key holds all
+// of the information and a nil value would be treated as a delete. It is an empty JSON
+// object so that readers of the whole world state can unmarshal it.
+var indexValueMarker = []byte("{}")
+
+var indexrouter = make(map[AssetClass][]string, 0)
+
+func classIndexes(c AssetClass) []string {
+	i := indexrouter[c]
+	if i == nil {
+		return []string{}
+	}
+	return i
+}
+
+// AddIndex allows a class to declare a secondary index on a qualified property in
+// asset state, one at a time. Queries that use a "match all" filter on an indexed property
+// will be answered from the index instead of a full scan of the class.
+// class is the asset class that owns the index
+// qprop is a qualified property path relative to asset state, e.g. "surgicalkit.carrier"
+func AddIndex(class AssetClass, qprop string) error {
+	if qprop == "" {
+		err := fmt.Errorf("AddIndex: class %s attempted to register an index with a blank property", class.Name)
+		log.Error(err)
+		return err
+	}
+	for _, p := range indexrouter[class] {
+		if p == qprop {
+			err := fmt.Errorf("AddIndex: index on %s is already registered against class %s", qprop, class.Name)
+			log.Error(err)
+			return err
+		}
+	}
+	indexrouter[class] = append(indexrouter[class], qprop)
+	log.Debugf("Class %s added index on %s", class.Name, qprop)
+	return nil
+}
+
+// indexValues converts a property into the string forms stored in the index, arrays are
+// indexed by each of their scalar elements as filters match an array when any element
+// matches
+func indexValues(v interface{}) []string {
+	var values = make([]string, 0)
+	if arr, ok := v.([]interface{}); ok {
+		for _, e := range arr {
+			values = append(values, indexValues(e)...)
+		}
+		return values
+	}
+	if s, ok := indexValue(v); ok {
+		values = append(values, s)
+	}
+	return values
+}
+
+// indexValue converts a leaf property into the string form stored in the index, only
+// scalar values can be indexed
+func indexValue(v interface{}) (string, bool) {
+	switch t := v.(type) {
+	case string:
+		return t, true
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64), true
+	case int:
+		return strconv.Itoa(t), true
+	case bool:
+		return strconv.FormatBool(t), true
+	default:
+		return "", false
+	}
+}
+
+// indexCandidates returns every form in which a filter value may have been stored,
+// as filter values always arrive as strings and the index stores the typed value
+func indexCandidates(value string) []string {
+	var candidates = []string{value}
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		if s := strconv.FormatFloat(f, 'f', -1, 64); s != value {
+			candidates = append(candidates, s)
+		}
+	}
+	if b, err := strconv.ParseBool(value); err == nil {
+		if s := strconv.FormatBool(b); s != value {
+			candidates = append(candidates, s)
+		}
+	}
+	return candidates
+}
+
+// indexKeys returns the composite keys that this asset's state should have in the index
+func (a *Asset) indexKeys(stub shim.ChaincodeStubInterface) (map[string]struct{}, error) {
+	var keys = make(map[string]struct{}, 0)
+	if a.State == nil {
+		return keys, nil
+	}
+	for _, qprop := range classIndexes(a.Class) {
+		o, found := GetObject(a.State, qprop)
+		if !found {
+			continue
+		}
+		values := indexValues(o)
+		if len(values) == 0 {
+			log.Debugf("indexKeys: asset %s property %s of type %T cannot be indexed", a.AssetKey, qprop, o)
+			continue
+		}
+		for _, v := range values {
+			key, err := stub.CreateCompositeKey(INDEXOBJECTTYPE, []string{a.Class.Name, qprop, v, a.AssetKey})
+			if err != nil {
+				err = fmt.Errorf("indexKeys: asset %s failed to create index key for %s: %s", a.AssetKey, qprop, err)
+				log.Error(err)
+				return nil, err
+			}
+			keys[key] = struct{}{}
+		}
+	}
+	return keys, nil
+}
+
+// priorIndexKeys returns the index keys for the asset as currently committed in world state
+func (a *Asset) priorIndexKeys(stub shim.ChaincodeStubInterface) (map[string]struct{}, error) {
+	prior, exists, err := GetAssetFromLedger(stub, a.AssetKey)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return make(map[string]struct{}, 0), nil
+	}
+	// the class is taken from the new state, as the stored copy may predate the index
+	prior.Class = a.Class
+	return prior.indexKeys(stub)
+}
+
+// putIndexEntries brings the index entries for an asset in step with its new state,
+// must be called before the new state is written
+func (a *Asset) putIndexEntries(stub shim.ChaincodeStubInterface) error {
+	if len(classIndexes(a.Class)) == 0 {
+		return nil
+	}
+	oldKeys, err := a.priorIndexKeys(stub)
+	if err != nil {
+		err = fmt.Errorf("putIndexEntries: asset %s failed to read prior index keys: %s", a.AssetKey, err)
+		log.Error(err)
+		return err
+	}
+	newKeys, err := a.indexKeys(stub)
+	if err != nil {
+		return err
+	}
+	for k := range oldKeys {
+		if _, found := newKeys[k]; !found {
+			if err := stub.DelState(k); err != nil {
+				err = fmt.Errorf("putIndexEntries: asset %s failed to delete stale index entry: %s", a.AssetKey, err)
+				log.Error(err)
+				return err
+			}
+		}
+	}
+	for k := range newKeys {
+		if _, found := oldKeys[k]; !found {
+			if err := stub.PutState(k, indexValueMarker); err != nil {
+				err = fmt.Errorf("putIndexEntries: asset %s failed to put index entry: %s", a.AssetKey, err)
+				log.Error(err)
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// removeIndexEntries deletes all index entries for an asset, must be called before the
+// asset is deleted
+func (a *Asset) removeIndexEntries(stub shim.ChaincodeStubInterface) error {
+	if len(classIndexes(a.Class)) == 0 {
+		return nil
+	}
+	oldKeys, err := a.priorIndexKeys(stub)
+	if err != nil {
+		err = fmt.Errorf("removeIndexEntries: asset %s failed to read prior index keys: %s", a.AssetKey, err)
+		log.Error(err)
+		return err
+	}
+	for k := range oldKeys {
+		if err := stub.DelState(k); err != nil {
+			err = fmt.Errorf("removeIndexEntries: asset %s failed to delete index entry: %s", a.AssetKey, err)
+			log.Error(err)
+			return err
+		}
+	}
+	return nil
+}
+
+// indexedSelect finds the first equality term in a "match all" filter that is indexed for
+// this class. Filter properties are addressed from the root of the asset, so the
+// "assetstate." level is removed before comparing with the index.
+func (c AssetClass) indexedSelect(filter StateFilter) (string, QPropNV, bool) {
+	if canonicalMatch(filter.Match) != "all" {
+		return "", QPropNV{}, false
+	}
+	indexes := classIndexes(c)
+	for _, s := range filter.Select {
+		if !s.isEquality() {
+			continue
+		}
+		qprop := strings.TrimPrefix(s.QProp, "assetstate.")
+		for _, i := range indexes {
+			if i == qprop {
+				return qprop, s, true
+			}
+		}
+	}
+	return "", QPropNV{}, false
+}
+
+// getAssetKeysFromIndex returns the sorted, de-duplicated asset keys whose indexed property
+// holds the given value
+func (c AssetClass) getAssetKeysFromIndex(stub shim.ChaincodeStubInterface, qprop string, value string) ([]string, error) {
+	var set = make(map[string]struct{}, 0)
+	for _, v := range indexCandidates(value) {
+		iter, err := stub.GetStateByPartialCompositeKey(INDEXOBJECTTYPE, []string{c.Name, qprop, v})
+		if err != nil {
+			err = fmt.Errorf("getAssetKeysFromIndex failed to get a partial composite key iterator for %s: %s", qprop, err)
+			log.Error(err)
+			return nil, err
+		}
+		for iter.HasNext() {
+			key, _, err := iter.Next()
+			if err != nil {
+				iter.Close()
+				err = fmt.Errorf("getAssetKeysFromIndex iter.Next() failed: %s", err)
+				log.Error(err)
+				return nil, err
+			}
+			_, attrs, err := stub.SplitCompositeKey(key)
+			if err != nil || len(attrs) != 4 {
+				iter.Close()
+				err = fmt.Errorf("getAssetKeysFromIndex index key %s is malformed: %v", key, err)
+				log.Error(err)
+				return nil, err
+			}
+			set[attrs[3]] = struct{}{}
+		}
+		iter.Close()
+	}
+	var keys = make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// readAssetsFromIndex returns the assets found through the index that also pass the
+// complete filter, one page at a time when the page request asks for it. The last
+// key read is returned when the page is full and more keys remain.
+func (c AssetClass) readAssetsFromIndex(stub shim.ChaincodeStubInterface, qprop string, value string, filter StateFilter, page PageRequest) (AssetArray, string, error) {
+	var assets = make(AssetArray, 0)
+	keys, err := c.getAssetKeysFromIndex(stub, qprop, value)
+	if err != nil {
+		return nil, "", err
+	}
+	after, err := decodeBookmark(page.Bookmark, c.Prefix)
+	if err != nil {
+		return nil, "", err
+	}
+	for i, k := range keys {
+		if after != "" && k <= after {
+			continue
+		}
+		if page.full(len(assets)) {
+			return assets, keys[i-1], nil
+		}
+		assetBytes, exists, err := c.getAssetFromWorldState(stub, k)
+		if err != nil {
+			return nil, "", err
+		}
+		if !exists {
+			// index is stale for this key, which is harmless
+			log.Warningf("readAssetsFromIndex: index for class %s refers to missing asset %s", c.Name, k)
+			continue
+		}
+		var state = new(Asset)
+		err = json.Unmarshal(assetBytes, state)
+		if err != nil {
+			err = fmt.Errorf("readAssetsFromIndex unmarshal %s failed: %s", k, err)
+			log.Error(err)
+			return nil, "", err
+		}
+		if state.Filter(filter) {
+			assets = append(assets, *state)
+		}
+	}
+	return assets, "", nil
+}
+
+// rebuildIndexes recreates the index entries for every asset of a class, used after an
+// index is added to a class that already has assets in world state
+var rebuildIndexes = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	type RebuildArg struct {
+		ClassName string `json:"classname"`
+	}
+	var arg RebuildArg
+	if len(args) != 1 {
+		err := errors.New("rebuildIndexes expects a JSON object with a classname")
+		log.Error(err)
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(args[0]), &arg); err != nil {
+		err = fmt.Errorf("rebuildIndexes failed to unmarshal arg: %s", err)
+		log.Error(err)
+		return nil, err
+	}
+	var class AssetClass
+	var found bool
+	for c := range indexrouter {
+		if c.Name == arg.ClassName {
+			class, found = c, true
+			break
+		}
+	}
+	if !found {
+		err := fmt.Errorf("rebuildIndexes: class %s has no registered indexes", arg.ClassName)
+		log.Error(err)
+		return nil, err
+	}
+	// entries for values and assets that no longer exist are deleted, so that the index
+	// matches world state when the rebuild is done
+	stale, err := getKeysByPartialCompositeKey(stub, INDEXOBJECTTYPE, []string{class.Name})
+	if err != nil {
+		err = fmt.Errorf("rebuildIndexes failed to read the index of class %s: %s", class.Name, err)
+		log.Error(err)
+		return nil, err
+	}
+	iter, err := stub.GetStateByRange(class.Prefix, class.Prefix+"}")
+	if err != nil {
+		err = fmt.Errorf("rebuildIndexes failed to get a range query iterator: %s", err)
+		log.Error(err)
+		return nil, err
+	}
+	defer iter.Close()
+	var fresh = make([]string, 0)
+	for iter.HasNext() {
+		key, assetBytes, err := iter.Next()
+		if err != nil {
+			err = fmt.Errorf("rebuildIndexes iter.Next() failed: %s", err)
+			log.Error(err)
+			return nil, err
+		}
+		var a Asset
+		if err := json.Unmarshal(assetBytes, &a); err != nil {
+			err = fmt.Errorf("rebuildIndexes unmarshal %s failed: %s", key, err)
+			log.Error(err)
+			return nil, err
+		}
+		a.Class = class
+		keys, err := a.indexKeys(stub)
+		if err != nil {
+			return nil, err
+		}
+		for k := range keys {
+			if _, found := stale[k]; found {
+				delete(stale, k)
+				continue
+			}
+			fresh = append(fresh, k)
+		}
+	}
+	for k := range stale {
+		if err := stub.DelState(k); err != nil {
+			err = fmt.Errorf("rebuildIndexes failed to delete stale index entry: %s", err)
+			log.Error(err)
+			return nil, err
+		}
+	}
+	for _, k := range fresh {
+		if err := stub.PutState(k, indexValueMarker); err != nil {
+			err = fmt.Errorf("rebuildIndexes failed to put index entry: %s", err)
+			log.Error(err)
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// getKeysByPartialCompositeKey returns the set of composite keys that start with the
+// given attributes
+func getKeysByPartialCompositeKey(stub shim.ChaincodeStubInterface, objectType string, attributes []string) (map[string]struct{}, error) {
+	var keys = make(map[string]struct{}, 0)
+	iter, err := stub.GetStateByPartialCompositeKey(objectType, attributes)
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+	for iter.HasNext() {
+		key, _, err := iter.Next()
+		if err != nil {
+			return nil, err
+		}
+		keys[key] = struct{}{}
+	}
+	return keys, nil
+}
+
+// readAllIndexes shows all registered indexes
+var readAllIndexes = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	type IndexOut struct {
+		Class AssetClass `json:"class"`
+		QProp string     `json:"qprop"`
+	}
+	var r = make([]IndexOut, 0, len(indexrouter))
+	for c, qprops := range indexrouter {
+		for _, qprop := range qprops {
+			r = append(r, IndexOut{c, qprop})
+		}
+	}
+	return json.Marshal(r)
+}
+
+func init() {
+	AddRoute("rebuildIndexes", "invoke", SystemClass, rebuildIndexes)
+	AddRoute("readAllIndexes", "query", SystemClass, readAllIndexes)
+}
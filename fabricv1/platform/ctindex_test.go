@@ -0,0 +1,111 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+package iotcontractplatform
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/ibm-watson-iot/blockchain-samples/fabricv1/platform/cttest"
+)
+
+var indexTestClass = AssetClass{Name: "idxtest", Prefix: "IDX", AssetIDPath: "kit.id"}
+
+func init() {
+	AddIndex(indexTestClass, "kit.carrier")
+	AddIndex(indexTestClass, "kit.tags")
+	AddRoute("createAssetIdxtest", "invoke", indexTestClass, func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+		return indexTestClass.CreateAsset(stub, args, "createAssetIdxtest", []QPropNV{})
+	})
+	AddRoute("readAllAssetsIdxtest", "query", indexTestClass, func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+		return indexTestClass.ReadAllAssets(stub, args)
+	})
+}
+
+func readIndexedKits(t *testing.T, stub *cttest.MockStub, qprop string, value string) []string {
+	filter := fmt.Sprintf(`{"filter": {"match": "all", "select": [{"qprop": "assetstate.%s", "value": "%s"}]}}`, qprop, value)
+	resp := stub.MockQuery("readAllAssetsIdxtest", []string{filter})
+	if resp.Status != shim.OK {
+		t.Fail()
+		fmt.Printf("*** readAllAssetsIdxtest failed: %s\n", resp.Message)
+		return nil
+	}
+	var assets AssetArray
+	if err := json.Unmarshal(resp.Payload, &assets); err != nil {
+		t.Fatal(err)
+	}
+	var keys = make([]string, 0, len(assets))
+	for _, a := range assets {
+		keys = append(keys, a.AssetKey)
+	}
+	return keys
+}
+
+func TestIndex(t *testing.T) {
+	stub := cttest.NewMockStub("index", scenarioChaincode{})
+	stub.MockInit("init", []string{`{"version": "1.0"}`})
+	for _, kit := range []string{
+		`{"kit": {"id": "K1", "carrier": "UPS", "tags": ["cold", "fragile"]}}`,
+		`{"kit": {"id": "K2", "carrier": "DHL", "tags": ["cold"]}}`,
+	} {
+		if resp := stub.MockInvoke("createAssetIdxtest", []string{kit}); resp.Status != shim.OK {
+			t.Fatalf("createAssetIdxtest failed: %s", resp.Message)
+		}
+	}
+
+	// index entries must not break readers of the whole world state
+	if resp := stub.MockQuery("readWorldState", []string{}); resp.Status != shim.OK {
+		t.Fail()
+		fmt.Printf("*** readWorldState failed with index entries present: %s\n", resp.Message)
+	}
+
+	// array properties are indexed by element
+	if keys := readIndexedKits(t, stub, "kit.tags", "cold"); fmt.Sprint(keys) != "[IDXK1 IDXK2]" {
+		t.Fail()
+		fmt.Printf("*** kits tagged cold are %v\n", keys)
+	}
+	if keys := readIndexedKits(t, stub, "kit.tags", "fragile"); fmt.Sprint(keys) != "[IDXK1]" {
+		t.Fail()
+		fmt.Printf("*** kits tagged fragile are %v\n", keys)
+	}
+
+	// a rebuild removes entries that no longer match world state
+	stale, _ := stub.CreateCompositeKey(INDEXOBJECTTYPE, []string{"idxtest", "kit.carrier", "UPS", "IDXK9"})
+	stub.State[stale] = indexValueMarker
+	if resp := stub.MockInvoke("rebuildIndexes", []string{`{"classname": "idxtest"}`}); resp.Status != shim.OK {
+		t.Fatalf("rebuildIndexes failed: %s", resp.Message)
+	}
+	if _, found := stub.State[stale]; found {
+		t.Fail()
+		fmt.Printf("*** rebuildIndexes left the stale entry for IDXK9\n")
+	}
+	if keys := readIndexedKits(t, stub, "kit.carrier", "UPS"); fmt.Sprint(keys) != "[IDXK1]" {
+		t.Fail()
+		fmt.Printf("*** kits carried by UPS are %v\n", keys)
+	}
+
+	// range queries skip composite keys, so the world state delete clears them by type
+	if resp := stub.MockInvoke("deleteWorldState", []string{}); resp.Status != shim.OK {
+		t.Fatalf("deleteWorldState failed: %s", resp.Message)
+	}
+	if keys := stub.Keys("\x00"); len(keys) != 0 {
+		t.Fail()
+		fmt.Printf("*** deleteWorldState left composite keys %q\n", keys)
+	}
+}
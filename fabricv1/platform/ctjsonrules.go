@@ -0,0 +1,307 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+// v0.1 KL -- declarative rules stored in world state, so that thresholds can change
+//            without redeploying the contract
+
+package iotcontractplatform
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// RULESETKEY holds the current declarative rule set in world state
+const RULESETKEY string = "IOTCP.RULESET"
+
+// RULESETVERSIONKEY is prepended to the zero padded version number of every rule set that
+// was ever current, so that the rule set that judged a historical state can be read back
+const RULESETVERSIONKEY string = "IOTCP.RULESET.V."
+
+// Rule actions, the default raises the alert when the condition matches and clears
+// it when the condition does not match, which is how the registered Go rules behave
+const (
+	ActionRaiseClear = "raiseclear"
+	ActionRaise      = "raise"
+	ActionClear      = "clear"
+)
+
+// JSONRule is a declarative rule. The condition is a state filter, so its qprops are
+// addressed from the root of the asset, e.g. "assetstate.asset.temperature". A class name
+// of "All" applies the rule to every asset class.
+type JSONRule struct {
+	RuleName  string      `json:"rulename"`
+	ClassName string      `json:"classname"`
+	Alert     AlertName   `json:"alert"`
+	Action    string      `json:"action,omitempty"`
+	Condition StateFilter `json:"condition"`
+}
+
+// RuleSet is the versioned collection of declarative rules, the version is incremented
+// by every change and is recorded in each asset state that the rule set judges
+type RuleSet struct {
+	Version int        `json:"version"`
+	Rules   []JSONRule `json:"rules"`
+}
+
+func ruleSetVersionKey(version int) string {
+	return fmt.Sprintf("%s%010d", RULESETVERSIONKEY, version)
+}
+
+// getRuleSet returns the current rule set, or version 0 with no rules when no
+// declarative rule has ever been created
+func getRuleSet(stub shim.ChaincodeStubInterface) (RuleSet, error) {
+	return getRuleSetAtKey(stub, RULESETKEY)
+}
+
+func getRuleSetAtKey(stub shim.ChaincodeStubInterface, key string) (RuleSet, error) {
+	var rs = RuleSet{Version: 0, Rules: make([]JSONRule, 0)}
+	rsBytes, err := stub.GetState(key)
+	if err != nil {
+		err = fmt.Errorf("getRuleSet GetState for %s failed: %s", key, err)
+		log.Error(err)
+		return rs, err
+	}
+	if len(rsBytes) == 0 {
+		return rs, nil
+	}
+	err = json.Unmarshal(rsBytes, &rs)
+	if err != nil {
+		err = fmt.Errorf("getRuleSet unmarshal for %s failed: %s", key, err)
+		log.Error(err)
+		return rs, err
+	}
+	return rs, nil
+}
+
+// putRuleSet increments the version and writes the rule set as current and as a
+// permanent copy under its version
+func putRuleSet(stub shim.ChaincodeStubInterface, rs RuleSet) (RuleSet, error) {
+	rs.Version++
+	rsBytes, err := json.Marshal(rs)
+	if err != nil {
+		err = fmt.Errorf("putRuleSet marshal failed: %s", err)
+		log.Error(err)
+		return rs, err
+	}
+	if err = stub.PutState(RULESETKEY, rsBytes); err != nil {
+		err = fmt.Errorf("putRuleSet PUT failed: %s", err)
+		log.Error(err)
+		return rs, err
+	}
+	if err = stub.PutState(ruleSetVersionKey(rs.Version), rsBytes); err != nil {
+		err = fmt.Errorf("putRuleSet PUT for version %d failed: %s", rs.Version, err)
+		log.Error(err)
+		return rs, err
+	}
+	return rs, nil
+}
+
+func (rs RuleSet) findRule(ruleName string) int {
+	for i, r := range rs.Rules {
+		if r.RuleName == ruleName {
+			return i
+		}
+	}
+	return -1
+}
+
+// validate checks that a rule can be evaluated
+func (r JSONRule) validate() error {
+	if r.RuleName == "" {
+		return errors.New("rule has no rulename")
+	}
+	if r.ClassName == "" {
+		return fmt.Errorf("rule %s has no classname", r.RuleName)
+	}
+	if r.Alert == "" {
+		return fmt.Errorf("rule %s has no alert", r.RuleName)
+	}
+	switch r.Action {
+	case "", ActionRaiseClear, ActionRaise, ActionClear:
+	default:
+		return fmt.Errorf("rule %s has unknown action %s", r.RuleName, r.Action)
+	}
+	if !r.Condition.isActive() {
+		return fmt.Errorf("rule %s has no condition", r.RuleName)
+	}
+	if err := r.Condition.validateOps(); err != nil {
+		return fmt.Errorf("rule %s condition: %s", r.RuleName, err)
+	}
+	return nil
+}
+
+// validateOps checks every operator in a filter, including those in nested groups
+func (filter StateFilter) validateOps() error {
+	for _, s := range filter.Select {
+		if _, found := OpAlias[s.Op]; !found {
+			return fmt.Errorf("unknown operator %s on %s", s.Op, s.QProp)
+		}
+	}
+	for _, g := range filter.Groups {
+		if err := g.validateOps(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appliesTo returns true when the rule is declared for the asset's class
+func (r JSONRule) appliesTo(a *Asset) bool {
+	return r.ClassName == a.Class.Name || r.ClassName == AllAssetClass.Name
+}
+
+// execute raises or clears the rule's alert according to the rule's action
+func (r JSONRule) execute(a *Asset) {
+	matched := a.Filter(r.Condition)
+	switch r.Action {
+	case ActionRaise:
+		if matched {
+			RaiseAlert(a, r.Alert)
+		}
+	case ActionClear:
+		if matched {
+			ClearAlert(a, r.Alert)
+		}
+	default:
+		if matched {
+			RaiseAlert(a, r.Alert)
+		} else {
+			ClearAlert(a, r.Alert)
+		}
+	}
+}
+
+// executeJSONRules evaluates the current declarative rule set against the asset and
+// records the version of the rule set in the asset
+func (a *Asset) executeJSONRules(stub shim.ChaincodeStubInterface) error {
+	rs, err := getRuleSet(stub)
+	if err != nil {
+		return err
+	}
+	if rs.Version == 0 {
+		return nil
+	}
+	a.RulesVersion = rs.Version
+	for _, r := range rs.Rules {
+		if r.appliesTo(a) {
+			r.execute(a)
+		}
+	}
+	return nil
+}
+
+func getUnmarshalledJSONRule(caller string, args []string) (JSONRule, error) {
+	var r JSONRule
+	if len(args) != 1 {
+		err := fmt.Errorf("%s expects a rule as a JSON object in args[0]", caller)
+		log.Error(err)
+		return r, err
+	}
+	if err := json.Unmarshal([]byte(args[0]), &r); err != nil {
+		err = fmt.Errorf("%s failed to unmarshal rule: %s", caller, err)
+		log.Error(err)
+		return r, err
+	}
+	return r, nil
+}
+
+var createRule = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	r, err := getUnmarshalledJSONRule("createRule", args)
+	if err != nil {
+		return nil, err
+	}
+	if err = r.validate(); err != nil {
+		err = fmt.Errorf("createRule: %s", err)
+		log.Error(err)
+		return nil, err
+	}
+	rs, err := getRuleSet(stub)
+	if err != nil {
+		return nil, err
+	}
+	if rs.findRule(r.RuleName) >= 0 {
+		err = fmt.Errorf("createRule: rule %s already exists", r.RuleName)
+		log.Error(err)
+		return nil, err
+	}
+	rs.Rules = append(rs.Rules, r)
+	rs, err = putRuleSet(stub, rs)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(rs)
+}
+
+var updateRule = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	r, err := getUnmarshalledJSONRule("updateRule", args)
+	if err != nil {
+		return nil, err
+	}
+	if err = r.validate(); err != nil {
+		err = fmt.Errorf("updateRule: %s", err)
+		log.Error(err)
+		return nil, err
+	}
+	rs, err := getRuleSet(stub)
+	if err != nil {
+		return nil, err
+	}
+	i := rs.findRule(r.RuleName)
+	if i < 0 {
+		err = fmt.Errorf("updateRule: rule %s does not exist", r.RuleName)
+		log.Error(err)
+		return nil, err
+	}
+	rs.Rules[i] = r
+	rs, err = putRuleSet(stub, rs)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(rs)
+}
+
+var deleteRule = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	r, err := getUnmarshalledJSONRule("deleteRule", args)
+	if err != nil {
+		return nil, err
+	}
+	rs, err := getRuleSet(stub)
+	if err != nil {
+		return nil, err
+	}
+	i := rs.findRule(r.RuleName)
+	if i < 0 {
+		err = fmt.Errorf("deleteRule: rule %s does not exist", r.RuleName)
+		log.Error(err)
+		return nil, err
+	}
+	rs.Rules = append(rs.Rules[:i], rs.Rules[i+1:]...)
+	rs, err = putRuleSet(stub, rs)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(rs)
+}
+
+func init() {
+	AddRoute("createRule", "invoke", SystemClass, createRule)
+	AddRoute("updateRule", "invoke", SystemClass, updateRule)
+	AddRoute("deleteRule", "invoke", SystemClass, deleteRule)
+}
@@ -0,0 +1,74 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+package iotcontractplatform
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+var jr1 = "{\"rulename\":\"excess force\",\"classname\":\"default\",\"alert\":\"EXCESSFORCE\",\"condition\":{\"match\":\"all\",\"select\":[{\"qprop\":\"assetstate.kit.maxgforce\",\"op\":\">\",\"value\":\"2\"}]}}"
+
+func TestJSONRuleExecute(t *testing.T) {
+	var r JSONRule
+	if err := json.Unmarshal([]byte(jr1), &r); err != nil || r.validate() != nil {
+		t.Fail()
+		fmt.Printf("*** rule did not unmarshal and validate: %+v || %+v\n", r, err)
+		return
+	}
+	a := newFilterTestAsset(2.5, "transit")
+	if !r.appliesTo(a) {
+		t.Fail()
+		fmt.Printf("*** rule for class %s does not apply to class %s\n", r.ClassName, a.Class.Name)
+	}
+	r.execute(a)
+	if !Contains(a.AlertsActive, AlertName("EXCESSFORCE")) {
+		t.Fail()
+		fmt.Printf("*** rule did not raise alert: %+v\n", a.AlertsActive)
+	}
+	(*a.State)["kit"].(map[string]interface{})["maxgforce"] = 1.0
+	r.execute(a)
+	if Contains(a.AlertsActive, AlertName("EXCESSFORCE")) {
+		t.Fail()
+		fmt.Printf("*** rule did not clear alert: %+v\n", a.AlertsActive)
+	}
+	r.Action = ActionRaise
+	RaiseAlert(a, "EXCESSFORCE")
+	r.execute(a)
+	if !Contains(a.AlertsActive, AlertName("EXCESSFORCE")) {
+		t.Fail()
+		fmt.Printf("*** raise only rule cleared alert: %+v\n", a.AlertsActive)
+	}
+}
+
+func TestJSONRuleValidate(t *testing.T) {
+	var tests = []JSONRule{
+		{ClassName: "default", Alert: "A", Condition: StateFilter{Match: "all", Select: []QPropNV{{QProp: "alerts", Value: "B"}}}},
+		{RuleName: "r", Alert: "A", Condition: StateFilter{Match: "all", Select: []QPropNV{{QProp: "alerts", Value: "B"}}}},
+		{RuleName: "r", ClassName: "default", Condition: StateFilter{Match: "all", Select: []QPropNV{{QProp: "alerts", Value: "B"}}}},
+		{RuleName: "r", ClassName: "default", Alert: "A"},
+		{RuleName: "r", ClassName: "default", Alert: "A", Action: "toggle", Condition: StateFilter{Match: "all", Select: []QPropNV{{QProp: "alerts", Value: "B"}}}},
+		{RuleName: "r", ClassName: "default", Alert: "A", Condition: StateFilter{Match: "all", Select: []QPropNV{{QProp: "alerts", Op: "~", Value: "B"}}}},
+	}
+	for _, r := range tests {
+		if r.validate() == nil {
+			t.Fail()
+			fmt.Printf("*** invalid rule passed validation: %+v\n", r)
+		}
+	}
+}
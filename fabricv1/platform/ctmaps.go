@@ -317,6 +317,89 @@ func GetObjectAsInteger(objIn *map[string]interface{}, qname string) (int, bool)
 	return 0, false
 }
 
+// GetObjectAsStruct retrieves an object by qualified name, or the whole object when qname
+// is empty, and unmarshals it into v, usually a pointer to a struct generated from the schema
+func GetObjectAsStruct(objIn *map[string]interface{}, qname string, v interface{}) (bool, error) {
+	var obj interface{}
+	if objIn == nil {
+		return false, nil
+	}
+	if qname == "" {
+		obj = *objIn
+	} else {
+		o, found := GetObject(objIn, qname)
+		if !found {
+			return false, nil
+		}
+		obj = o
+	}
+	objBytes, err := json.Marshal(obj)
+	if err != nil {
+		err = fmt.Errorf("GetObjectAsStruct object %s could not be marshaled: %s", qname, err)
+		log.Errorf(err.Error())
+		return true, err
+	}
+	err = json.Unmarshal(objBytes, v)
+	if err != nil {
+		err = fmt.Errorf("GetObjectAsStruct object %s does not fit %T: %s", qname, v, err)
+		log.Errorf(err.Error())
+		return true, err
+	}
+	return true, nil
+}
+
+// MergeStruct deep merges v, usually a struct generated from the schema, into the object
+// at a qualified name, or into the whole object when qname is empty. Optional fields that
+// are nil are omitted, so only the properties that are set change.
+func MergeStruct(objIn *map[string]interface{}, qname string, v interface{}) error {
+	if objIn == nil {
+		err := fmt.Errorf("MergeStruct passed NIL object, merging into '%s'", qname)
+		log.Errorf(err.Error())
+		return err
+	}
+	vBytes, err := json.Marshal(v)
+	if err != nil {
+		err = fmt.Errorf("MergeStruct %T could not be marshaled: %s", v, err)
+		log.Errorf(err.Error())
+		return err
+	}
+	var value interface{}
+	err = json.Unmarshal(vBytes, &value)
+	if err != nil {
+		err = fmt.Errorf("MergeStruct %T could not be unmarshaled: %s", v, err)
+		log.Errorf(err.Error())
+		return err
+	}
+	vmap, isMap := value.(map[string]interface{})
+	if qname == "" {
+		if !isMap {
+			err = fmt.Errorf("MergeStruct %T is not an object and cannot replace the whole object", v)
+			log.Errorf(err.Error())
+			return err
+		}
+		if *objIn == nil {
+			*objIn = make(map[string]interface{})
+		}
+		DeepMergeMap(vmap, *objIn)
+		return nil
+	}
+	if existing, found := GetObject(objIn, qname); found && isMap {
+		if emap, ok := existing.(map[string]interface{}); ok {
+			DeepMergeMap(vmap, emap)
+			return nil
+		}
+	}
+	if *objIn == nil {
+		*objIn = make(map[string]interface{})
+	}
+	if !PutObject(objIn, qname, value) {
+		err = fmt.Errorf("MergeStruct could not put %T at %s", v, qname)
+		log.Errorf(err.Error())
+		return err
+	}
+	return nil
+}
+
 // Contains checks every element with a deepEqual
 func Contains(arr interface{}, val interface{}) bool {
 	switch arr.(type) {
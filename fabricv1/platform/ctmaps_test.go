@@ -344,6 +344,54 @@ func TestRemoveObject(t *testing.T) {
 	fmt.Printf("Object after: %+v\n\n", o)
 }
 
+func TestStruct(t *testing.T) {
+	type sensors struct {
+		Maxgforce *float64 `json:"maxgforce,omitempty"`
+		Maxtilt   *float64 `json:"maxtilt,omitempty"`
+	}
+	type kit struct {
+		SkitID  string   `json:"skitID"`
+		Status  *string  `json:"status,omitempty"`
+		Sensors *sensors `json:"sensors,omitempty"`
+	}
+	var o = map[string]interface{}{}
+	json.Unmarshal([]byte(`{"kit": {"skitID": "K1", "status": "ready", "sensors": {"maxgforce": 1.5, "maxtilt": 10}}}`), &o)
+
+	var k kit
+	found, err := GetObjectAsStruct(&o, "kit", &k)
+	if !found || err != nil || k.SkitID != "K1" || *k.Status != "ready" || *k.Sensors.Maxgforce != 1.5 {
+		t.Fatalf("GetObjectAsStruct returned %t, %v, %+v", found, err, k)
+	}
+	found, err = GetObjectAsStruct(&o, "nokit", &k)
+	if found || err != nil {
+		t.Fatal("GetObjectAsStruct found nokit")
+	}
+	_, err = GetObjectAsStruct(&o, "kit.skitID", &k)
+	if err == nil {
+		t.Fatal("GetObjectAsStruct unmarshaled a string into a struct")
+	}
+
+	// nil fields are left alone by the merge
+	g := 3.0
+	err = MergeStruct(&o, "kit", kit{SkitID: "K1", Sensors: &sensors{Maxgforce: &g}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := GetObjectAsString(&o, "kit.status"); s != "ready" {
+		t.Fatalf("MergeStruct changed status to %s", s)
+	}
+	if f, _ := GetObjectAsNumber(&o, "kit.sensors.maxgforce"); f != 3 {
+		t.Fatalf("MergeStruct set maxgforce to %f", f)
+	}
+	if f, _ := GetObjectAsNumber(&o, "kit.sensors.maxtilt"); f != 10 {
+		t.Fatalf("MergeStruct changed maxtilt to %f", f)
+	}
+	err = MergeStruct(&o, "other.kit", kit{SkitID: "K2"})
+	if s, _ := GetObjectAsString(&o, "other.kit.skitID"); err != nil || s != "K2" {
+		t.Fatal("MergeStruct did not create other.kit")
+	}
+}
+
 func TestAsStringArray(t *testing.T) {
 	// fmt.Println("Enter TestAsStringArray")
 
@@ -0,0 +1,345 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+// v0.1 KL -- data migrations between contract versions, run in resumable batches when
+//            the contract is upgraded
+
+package iotcontractplatform
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// DefaultMigrationBatchSize is the number of assets migrated by the upgrade itself and
+// by each runMigration that does not pass a batch size
+const DefaultMigrationBatchSize int = 500
+
+// Migration status values
+const (
+	MigrationRunning  = "running"
+	MigrationComplete = "complete"
+)
+
+// MigrationFunc reshapes the state of one asset from one contract version to the next.
+// Private values are merged into the state before it is called.
+type MigrationFunc func(stub shim.ChaincodeStubInterface, a *Asset) error
+
+// Migration moves the assets of a class from one contract version to another
+type Migration struct {
+	From     string
+	To       string
+	Function MigrationFunc
+}
+
+// ClassMigrationStatus records the progress of one class. LastKey is the last asset
+// migrated, the next batch resumes after it.
+type ClassMigrationStatus struct {
+	ClassName string   `json:"classname"`
+	Steps     []string `json:"steps"`
+	LastKey   string   `json:"lastkey"`
+	Migrated  int      `json:"migrated"`
+	Done      bool     `json:"done"`
+}
+
+// MigrationStatus is stored in the contract state while and after an upgrade migrates data
+type MigrationStatus struct {
+	From    string                 `json:"from"`
+	To      string                 `json:"to"`
+	Status  string                 `json:"status"`
+	Started string                 `json:"started"`
+	Updated string                 `json:"updated"`
+	Classes []ClassMigrationStatus `json:"classes"`
+}
+
+var migrationrouter = make(map[AssetClass][]Migration, 0)
+
+// AddMigration allows a class to register the migration of its stored states from one
+// contract version to the next. A class's migrations chain by version, so there can be
+// only one migration from any version.
+func AddMigration(class AssetClass, from string, to string, function MigrationFunc) error {
+	if from == "" || to == "" || from == to {
+		err := fmt.Errorf("AddMigration: class %s migration from '%s' to '%s' is not valid", class.Name, from, to)
+		log.Error(err)
+		return err
+	}
+	for _, m := range migrationrouter[class] {
+		if m.From == from {
+			err := fmt.Errorf("AddMigration: class %s already has a migration from %s", class.Name, from)
+			log.Error(err)
+			return err
+		}
+	}
+	migrationrouter[class] = append(migrationrouter[class], Migration{from, to, function})
+	log.Debugf("Class %s added migration from %s to %s", class.Name, from, to)
+	return nil
+}
+
+// migrationSteps returns the chain of migrations that takes a class from one version
+// towards another, which is empty when the class's states did not change
+func migrationSteps(class AssetClass, from string, to string) []Migration {
+	var steps = make([]Migration, 0)
+	var v = from
+	for len(steps) < len(migrationrouter[class]) && v != to {
+		var next *Migration
+		for i, m := range migrationrouter[class] {
+			if m.From == v {
+				next = &migrationrouter[class][i]
+				break
+			}
+		}
+		if next == nil {
+			break
+		}
+		steps = append(steps, *next)
+		v = next.To
+	}
+	return steps
+}
+
+func getMigrationClass(name string) (AssetClass, bool) {
+	for c := range migrationrouter {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return AssetClass{}, false
+}
+
+// planMigration returns the migration status for an upgrade, or nil when no class has
+// registered migrations that apply
+func planMigration(stub shim.ChaincodeStubInterface, from string, to string) (*MigrationStatus, error) {
+	var names = make([]string, 0, len(migrationrouter))
+	for c := range migrationrouter {
+		names = append(names, c.Name)
+	}
+	sort.Strings(names)
+	now, err := getTxTime(stub)
+	if err != nil {
+		err = fmt.Errorf("planMigration failed to get the transaction time: %s", err)
+		log.Error(err)
+		return nil, err
+	}
+	var ms = &MigrationStatus{
+		From:    from,
+		To:      to,
+		Status:  MigrationRunning,
+		Started: now.UTC().Format(time.RFC3339Nano),
+		Classes: make([]ClassMigrationStatus, 0),
+	}
+	for _, name := range names {
+		class, _ := getMigrationClass(name)
+		steps := migrationSteps(class, from, to)
+		if len(steps) == 0 {
+			continue
+		}
+		cs := ClassMigrationStatus{ClassName: name, Steps: make([]string, 0, len(steps))}
+		for _, s := range steps {
+			cs.Steps = append(cs.Steps, s.From+"->"+s.To)
+		}
+		ms.Classes = append(ms.Classes, cs)
+	}
+	if len(ms.Classes) == 0 {
+		return nil, nil
+	}
+	return ms, nil
+}
+
+// migrateAsset runs the steps against one stored asset and writes it back, keeping its
+// indexes and private collections current. Migrations are not events, so no rules,
+// history or recent states are written.
+func migrateAsset(stub shim.ChaincodeStubInterface, steps []Migration, key string, assetBytes []byte) error {
+	var a Asset
+	if err := json.Unmarshal(assetBytes, &a); err != nil {
+		return fmt.Errorf("unmarshal of %s failed: %s", key, err)
+	}
+	if err := a.mergePrivateState(stub, nil); err != nil {
+		return err
+	}
+	for _, s := range steps {
+		if err := s.Function(stub, &a); err != nil {
+			return fmt.Errorf("migration from %s to %s failed for %s: %s", s.From, s.To, key, err)
+		}
+	}
+	if err := a.putPrivateState(stub); err != nil {
+		return err
+	}
+	if err := a.putIndexEntries(stub); err != nil {
+		return err
+	}
+	aBytes, err := json.Marshal(a)
+	if err != nil {
+		return fmt.Errorf("marshal of %s failed: %s", key, err)
+	}
+	return stub.PutState(key, aBytes)
+}
+
+// runMigrationBatch migrates up to batchSize assets, resuming each class after its last
+// migrated key, and marks the migration complete when every class is done
+func (ms *MigrationStatus) runMigrationBatch(stub shim.ChaincodeStubInterface, batchSize int) error {
+	var count int
+	for i := range ms.Classes {
+		cs := &ms.Classes[i]
+		if cs.Done {
+			continue
+		}
+		if count >= batchSize {
+			break
+		}
+		class, found := getMigrationClass(cs.ClassName)
+		if !found {
+			return fmt.Errorf("runMigrationBatch: class %s no longer has migrations", cs.ClassName)
+		}
+		steps := migrationSteps(class, ms.From, ms.To)
+		start := class.Prefix
+		if cs.LastKey != "" {
+			start = cs.LastKey + "\x00"
+		}
+		iter, err := stub.GetStateByRange(start, class.Prefix+"}")
+		if err != nil {
+			return fmt.Errorf("runMigrationBatch failed to get a range query iterator: %s", err)
+		}
+		for count < batchSize && iter.HasNext() {
+			key, assetBytes, err := iter.Next()
+			if err != nil {
+				iter.Close()
+				return fmt.Errorf("runMigrationBatch iter.Next() failed: %s", err)
+			}
+			if err = migrateAsset(stub, steps, key, assetBytes); err != nil {
+				iter.Close()
+				return fmt.Errorf("runMigrationBatch class %s: %s", class.Name, err)
+			}
+			cs.LastKey = key
+			cs.Migrated++
+			count++
+		}
+		cs.Done = !iter.HasNext()
+		iter.Close()
+	}
+	ms.Status = MigrationComplete
+	for _, cs := range ms.Classes {
+		if !cs.Done {
+			ms.Status = MigrationRunning
+		}
+	}
+	now, err := getTxTime(stub)
+	if err != nil {
+		return err
+	}
+	ms.Updated = now.UTC().Format(time.RFC3339Nano)
+	log.Noticef("Migration from %s to %s migrated %d assets, status %s", ms.From, ms.To, count, ms.Status)
+	return nil
+}
+
+// upgradeContractState plans the migrations from the stored version to the contract
+// version and runs the first batch
+func upgradeContractState(stub shim.ChaincodeStubInterface, state *ContractState, contractversion string) error {
+	if state.Migration != nil && state.Migration.Status == MigrationRunning {
+		err := fmt.Errorf("upgrade to %s refused, migration from %s to %s has not completed", contractversion, state.Migration.From, state.Migration.To)
+		log.Critical(err)
+		return err
+	}
+	ms, err := planMigration(stub, state.Version, contractversion)
+	if err != nil {
+		return err
+	}
+	state.Version = contractversion
+	state.Migration = ms
+	if ms == nil {
+		return nil
+	}
+	if err = ms.runMigrationBatch(stub, DefaultMigrationBatchSize); err != nil {
+		log.Critical(err)
+		return err
+	}
+	return nil
+}
+
+// checkMigration refuses invokes against a class whose assets are still being migrated,
+// as they would mix old and new state shapes
+func checkMigration(stub shim.ChaincodeStubInterface, class AssetClass) error {
+	if len(migrationrouter[class]) == 0 {
+		return nil
+	}
+	state, err := GETContractStateFromLedger(stub)
+	if err != nil || state.Migration == nil || state.Migration.Status != MigrationRunning {
+		return nil
+	}
+	for _, cs := range state.Migration.Classes {
+		if cs.ClassName == class.Name && !cs.Done {
+			err = fmt.Errorf("class %s is being migrated from %s to %s, call runMigration until it completes", class.Name, state.Migration.From, state.Migration.To)
+			log.Error(err)
+			return err
+		}
+	}
+	return nil
+}
+
+// runMigration continues a running migration, {"batchsize": 500}, and returns its status
+var runMigration ChaincodeFunc = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	type BatchArg struct {
+		BatchSize int `json:"batchsize"`
+	}
+	var arg BatchArg
+	if len(args) > 0 {
+		if err := json.Unmarshal([]byte(args[0]), &arg); err != nil {
+			err = fmt.Errorf("runMigration failed to unmarshal arg: %s", err)
+			log.Error(err)
+			return nil, err
+		}
+	}
+	if arg.BatchSize <= 0 {
+		arg.BatchSize = DefaultMigrationBatchSize
+	}
+	state, err := GETContractStateFromLedger(stub)
+	if err != nil {
+		return nil, err
+	}
+	if state.Migration == nil || state.Migration.Status != MigrationRunning {
+		err = fmt.Errorf("runMigration: there is no migration running")
+		log.Error(err)
+		return nil, err
+	}
+	if err = state.Migration.runMigrationBatch(stub, arg.BatchSize); err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	if err = PUTContractStateToLedger(stub, state); err != nil {
+		return nil, err
+	}
+	return json.Marshal(state.Migration)
+}
+
+// readMigrationStatus returns the progress of the current or last migration
+var readMigrationStatus ChaincodeFunc = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	state, err := GETContractStateFromLedger(stub)
+	if err != nil {
+		return nil, err
+	}
+	if state.Migration == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(state.Migration)
+}
+
+func init() {
+	AddRoute("runMigration", "invoke", SystemClass, runMigration)
+	AddRoute("readMigrationStatus", "query", SystemClass, readMigrationStatus)
+}
@@ -0,0 +1,62 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+package iotcontractplatform
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+func TestMigrationSteps(t *testing.T) {
+	class := AssetClass{Name: "migtest", Prefix: "MIG", AssetIDPath: "mig.id"}
+	defer delete(migrationrouter, class)
+	noop := func(stub shim.ChaincodeStubInterface, a *Asset) error { return nil }
+	for _, v := range [][2]string{{"1.0", "1.1"}, {"1.1", "2.0"}, {"2.0", "2.1"}} {
+		if err := AddMigration(class, v[0], v[1], noop); err != nil {
+			t.Fail()
+			fmt.Printf("*** AddMigration %v failed: %s\n", v, err)
+		}
+	}
+	if err := AddMigration(class, "1.0", "1.2", noop); err == nil {
+		t.Fail()
+		fmt.Printf("*** AddMigration accepted a second migration from 1.0\n")
+	}
+	var tests = []struct {
+		from string
+		to   string
+		want string
+	}{
+		{"1.0", "2.1", "1.0->1.1,1.1->2.0,2.0->2.1"},
+		{"1.1", "2.0", "1.1->2.0"},
+		{"1.0", "1.1", "1.0->1.1"},
+		{"2.1", "3.0", ""},
+		{"0.9", "1.1", ""},
+	}
+	for _, test := range tests {
+		var got = make([]string, 0)
+		for _, s := range migrationSteps(class, test.from, test.to) {
+			got = append(got, s.From+"->"+s.To)
+		}
+		if strings.Join(got, ",") != test.want {
+			t.Fail()
+			fmt.Printf("*** migrationSteps %s to %s returned %v, expected %s\n", test.from, test.to, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+// v0.1 KL -- pagination for queries that can return more than a gRPC message can hold
+
+package iotcontractplatform
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// DefaultPageSize is used when a bookmark is passed without a page size
+const DefaultPageSize int = 100
+
+// PageRequest holds the optional pagination arguments found in args[0]. The bookmark
+// is opaque to clients and is passed back exactly as it was received.
+type PageRequest struct {
+	PageSize int    `json:"pagesize"`
+	Bookmark string `json:"bookmark"`
+}
+
+// PagedResults is the response envelope for paginated queries. An empty bookmark means
+// that there are no more results.
+type PagedResults struct {
+	Results  AssetArray `json:"results"`
+	Bookmark string     `json:"bookmark"`
+	Count    int        `json:"count"`
+}
+
+// isPaged returns true when the client asked for a paginated response, older clients
+// that send neither argument receive a plain array
+func (p PageRequest) isPaged() bool {
+	return p.PageSize > 0 || p.Bookmark != ""
+}
+
+// full returns true when a page holds as many results as requested
+func (p PageRequest) full(count int) bool {
+	return p.isPaged() && count >= p.PageSize
+}
+
+// Returns the pagination arguments found in the json object in args[0]
+func getUnmarshalledPageRequest(args []string) (PageRequest, error) {
+	var page PageRequest
+
+	if len(args) == 0 {
+		// perfectly normal to not have pagination
+		return PageRequest{}, nil
+	}
+
+	err := json.Unmarshal([]byte(args[0]), &page)
+	if err != nil {
+		err = fmt.Errorf("getUnmarshalledPageRequest failed to unmarshal %s as a page request, error: %s", args[0], err)
+		log.Error(err)
+		return PageRequest{}, err
+	}
+	if page.PageSize < 0 {
+		err = fmt.Errorf("getUnmarshalledPageRequest invalid pagesize %d, must not be negative", page.PageSize)
+		log.Error(err)
+		return PageRequest{}, err
+	}
+	if page.PageSize == 0 && page.Bookmark != "" {
+		page.PageSize = DefaultPageSize
+	}
+	return page, nil
+}
+
+// encodeBookmark hides the last key read from the client
+func encodeBookmark(key string) string {
+	if key == "" {
+		return ""
+	}
+	return base64.URLEncoding.EncodeToString([]byte(key))
+}
+
+// decodeBookmark returns the last key read, which must lie under the given prefix so
+// that a bookmark cannot be used to read outside of the query's range
+func decodeBookmark(bookmark string, prefix string) (string, error) {
+	if bookmark == "" {
+		return "", nil
+	}
+	key, err := base64.URLEncoding.DecodeString(bookmark)
+	if err != nil {
+		err = fmt.Errorf("decodeBookmark: bookmark %s is not valid: %s", bookmark, err)
+		log.Error(err)
+		return "", err
+	}
+	if !strings.HasPrefix(string(key), prefix) {
+		err = fmt.Errorf("decodeBookmark: bookmark %s does not belong to this query", bookmark)
+		log.Error(err)
+		return "", err
+	}
+	return string(key), nil
+}
+
+// marshalPage returns the envelope when the client asked for pagination, and the plain
+// array otherwise
+func marshalPage(page PageRequest, assets AssetArray, lastKey string) ([]byte, error) {
+	if !page.isPaged() {
+		return json.Marshal(assets)
+	}
+	return json.Marshal(PagedResults{
+		Results:  assets,
+		Bookmark: encodeBookmark(lastKey),
+		Count:    len(assets),
+	})
+}
@@ -0,0 +1,130 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+package iotcontractplatform
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/ibm-watson-iot/blockchain-samples/fabricv1/platform/cttest"
+)
+
+var pagingTestClass = AssetClass{Name: "pagetest", Prefix: "PGT", AssetIDPath: "kit.id"}
+
+func init() {
+	AddRoute("updateAssetPagetest", "invoke", pagingTestClass, func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+		return pagingTestClass.UpdateAsset(stub, args, "updateAssetPagetest", []QPropNV{})
+	})
+	AddRoute("readAssetStateHistoryPagetest", "query", pagingTestClass, func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+		return pagingTestClass.ReadAssetStateHistory(stub, args)
+	})
+}
+
+func TestGetUnmarshalledPageRequest(t *testing.T) {
+	page, err := getUnmarshalledPageRequest([]string{"{\"asset\":{\"assetID\":\"A1\"},\"pagesize\":5}"})
+	if err != nil || page.PageSize != 5 || !page.isPaged() {
+		t.Fail()
+		fmt.Printf("*** pagesize not parsed: %+v || %+v\n", page, err)
+	}
+	page, err = getUnmarshalledPageRequest([]string{"{\"asset\":{\"assetID\":\"A1\"}}"})
+	if err != nil || page.isPaged() {
+		t.Fail()
+		fmt.Printf("*** unpaged request is paged: %+v || %+v\n", page, err)
+	}
+	page, err = getUnmarshalledPageRequest([]string{"{\"bookmark\":\"" + encodeBookmark("DEFA1") + "\"}"})
+	if err != nil || page.PageSize != DefaultPageSize {
+		t.Fail()
+		fmt.Printf("*** bookmark without pagesize did not default: %+v || %+v\n", page, err)
+	}
+	_, err = getUnmarshalledPageRequest([]string{"{\"pagesize\":-1}"})
+	if err == nil {
+		t.Fail()
+		fmt.Println("*** negative pagesize accepted")
+	}
+}
+
+func TestBookmark(t *testing.T) {
+	key, err := decodeBookmark(encodeBookmark("DEFA1"), "DEF")
+	if err != nil || key != "DEFA1" {
+		t.Fail()
+		fmt.Printf("*** bookmark did not round trip: %s || %+v\n", key, err)
+	}
+	_, err = decodeBookmark(encodeBookmark("OTHERA1"), "DEF")
+	if err == nil {
+		t.Fail()
+		fmt.Println("*** bookmark from another range accepted")
+	}
+	_, err = decodeBookmark("not base64!", "DEF")
+	if err == nil {
+		t.Fail()
+		fmt.Println("*** malformed bookmark accepted")
+	}
+}
+
+func TestMarshalPage(t *testing.T) {
+	var assets = AssetArray{Asset{AssetKey: "DEFA1"}}
+	b, err := marshalPage(PageRequest{}, assets, "DEFA1")
+	var plain AssetArray
+	if err != nil || json.Unmarshal(b, &plain) != nil || len(plain) != 1 {
+		t.Fail()
+		fmt.Printf("*** unpaged result is not a plain array: %s || %+v\n", string(b), err)
+	}
+	b, err = marshalPage(PageRequest{PageSize: 1}, assets, "DEFA1")
+	var paged PagedResults
+	if err != nil || json.Unmarshal(b, &paged) != nil || paged.Count != 1 || paged.Bookmark != encodeBookmark("DEFA1") {
+		t.Fail()
+		fmt.Printf("*** paged result envelope is wrong: %s || %+v\n", string(b), err)
+	}
+}
+
+func TestHistoryPages(t *testing.T) {
+	stub := cttest.NewMockStub("paging", scenarioChaincode{})
+	stub.MockInit("init", []string{`{"version": "1.0"}`})
+	for i := 1; i <= 5; i++ {
+		resp := stub.MockInvoke("updateAssetPagetest", []string{fmt.Sprintf(`{"kit": {"id": "P1", "count": %d}}`, i)})
+		if resp.Status != shim.OK {
+			t.Fatalf("updateAssetPagetest failed: %s", resp.Message)
+		}
+	}
+
+	// pages are read forward from the bookmark, oldest first
+	var counts []float64
+	var bookmark string
+	for pages := 0; pages < 5; pages++ {
+		resp := stub.MockQuery("readAssetStateHistoryPagetest", []string{fmt.Sprintf(`{"kit": {"id": "P1"}, "pagesize": 2, "bookmark": "%s"}`, bookmark)})
+		if resp.Status != shim.OK {
+			t.Fatalf("readAssetStateHistoryPagetest failed: %s", resp.Message)
+		}
+		var paged PagedResults
+		if err := json.Unmarshal(resp.Payload, &paged); err != nil {
+			t.Fatal(err)
+		}
+		for _, a := range paged.Results {
+			n, _ := GetObjectAsNumber(a.State, "kit.count")
+			counts = append(counts, n)
+		}
+		if bookmark = paged.Bookmark; bookmark == "" {
+			break
+		}
+	}
+	if fmt.Sprint(counts) != "[1 2 3 4 5]" {
+		t.Fail()
+		fmt.Printf("*** paged history returned counts %v\n", counts)
+	}
+}
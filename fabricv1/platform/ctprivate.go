@@ -0,0 +1,419 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+// v0.1 KL -- private properties stored in the peer's private data collections, the public
+//            asset state keeps only a marker naming the collection
+// v0.2 KL -- the marker carries a salted hash of the private value, so that public state,
+//            history and diffs show when a private value changed
+
+package iotcontractplatform
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// PRIVATESTATEKEY is prepended to the asset key to store the private values of one asset
+// in a collection
+const PRIVATESTATEKEY string = "IOTCP.PRIV." // + assetKey
+
+// PRIVATESALTKEY is prepended to the asset key to store the salt of one asset's hashes in
+// a collection
+const PRIVATESALTKEY string = "IOTCP.PRIVSALT." // + assetKey
+
+// PRIVATEMARKERPREFIX is prepended to the collection name and the salted hash of the value
+// to mark a public value that is held in that private data collection
+const PRIVATEMARKERPREFIX string = "private:"
+
+// PRIVATETRANSIENTKEY is the transient map entry in which a client can send private values,
+// as a JSON object shaped like the event. The arguments of a transaction are stored in the
+// block, so private values sent there are seen by every peer of the channel.
+const PRIVATETRANSIENTKEY string = "private"
+
+// PRIVATESALTTRANSIENTKEY is the transient map entry from which the salt of an asset's
+// hashes is derived when its first private value is written. Without it the salt is derived
+// from the transaction ID, which is public, so a low entropy value such as a price can be
+// found by trying candidates against its hash.
+const PRIVATESALTTRANSIENTKEY string = "privatesalt"
+
+// PrivateCollection is a named group of property paths, relative to asset state, whose
+// values are stored in the private data collection of the same name. The readers policy
+// decides which callers see them, and its MSP IDs are the members of the collection.
+// Typically there is one collection per organisation.
+type PrivateCollection struct {
+	Name    string       `json:"name"`
+	QProps  []string     `json:"qprops"`
+	Readers AccessPolicy `json:"readers"`
+}
+
+// CollectionConfig is one entry of the collections configuration that the contract must be
+// instantiated with, so that the peers hold each collection for its member organisations
+type CollectionConfig struct {
+	Name              string `json:"name"`
+	Policy            string `json:"policy"`
+	RequiredPeerCount int    `json:"requiredPeerCount"`
+	MaxPeerCount      int    `json:"maxPeerCount"`
+	BlockToLive       uint64 `json:"blockToLive"`
+}
+
+// privateState holds the values of one collection for one asset, keyed by qprop
+type privateState map[string]interface{}
+
+var privaterouter = make(map[AssetClass][]PrivateCollection, 0)
+
+func classPrivateCollections(c AssetClass) []PrivateCollection {
+	p := privaterouter[c]
+	if p == nil {
+		return []PrivateCollection{}
+	}
+	return p
+}
+
+// AddPrivateCollection allows a class to declare property paths whose values are stored
+// in a private data collection. Rules see the private values while an asset is written,
+// and ReadAsset merges them back for callers that satisfy the readers policy, which must
+// name the MSPs that are members of the collection.
+func AddPrivateCollection(class AssetClass, name string, qprops []string, readers AccessPolicy) error {
+	for _, pc := range classPrivateCollections(class) {
+		if pc.Name == name {
+			err := fmt.Errorf("AddPrivateCollection: class %s already has collection %s", class.Name, name)
+			log.Error(err)
+			return err
+		}
+	}
+	if name == "" || strings.Contains(name, ".") {
+		err := fmt.Errorf("AddPrivateCollection: class %s collection name '%s' must be non-empty and contain no dots", class.Name, name)
+		log.Error(err)
+		return err
+	}
+	if len(readers.MSPIDs) == 0 {
+		err := fmt.Errorf("AddPrivateCollection: class %s collection %s readers policy must name the member MSPs", class.Name, name)
+		log.Error(err)
+		return err
+	}
+	for c, pcs := range privaterouter {
+		for _, pc := range pcs {
+			if pc.Name == name && strings.Join(pc.Readers.MSPIDs, ",") != strings.Join(readers.MSPIDs, ",") {
+				err := fmt.Errorf("AddPrivateCollection: class %s collection %s has members %v, but class %s declared it with members %v", class.Name, name, readers.MSPIDs, c.Name, pc.Readers.MSPIDs)
+				log.Error(err)
+				return err
+			}
+		}
+	}
+	pc := PrivateCollection{
+		Name:    name,
+		QProps:  qprops,
+		Readers: readers,
+	}
+	privaterouter[class] = append(privaterouter[class], pc)
+	log.Debugf("Class %s added private collection %s with properties %v", class.Name, name, qprops)
+	return nil
+}
+
+// CollectionsConfig returns the collections configuration for all declared collections,
+// with a member policy of their readers' MSPs
+func CollectionsConfig() []CollectionConfig {
+	var members = make(map[string][]string, 0)
+	for _, pcs := range privaterouter {
+		for _, pc := range pcs {
+			members[pc.Name] = pc.Readers.MSPIDs
+		}
+	}
+	var names = make([]string, 0, len(members))
+	for name := range members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var config = make([]CollectionConfig, 0, len(names))
+	for _, name := range names {
+		var principals = make([]string, 0, len(members[name]))
+		for _, mspid := range members[name] {
+			principals = append(principals, fmt.Sprintf("'%s.member'", mspid))
+		}
+		config = append(config, CollectionConfig{
+			Name:              name,
+			Policy:            "OR(" + strings.Join(principals, ", ") + ")",
+			RequiredPeerCount: 0,
+			MaxPeerCount:      3,
+			BlockToLive:       0,
+		})
+	}
+	return config
+}
+
+func privateStateKey(assetKey string) string {
+	return PRIVATESTATEKEY + assetKey
+}
+
+func privateSaltKey(assetKey string) string {
+	return PRIVATESALTKEY + assetKey
+}
+
+// privateMarker hashes the salt, the path and the JSON of the value, so that the marker
+// changes with the value but cannot be compared across paths or assets
+func privateMarker(collection string, salt string, qprop string, value interface{}) (string, error) {
+	vBytes, err := json.Marshal(value)
+	if err != nil {
+		err = fmt.Errorf("privateMarker marshal for %s in %s failed: %s", qprop, collection, err)
+		log.Error(err)
+		return "", err
+	}
+	h := sha256.New()
+	h.Write([]byte(salt))
+	h.Write([]byte(qprop))
+	h.Write(vBytes)
+	return PRIVATEMARKERPREFIX + collection + ":" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func isPrivateMarker(value interface{}) bool {
+	s, ok := value.(string)
+	return ok && strings.HasPrefix(s, PRIVATEMARKERPREFIX)
+}
+
+// getPrivateSalt returns the salt of an asset's hashes in a collection, storing a new one
+// when there is none. The salt must be the same on every endorsing peer, so it is derived
+// from the transient entry or the transaction ID rather than drawn at random.
+func getPrivateSalt(stub shim.ChaincodeStubInterface, collection string, assetKey string) (string, error) {
+	key := privateSaltKey(assetKey)
+	salt, err := stub.GetPrivateData(collection, key)
+	if err != nil {
+		err = fmt.Errorf("getPrivateSalt GetPrivateData for %s in %s failed: %s", key, collection, err)
+		log.Error(err)
+		return "", err
+	}
+	if len(salt) > 0 {
+		return string(salt), nil
+	}
+	transient, err := stub.GetTransient()
+	if err != nil {
+		err = fmt.Errorf("getPrivateSalt GetTransient failed: %s", err)
+		log.Error(err)
+		return "", err
+	}
+	seed, found := transient[PRIVATESALTTRANSIENTKEY]
+	if !found {
+		seed = []byte(stub.GetTxID())
+	}
+	sum := sha256.Sum256([]byte(string(seed) + collection + assetKey))
+	salt = []byte(hex.EncodeToString(sum[:]))
+	if err = stub.PutPrivateData(collection, key, salt); err != nil {
+		err = fmt.Errorf("getPrivateSalt PutPrivateData for %s in %s failed: %s", key, collection, err)
+		log.Error(err)
+		return "", err
+	}
+	return string(salt), nil
+}
+
+func getPrivateState(stub shim.ChaincodeStubInterface, collection string, key string) (privateState, error) {
+	var ps = make(privateState)
+	psBytes, err := stub.GetPrivateData(collection, key)
+	if err != nil {
+		err = fmt.Errorf("getPrivateState GetPrivateData for %s in %s failed: %s", key, collection, err)
+		log.Error(err)
+		return nil, err
+	}
+	if len(psBytes) == 0 {
+		return ps, nil
+	}
+	if err = json.Unmarshal(psBytes, &ps); err != nil {
+		err = fmt.Errorf("getPrivateState unmarshal for %s in %s failed: %s", key, collection, err)
+		log.Error(err)
+		return nil, err
+	}
+	return ps, nil
+}
+
+// mergePrivateTransient copies the private values found in the transient map into the
+// event, only for the paths that the class declares private
+func (a *Asset) mergePrivateTransient(stub shim.ChaincodeStubInterface) error {
+	if len(classPrivateCollections(a.Class)) == 0 || a.EventIn == nil {
+		return nil
+	}
+	transient, err := stub.GetTransient()
+	if err != nil {
+		err = fmt.Errorf("mergePrivateTransient GetTransient failed: %s", err)
+		log.Error(err)
+		return err
+	}
+	tBytes, found := transient[PRIVATETRANSIENTKEY]
+	if !found {
+		return nil
+	}
+	var private map[string]interface{}
+	if err = json.Unmarshal(tBytes, &private); err != nil {
+		err = fmt.Errorf("mergePrivateTransient failed to unmarshal the %s transient entry: %s", PRIVATETRANSIENTKEY, err)
+		log.Error(err)
+		return err
+	}
+	for _, pc := range classPrivateCollections(a.Class) {
+		for _, qprop := range pc.QProps {
+			if v, found := GetObject(&private, qprop); found {
+				PutObject(a.EventIn, qprop, v)
+			}
+		}
+	}
+	return nil
+}
+
+// putPrivateState moves the private values out of the asset's state and event into their
+// collections, leaving markers with a salted hash of each value behind. Values that are
+// still markers were not changed by this transaction and keep their stored value; paths
+// that are gone are removed. The peer records only a hash of the collection writes in the
+// block.
+func (a *Asset) putPrivateState(stub shim.ChaincodeStubInterface) error {
+	key := privateStateKey(a.AssetKey)
+	for _, pc := range classPrivateCollections(a.Class) {
+		ps, err := getPrivateState(stub, pc.Name, key)
+		if err != nil {
+			return err
+		}
+		var salt string
+		marker := func(qprop string, v interface{}) (string, error) {
+			if salt == "" {
+				if salt, err = getPrivateSalt(stub, pc.Name, a.AssetKey); err != nil {
+					return "", err
+				}
+			}
+			return privateMarker(pc.Name, salt, qprop, v)
+		}
+		for _, qprop := range pc.QProps {
+			// the state is read before the event is marked, as they can share maps
+			v, found := GetObject(a.State, qprop)
+			if !found {
+				delete(ps, qprop)
+			} else if !isPrivateMarker(v) {
+				m, err := marker(qprop, v)
+				if err != nil {
+					return err
+				}
+				ps[qprop] = v
+				PutObject(a.State, qprop, m)
+			}
+			if a.EventIn != nil {
+				if v, found := GetObject(a.EventIn, qprop); found && !isPrivateMarker(v) {
+					m, err := marker(qprop, v)
+					if err != nil {
+						return err
+					}
+					PutObject(a.EventIn, qprop, m)
+				}
+			}
+		}
+		if len(ps) == 0 {
+			if err = a.removeCollectionState(stub, pc.Name); err != nil {
+				return err
+			}
+			continue
+		}
+		psBytes, err := json.Marshal(ps)
+		if err != nil {
+			err = fmt.Errorf("putPrivateState marshal for %s in %s failed: %s", key, pc.Name, err)
+			log.Error(err)
+			return err
+		}
+		if err = stub.PutPrivateData(pc.Name, key, psBytes); err != nil {
+			err = fmt.Errorf("putPrivateState PutPrivateData for %s in %s failed: %s", key, pc.Name, err)
+			log.Error(err)
+			return err
+		}
+	}
+	return nil
+}
+
+// mergePrivateState puts the private values back into the asset's state. With a nil
+// identity all collections are merged, which is how the contract itself sees an asset;
+// otherwise only the collections whose readers policy the identity satisfies and that
+// this peer holds.
+func (a *Asset) mergePrivateState(stub shim.ChaincodeStubInterface, id *CallerIdentity) error {
+	if a.State == nil {
+		return nil
+	}
+	for _, pc := range classPrivateCollections(a.Class) {
+		if id != nil && pc.Readers.check(*id) != "" {
+			continue
+		}
+		ps, err := getPrivateState(stub, pc.Name, privateStateKey(a.AssetKey))
+		if err != nil {
+			if id != nil {
+				// a peer that is not a member of the collection cannot serve it
+				continue
+			}
+			return err
+		}
+		for qprop, v := range ps {
+			PutObject(a.State, qprop, v)
+		}
+	}
+	return nil
+}
+
+// removeCollectionState deletes the private values of an asset and their salt from one
+// collection
+func (a *Asset) removeCollectionState(stub shim.ChaincodeStubInterface, collection string) error {
+	for _, key := range []string{privateStateKey(a.AssetKey), privateSaltKey(a.AssetKey)} {
+		if err := stub.DelPrivateData(collection, key); err != nil {
+			err = fmt.Errorf("removeCollectionState DelPrivateData for %s in %s failed: %s", key, collection, err)
+			log.Error(err)
+			return err
+		}
+	}
+	return nil
+}
+
+// removePrivateState deletes the private values of an asset from all collections
+func (a *Asset) removePrivateState(stub shim.ChaincodeStubInterface) error {
+	for _, pc := range classPrivateCollections(a.Class) {
+		if err := a.removeCollectionState(stub, pc.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readPrivateAsset merges the collections that the caller may see into the stored asset
+func (c *AssetClass) readPrivateAsset(stub shim.ChaincodeStubInterface, assetBytes []byte) ([]byte, error) {
+	var a Asset
+	if err := json.Unmarshal(assetBytes, &a); err != nil {
+		err = fmt.Errorf("readPrivateAsset for class %s unmarshal failed: %s", c.Name, err)
+		log.Error(err)
+		return nil, err
+	}
+	id, err := getCallerIdentity(stub)
+	if err != nil {
+		// an unidentified caller sees the public state only
+		log.Warningf("readPrivateAsset for class %s could not identify caller: %s", c.Name, err)
+		return assetBytes, nil
+	}
+	if err = a.mergePrivateState(stub, &id); err != nil {
+		return nil, err
+	}
+	return json.Marshal(a)
+}
+
+// readCollectionsConfig returns the collections configuration to instantiate the contract with
+var readCollectionsConfig ChaincodeFunc = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	return json.MarshalIndent(CollectionsConfig(), "", "    ")
+}
+
+func init() {
+	AddRoute("readCollectionsConfig", "query", SystemClass, readCollectionsConfig)
+}
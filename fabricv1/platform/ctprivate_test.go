@@ -0,0 +1,183 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+package iotcontractplatform
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/ibm-watson-iot/blockchain-samples/fabricv1/platform/cttest"
+)
+
+var privateTestClass = AssetClass{Name: "privtest", Prefix: "PRV", AssetIDPath: "kit.id"}
+
+func init() {
+	AddPrivateCollection(privateTestClass, "prvorg1", []string{"kit.price"}, AccessPolicy{MSPIDs: []string{cttest.DefaultMSPID}})
+	AddPrivateCollection(privateTestClass, "prvorg2", []string{"kit.cost"}, AccessPolicy{MSPIDs: []string{"Org2MSP"}})
+	AddRoute("updateAssetPrivtest", "invoke", privateTestClass, func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+		return privateTestClass.UpdateAsset(stub, args, "updateAssetPrivtest", []QPropNV{})
+	})
+	AddRoute("deleteAssetPrivtest", "invoke", privateTestClass, func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+		return privateTestClass.DeleteAsset(stub, args)
+	})
+	AddRoute("readAssetPrivtest", "query", privateTestClass, func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+		return privateTestClass.ReadAsset(stub, args)
+	})
+}
+
+func readPrivateKit(t *testing.T, stub *cttest.MockStub) map[string]interface{} {
+	resp := stub.MockQuery("readAssetPrivtest", []string{`{"kit": {"id": "K1"}}`})
+	if resp.Status != shim.OK {
+		t.Fatalf("readAssetPrivtest failed: %s", resp.Message)
+	}
+	var a Asset
+	if err := json.Unmarshal(resp.Payload, &a); err != nil {
+		t.Fatal(err)
+	}
+	return *a.State
+}
+
+func publicPrivateValue(t *testing.T, stub *cttest.MockStub, qprop string) string {
+	var a Asset
+	if err := json.Unmarshal(stub.State["PRVK1"], &a); err != nil {
+		t.Fatal(err)
+	}
+	m, _ := GetObjectAsString(a.State, qprop)
+	return m
+}
+
+func TestPrivateCollections(t *testing.T) {
+	stub := cttest.NewMockStub("private", scenarioChaincode{})
+	stub.MockInit("init", []string{`{"version": "1.0"}`})
+	stub.Transient = map[string][]byte{PRIVATETRANSIENTKEY: []byte(`{"kit": {"cost": 7}}`)}
+	resp := stub.MockInvoke("updateAssetPrivtest", []string{`{"kit": {"id": "K1", "price": 1234, "temp": 5}}`})
+	stub.Transient = nil
+	if resp.Status != shim.OK {
+		t.Fatalf("updateAssetPrivtest failed: %s", resp.Message)
+	}
+
+	// the public state and event hold markers, the values are in the collections
+	public := string(stub.State["PRVK1"])
+	if strings.Contains(public, "1234") || !strings.Contains(public, PRIVATEMARKERPREFIX+"prvorg1") {
+		t.Fail()
+		fmt.Printf("*** public state holds a private value or no marker: %s\n", public)
+	}
+	if stub.Event == nil || strings.Contains(string(stub.Event.Payload), "1234") {
+		t.Fail()
+		fmt.Println("*** event holds a private value")
+	}
+	if !strings.Contains(string(stub.Private["prvorg1"][privateStateKey("PRVK1")]), "1234") ||
+		!strings.Contains(string(stub.Private["prvorg2"][privateStateKey("PRVK1")]), "7") {
+		t.Fail()
+		fmt.Printf("*** collections do not hold the private values: %v\n", stub.Private)
+	}
+	for key, value := range stub.State {
+		if strings.Contains(string(value), "1234") {
+			t.Fail()
+			fmt.Printf("*** public key %s holds a private value\n", key)
+		}
+	}
+
+	// a reader sees its own collection only
+	state := readPrivateKit(t, stub)
+	if price, _ := GetObjectAsNumber(&state, "kit.price"); price != 1234 {
+		t.Fail()
+		fmt.Printf("*** reader does not see the private price: %v\n", state)
+	}
+	if cost, _ := GetObjectAsString(&state, "kit.cost"); !strings.HasPrefix(cost, PRIVATEMARKERPREFIX+"prvorg2:") {
+		t.Fail()
+		fmt.Printf("*** reader sees another collection: %v\n", cost)
+	}
+
+	// an update that leaves the private value out keeps it and its hash
+	marker := publicPrivateValue(t, stub, "kit.price")
+	resp = stub.MockInvoke("updateAssetPrivtest", []string{`{"kit": {"id": "K1", "temp": 6}}`})
+	if resp.Status != shim.OK {
+		t.Fatalf("updateAssetPrivtest failed: %s", resp.Message)
+	}
+	state = readPrivateKit(t, stub)
+	if price, _ := GetObjectAsNumber(&state, "kit.price"); price != 1234 {
+		t.Fail()
+		fmt.Printf("*** private price lost on update: %v\n", state)
+	}
+	if m := publicPrivateValue(t, stub, "kit.price"); m != marker {
+		t.Fail()
+		fmt.Printf("*** unchanged private price changed its hash from %s to %s\n", marker, m)
+	}
+
+	// a changed private value changes its hash, and a member can check the hash
+	resp = stub.MockInvoke("updateAssetPrivtest", []string{`{"kit": {"id": "K1", "price": 1235}}`})
+	if resp.Status != shim.OK {
+		t.Fatalf("updateAssetPrivtest failed: %s", resp.Message)
+	}
+	changed := publicPrivateValue(t, stub, "kit.price")
+	if changed == marker || strings.Contains(changed, "1235") {
+		t.Fail()
+		fmt.Printf("*** changed private price has marker %s, was %s\n", changed, marker)
+	}
+	salt := string(stub.Private["prvorg1"][privateSaltKey("PRVK1")])
+	if m, _ := privateMarker("prvorg1", salt, "kit.price", 1235.0); salt == "" || m != changed {
+		t.Fail()
+		fmt.Printf("*** salted hash %s does not match the public marker %s\n", m, changed)
+	}
+
+	// another organisation sees the marker
+	cert, err := cttest.NewCertificate("other", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stub.SetCreator("Org3MSP", cert)
+	state = readPrivateKit(t, stub)
+	if price, _ := GetObject(&state, "kit.price"); price != changed {
+		t.Fail()
+		fmt.Printf("*** non reader sees the private price: %v\n", price)
+	}
+
+	resp = stub.MockInvoke("deleteAssetPrivtest", []string{`{"kit": {"id": "K1"}}`})
+	if resp.Status != shim.OK {
+		t.Fatalf("deleteAssetPrivtest failed: %s", resp.Message)
+	}
+	if len(stub.Private["prvorg1"][privateStateKey("PRVK1")]) > 0 || len(stub.Private["prvorg2"][privateStateKey("PRVK1")]) > 0 ||
+		len(stub.Private["prvorg1"][privateSaltKey("PRVK1")]) > 0 {
+		t.Fail()
+		fmt.Println("*** delete left private values behind")
+	}
+}
+
+func TestCollectionsConfig(t *testing.T) {
+	var found bool
+	for _, cc := range CollectionsConfig() {
+		if cc.Name == "prvorg2" {
+			found = cc.Policy == "OR('Org2MSP.member')"
+		}
+	}
+	if !found {
+		t.Fail()
+		fmt.Printf("*** collections config is wrong: %+v\n", CollectionsConfig())
+	}
+	if err := AddPrivateCollection(AssetClass{Name: "other", Prefix: "OTH"}, "prvorg2", []string{"x"}, AccessPolicy{MSPIDs: []string{"Org1MSP"}}); err == nil {
+		t.Fail()
+		fmt.Println("*** collection redeclared with other members")
+	}
+	if err := AddPrivateCollection(AssetClass{Name: "other", Prefix: "OTH"}, "nomembers", []string{"x"}, AccessPolicy{}); err == nil {
+		t.Fail()
+		fmt.Println("*** collection without members accepted")
+	}
+}
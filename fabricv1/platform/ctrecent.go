@@ -18,12 +18,19 @@ Kim Letkeman - Initial Contribution
 // v0.2 KL -- dramatic reduction in memory and disk space by storing only the keys
 //            and reading the states only when queried, raised limit to 100, added
 //            range to query
+// v0.3 KL -- append-only feed with one time ordered key per write, so that concurrent
+//            transactions no longer conflict on a single recent states document;
+//            retention window, class and since filters, pagination
+// v0.4 KL -- feed keyed by inverted transaction time, so that a newest first read stops
+//            after one page instead of scanning the retention window
 
 package iotcontractplatform
 
 import (
 	"encoding/json"
 	"fmt"
+	"math"
+	"time"
 
 	"github.com/hyperledger/fabric/core/chaincode/shim"
 )
@@ -34,212 +41,377 @@ import (
 //***************************************************
 //***************************************************
 
-// A state is simply a JSON encoded string, just as written to the ledger
-// This module stores in memory only, as recent states make no sense after
-// a begin.
+// Every asset write appends the written state under its own key, ordered newest first by
+// inverted transaction time. Writers never read the feed, so they cannot conflict with
+// each other. Readers ignore entries that have aged beyond the retention window, and
+// pruneRecentStates deletes them in bounded batches.
 
-// RECENTSTATESKEY is used as key for recent states bucket
-const RECENTSTATESKEY string = "IOTCP.RecentStates"
+// RECENTSTATESKEY is prepended to the transaction time and asset key of each feed entry
+const RECENTSTATESKEY string = "IOTCP.RECENT." // + inverted txnts + '.' + assetKey
 
-// MaxRecentStates is an arbitrary limit on how many asset states we track across the
-// entire contract
+// LEGACYRECENTSTATESKEY is the single document used by earlier versions, it is
+// removed by ClearRecentStates
+const LEGACYRECENTSTATESKEY string = "IOTCP.RecentStates"
+
+// RECENTSTATESRETENTIONKEY holds the retention window in seconds
+const RECENTSTATESRETENTIONKEY string = "IOTCP:RecentStatesRetention"
+
+// MaxRecentStates is the number of states returned when the query is not paginated
 const MaxRecentStates int = 40
 
-// RecentStates in world state
-type RecentStates struct {
-	States []string `json:"recentstates"`
-}
+// DefaultRecentStatesRetention applies until setRecentStatesRetention is called
+const DefaultRecentStatesRetention = 24 * time.Hour
+
+// DefaultPruneLimit bounds the number of entries one pruneRecentStates deletes
+const DefaultPruneLimit int = 1000
+
+// recentTimeWidth is the number of digits of an inverted time, fixed so that keys sort in
+// reverse time order
+const recentTimeWidth int = 19
 
 // RecentStatesOut is query output format
 type RecentStatesOut AssetArray
 
-// GETRecentStatesFromLedger returns the unmarshaled recent states
-func GETRecentStatesFromLedger(stub shim.ChaincodeStubInterface) (RecentStates, error) {
-	var rstates = RecentStates{make([]string, 0, MaxRecentStates+1)}
-	var err error
-	recentStatesBytes, err := stub.GetState(RECENTSTATESKEY)
+// RecentStatesArg holds the optional arguments of readRecentStates. begin and end are
+// zero based positions in the newest first feed and apply only without pagination.
+type RecentStatesArg struct {
+	ClassName string `json:"classname"`
+	Since     string `json:"since"`
+	Begin     *int   `json:"begin"`
+	End       *int   `json:"end"`
+}
+
+// recentTime inverts the time, later times give smaller keys
+func recentTime(t time.Time) string {
+	return fmt.Sprintf("%0*d", recentTimeWidth, math.MaxInt64-t.UnixNano())
+}
+
+// recentTimeBound is the exclusive end of a range that holds the entries written at or
+// after the time
+func recentTimeBound(t time.Time) string {
+	return RECENTSTATESKEY + recentTime(t.Add(-time.Nanosecond))
+}
+
+func recentStateKey(a *Asset) string {
+	return RECENTSTATESKEY + recentTime(*a.TXNTS) + "." + a.AssetKey
+}
+
+// getTxTime returns the transaction time, which is the same on every endorser
+func getTxTime(stub shim.ChaincodeStubInterface) (time.Time, error) {
+	ts, err := stub.GetTxTimestamp()
 	if err != nil {
-		err = fmt.Errorf("Failed to get recent states from world state: %s", err)
-		log.Errorf(err.Error())
-		return rstates, err
+		return time.Time{}, err
 	}
-	// this MUST be here
-	if recentStatesBytes == nil || len(recentStatesBytes) == 0 {
-		log.Debugf("GETRecentStatesFromLedger: returning empty recent states")
-		return rstates, nil
-	}
-	err = json.Unmarshal(recentStatesBytes, &rstates)
+	return time.Unix(ts.Seconds, int64(ts.Nanos)), nil
+}
+
+// getRecentStatesRetention returns the configured retention window
+func getRecentStatesRetention(stub shim.ChaincodeStubInterface) (time.Duration, error) {
+	rBytes, err := stub.GetState(RECENTSTATESRETENTIONKEY)
 	if err != nil {
-		err = PUTRecentStatesToLedger(stub, rstates)
-		if err != nil {
-			err = fmt.Errorf("Failed to store empty recent states: %s", err)
-			log.Errorf(err.Error())
-			return rstates, err
-		}
+		err = fmt.Errorf("getRecentStatesRetention GetState failed: %s", err)
+		log.Error(err)
+		return 0, err
+	}
+	if len(rBytes) == 0 {
+		return DefaultRecentStatesRetention, nil
+	}
+	var seconds int64
+	if err = json.Unmarshal(rBytes, &seconds); err != nil {
+		err = fmt.Errorf("getRecentStatesRetention unmarshal failed: %s", err)
+		log.Error(err)
+		return 0, err
 	}
-	return rstates, nil
+	return time.Duration(seconds) * time.Second, nil
 }
 
-// PUTRecentStatesToLedger marshals and writes the recent states
-func PUTRecentStatesToLedger(stub shim.ChaincodeStubInterface, rstates RecentStates) error {
-	var recentStatesJSON []byte
-	var err error
-	recentStatesJSON, err = json.Marshal(rstates)
+// recentStatesCutoff returns the oldest time that is still within the retention window
+func recentStatesCutoff(stub shim.ChaincodeStubInterface) (time.Time, error) {
+	retention, err := getRecentStatesRetention(stub)
 	if err != nil {
-		log.Criticalf("Failed to marshal recent states: %s", err)
-		return err
+		return time.Time{}, err
 	}
-	err = stub.PutState(RECENTSTATESKEY, recentStatesJSON)
+	now, err := getTxTime(stub)
 	if err != nil {
-		log.Criticalf("Failed to PUTSTATE recent states: %s", err)
-		return err
+		err = fmt.Errorf("recentStatesCutoff failed to get the transaction time: %s", err)
+		log.Error(err)
+		return time.Time{}, err
 	}
-	return nil
+	return now.Add(-retention), nil
 }
 
-// ClearRecentStates resets recent states to an empty array
+// ClearRecentStates deletes the whole feed, including the document used by earlier versions
 func ClearRecentStates(stub shim.ChaincodeStubInterface) error {
-	var rstates RecentStates
-	rstates = RecentStates{make([]string, 0, MaxRecentStates)}
-	return PUTRecentStatesToLedger(stub, rstates)
+	iter, err := stub.GetStateByRange(RECENTSTATESKEY, RECENTSTATESKEY+"}")
+	if err != nil {
+		err = fmt.Errorf("ClearRecentStates failed to get a range query iterator: %s", err)
+		log.Error(err)
+		return err
+	}
+	defer iter.Close()
+	for iter.HasNext() {
+		key, _, err := iter.Next()
+		if err != nil {
+			err = fmt.Errorf("ClearRecentStates iter.Next() failed: %s", err)
+			log.Error(err)
+			return err
+		}
+		if err = stub.DelState(key); err != nil {
+			err = fmt.Errorf("ClearRecentStates DelState for %s failed: %s", key, err)
+			log.Error(err)
+			return err
+		}
+	}
+	return stub.DelState(LEGACYRECENTSTATESKEY)
 }
 
-// PushRecentState pushes the state to the first entry, or moves it to
-// the first entry if this asset already shows up
+// PushRecentState appends the state to the feed, it never reads the feed
 func (a *Asset) PushRecentState(stub shim.ChaincodeStubInterface) error {
-	var err error
-
-	rstates, err := GETRecentStatesFromLedger(stub)
+	if a.TXNTS == nil {
+		err := fmt.Errorf("PushRecentState: asset %s has no transaction timestamp", a.AssetKey)
+		log.Error(err)
+		return err
+	}
+	assetBytes, err := json.Marshal(a)
 	if err != nil {
+		err = fmt.Errorf("PushRecentState: asset %s marshal failed: %s", a.AssetKey, err)
+		log.Error(err)
 		return err
 	}
-
-	// shift slice to the right
-	assetPosn := findAssetInRecent(a.AssetKey, rstates)
-	if assetPosn == -1 {
-		// shift right
-		rstates.States = append(rstates.States, a.AssetKey)
-		copy(rstates.States[1:], rstates.States[0:])
-	} else {
-		// shift right to close the gap
-		copy(rstates.States[1:], rstates.States[0:assetPosn])
+	if err = stub.PutState(recentStateKey(a), assetBytes); err != nil {
+		err = fmt.Errorf("PushRecentState: asset %s PUTSTATE failed: %s", a.AssetKey, err)
+		log.Error(err)
+		return err
 	}
-	// insert at the front
-	rstates.States[0] = a.AssetKey
 	log.Debugf("pushRecentStates succeeded for asset %s", a.AssetKey)
-	return PUTRecentStatesToLedger(stub, rstates)
+	return nil
 }
 
-// RemoveAssetFromRecentStates is called when an asset is deleted
-func (a *Asset) RemoveAssetFromRecentStates(stub shim.ChaincodeStubInterface) error {
-	var rstates RecentStates
-	var err error
+// readRecentStates returns recently written asset states, newest first. Entries for
+// assets that have since been deleted are skipped.
+var readRecentStates = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	var arg RecentStatesArg
+	var assets = make(RecentStatesOut, 0)
+	var lastKey string
+	var more bool
 
-	rstates, err = GETRecentStatesFromLedger(stub)
+	filter, err := getUnmarshalledStateFilter(args)
 	if err != nil {
-		return err
+		err = fmt.Errorf("readRecentStates: failed to get a filter: %s", err)
+		log.Error(err)
+		return nil, err
 	}
-	posn := findAssetInRecent(a.AssetKey, rstates)
-	if posn >= 0 {
-		rstates.States = append(rstates.States[:posn], rstates.States[posn+1:]...)
+	page, err := getUnmarshalledPageRequest(args)
+	if err != nil {
+		err = fmt.Errorf("readRecentStates: failed to get a page request: %s", err)
+		log.Error(err)
+		return nil, err
 	}
-	return PUTRecentStatesToLedger(stub, rstates)
-}
-
-func findAssetInRecent(assetID string, rstates RecentStates) int {
-	// returns -1 to signify not found (or error)
-	for i := 0; i < len(rstates.States); i++ {
-		if rstates.States[i] == assetID {
-			log.Debugf("findAssetInRecent found assetID %s at position %d in recent states", assetID, i)
-			return i
+	if len(args) > 0 {
+		if err = json.Unmarshal([]byte(args[0]), &arg); err != nil {
+			err = fmt.Errorf("readRecentStates: failed to unmarshal args[0] ' %s': %s", args[0], err)
+			log.Error(err)
+			return nil, err
 		}
 	}
-	// not found
-	log.Debugf("findAssetInRecent Did not find assetID %s in recent states", assetID)
-	return -1
-}
 
-// readRecentStates returns the marshaled recent states from the ledger
-var readRecentStates = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
-	var begin, end, count int
-	var found bool
-
-	var rstatesout = make(RecentStatesOut, 0, count)
-
-	r, err := GETRecentStatesFromLedger(stub)
-	if err != nil {
-		err = fmt.Errorf("readRecentStates: failed to get recent states from ledger: %s", err)
+	// without pagination, keep the newest end+1 states and return from begin
+	begin, end := 0, MaxRecentStates-1
+	if arg.Begin != nil {
+		begin = *arg.Begin
+	}
+	if arg.End != nil {
+		end = *arg.End
+	}
+	if begin < 0 || end < begin {
+		err = fmt.Errorf("readRecentStates: invalid range begin %d end %d", begin, end)
 		log.Error(err)
 		return nil, err
 	}
-
-	if len(r.States) == 0 {
-		return []byte("[]"), nil
+	limit := page
+	if !page.isPaged() {
+		limit.PageSize = end + 1
 	}
 
-	if len(args) > 0 {
-		var arg map[string]interface{}
-		eventBytes := []byte(args[0])
-		err := json.Unmarshal(eventBytes, &arg)
+	oldest, err := recentStatesCutoff(stub)
+	if err != nil {
+		return nil, err
+	}
+	if arg.Since != "" {
+		since, err := time.Parse(time.RFC3339Nano, arg.Since)
 		if err != nil {
-			err = fmt.Errorf("readRecentStates: failed to unmarshal args[0] ' %s': %s", args[0], err)
+			err = fmt.Errorf("readRecentStates: since %s is not an RFC3339 timestamp: %s", arg.Since, err)
 			log.Error(err)
 			return nil, err
 		}
-		begin, found = GetObjectAsInteger(&arg, "begin")
-		if !found {
-			begin = 0
-		} else {
-			if begin < 0 || begin > (MaxRecentStates-1) {
-				err := fmt.Errorf("readRecentStates: invalid begin argument %d, should be between 0 and %d inclusive", begin, MaxRecentStates-1)
-				log.Error(err)
-				return nil, err
-			}
-		}
-		end, found = GetObjectAsInteger(&arg, "end")
-		if !found {
-			end = MaxRecentStates - begin - 1
-		} else {
-			if end < begin {
-				err := fmt.Errorf("readRecentStates: invalid end argument %d, should be > begin arg (%d)", end, begin)
-				log.Error(err)
-				return nil, err
-			}
-		}
-	} else {
-		begin = 0
-		end = MaxRecentStates - 1
-	}
-
-	if begin >= len(r.States) {
-		err := fmt.Errorf("readRecentStates: begin position %d beyond end of recent states, last state is position %d", begin, len(r.States)-1)
+		if since.After(oldest) {
+			oldest = since
+		}
+	}
+	// the feed is newest first, so pages read forward from the bookmark and the scan
+	// ends at the first entry older than the oldest time wanted
+	start := RECENTSTATESKEY
+	bookmark, err := decodeBookmark(page.Bookmark, RECENTSTATESKEY)
+	if err != nil {
+		return nil, err
+	}
+	if bookmark != "" {
+		start = bookmark + "\x00"
+	}
+
+	iter, err := stub.GetStateByRange(start, recentTimeBound(oldest))
+	if err != nil {
+		err = fmt.Errorf("readRecentStates failed to get a range query iterator: %s", err)
 		log.Error(err)
 		return nil, err
 	}
+	defer iter.Close()
+	for iter.HasNext() {
+		key, assetBytes, err := iter.Next()
+		if err != nil {
+			err = fmt.Errorf("readRecentStates iter.Next() failed: %s", err)
+			log.Error(err)
+			return nil, err
+		}
+		var a Asset
+		if err = json.Unmarshal(assetBytes, &a); err != nil {
+			err = fmt.Errorf("readRecentStates unmarshal %s failed: %s", key, err)
+			log.Error(err)
+			return nil, err
+		}
+		if arg.ClassName != "" && a.Class.Name != arg.ClassName {
+			continue
+		}
+		if !a.Filter(filter) {
+			continue
+		}
+		if limit.full(len(assets)) {
+			// older states remain for the next page
+			more = true
+			break
+		}
+		assets = append(assets, a)
+		lastKey = key
+	}
 
-	if end >= len(r.States) {
-		end = len(r.States) - 1
+	var out = make(AssetArray, 0, len(assets))
+	for _, a := range assets {
+		exists, err := assetStillExists(stub, a.AssetKey)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			out = append(out, a)
+		}
 	}
+	if !more {
+		lastKey = ""
+	}
+	if !page.isPaged() {
+		if begin >= len(out) {
+			return []byte("[]"), nil
+		}
+		return json.Marshal(out[begin:])
+	}
+	return marshalPage(page, out, lastKey)
+}
 
-	count = end - begin + 1
+func assetStillExists(stub shim.ChaincodeStubInterface, assetKey string) (bool, error) {
+	assetBytes, err := stub.GetState(assetKey)
+	if err != nil {
+		err = fmt.Errorf("readRecentStates: failed to get asset %s from world state: %s", assetKey, err)
+		log.Error(err)
+		return false, err
+	}
+	return len(assetBytes) > 0, nil
+}
 
-	for i := begin; i <= end; i++ {
-		a, exists, err := GetAssetFromLedger(stub, r.States[i])
+// setRecentStatesRetention sets the retention window, {"seconds": 86400}
+var setRecentStatesRetention ChaincodeFunc = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	type RetentionArg struct {
+		Seconds int64 `json:"seconds"`
+	}
+	var arg RetentionArg
+	if len(args) != 1 {
+		err := fmt.Errorf("setRecentStatesRetention expects one argument, got %d", len(args))
+		log.Error(err)
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(args[0]), &arg); err != nil {
+		err = fmt.Errorf("setRecentStatesRetention failed to unmarshal arg: %s", err)
+		log.Error(err)
+		return nil, err
+	}
+	if arg.Seconds <= 0 {
+		err := fmt.Errorf("setRecentStatesRetention: seconds must be positive, got %d", arg.Seconds)
+		log.Error(err)
+		return nil, err
+	}
+	rBytes, _ := json.Marshal(arg.Seconds)
+	if err := stub.PutState(RECENTSTATESRETENTIONKEY, rBytes); err != nil {
+		err = fmt.Errorf("setRecentStatesRetention PUTSTATE failed: %s", err)
+		log.Error(err)
+		return nil, err
+	}
+	return nil, nil
+}
+
+// pruneRecentStates deletes up to limit entries that have aged beyond the retention
+// window, {"limit": 1000}, and returns {"pruned": n, "more": bool}
+var pruneRecentStates ChaincodeFunc = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	type PruneArg struct {
+		Limit int `json:"limit"`
+	}
+	type PruneResult struct {
+		Pruned int  `json:"pruned"`
+		More   bool `json:"more"`
+	}
+	var arg PruneArg
+	var result PruneResult
+	if len(args) > 0 {
+		if err := json.Unmarshal([]byte(args[0]), &arg); err != nil {
+			err = fmt.Errorf("pruneRecentStates failed to unmarshal arg: %s", err)
+			log.Error(err)
+			return nil, err
+		}
+	}
+	if arg.Limit <= 0 {
+		arg.Limit = DefaultPruneLimit
+	}
+	cutoff, err := recentStatesCutoff(stub)
+	if err != nil {
+		return nil, err
+	}
+	iter, err := stub.GetStateByRange(recentTimeBound(cutoff), RECENTSTATESKEY+"}")
+	if err != nil {
+		err = fmt.Errorf("pruneRecentStates failed to get a range query iterator: %s", err)
+		log.Error(err)
+		return nil, err
+	}
+	defer iter.Close()
+	for iter.HasNext() {
+		if result.Pruned >= arg.Limit {
+			result.More = true
+			break
+		}
+		key, _, err := iter.Next()
 		if err != nil {
-			err = fmt.Errorf("readRecentStates: failed to get asset from ledger: %s", err)
-			log.Errorf(err.Error())
+			err = fmt.Errorf("pruneRecentStates iter.Next() failed: %s", err)
+			log.Error(err)
 			return nil, err
 		}
-		if !exists {
-			err = fmt.Errorf("readRecentStates: recent asset state does not exist: %s", err)
-			log.Errorf(err.Error())
+		if err = stub.DelState(key); err != nil {
+			err = fmt.Errorf("pruneRecentStates DelState for %s failed: %s", key, err)
+			log.Error(err)
 			return nil, err
 		}
-		rstatesout = append(rstatesout, a)
+		result.Pruned++
 	}
-	return json.Marshal(rstatesout)
+	return json.Marshal(result)
 }
 
 func init() {
 	AddRoute("readRecentStates", "query", SystemClass, readRecentStates)
+	AddRoute("setRecentStatesRetention", "invoke", SystemClass, setRecentStatesRetention)
+	AddRoute("pruneRecentStates", "invoke", SystemClass, pruneRecentStates)
 }
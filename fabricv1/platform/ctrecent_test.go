@@ -0,0 +1,115 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+package iotcontractplatform
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/ibm-watson-iot/blockchain-samples/fabricv1/platform/cttest"
+)
+
+// countingStub counts the entries that range queries return
+type countingStub struct {
+	*cttest.MockStub
+	read int
+}
+
+type countingIterator struct {
+	shim.StateQueryIteratorInterface
+	stub *countingStub
+}
+
+func (s *countingStub) GetStateByRange(startKey string, endKey string) (shim.StateQueryIteratorInterface, error) {
+	iter, err := s.MockStub.GetStateByRange(startKey, endKey)
+	return &countingIterator{iter, s}, err
+}
+
+func (i *countingIterator) Next() (string, []byte, error) {
+	i.stub.read++
+	return i.StateQueryIteratorInterface.Next()
+}
+
+func TestRecentStateKeyOrder(t *testing.T) {
+	base := time.Date(2017, 3, 1, 10, 0, 0, 0, time.UTC)
+	times := []time.Time{
+		base,
+		base.Add(time.Nanosecond),
+		base.Add(100 * time.Millisecond),
+		base.Add(time.Second),
+		base.Add(time.Hour).In(time.FixedZone("EST", -5*3600)),
+	}
+	var prior = RECENTSTATESKEY + "}"
+	for _, ts := range times {
+		a := Asset{AssetKey: "TST1", TXNTS: &ts}
+		key := recentStateKey(&a)
+		if len(key) != len(RECENTSTATESKEY)+recentTimeWidth+len(".TST1") {
+			t.Fail()
+			fmt.Printf("*** recent state key %s is not fixed width\n", key)
+		}
+		if key >= prior {
+			t.Fail()
+			fmt.Printf("*** recent state key %s does not sort before %s\n", key, prior)
+		}
+		prior = key
+	}
+}
+
+func TestRecentStatesPages(t *testing.T) {
+	stub := cttest.NewMockStub("recent", scenarioChaincode{})
+	stub.MockInit("init", []string{`{"version": "1.0"}`})
+	for i := 1; i <= 10; i++ {
+		resp := stub.MockInvoke("updateAssetPagetest", []string{fmt.Sprintf(`{"kit": {"id": "R%d", "count": %d}}`, i%3, i)})
+		if resp.Status != shim.OK {
+			t.Fatalf("updateAssetPagetest failed: %s", resp.Message)
+		}
+	}
+
+	// a page reads one entry past its end and no further
+	counter := &countingStub{MockStub: stub}
+	var counts []float64
+	var bookmark string
+	for pages := 0; pages < 10; pages++ {
+		counter.read = 0
+		b, err := readRecentStates(counter, []string{fmt.Sprintf(`{"pagesize": 3, "bookmark": "%s"}`, bookmark)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if counter.read > 4 {
+			t.Fail()
+			fmt.Printf("*** a page of 3 read %d feed entries\n", counter.read)
+		}
+		var paged PagedResults
+		if err := json.Unmarshal(b, &paged); err != nil {
+			t.Fatal(err)
+		}
+		for _, a := range paged.Results {
+			n, _ := GetObjectAsNumber(a.State, "kit.count")
+			counts = append(counts, n)
+		}
+		if bookmark = paged.Bookmark; bookmark == "" {
+			break
+		}
+	}
+	if fmt.Sprint(counts) != "[10 9 8 7 6 5 4 3 2 1]" {
+		t.Fail()
+		fmt.Printf("*** paged recent states returned counts %v\n", counts)
+	}
+}
@@ -0,0 +1,561 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+// v0.1 KL -- parent / child relationships between assets, stored as composite keys
+// v0.2 KL -- links that would make an asset its own ancestor are refused, and a cascade
+//            removes each asset once
+
+package iotcontractplatform
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// RELCHILDOBJECTTYPE composite keys list a parent's children, [relationship, parentKey, childKey]
+const RELCHILDOBJECTTYPE string = "IOTCP.REL.C"
+
+// RELPARENTOBJECTTYPE composite keys hold a child's parent key, [relationship, childKey]
+const RELPARENTOBJECTTYPE string = "IOTCP.REL.P"
+
+// Cardinality is the number of children that a parent may hold in one relationship, a
+// child always has at most one parent
+const (
+	CardinalityOne  = "one"
+	CardinalityMany = "many"
+)
+
+// Delete policies apply when a parent is deleted, unlink is the default
+const (
+	OnDeleteUnlink   = "unlink"
+	OnDeleteCascade  = "cascade"
+	OnDeleteRestrict = "restrict"
+)
+
+// MaxRelationDepth bounds readAssetWithRelations
+const MaxRelationDepth int = 5
+
+// Relationship declares how assets of the parent class hold assets of the child class,
+// e.g. "installedOn" from assembly to aircraft
+type Relationship struct {
+	Name        string     `json:"name"`
+	Parent      AssetClass `json:"parent"`
+	Child       AssetClass `json:"child"`
+	Cardinality string     `json:"cardinality"`
+	OnDelete    string     `json:"ondelete"`
+}
+
+var relationrouter = make(map[string]Relationship, 0)
+
+// AddRelationship allows a contract to declare a relationship between two classes
+func AddRelationship(r Relationship) error {
+	if _, found := relationrouter[r.Name]; found || r.Name == "" {
+		err := fmt.Errorf("AddRelationship: relationship name '%s' is empty or already registered", r.Name)
+		log.Error(err)
+		return err
+	}
+	if r.Cardinality == "" {
+		r.Cardinality = CardinalityMany
+	}
+	if r.OnDelete == "" {
+		r.OnDelete = OnDeleteUnlink
+	}
+	if r.Cardinality != CardinalityOne && r.Cardinality != CardinalityMany {
+		err := fmt.Errorf("AddRelationship: relationship %s has unknown cardinality %s", r.Name, r.Cardinality)
+		log.Error(err)
+		return err
+	}
+	if r.OnDelete != OnDeleteUnlink && r.OnDelete != OnDeleteCascade && r.OnDelete != OnDeleteRestrict {
+		err := fmt.Errorf("AddRelationship: relationship %s has unknown delete policy %s", r.Name, r.OnDelete)
+		log.Error(err)
+		return err
+	}
+	relationrouter[r.Name] = r
+	log.Debugf("Relationship %s added from class %s to class %s", r.Name, r.Parent.Name, r.Child.Name)
+	return nil
+}
+
+func getRelationship(name string) (Relationship, error) {
+	r, found := relationrouter[name]
+	if !found {
+		err := fmt.Errorf("relationship %s is not registered", name)
+		log.Error(err)
+		return r, err
+	}
+	return r, nil
+}
+
+// sortedRelationships returns the relationships in name order so that output is stable
+func sortedRelationships() []Relationship {
+	var names = make([]string, 0, len(relationrouter))
+	for n := range relationrouter {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	var rels = make([]Relationship, 0, len(names))
+	for _, n := range names {
+		rels = append(rels, relationrouter[n])
+	}
+	return rels
+}
+
+// getChildKeys returns the keys of a parent's children in one relationship
+func getChildKeys(stub shim.ChaincodeStubInterface, rel string, parentKey string) ([]string, error) {
+	var keys = make([]string, 0)
+	iter, err := stub.GetStateByPartialCompositeKey(RELCHILDOBJECTTYPE, []string{rel, parentKey})
+	if err != nil {
+		err = fmt.Errorf("getChildKeys failed to get a partial composite key iterator for %s: %s", rel, err)
+		log.Error(err)
+		return nil, err
+	}
+	defer iter.Close()
+	for iter.HasNext() {
+		key, _, err := iter.Next()
+		if err != nil {
+			err = fmt.Errorf("getChildKeys iter.Next() failed: %s", err)
+			log.Error(err)
+			return nil, err
+		}
+		_, attrs, err := stub.SplitCompositeKey(key)
+		if err != nil || len(attrs) != 3 {
+			err = fmt.Errorf("getChildKeys relationship key %s is malformed: %v", key, err)
+			log.Error(err)
+			return nil, err
+		}
+		keys = append(keys, attrs[2])
+	}
+	return keys, nil
+}
+
+// getParentKey returns the key of a child's parent in one relationship, or an empty
+// string when the child is not linked
+func getParentKey(stub shim.ChaincodeStubInterface, rel string, childKey string) (string, error) {
+	pk, err := stub.CreateCompositeKey(RELPARENTOBJECTTYPE, []string{rel, childKey})
+	if err != nil {
+		return "", err
+	}
+	parentBytes, err := stub.GetState(pk)
+	if err != nil {
+		err = fmt.Errorf("getParentKey GetState for %s failed: %s", childKey, err)
+		log.Error(err)
+		return "", err
+	}
+	return string(parentBytes), nil
+}
+
+func linkKeys(stub shim.ChaincodeStubInterface, rel string, parentKey string, childKey string) (string, string, error) {
+	ck, err := stub.CreateCompositeKey(RELCHILDOBJECTTYPE, []string{rel, parentKey, childKey})
+	if err != nil {
+		return "", "", err
+	}
+	pk, err := stub.CreateCompositeKey(RELPARENTOBJECTTYPE, []string{rel, childKey})
+	if err != nil {
+		return "", "", err
+	}
+	return ck, pk, nil
+}
+
+func assetExists(stub shim.ChaincodeStubInterface, c AssetClass, assetKey string) error {
+	_, exists, err := c.getAssetFromWorldState(stub, assetKey)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("class %s asset %s does not exist", c.Name, assetKey)
+	}
+	return nil
+}
+
+// linkAsset makes the child a child of the parent, the child must not have a parent
+func linkAsset(stub shim.ChaincodeStubInterface, r Relationship, parentKey string, childKey string) error {
+	current, err := getParentKey(stub, r.Name, childKey)
+	if err != nil {
+		return err
+	}
+	if current != "" {
+		return fmt.Errorf("asset %s already has parent %s in relationship %s", childKey, current, r.Name)
+	}
+	return putLink(stub, r, parentKey, childKey)
+}
+
+// putLink checks the assets, the parent's ancestors and the parent's cardinality and
+// writes the link keys. The child's parent key is overwritten, the caller decides whether
+// it may already have one, as the peer does not return a transaction's own writes.
+func putLink(stub shim.ChaincodeStubInterface, r Relationship, parentKey string, childKey string) error {
+	if err := assetExists(stub, r.Parent, parentKey); err != nil {
+		return err
+	}
+	// a relationship within one class must not make the child an ancestor of itself
+	var seen = make(map[string]bool)
+	for ancestor := parentKey; ancestor != "" && !seen[ancestor]; {
+		if ancestor == childKey {
+			return fmt.Errorf("asset %s cannot be linked to parent %s in relationship %s, as it would become its own ancestor", childKey, parentKey, r.Name)
+		}
+		seen[ancestor] = true
+		next, err := getParentKey(stub, r.Name, ancestor)
+		if err != nil {
+			return err
+		}
+		ancestor = next
+	}
+	if err := assetExists(stub, r.Child, childKey); err != nil {
+		return err
+	}
+	if r.Cardinality == CardinalityOne {
+		children, err := getChildKeys(stub, r.Name, parentKey)
+		if err != nil {
+			return err
+		}
+		if len(children) > 0 {
+			return fmt.Errorf("asset %s already has child %s in relationship %s", parentKey, children[0], r.Name)
+		}
+	}
+	ck, pk, err := linkKeys(stub, r.Name, parentKey, childKey)
+	if err != nil {
+		return err
+	}
+	if err = stub.PutState(ck, indexValueMarker); err != nil {
+		return err
+	}
+	return stub.PutState(pk, []byte(parentKey))
+}
+
+// moveLink moves the child from its current parent to the new parent, replacing the
+// parent key rather than deleting it and reading it back
+func moveLink(stub shim.ChaincodeStubInterface, r Relationship, newParentKey string, childKey string) error {
+	current, err := getParentKey(stub, r.Name, childKey)
+	if err != nil {
+		return err
+	}
+	if current == "" {
+		return fmt.Errorf("asset %s has no parent in relationship %s", childKey, r.Name)
+	}
+	if current == newParentKey {
+		return fmt.Errorf("asset %s already has parent %s in relationship %s", childKey, current, r.Name)
+	}
+	ck, _, err := linkKeys(stub, r.Name, current, childKey)
+	if err != nil {
+		return err
+	}
+	if err = stub.DelState(ck); err != nil {
+		return err
+	}
+	return putLink(stub, r, newParentKey, childKey)
+}
+
+// unlinkAsset removes the child from its parent
+func unlinkAsset(stub shim.ChaincodeStubInterface, r Relationship, childKey string) (string, error) {
+	parentKey, err := getParentKey(stub, r.Name, childKey)
+	if err != nil {
+		return "", err
+	}
+	if parentKey == "" {
+		return "", fmt.Errorf("asset %s has no parent in relationship %s", childKey, r.Name)
+	}
+	ck, pk, err := linkKeys(stub, r.Name, parentKey, childKey)
+	if err != nil {
+		return "", err
+	}
+	if err = stub.DelState(ck); err != nil {
+		return "", err
+	}
+	return parentKey, stub.DelState(pk)
+}
+
+// removeRelations applies the delete policies of the relationships in which the asset
+// is a parent, and unlinks the asset from its own parents. Deletes are not visible in
+// the same transaction, so a cascade skips the assets that it has already removed.
+func (a *Asset) removeRelations(stub shim.ChaincodeStubInterface, removed map[string]bool) error {
+	removed[a.AssetKey] = true
+	for _, r := range sortedRelationships() {
+		if r.Parent == a.Class {
+			children, err := getChildKeys(stub, r.Name, a.AssetKey)
+			if err != nil {
+				return err
+			}
+			if len(children) > 0 && r.OnDelete == OnDeleteRestrict {
+				return fmt.Errorf("asset %s has %d children in relationship %s, which restricts deletion", a.AssetKey, len(children), r.Name)
+			}
+			for _, ck := range children {
+				if _, err = unlinkAsset(stub, r, ck); err != nil {
+					return err
+				}
+				if r.OnDelete != OnDeleteCascade || removed[ck] {
+					continue
+				}
+				child, exists, err := GetAssetFromLedger(stub, ck)
+				if err != nil {
+					return err
+				}
+				if exists {
+					if err = child.removeAssetFromWorldState(stub, removed); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		if r.Child == a.Class {
+			parentKey, err := getParentKey(stub, r.Name, a.AssetKey)
+			if err != nil {
+				return err
+			}
+			if parentKey != "" {
+				if _, err = unlinkAsset(stub, r, a.AssetKey); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// AssetWithRelations is an asset with its related assets embedded, children are found
+// by following children and parents by following parents, so there are no cycles
+type AssetWithRelations struct {
+	Asset    Asset                           `json:"asset"`
+	Parents  map[string]*AssetWithRelations  `json:"parents,omitempty"`
+	Children map[string][]AssetWithRelations `json:"children,omitempty"`
+}
+
+func readRelatedAsset(stub shim.ChaincodeStubInterface, assetKey string, depth int, up bool, down bool) (*AssetWithRelations, error) {
+	a, exists, err := GetAssetFromLedger(stub, assetKey)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("related asset %s does not exist", assetKey)
+	}
+	var out = &AssetWithRelations{Asset: a}
+	if depth <= 0 {
+		return out, nil
+	}
+	for _, r := range sortedRelationships() {
+		if down && r.Parent == a.Class {
+			children, err := getChildKeys(stub, r.Name, assetKey)
+			if err != nil {
+				return nil, err
+			}
+			for _, ck := range children {
+				child, err := readRelatedAsset(stub, ck, depth-1, false, true)
+				if err != nil {
+					return nil, err
+				}
+				if out.Children == nil {
+					out.Children = make(map[string][]AssetWithRelations)
+				}
+				out.Children[r.Name] = append(out.Children[r.Name], *child)
+			}
+		}
+		if up && r.Child == a.Class {
+			parentKey, err := getParentKey(stub, r.Name, assetKey)
+			if err != nil {
+				return nil, err
+			}
+			if parentKey == "" {
+				continue
+			}
+			parent, err := readRelatedAsset(stub, parentKey, depth-1, true, false)
+			if err != nil {
+				return nil, err
+			}
+			if out.Parents == nil {
+				out.Parents = make(map[string]*AssetWithRelations)
+			}
+			out.Parents[r.Name] = parent
+		}
+	}
+	return out, nil
+}
+
+// RelationArg identifies the assets of a relationship request by asset ID
+type RelationArg struct {
+	Relationship string `json:"relationship"`
+	ParentID     string `json:"parentid"`
+	ChildID      string `json:"childid"`
+	NewParentID  string `json:"newparentid"`
+}
+
+func getUnmarshalledRelationArg(caller string, args []string) (RelationArg, Relationship, error) {
+	var arg RelationArg
+	if len(args) != 1 {
+		err := fmt.Errorf("%s expects a JSON object with relationship and asset IDs", caller)
+		log.Error(err)
+		return arg, Relationship{}, err
+	}
+	if err := json.Unmarshal([]byte(args[0]), &arg); err != nil {
+		err = fmt.Errorf("%s failed to unmarshal arg: %s", caller, err)
+		log.Error(err)
+		return arg, Relationship{}, err
+	}
+	r, err := getRelationship(arg.Relationship)
+	if err != nil {
+		err = fmt.Errorf("%s: %s", caller, err)
+		return arg, r, err
+	}
+	return arg, r, nil
+}
+
+var linkAssets ChaincodeFunc = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	arg, r, err := getUnmarshalledRelationArg("linkAssets", args)
+	if err != nil {
+		return nil, err
+	}
+	if err = linkAsset(stub, r, r.Parent.Prefix+arg.ParentID, r.Child.Prefix+arg.ChildID); err != nil {
+		err = fmt.Errorf("linkAssets failed: %s", err)
+		log.Error(err)
+		return nil, err
+	}
+	return nil, nil
+}
+
+var unlinkAssets ChaincodeFunc = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	arg, r, err := getUnmarshalledRelationArg("unlinkAssets", args)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = unlinkAsset(stub, r, r.Child.Prefix+arg.ChildID); err != nil {
+		err = fmt.Errorf("unlinkAssets failed: %s", err)
+		log.Error(err)
+		return nil, err
+	}
+	return nil, nil
+}
+
+// moveAsset moves a child from its current parent to the new parent in one transaction
+var moveAsset ChaincodeFunc = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	arg, r, err := getUnmarshalledRelationArg("moveAsset", args)
+	if err != nil {
+		return nil, err
+	}
+	if err = moveLink(stub, r, r.Parent.Prefix+arg.NewParentID, r.Child.Prefix+arg.ChildID); err != nil {
+		err = fmt.Errorf("moveAsset failed: %s", err)
+		log.Error(err)
+		return nil, err
+	}
+	return nil, nil
+}
+
+var readChildren ChaincodeFunc = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	arg, r, err := getUnmarshalledRelationArg("readChildren", args)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := getChildKeys(stub, r.Name, r.Parent.Prefix+arg.ParentID)
+	if err != nil {
+		return nil, err
+	}
+	var children = make(AssetArray, 0, len(keys))
+	for _, k := range keys {
+		child, exists, err := GetAssetFromLedger(stub, k)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			children = append(children, child)
+		}
+	}
+	return json.Marshal(children)
+}
+
+var readParent ChaincodeFunc = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	arg, r, err := getUnmarshalledRelationArg("readParent", args)
+	if err != nil {
+		return nil, err
+	}
+	parentKey, err := getParentKey(stub, r.Name, r.Child.Prefix+arg.ChildID)
+	if err != nil {
+		return nil, err
+	}
+	if parentKey == "" {
+		err = fmt.Errorf("readParent: asset %s has no parent in relationship %s", arg.ChildID, r.Name)
+		log.Error(err)
+		return nil, err
+	}
+	parent, exists, err := GetAssetFromLedger(stub, parentKey)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		err = fmt.Errorf("readParent: parent %s does not exist", parentKey)
+		log.Error(err)
+		return nil, err
+	}
+	return json.Marshal(parent)
+}
+
+// readAssetWithRelations returns an asset with its children and parents embedded to the
+// given depth, {"classname": "aircraft", "assetid": "A1", "depth": 2}
+var readAssetWithRelations ChaincodeFunc = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	type RelationsArg struct {
+		ClassName string `json:"classname"`
+		AssetID   string `json:"assetid"`
+		Depth     int    `json:"depth"`
+	}
+	var arg RelationsArg
+	if len(args) != 1 {
+		err := errors.New("readAssetWithRelations expects a JSON object with classname, assetid and depth")
+		log.Error(err)
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(args[0]), &arg); err != nil {
+		err = fmt.Errorf("readAssetWithRelations failed to unmarshal arg: %s", err)
+		log.Error(err)
+		return nil, err
+	}
+	var class AssetClass
+	var found bool
+	for _, r := range sortedRelationships() {
+		if r.Parent.Name == arg.ClassName {
+			class, found = r.Parent, true
+			break
+		}
+		if r.Child.Name == arg.ClassName {
+			class, found = r.Child, true
+			break
+		}
+	}
+	if !found {
+		err := fmt.Errorf("readAssetWithRelations: class %s has no relationships", arg.ClassName)
+		log.Error(err)
+		return nil, err
+	}
+	if arg.Depth <= 0 {
+		arg.Depth = 1
+	}
+	if arg.Depth > MaxRelationDepth {
+		arg.Depth = MaxRelationDepth
+	}
+	out, err := readRelatedAsset(stub, class.Prefix+arg.AssetID, arg.Depth, true, true)
+	if err != nil {
+		err = fmt.Errorf("readAssetWithRelations failed: %s", err)
+		log.Error(err)
+		return nil, err
+	}
+	return json.Marshal(out)
+}
+
+func init() {
+	AddRoute("linkAssets", "invoke", SystemClass, linkAssets)
+	AddRoute("unlinkAssets", "invoke", SystemClass, unlinkAssets)
+	AddRoute("moveAsset", "invoke", SystemClass, moveAsset)
+	AddRoute("readChildren", "query", SystemClass, readChildren)
+	AddRoute("readParent", "query", SystemClass, readParent)
+	AddRoute("readAssetWithRelations", "query", SystemClass, readAssetWithRelations)
+}
@@ -0,0 +1,193 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+package iotcontractplatform
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/ibm-watson-iot/blockchain-samples/fabricv1/platform/cttest"
+)
+
+var relBoxClass = AssetClass{Name: "reltestbox", Prefix: "RBX", AssetIDPath: "box.id"}
+var relItemClass = AssetClass{Name: "reltestitem", Prefix: "RIT", AssetIDPath: "item.id"}
+
+func init() {
+	AddRelationship(Relationship{Name: "reltestcontains", Parent: relBoxClass, Child: relItemClass, OnDelete: OnDeleteCascade})
+	AddRelationship(Relationship{Name: "reltestholds", Parent: relBoxClass, Child: relBoxClass, Cardinality: CardinalityOne, OnDelete: OnDeleteRestrict})
+	AddRelationship(Relationship{Name: "reltestnests", Parent: relBoxClass, Child: relBoxClass, OnDelete: OnDeleteCascade})
+	AddRoute("createAssetReltestbox", "invoke", relBoxClass, func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+		return relBoxClass.CreateAsset(stub, args, "createAssetReltestbox", []QPropNV{})
+	})
+	AddRoute("deleteAssetReltestbox", "invoke", relBoxClass, func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+		return relBoxClass.DeleteAsset(stub, args)
+	})
+	AddRoute("createAssetReltestitem", "invoke", relItemClass, func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+		return relItemClass.CreateAsset(stub, args, "createAssetReltestitem", []QPropNV{})
+	})
+}
+
+func TestAddRelationship(t *testing.T) {
+	var tests = []Relationship{
+		{Name: "", Parent: relBoxClass, Child: relItemClass},
+		{Name: "reltestother", Parent: relBoxClass, Child: relItemClass, Cardinality: "few"},
+		{Name: "reltestother", Parent: relBoxClass, Child: relItemClass, OnDelete: "ignore"},
+		{Name: "reltestcontains", Parent: relBoxClass, Child: relItemClass},
+	}
+	for _, test := range tests {
+		if err := AddRelationship(test); err == nil {
+			t.Fail()
+			fmt.Printf("*** AddRelationship %+v accepted\n", test)
+		}
+	}
+	r := relationrouter["reltestcontains"]
+	if r.Cardinality != CardinalityMany {
+		t.Fail()
+		fmt.Printf("*** relationship defaults not applied: %+v\n", r)
+	}
+}
+
+func relationStep(t *testing.T, stub *cttest.MockStub, function string, arg string, ok bool) []byte {
+	var resp = stub.MockInvoke(function, []string{arg})
+	if strings.HasPrefix(function, "read") {
+		resp = stub.MockQuery(function, []string{arg})
+	}
+	if (resp.Status == shim.OK) != ok {
+		t.Fail()
+		fmt.Printf("*** %s %s returned %d, expected ok %t: %s\n", function, arg, resp.Status, ok, resp.Message)
+	}
+	return resp.Payload
+}
+
+func readChildIDs(t *testing.T, stub *cttest.MockStub, rel string, parentID string) string {
+	var children AssetArray
+	b := relationStep(t, stub, "readChildren", fmt.Sprintf(`{"relationship": "%s", "parentid": "%s"}`, rel, parentID), true)
+	if err := json.Unmarshal(b, &children); err != nil {
+		t.Fatal(err)
+	}
+	var keys = make([]string, 0, len(children))
+	for _, c := range children {
+		keys = append(keys, c.AssetKey)
+	}
+	return strings.Join(keys, ",")
+}
+
+func readParentKey(t *testing.T, stub *cttest.MockStub, childID string) string {
+	var parent Asset
+	b := relationStep(t, stub, "readParent", fmt.Sprintf(`{"relationship": "reltestcontains", "childid": "%s"}`, childID), true)
+	json.Unmarshal(b, &parent)
+	return parent.AssetKey
+}
+
+func TestRelations(t *testing.T) {
+	stub := cttest.NewMockStub("relations", scenarioChaincode{})
+	stub.MockInit("init", []string{`{"version": "1.0"}`})
+	for _, id := range []string{"B1", "B2", "B3"} {
+		relationStep(t, stub, "createAssetReltestbox", fmt.Sprintf(`{"box": {"id": "%s"}}`, id), true)
+	}
+	for _, id := range []string{"I1", "I2", "I3"} {
+		relationStep(t, stub, "createAssetReltestitem", fmt.Sprintf(`{"item": {"id": "%s"}}`, id), true)
+	}
+	contains := `{"relationship": "reltestcontains", "parentid": "%s", "childid": "%s", "newparentid": "%s"}`
+	holds := `{"relationship": "reltestholds", "parentid": "%s", "childid": "%s"}`
+
+	// link
+	relationStep(t, stub, "linkAssets", fmt.Sprintf(contains, "B1", "I1", ""), true)
+	relationStep(t, stub, "linkAssets", fmt.Sprintf(contains, "B1", "I2", ""), true)
+	relationStep(t, stub, "linkAssets", fmt.Sprintf(contains, "B2", "I1", ""), false)
+	relationStep(t, stub, "linkAssets", fmt.Sprintf(contains, "B9", "I3", ""), false)
+	if got := readChildIDs(t, stub, "reltestcontains", "B1"); got != "RITI1,RITI2" {
+		t.Fail()
+		fmt.Printf("*** B1 has children %s after link\n", got)
+	}
+
+	// move
+	relationStep(t, stub, "moveAsset", fmt.Sprintf(contains, "", "I1", "B2"), true)
+	relationStep(t, stub, "moveAsset", fmt.Sprintf(contains, "", "I1", "B2"), false)
+	relationStep(t, stub, "moveAsset", fmt.Sprintf(contains, "", "I3", "B2"), false)
+	if got := readParentKey(t, stub, "I1"); got != "RBXB2" {
+		t.Fail()
+		fmt.Printf("*** I1 has parent %s after move\n", got)
+	}
+	if got := readChildIDs(t, stub, "reltestcontains", "B1"); got != "RITI2" {
+		t.Fail()
+		fmt.Printf("*** B1 has children %s after move\n", got)
+	}
+
+	// unlink
+	relationStep(t, stub, "unlinkAssets", fmt.Sprintf(contains, "", "I2", ""), true)
+	relationStep(t, stub, "unlinkAssets", fmt.Sprintf(contains, "", "I2", ""), false)
+	relationStep(t, stub, "readParent", `{"relationship": "reltestcontains", "childid": "I2"}`, false)
+	if got := readChildIDs(t, stub, "reltestcontains", "B1"); got != "" {
+		t.Fail()
+		fmt.Printf("*** B1 has children %s after unlink\n", got)
+	}
+
+	// cardinality one and restrict
+	relationStep(t, stub, "linkAssets", fmt.Sprintf(holds, "B1", "B2"), true)
+	relationStep(t, stub, "linkAssets", fmt.Sprintf(holds, "B1", "B3"), false)
+	relationStep(t, stub, "deleteAssetReltestbox", `{"box": {"id": "B1"}}`, false)
+
+	// cascade delete removes the children and every link key
+	relationStep(t, stub, "linkAssets", fmt.Sprintf(contains, "B2", "I2", ""), true)
+	relationStep(t, stub, "deleteAssetReltestbox", `{"box": {"id": "B2"}}`, true)
+	for _, key := range []string{"RBXB2", "RITI1", "RITI2"} {
+		if len(stub.State[key]) > 0 {
+			t.Fail()
+			fmt.Printf("*** %s survived the cascade delete\n", key)
+		}
+	}
+	if len(stub.State["RITI3"]) == 0 {
+		t.Fail()
+		fmt.Println("*** unrelated item I3 was deleted")
+	}
+	if keys := stub.Keys("\x00IOTCP.REL"); len(keys) != 0 {
+		t.Fail()
+		fmt.Printf("*** link keys survived the cascade delete: %q\n", keys)
+	}
+	relationStep(t, stub, "deleteAssetReltestbox", `{"box": {"id": "B1"}}`, true)
+
+	// a relationship within one class refuses links that make an asset its own ancestor
+	nests := `{"relationship": "reltestnests", "parentid": "%s", "childid": "%s", "newparentid": "%s"}`
+	for _, id := range []string{"Z1", "Z2", "Z3", "Z4"} {
+		relationStep(t, stub, "createAssetReltestbox", fmt.Sprintf(`{"box": {"id": "%s"}}`, id), true)
+	}
+	relationStep(t, stub, "linkAssets", fmt.Sprintf(nests, "Z1", "Z1", ""), false)
+	relationStep(t, stub, "linkAssets", fmt.Sprintf(nests, "Z1", "Z2", ""), true)
+	relationStep(t, stub, "linkAssets", fmt.Sprintf(nests, "Z2", "Z3", ""), true)
+	relationStep(t, stub, "linkAssets", fmt.Sprintf(nests, "Z3", "Z1", ""), false)
+	relationStep(t, stub, "linkAssets", fmt.Sprintf(nests, "Z4", "Z1", ""), true)
+	relationStep(t, stub, "moveAsset", fmt.Sprintf(nests, "", "Z4", "Z3"), false)
+
+	// a cascade removes each asset once, even when the links hold a cycle
+	ck, pk, err := linkKeys(stub, "reltestnests", "RBXZ3", "RBXZ4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stub.State[ck] = indexValueMarker
+	stub.State[pk] = []byte("RBXZ3")
+	relationStep(t, stub, "deleteAssetReltestbox", `{"box": {"id": "Z1"}}`, true)
+	for _, key := range []string{"RBXZ1", "RBXZ2", "RBXZ3", "RBXZ4"} {
+		if len(stub.State[key]) > 0 {
+			t.Fail()
+			fmt.Printf("*** %s survived the nested cascade delete\n", key)
+		}
+	}
+}
@@ -0,0 +1,449 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+// v0.1 KL -- per class retention of state history, pruned in bounded batches with an
+//            optional archive event so that an off-chain store can keep what is removed
+// v0.2 KL -- per asset pruned-before watermark for point in time reads, archive batches
+//            bounded by size
+
+package iotcontractplatform
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// HISTORYPRUNESTATUSKEY holds the class and asset where the next pruneAssetStateHistory
+// resumes
+const HISTORYPRUNESTATUSKEY string = "IOTCP:HistoryPruneStatus"
+
+// HISTORYPRUNEDBEFOREKEY holds the time before which an asset's history has been pruned
+const HISTORYPRUNEDBEFOREKEY string = "IOTCP:HistoryPrunedBefore." // + assetKey
+
+// MaxArchiveBytes bounds the archived states returned by one pruneAssetStateHistory, so
+// that the invoke result event stays well within the peer's message size
+const MaxArchiveBytes int = 512 * 1024
+
+// HISTORYARCHIVEEVENT is the declared name of the events that carry archived history, so
+// an off-chain store can find it in the payload names, or subscribe to
+// EVT.IOTCP.INVOKE.RESULT:IOTCP.HISTORY.ARCHIVE when the event name suffix is on
+const HISTORYARCHIVEEVENT string = "IOTCP.HISTORY.ARCHIVE"
+
+// Downsampling periods
+const (
+	DownsampleHour = "hour"
+	DownsampleDay  = "day"
+)
+
+// HistoryRetention is the retention policy of a class's state history, applied to each
+// asset by pruneAssetStateHistory. The newest state of an asset is always kept. A state
+// is pruned when it is beyond the newest MaxVersions, older than MaxAge, or older than
+// DownsampleAfter and not the newest state in its hour or day. Compact removes the event
+// payload and event out from the states that are kept, other than the newest. Zero values
+// disable each rule.
+type HistoryRetention struct {
+	MaxVersions     int           `json:"maxversions,omitempty"`
+	MaxAge          time.Duration `json:"maxage,omitempty"`
+	Downsample      string        `json:"downsample,omitempty"`
+	DownsampleAfter time.Duration `json:"downsampleafter,omitempty"`
+	Compact         bool          `json:"compact,omitempty"`
+}
+
+// historyPruneStatus records where a pass over the classes stopped
+type historyPruneStatus struct {
+	ClassName string `json:"classname"`
+	AssetKey  string `json:"assetkey"`
+}
+
+// historyState is one stored history state and its key
+type historyState struct {
+	key   string
+	state *Asset
+}
+
+var historyretentionrouter = make(map[AssetClass]HistoryRetention, 0)
+
+// AddHistoryRetention sets the retention policy of a class's state history, which applies
+// to classes that keep KeyHistory
+func AddHistoryRetention(class AssetClass, policy HistoryRetention) error {
+	if policy.MaxVersions < 0 || policy.MaxAge < 0 || policy.DownsampleAfter < 0 {
+		err := fmt.Errorf("AddHistoryRetention: class %s policy %+v has negative limits", class.Name, policy)
+		log.Error(err)
+		return err
+	}
+	if policy.Downsample != "" && policy.Downsample != DownsampleHour && policy.Downsample != DownsampleDay {
+		err := fmt.Errorf("AddHistoryRetention: class %s downsample must be %s or %s, got %s", class.Name, DownsampleHour, DownsampleDay, policy.Downsample)
+		log.Error(err)
+		return err
+	}
+	historyretentionrouter[class] = policy
+	log.Debugf("Class %s added history retention %+v", class.Name, policy)
+	return nil
+}
+
+func getHistoryRetentionClass(name string) (AssetClass, HistoryRetention, bool) {
+	for c, p := range historyretentionrouter {
+		if c.Name == name {
+			return c, p, true
+		}
+	}
+	return AssetClass{}, HistoryRetention{}, false
+}
+
+func downsampleBucket(ts time.Time, period string) time.Time {
+	if period == DownsampleDay {
+		return ts.UTC().Truncate(24 * time.Hour)
+	}
+	return ts.UTC().Truncate(time.Hour)
+}
+
+// retentionActions splits one asset's history into the states to prune and the states to
+// compact, each oldest first
+func (p HistoryRetention) retentionActions(states []historyState, now time.Time) (prune []historyState, compact []historyState) {
+	// newest first, keys do not sort by time within a second
+	sort.Sort(sort.Reverse(byHistoryTime(states)))
+	var buckets = make(map[time.Time]bool)
+	for i, hs := range states {
+		if i == 0 {
+			continue
+		}
+		age := now.Sub(*hs.state.TXNTS)
+		switch {
+		case p.MaxVersions > 0 && i >= p.MaxVersions:
+			prune = append(prune, hs)
+			continue
+		case p.MaxAge > 0 && age > p.MaxAge:
+			prune = append(prune, hs)
+			continue
+		case p.Downsample != "" && age > p.DownsampleAfter:
+			b := downsampleBucket(*hs.state.TXNTS, p.Downsample)
+			if buckets[b] {
+				prune = append(prune, hs)
+				continue
+			}
+			buckets[b] = true
+		}
+		if p.Compact && (hs.state.EventIn != nil || hs.state.EventOut != nil) {
+			compact = append(compact, hs)
+		}
+	}
+	sort.Sort(byHistoryTime(prune))
+	sort.Sort(byHistoryTime(compact))
+	return prune, compact
+}
+
+type byHistoryTime []historyState
+
+func (b byHistoryTime) Len() int           { return len(b) }
+func (b byHistoryTime) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+func (b byHistoryTime) Less(i, j int) bool { return b[i].state.TXNTS.Before(*b[j].state.TXNTS) }
+
+// HistoryPruneResult is returned by pruneAssetStateHistory and so becomes the invoke
+// result event. Archive holds the pruned states when archiving was asked for.
+type HistoryPruneResult struct {
+	Pruned    int        `json:"pruned"`
+	Compacted int        `json:"compacted"`
+	More      bool       `json:"more"`
+	Archive   AssetArray `json:"archive,omitempty"`
+	Events    []IOTEvent `json:"events,omitempty"`
+
+	archiveBytes int
+}
+
+// getHistoryPrunedBefore returns the time before which the asset's history was pruned,
+// or nil when none was
+func getHistoryPrunedBefore(stub shim.ChaincodeStubInterface, assetKey string) (*time.Time, error) {
+	wBytes, err := stub.GetState(HISTORYPRUNEDBEFOREKEY + assetKey)
+	if err != nil {
+		return nil, fmt.Errorf("GetState for the pruned before time of %s failed: %s", assetKey, err)
+	}
+	if len(wBytes) == 0 {
+		return nil, nil
+	}
+	var before time.Time
+	if err = json.Unmarshal(wBytes, &before); err != nil {
+		return nil, fmt.Errorf("unmarshal of the pruned before time of %s failed: %s", assetKey, err)
+	}
+	return &before, nil
+}
+
+// putHistoryPrunedBefore records the time of the oldest state kept after the newest state
+// pruned, given the states newest first. Reads as of that time or later are exact.
+func putHistoryPrunedBefore(stub shim.ChaincodeStubInterface, states []historyState, pruned map[string]bool) error {
+	if len(pruned) == 0 {
+		return nil
+	}
+	var kept *time.Time
+	for _, hs := range states {
+		if pruned[hs.key] {
+			break
+		}
+		kept = hs.state.TXNTS
+	}
+	if kept == nil {
+		return nil
+	}
+	assetKey := states[0].state.AssetKey
+	before, err := getHistoryPrunedBefore(stub, assetKey)
+	if err != nil {
+		return err
+	}
+	if before != nil && !kept.After(*before) {
+		return nil
+	}
+	wBytes, err := json.Marshal(kept.UTC())
+	if err != nil {
+		return fmt.Errorf("marshal of the pruned before time of %s failed: %s", assetKey, err)
+	}
+	if err = stub.PutState(HISTORYPRUNEDBEFOREKEY+assetKey, wBytes); err != nil {
+		return fmt.Errorf("PutState for the pruned before time of %s failed: %s", assetKey, err)
+	}
+	return nil
+}
+
+// pruneAsset applies the class policy to one asset's history, making no more than limit
+// writes. It returns false when the limit or the archive size stopped it before the asset
+// was done.
+func (r *HistoryPruneResult) pruneAsset(stub shim.ChaincodeStubInterface, policy HistoryRetention, states []historyState, now time.Time, limit int, archive bool) (bool, error) {
+	prune, compact := policy.retentionActions(states, now)
+	var pruned = make(map[string]bool, len(prune))
+	var archived int
+	var done = true
+	for _, hs := range prune {
+		if r.Pruned+r.Compacted >= limit {
+			done = false
+			break
+		}
+		if archive {
+			sBytes, err := json.Marshal(hs.state)
+			if err != nil {
+				return false, fmt.Errorf("marshal of %s failed: %s", hs.key, err)
+			}
+			if len(r.Archive) > 0 && r.archiveBytes+len(sBytes) > MaxArchiveBytes {
+				done = false
+				break
+			}
+			r.archiveBytes += len(sBytes)
+		}
+		if err := stub.DelState(hs.key); err != nil {
+			return false, fmt.Errorf("DelState for %s failed: %s", hs.key, err)
+		}
+		pruned[hs.key] = true
+		r.Pruned++
+		if archive {
+			r.Archive = append(r.Archive, *hs.state)
+			archived++
+		}
+	}
+	// states are newest first after retentionActions
+	if err := putHistoryPrunedBefore(stub, states, pruned); err != nil {
+		return false, err
+	}
+	if archived > 0 {
+		s := states[0].state
+		r.Events = append(r.Events, IOTEvent{
+			Type:     EventHistoryArchived,
+			Name:     HISTORYARCHIVEEVENT,
+			Class:    s.Class.Name,
+			AssetKey: s.AssetKey,
+			TXNID:    stub.GetTxID(),
+		})
+	}
+	if !done {
+		return false, nil
+	}
+	for _, hs := range compact {
+		if r.Pruned+r.Compacted >= limit {
+			return false, nil
+		}
+		hs.state.EventIn = nil
+		hs.state.EventOut = nil
+		sBytes, err := json.Marshal(hs.state)
+		if err != nil {
+			return false, fmt.Errorf("marshal of %s failed: %s", hs.key, err)
+		}
+		if err = stub.PutState(hs.key, sBytes); err != nil {
+			return false, fmt.Errorf("PutState for %s failed: %s", hs.key, err)
+		}
+		r.Compacted++
+	}
+	return true, nil
+}
+
+// pruneClass walks the history of a class one asset at a time, starting at the asset
+// key, and returns the asset where the limit stopped it, or "" when the class is done
+func (r *HistoryPruneResult) pruneClass(stub shim.ChaincodeStubInterface, class AssetClass, policy HistoryRetention, startKey string, now time.Time, limit int, archive bool) (string, error) {
+	var historyKey = STATEHISTORYKEY + class.Prefix
+	var start = historyKey
+	if startKey != "" {
+		start = STATEHISTORYKEY + startKey + "."
+	}
+	iter, err := stub.GetStateByRange(start, historyKey+"}")
+	if err != nil {
+		return "", fmt.Errorf("failed to get a range query iterator: %s", err)
+	}
+	defer iter.Close()
+	var states = make([]historyState, 0)
+	flush := func() (bool, error) {
+		if len(states) == 0 {
+			return true, nil
+		}
+		done, err := r.pruneAsset(stub, policy, states, now, limit, archive)
+		if err != nil || !done {
+			return false, err
+		}
+		states = states[:0]
+		return true, nil
+	}
+	for iter.HasNext() {
+		key, assetBytes, err := iter.Next()
+		if err != nil {
+			return "", fmt.Errorf("iter.Next() failed: %s", err)
+		}
+		var state = new(Asset)
+		if err = json.Unmarshal(assetBytes, state); err != nil {
+			return "", fmt.Errorf("unmarshal %s failed: %s", key, err)
+		}
+		// another class's prefix can start with this one
+		if state.Class.Name != class.Name || state.TXNTS == nil {
+			continue
+		}
+		if len(states) > 0 && states[0].state.AssetKey != state.AssetKey {
+			assetKey := states[0].state.AssetKey
+			done, err := flush()
+			if err != nil {
+				return "", err
+			}
+			if !done || r.Pruned+r.Compacted >= limit {
+				if !done {
+					return assetKey, nil
+				}
+				return state.AssetKey, nil
+			}
+		}
+		states = append(states, historyState{key, state})
+	}
+	if len(states) > 0 {
+		assetKey := states[0].state.AssetKey
+		done, err := flush()
+		if err != nil {
+			return "", err
+		}
+		if !done {
+			return assetKey, nil
+		}
+	}
+	return "", nil
+}
+
+// pruneAssetStateHistory applies the retention policies of all classes, making up to
+// limit deletes and rewrites, {"limit": 1000, "archive": true}. Call it until more is
+// false. With archive, the pruned states are returned in the invoke result event, whose
+// name then carries IOTCP.HISTORY.ARCHIVE, in the same transaction that deletes them.
+var pruneAssetStateHistory ChaincodeFunc = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	type PruneArg struct {
+		Limit   int  `json:"limit"`
+		Archive bool `json:"archive"`
+	}
+	var arg PruneArg
+	var result HistoryPruneResult
+	if len(args) > 0 {
+		if err := json.Unmarshal([]byte(args[0]), &arg); err != nil {
+			err = fmt.Errorf("pruneAssetStateHistory failed to unmarshal arg: %s", err)
+			log.Error(err)
+			return nil, err
+		}
+	}
+	if arg.Limit <= 0 {
+		arg.Limit = DefaultPruneLimit
+	}
+	now, err := getTxTime(stub)
+	if err != nil {
+		err = fmt.Errorf("pruneAssetStateHistory failed to get the transaction time: %s", err)
+		log.Error(err)
+		return nil, err
+	}
+	var status historyPruneStatus
+	sBytes, err := stub.GetState(HISTORYPRUNESTATUSKEY)
+	if err != nil {
+		err = fmt.Errorf("pruneAssetStateHistory failed to read its status: %s", err)
+		log.Error(err)
+		return nil, err
+	}
+	if len(sBytes) > 0 {
+		if err = json.Unmarshal(sBytes, &status); err != nil {
+			err = fmt.Errorf("pruneAssetStateHistory failed to unmarshal its status: %s", err)
+			log.Error(err)
+			return nil, err
+		}
+	}
+
+	var names = make([]string, 0, len(historyretentionrouter))
+	for c := range historyretentionrouter {
+		names = append(names, c.Name)
+	}
+	sort.Strings(names)
+	var next historyPruneStatus
+	for _, name := range names {
+		if name < status.ClassName {
+			continue
+		}
+		var startKey string
+		if name == status.ClassName {
+			startKey = status.AssetKey
+		}
+		class, policy, _ := getHistoryRetentionClass(name)
+		if _, keys := classHistoryBackend(class).(keyHistory); !keys {
+			// the ledger's own history cannot be pruned
+			continue
+		}
+		stopped, err := result.pruneClass(stub, class, policy, startKey, now, arg.Limit, arg.Archive)
+		if err != nil {
+			err = fmt.Errorf("pruneAssetStateHistory class %s: %s", name, err)
+			log.Error(err)
+			return nil, err
+		}
+		if stopped != "" {
+			next = historyPruneStatus{name, stopped}
+			result.More = true
+			break
+		}
+	}
+
+	if result.More {
+		sBytes, err = json.Marshal(next)
+		if err == nil {
+			err = stub.PutState(HISTORYPRUNESTATUSKEY, sBytes)
+		}
+	} else {
+		err = stub.DelState(HISTORYPRUNESTATUSKEY)
+	}
+	if err != nil {
+		err = fmt.Errorf("pruneAssetStateHistory failed to save its status: %s", err)
+		log.Error(err)
+		return nil, err
+	}
+	log.Noticef("pruneAssetStateHistory pruned %d and compacted %d states, more is %t", result.Pruned, result.Compacted, result.More)
+	return json.Marshal(result)
+}
+
+func init() {
+	AddRoute("pruneAssetStateHistory", "invoke", SystemClass, pruneAssetStateHistory)
+}
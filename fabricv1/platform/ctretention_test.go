@@ -0,0 +1,121 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+package iotcontractplatform
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/ibm-watson-iot/blockchain-samples/fabricv1/platform/cttest"
+)
+
+func retentionTestStates(times ...string) []historyState {
+	var states = make([]historyState, 0, len(times))
+	for _, t := range times {
+		ts, _ := time.Parse(time.RFC3339, t)
+		states = append(states, historyState{t, &Asset{TXNTS: &ts}})
+	}
+	return states
+}
+
+func historyKeys(states []historyState) []string {
+	var keys = make([]string, 0, len(states))
+	for _, hs := range states {
+		keys = append(keys, hs.key)
+	}
+	return keys
+}
+
+func TestRetentionActions(t *testing.T) {
+	now, _ := time.Parse(time.RFC3339, "2017-03-10T12:00:00Z")
+	states := retentionTestStates(
+		"2017-03-01T10:10:00Z", "2017-03-01T10:50:00Z", "2017-03-01T11:05:00Z",
+		"2017-03-02T09:00:00Z", "2017-03-10T11:00:00Z", "2017-03-10T11:30:00Z",
+	)
+
+	p := HistoryRetention{Downsample: DownsampleHour, DownsampleAfter: 24 * time.Hour}
+	prune, _ := p.retentionActions(states, now)
+	if keys := historyKeys(prune); !reflect.DeepEqual(keys, []string{"2017-03-01T10:10:00Z"}) {
+		t.Fail()
+		fmt.Printf("*** hourly downsampling pruned %v\n", keys)
+	}
+
+	p = HistoryRetention{Downsample: DownsampleDay, DownsampleAfter: 24 * time.Hour}
+	prune, _ = p.retentionActions(states, now)
+	if keys := historyKeys(prune); !reflect.DeepEqual(keys, []string{"2017-03-01T10:10:00Z", "2017-03-01T10:50:00Z"}) {
+		t.Fail()
+		fmt.Printf("*** daily downsampling pruned %v\n", keys)
+	}
+
+	p = HistoryRetention{MaxAge: 7 * 24 * time.Hour, MaxVersions: 2}
+	prune, _ = p.retentionActions(states, now)
+	if len(prune) != 4 {
+		t.Fail()
+		fmt.Printf("*** age and version limits pruned %v\n", historyKeys(prune))
+	}
+
+	// the newest state is kept however old it is
+	p = HistoryRetention{MaxAge: time.Hour}
+	prune, _ = p.retentionActions(retentionTestStates("2016-01-01T00:00:00Z"), now)
+	if len(prune) != 0 {
+		t.Fail()
+		fmt.Printf("*** the newest state was pruned\n")
+	}
+}
+
+func TestPruneArchiveBytes(t *testing.T) {
+	stub := cttest.NewMockStub("archivebytes", scenarioChaincode{})
+	stub.MockInit("init", []string{`{"version": "1.0"}`})
+	// each state holds the notes twice, in its state and in its event
+	notes := strings.Repeat("x", MaxArchiveBytes/6)
+	for i := 1; i <= 8; i++ {
+		resp := stub.MockInvoke("updateAsset", []string{fmt.Sprintf(`{"asset": {"assetID": "AB1", "temperature": %d, "notes": "%s"}}`, i, notes)})
+		if resp.Status != shim.OK {
+			t.Fatalf("updateAsset failed: %s", resp.Message)
+		}
+	}
+
+	// 5 states are beyond the newest 3, an archive holds 2 of them
+	var archived []int
+	for calls := 0; calls < 5; calls++ {
+		resp := stub.MockInvoke("pruneAssetStateHistory", []string{`{"archive": true}`})
+		if resp.Status != shim.OK {
+			t.Fatalf("pruneAssetStateHistory failed: %s", resp.Message)
+		}
+		var result HistoryPruneResult
+		if err := json.Unmarshal(stub.Event.Payload, &result); err != nil {
+			t.Fatal(err)
+		}
+		if len(stub.Event.Payload) > MaxArchiveBytes+4096 {
+			t.Fail()
+			fmt.Printf("*** archive of %d states is %d bytes\n", len(result.Archive), len(stub.Event.Payload))
+		}
+		archived = append(archived, len(result.Archive))
+		if !result.More {
+			break
+		}
+	}
+	if fmt.Sprint(archived) != "[2 2 1]" {
+		t.Fail()
+		fmt.Printf("*** archives held %v states\n", archived)
+	}
+}
@@ -77,7 +77,9 @@ func getDeployFunctions() []ChaincodeFunc {
 // EVTCCINVRESULT is a chaincode event ID to be emitted always at the end of an invoke
 // The platform defines this as an event with a payload that is an array of objects that
 // can be added to along the way. If an error occurs, the array is wiped and only the
-// error appears in order to avoid confusion
+// error appears in order to avoid confusion. When the invoke emits events with declared
+// names, they are listed in the payload, and appended to the name when the contract
+// calls SetEventNameSuffix, e.g. EVT.IOTCP.INVOKE.RESULT:KIT.OUTOFAREA:KIT.UPDATED
 // TODO: What about using it as a debugging mechanism? COOL!!!
 const EVTCCINVRESULT string = "EVT.IOTCP.INVOKE.RESULT"
 
@@ -97,8 +99,15 @@ func setStubEvent(stub shim.ChaincodeStubInterface, err error, info map[string]i
 		ire.Payload["message"] = err.Error()
 	}
 	log.Debugf("SetStubEvent after err check %+v", ire)
+	ire.Payload["txnid"] = stub.GetTxID()
+	// declared event names are added to the event so that consumers can filter on them
+	names := eventNames(ire.Payload["events"])
+	if len(names) > 0 {
+		ire.Payload["names"] = names
+	}
+	ire.Name = chaincodeEventName(names)
 	evbytes, err := json.Marshal(ire.Payload)
-	_ = stub.SetEvent(EVTCCINVRESULT, evbytes)
+	_ = stub.SetEvent(ire.Name, evbytes)
 }
 
 // Init is called by deploy messages
@@ -148,6 +157,14 @@ func Invoke(stub shim.ChaincodeStubInterface) pb.Response {
 	if r.Method == "query" {
 		return Query(stub)
 	}
+	if err := checkAccess(stub, function); err != nil {
+		setStubEvent(stub, err, nil)
+		return shim.Error(err.Error())
+	}
+	if err := checkMigration(stub, r.Class); err != nil {
+		setStubEvent(stub, err, nil)
+		return shim.Error(err.Error())
+	}
 	eventToReportBytes, err := r.Function(stub, args)
 	if err != nil {
 		err := fmt.Errorf("Invoke (%s) failed with error %s", function, err)
@@ -181,6 +198,10 @@ func Query(stub shim.ChaincodeStubInterface) pb.Response {
 		log.Error(err)
 		return shim.Error(err.Error())
 	}
+	if err := checkAccess(stub, function); err != nil {
+		setStubEvent(stub, err, nil)
+		return shim.Error(err.Error())
+	}
 	result, err := r.Function(stub, args)
 	if err != nil {
 		err := fmt.Errorf("Query (%s) failed with error %s", function, err)
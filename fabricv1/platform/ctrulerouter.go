@@ -107,7 +107,8 @@ func AddComplianceRule(class AssetClass, rule RuleFunc) error {
 	return nil
 }
 
-// ExecuteRules executes all registered rules for the Asset's class
+// ExecuteRules executes all registered rules for the Asset's class, followed by the
+// declarative rules in world state
 func (a *Asset) ExecuteRules(stub shim.ChaincodeStubInterface) error {
 	log.Debugf("Executing rules input: %+v", a.AlertsActive)
 	rules := classRules(a.Class)
@@ -119,6 +120,11 @@ func (a *Asset) ExecuteRules(stub shim.ChaincodeStubInterface) error {
 			return err
 		}
 	}
+	if err := a.executeJSONRules(stub); err != nil {
+		err := fmt.Errorf("Declarative rules for class %s failed with error %s", a.Class.Name, err)
+		log.Error(err)
+		return err
+	}
 	crule, found := compliancerouter[a.Class]
 	if found {
 		err := crule.Function(stub, a)
@@ -134,24 +140,61 @@ func (a *Asset) ExecuteRules(stub shim.ChaincodeStubInterface) error {
 	return nil
 }
 
-// readAllRules shows all registered rules
+// readAllRules shows all registered rules followed by the declarative rules, which are
+// read from the current rule set or from the version passed as {"version": n}
 var readAllRules = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
 	type RulesOut struct {
-		RuleName string      `json:"rulename"`
-		Alerts   []AlertName `json:"alerts,omitempty"`
-		Class    AssetClass  `json:"class"`
+		RuleName  string       `json:"rulename"`
+		Alerts    []AlertName  `json:"alerts,omitempty"`
+		Class     AssetClass   `json:"class"`
+		Version   int          `json:"version,omitempty"`
+		Action    string       `json:"action,omitempty"`
+		Condition *StateFilter `json:"condition,omitempty"`
+	}
+	type VersionArg struct {
+		Version int `json:"version"`
+	}
+	var arg VersionArg
+	if len(args) > 0 {
+		if err := json.Unmarshal([]byte(args[0]), &arg); err != nil {
+			err = fmt.Errorf("readAllRules failed to unmarshal arg: %s", err)
+			log.Error(err)
+			return nil, err
+		}
 	}
 	var r = make([]RulesOut, 0, len(rulerouter)+1)
 	for _, rc := range rulerouter {
 		for _, rule := range rc {
 			ro := RulesOut{
-				rule.RuleName,
-				rule.Alerts,
-				rule.Class,
+				RuleName: rule.RuleName,
+				Alerts:   rule.Alerts,
+				Class:    rule.Class,
 			}
 			r = append(r, ro)
 		}
 	}
+	var rs RuleSet
+	var err error
+	if arg.Version > 0 {
+		rs, err = getRuleSetAtKey(stub, ruleSetVersionKey(arg.Version))
+	} else {
+		rs, err = getRuleSet(stub)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i := range rs.Rules {
+		jr := rs.Rules[i]
+		ro := RulesOut{
+			RuleName:  jr.RuleName,
+			Alerts:    []AlertName{jr.Alert},
+			Class:     AssetClass{Name: jr.ClassName},
+			Version:   rs.Version,
+			Action:    jr.Action,
+			Condition: &jr.Condition,
+		}
+		r = append(r, ro)
+	}
 	return json.Marshal(r)
 }
 
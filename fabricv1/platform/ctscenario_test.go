@@ -0,0 +1,59 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+package iotcontractplatform
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/ibm-watson-iot/blockchain-samples/fabricv1/platform/cttest"
+)
+
+type scenarioChaincode struct{}
+
+func (scenarioChaincode) Init(stub shim.ChaincodeStubInterface) pb.Response {
+	return Init(stub, "1.0")
+}
+
+func (scenarioChaincode) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
+	return Invoke(stub)
+}
+
+func init() {
+	RegisterDefaultRoutes()
+	AddAlertEvent(DefaultClass, overtempAlert, "DEF.OVERTEMP")
+	AddHistoryRetention(DefaultClass, HistoryRetention{MaxVersions: 3, Compact: true})
+	AddGeoLocation(DefaultClass, GeoLocation{"asset.location.latitude", "asset.location.longitude"})
+	threshold := 0.0
+	AddAggregation(DefaultClass, AggregationConfig{
+		Sensors: []SensorAggregation{{QProp: "asset.temperature", Threshold: &threshold, MKT: true}},
+		Windows: []time.Duration{3 * time.Second, time.Minute},
+	})
+}
+
+func TestScenarios(t *testing.T) {
+	files, err := filepath.Glob("testdata/*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		cttest.RunScenario(t, scenarioChaincode{}, f)
+	}
+}
@@ -0,0 +1,376 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+// v0.1 KL -- compatibility of contract schemas between versions
+// v0.2 KL -- output types and enums vary the other way from input
+
+// Package ctschema compares the resolved schemas of two versions of a contract, as served
+// by readAssetSchemas, to find the changes that break clients.
+package ctschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Change is one difference between two schemas
+type Change struct {
+	Breaking bool   `json:"breaking"`
+	Kind     string `json:"kind"`
+	Path     string `json:"path"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+func (c Change) String() string {
+	var s = "non-breaking"
+	if c.Breaking {
+		s = "BREAKING"
+	}
+	s = fmt.Sprintf("%-13s %-18s %s", s, c.Kind, c.Path)
+	if c.Detail != "" {
+		s += " (" + c.Detail + ")"
+	}
+	return s
+}
+
+// Kinds of change
+const (
+	APIAdded         = "api.added"
+	APIRemoved       = "api.removed"
+	APIMethod        = "api.method"
+	ArgsCount        = "args.count"
+	ModelAdded       = "model.added"
+	ModelRemoved     = "model.removed"
+	PropertyAdded    = "property.added"
+	PropertyRemoved  = "property.removed"
+	PropertyRequired = "property.required"
+	PropertyOptional = "property.optional"
+	TypeNarrowed     = "type.narrowed"
+	TypeWidened      = "type.widened"
+	TypeChanged      = "type.changed"
+	EnumAdded        = "enum.added"
+	EnumRemoved      = "enum.removed"
+)
+
+// ReadSchemas reads a resolved schema from a readAssetSchemas response, the schema that
+// processSchema.go writes with -debug, or the Go file that processSchema.go generates
+func ReadSchemas(filename string) ([]byte, error) {
+	src, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(filename, ".go") {
+		return src, nil
+	}
+	parts := strings.SplitN(string(src), "var schemas = `", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("%s does not contain a generated schema", filename)
+	}
+	end := strings.Index(parts[1], "`")
+	if end < 0 {
+		return nil, fmt.Errorf("%s has an unterminated schema", filename)
+	}
+	return []byte(parts[1][:end]), nil
+}
+
+// Compare returns the changes from the old schema to the new one, sorted by path. Changes
+// break clients when a function or Model entry is removed, a function changes method, an
+// argument becomes required, or a property is removed or changes type. Input, the args of
+// a function, breaks writers when a type narrows or an enum loses values or is imposed.
+// Output, its result and the Model, breaks readers the other way, when a property becomes
+// optional, a type widens, or an enum gains values or is lifted.
+func Compare(oldSchemas []byte, newSchemas []byte) ([]Change, error) {
+	var old, next map[string]interface{}
+	if err := unmarshalSchemas(oldSchemas, &old); err != nil {
+		return nil, fmt.Errorf("old schema: %s", err)
+	}
+	if err := unmarshalSchemas(newSchemas, &next); err != nil {
+		return nil, fmt.Errorf("new schema: %s", err)
+	}
+	var c comparison
+	c.compareAPI(asMap(old["API"]), asMap(next["API"]))
+	c.compareModel(asMap(old["Model"]), asMap(next["Model"]))
+	sort.SliceStable(c.changes, func(i, j int) bool {
+		if c.changes[i].Path != c.changes[j].Path {
+			return c.changes[i].Path < c.changes[j].Path
+		}
+		return c.changes[i].Kind < c.changes[j].Kind
+	})
+	return c.changes, nil
+}
+
+// Breaking counts the breaking changes
+func Breaking(changes []Change) int {
+	var n int
+	for _, c := range changes {
+		if c.Breaking {
+			n++
+		}
+	}
+	return n
+}
+
+func unmarshalSchemas(schemas []byte, v *map[string]interface{}) error {
+	if err := json.Unmarshal(schemas, v); err != nil {
+		return err
+	}
+	if _, found := (*v)["definitions"]; found {
+		return fmt.Errorf("schema is not resolved, compare the output of processSchema.go instead")
+	}
+	if (*v)["API"] == nil && (*v)["Model"] == nil {
+		return fmt.Errorf("schema has neither API nor Model")
+	}
+	return nil
+}
+
+func asMap(v interface{}) map[string]interface{} {
+	m, _ := v.(map[string]interface{})
+	return m
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	var keys = make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+type comparison struct {
+	changes []Change
+}
+
+func (c *comparison) add(breaking bool, kind string, path string, detail string) {
+	c.changes = append(c.changes, Change{breaking, kind, path, detail})
+}
+
+func (c *comparison) compareAPI(old map[string]interface{}, next map[string]interface{}) {
+	for _, name := range sortedKeys(old) {
+		path := "API/" + name
+		nf, found := next[name]
+		if !found {
+			c.add(true, APIRemoved, path, "")
+			continue
+		}
+		oprops := asMap(asMap(old[name])["properties"])
+		nprops := asMap(asMap(nf)["properties"])
+		if om, nm := fmt.Sprint(oprops["method"]), fmt.Sprint(nprops["method"]); om != nm {
+			c.add(true, APIMethod, path, om+" became "+nm)
+		}
+		oargs, nargs := asMap(oprops["args"]), asMap(nprops["args"])
+		omin, _ := oargs["minItems"].(float64)
+		nmin, _ := nargs["minItems"].(float64)
+		if nmin > omin {
+			c.add(true, ArgsCount, path+"/args", fmt.Sprintf("at least %v args are required instead of %v", nmin, omin))
+		}
+		omax, ohasMax := oargs["maxItems"].(float64)
+		nmax, nhasMax := nargs["maxItems"].(float64)
+		if nhasMax && (!ohasMax || nmax < omax) {
+			c.add(true, ArgsCount, path+"/args", fmt.Sprintf("at most %v args are accepted", nmax))
+		}
+		c.compareSchema(path+"/args", asMap(oargs["items"]), asMap(nargs["items"]), false)
+		c.compareSchema(path+"/result", asMap(oprops["result"]), asMap(nprops["result"]), true)
+	}
+	for _, name := range sortedKeys(next) {
+		if _, found := old[name]; !found {
+			c.add(false, APIAdded, "API/"+name, "")
+		}
+	}
+}
+
+func (c *comparison) compareModel(old map[string]interface{}, next map[string]interface{}) {
+	for _, name := range sortedKeys(old) {
+		path := "Model/" + name
+		if _, found := next[name]; !found {
+			c.add(true, ModelRemoved, path, "")
+			continue
+		}
+		c.compareSchema(path, asMap(old[name]), asMap(next[name]), true)
+	}
+	for _, name := range sortedKeys(next) {
+		if _, found := old[name]; !found {
+			c.add(false, ModelAdded, "Model/"+name, "")
+		}
+	}
+}
+
+// types returns the types that a schema accepts, nil when it accepts any
+func types(s map[string]interface{}) map[string]bool {
+	var set = make(map[string]bool)
+	switch t := s["type"].(type) {
+	case string:
+		set[t] = true
+	case []interface{}:
+		for _, e := range t {
+			set[fmt.Sprint(e)] = true
+		}
+	default:
+		return nil
+	}
+	// a number accepts every integer
+	if set["number"] {
+		set["integer"] = true
+	}
+	return set
+}
+
+func subset(a map[string]bool, b map[string]bool) bool {
+	for t := range a {
+		if !b[t] {
+			return false
+		}
+	}
+	return true
+}
+
+func typeString(s map[string]interface{}) string {
+	if s["type"] == nil {
+		return "any"
+	}
+	return fmt.Sprint(s["type"])
+}
+
+func requiredSet(s map[string]interface{}) map[string]bool {
+	var set = make(map[string]bool)
+	if req, found := s["required"].([]interface{}); found {
+		for _, r := range req {
+			set[fmt.Sprint(r)] = true
+		}
+	}
+	return set
+}
+
+// compareSchema compares the values that two schemas accept
+func (c *comparison) compareSchema(path string, old map[string]interface{}, next map[string]interface{}, output bool) {
+	if old == nil || next == nil {
+		if old == nil && next != nil && !output {
+			// arguments that were not described are no longer free
+			if len(types(next)) > 0 {
+				c.add(true, TypeNarrowed, path, "any became "+typeString(next))
+			}
+		}
+		return
+	}
+
+	// writers need the schema to accept at least what it did, readers to return at most
+	ot, nt := types(old), types(next)
+	switch {
+	case reflect.DeepEqual(ot, nt):
+	case ot == nil:
+		c.add(!output, TypeNarrowed, path, "any became "+typeString(next))
+	case nt == nil:
+		c.add(output, TypeWidened, path, typeString(old)+" became any")
+	case subset(nt, ot):
+		c.add(!output, TypeNarrowed, path, typeString(old)+" became "+typeString(next))
+	case subset(ot, nt):
+		c.add(output, TypeWidened, path, typeString(old)+" became "+typeString(next))
+	default:
+		c.add(true, TypeChanged, path, typeString(old)+" became "+typeString(next))
+		return
+	}
+
+	c.compareEnum(path, old, next, output)
+
+	// properties
+	oprops, nprops := asMap(old["properties"]), asMap(next["properties"])
+	oreq, nreq := requiredSet(old), requiredSet(next)
+	for _, name := range sortedKeys(oprops) {
+		ppath := path + "/" + name
+		if _, found := nprops[name]; !found {
+			c.add(true, PropertyRemoved, ppath, "")
+			continue
+		}
+		switch {
+		case nreq[name] && !oreq[name]:
+			c.add(!output, PropertyRequired, ppath, "")
+		case oreq[name] && !nreq[name]:
+			c.add(output, PropertyOptional, ppath, "")
+		}
+		c.compareSchema(ppath, asMap(oprops[name]), asMap(nprops[name]), output)
+	}
+	for _, name := range sortedKeys(nprops) {
+		if _, found := oprops[name]; !found {
+			required := nreq[name] && !output
+			var detail string
+			if required {
+				detail = "required"
+			}
+			c.add(required, PropertyAdded, path+"/"+name, detail)
+		}
+	}
+
+	// maps and arrays
+	opp, npp := asMap(old["patternProperties"]), asMap(next["patternProperties"])
+	for _, pattern := range sortedKeys(opp) {
+		if np, found := npp[pattern]; found {
+			c.compareSchema(path+"/"+pattern, asMap(opp[pattern]), asMap(np), output)
+		} else {
+			c.add(true, PropertyRemoved, path+"/"+pattern, "pattern")
+		}
+	}
+	for _, pattern := range sortedKeys(npp) {
+		if _, found := opp[pattern]; !found {
+			c.add(false, PropertyAdded, path+"/"+pattern, "pattern")
+		}
+	}
+	c.compareSchema(path+"/[]", asMap(old["items"]), asMap(next["items"]), output)
+}
+
+func (c *comparison) compareEnum(path string, old map[string]interface{}, next map[string]interface{}, output bool) {
+	oenum, ohas := old["enum"].([]interface{})
+	nenum, nhas := next["enum"].([]interface{})
+	if !nhas {
+		if ohas {
+			c.add(output, EnumRemoved, path, "any value is accepted")
+		}
+		return
+	}
+	if !ohas {
+		c.add(!output, TypeNarrowed, path, "values are limited to the enum")
+		return
+	}
+	var ovals, nvals = make(map[string]bool), make(map[string]bool)
+	for _, v := range oenum {
+		ovals[fmt.Sprint(v)] = true
+	}
+	for _, v := range nenum {
+		nvals[fmt.Sprint(v)] = true
+	}
+	var lost, gained []string
+	for v := range ovals {
+		if !nvals[v] {
+			lost = append(lost, v)
+		}
+	}
+	for v := range nvals {
+		if !ovals[v] {
+			gained = append(gained, v)
+		}
+	}
+	sort.Strings(lost)
+	sort.Strings(gained)
+	if len(lost) > 0 {
+		c.add(!output, EnumRemoved, path, "lost "+strings.Join(lost, ", "))
+	}
+	if len(gained) > 0 {
+		c.add(output, EnumAdded, path, "gained "+strings.Join(gained, ", "))
+	}
+}
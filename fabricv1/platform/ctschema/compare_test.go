@@ -0,0 +1,157 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+package ctschema
+
+import (
+	"fmt"
+	"testing"
+)
+
+var oldSchemas = `{"API": {
+	"createAsset": {"properties": {"method": "invoke",
+		"args": {"type": "array", "items": {"type": "object", "properties": {"asset": {"$ref": "#/Model/asset"}}}, "minItems": 1, "maxItems": 1}}},
+	"readAsset": {"properties": {"method": "query",
+		"args": {"type": "array", "items": {"type": "object", "properties": {"assetID": {"type": "string"}}}, "minItems": 1, "maxItems": 1},
+		"result": {"type": "object", "properties": {"assetID": {"type": "string"}, "temperature": {"type": "number"}}}}},
+	"deleteAsset": {"properties": {"method": "invoke",
+		"args": {"type": "array", "items": {"type": "object"}, "minItems": 1, "maxItems": 1}}}
+}, "Model": {
+	"asset": {"type": "object", "properties": {
+		"assetID": {"type": "string"},
+		"temperature": {"type": "number"},
+		"status": {"type": "string", "enum": ["ok", "late", "lost"]}}},
+	"alert": {"type": "string"}
+}}`
+
+var newSchemas = `{"API": {
+	"createAsset": {"properties": {"method": "invoke",
+		"args": {"type": "array", "items": {"type": "object", "properties": {"asset": {"$ref": "#/Model/asset"}, "owner": {"type": "string"}}, "required": ["owner"]}, "minItems": 2, "maxItems": 2}}},
+	"readAsset": {"properties": {"method": "query",
+		"args": {"type": "array", "items": {"type": "object", "properties": {"assetID": {"type": "string"}, "at": {"type": "string"}}}, "minItems": 1, "maxItems": 1},
+		"result": {"type": "object", "properties": {"assetID": {"type": ["string", "number"]}, "temperature": {"type": "integer"}}}}},
+	"readAllAssets": {"properties": {"method": "query",
+		"args": {"type": "array", "items": {"type": "object"}, "minItems": 0, "maxItems": 1}}}
+}, "Model": {
+	"asset": {"type": "object", "properties": {
+		"assetID": {"type": "string"},
+		"temperature": {"type": "integer"},
+		"status": {"type": "string", "enum": ["ok", "late", "stolen"]}}}
+}}`
+
+func TestCompare(t *testing.T) {
+	changes, err := Compare([]byte(oldSchemas), []byte(newSchemas))
+	if err != nil {
+		fmt.Printf("*** Compare failed: %s\n", err)
+		t.FailNow()
+	}
+	var expect = []string{
+		"BREAKING      args.count         API/createAsset/args (at least 2 args are required instead of 1)",
+		"BREAKING      property.added     API/createAsset/args/owner (required)",
+		"BREAKING      api.removed        API/deleteAsset",
+		"non-breaking  api.added          API/readAllAssets",
+		"non-breaking  property.added     API/readAsset/args/at",
+		"BREAKING      type.widened       API/readAsset/result/assetID (string became [string number])",
+		"non-breaking  type.narrowed      API/readAsset/result/temperature (number became integer)",
+		"BREAKING      model.removed      Model/alert",
+		"BREAKING      enum.added         Model/asset/status (gained stolen)",
+		"non-breaking  enum.removed       Model/asset/status (lost lost)",
+		"non-breaking  type.narrowed      Model/asset/temperature (number became integer)",
+	}
+	if len(changes) != len(expect) {
+		fmt.Printf("*** expected %d changes, got %d\n", len(expect), len(changes))
+		t.Fail()
+	}
+	for i, c := range changes {
+		if i < len(expect) && c.String() != expect[i] {
+			fmt.Printf("*** change %d expected %q, got %q\n", i, expect[i], c)
+			t.Fail()
+		}
+	}
+	if n := Breaking(changes); n != 6 {
+		fmt.Printf("*** expected 6 breaking changes, got %d\n", n)
+		t.Fail()
+	}
+
+	changes, err = Compare([]byte(oldSchemas), []byte(oldSchemas))
+	if err != nil || len(changes) != 0 {
+		fmt.Printf("*** a schema compared with itself should have no changes, got %v %v\n", changes, err)
+		t.Fail()
+	}
+	if _, err = Compare([]byte(`{"definitions": {}}`), []byte(oldSchemas)); err == nil {
+		fmt.Printf("*** an unresolved schema should be rejected\n")
+		t.Fail()
+	}
+}
+
+// TestCompareVariance checks that the same change breaks writers of args and readers of
+// results the other way round
+func TestCompareVariance(t *testing.T) {
+	schema := func(kind string, level string, code string) string {
+		props := `{"type": "object", "properties": {"kind": ` + kind + `, "level": ` + level + `, "code": ` + code + `}}`
+		return `{"API": {"readStatus": {"properties": {"method": "query",
+			"args": {"type": "array", "items": ` + props + `},
+			"result": ` + props + `}}}}`
+	}
+	old := schema(`{"type": "string", "enum": ["a", "b"]}`, `{"type": "integer"}`, `{"type": "string"}`)
+	next := schema(`{"type": "string"}`, `{"type": "number"}`, `{"type": "string", "enum": ["x"]}`)
+	changes, err := Compare([]byte(old), []byte(next))
+	if err != nil {
+		fmt.Printf("*** Compare failed: %s\n", err)
+		t.FailNow()
+	}
+	var expect = []string{
+		"BREAKING      type.narrowed      API/readStatus/args/code (values are limited to the enum)",
+		"non-breaking  enum.removed       API/readStatus/args/kind (any value is accepted)",
+		"non-breaking  type.widened       API/readStatus/args/level (integer became number)",
+		"non-breaking  type.narrowed      API/readStatus/result/code (values are limited to the enum)",
+		"BREAKING      enum.removed       API/readStatus/result/kind (any value is accepted)",
+		"BREAKING      type.widened       API/readStatus/result/level (integer became number)",
+	}
+	if len(changes) != len(expect) {
+		fmt.Printf("*** expected %d changes, got %v\n", len(expect), changes)
+		t.Fail()
+	}
+	for i, c := range changes {
+		if i < len(expect) && c.String() != expect[i] {
+			fmt.Printf("*** change %d expected %q, got %q\n", i, expect[i], c)
+			t.Fail()
+		}
+	}
+
+	// and in reverse, an output enum that loses values is safe, one that gains them is not
+	changes, err = Compare([]byte(schema(`{"enum": ["a", "b"]}`, `{}`, `{}`)), []byte(schema(`{"enum": ["b", "c"]}`, `{}`, `{}`)))
+	if err != nil {
+		fmt.Printf("*** Compare failed: %s\n", err)
+		t.FailNow()
+	}
+	expect = []string{
+		"non-breaking  enum.added         API/readStatus/args/kind (gained c)",
+		"BREAKING      enum.removed       API/readStatus/args/kind (lost a)",
+		"BREAKING      enum.added         API/readStatus/result/kind (gained c)",
+		"non-breaking  enum.removed       API/readStatus/result/kind (lost a)",
+	}
+	if len(changes) != len(expect) {
+		fmt.Printf("*** expected %d enum changes, got %v\n", len(expect), changes)
+		t.Fail()
+	}
+	for i, c := range changes {
+		if i < len(expect) && c.String() != expect[i] {
+			fmt.Printf("*** enum change %d expected %q, got %q\n", i, expect[i], c)
+			t.Fail()
+		}
+	}
+}
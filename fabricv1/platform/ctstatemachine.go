@@ -0,0 +1,254 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+// v0.1 KL -- asset lifecycle state machines, so that contracts declare their status
+//            transitions instead of hand coding them
+
+package iotcontractplatform
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// AnyState in a transition's From list allows the transition from every state
+const AnyState string = "*"
+
+// Guard is a named RuleFunc that must return nil for a transition to be allowed
+type Guard struct {
+	Name     string
+	Function RuleFunc
+}
+
+// Transition moves an asset from one of the From states to the To state. When Trigger
+// is set, only that function may make the transition, and calling it moves the asset
+// even when the event does not set the status.
+type Transition struct {
+	From    []string `json:"from"`
+	To      string   `json:"to"`
+	Trigger string   `json:"trigger,omitempty"`
+	Guards  []Guard  `json:"-"`
+}
+
+// StateMachine declares the lifecycle of an asset class. QProp is the path of the status
+// property relative to asset state, e.g. "part.status".
+type StateMachine struct {
+	QProp       string       `json:"qprop"`
+	States      []string     `json:"states"`
+	Initial     string       `json:"initial"`
+	Transitions []Transition `json:"transitions"`
+}
+
+// StateTransition is recorded in the asset state that a transition wrote, and so in
+// that state's history
+type StateTransition struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Trigger string `json:"trigger"`
+}
+
+var statemachinerouter = make(map[AssetClass]StateMachine, 0)
+
+// MarshalJSON includes the guard names so that a UI can label the transition
+func (t Transition) MarshalJSON() ([]byte, error) {
+	type transitionOut struct {
+		From    []string `json:"from"`
+		To      string   `json:"to"`
+		Trigger string   `json:"trigger,omitempty"`
+		Guards  []string `json:"guards,omitempty"`
+	}
+	out := transitionOut{From: t.From, To: t.To, Trigger: t.Trigger}
+	for _, g := range t.Guards {
+		out.Guards = append(out.Guards, g.Name)
+	}
+	return json.Marshal(out)
+}
+
+// AddStateMachine allows a class to register its lifecycle. Every state named in a
+// transition must be declared, and the initial state must be one of the states.
+func AddStateMachine(class AssetClass, sm StateMachine) error {
+	if _, found := statemachinerouter[class]; found {
+		err := fmt.Errorf("AddStateMachine: class %s already has a state machine", class.Name)
+		log.Error(err)
+		return err
+	}
+	if sm.QProp == "" {
+		err := fmt.Errorf("AddStateMachine: class %s state machine has no qprop", class.Name)
+		log.Error(err)
+		return err
+	}
+	if !containsString(sm.States, sm.Initial) {
+		err := fmt.Errorf("AddStateMachine: class %s initial state %s is not declared", class.Name, sm.Initial)
+		log.Error(err)
+		return err
+	}
+	for _, t := range sm.Transitions {
+		for _, f := range t.From {
+			if f != AnyState && !containsString(sm.States, f) {
+				err := fmt.Errorf("AddStateMachine: class %s transition from undeclared state %s", class.Name, f)
+				log.Error(err)
+				return err
+			}
+		}
+		if !containsString(sm.States, t.To) {
+			err := fmt.Errorf("AddStateMachine: class %s transition to undeclared state %s", class.Name, t.To)
+			log.Error(err)
+			return err
+		}
+	}
+	statemachinerouter[class] = sm
+	log.Debugf("Class %s added state machine on %s with states %v", class.Name, sm.QProp, sm.States)
+	return nil
+}
+
+func (t Transition) allowsFrom(state string) bool {
+	return containsString(t.From, AnyState) || containsString(t.From, state)
+}
+
+// findTransition returns the transition from one state to another that the caller may
+// make, preferring one that names the caller as its trigger
+func (sm StateMachine) findTransition(from string, to string, caller string) (Transition, bool) {
+	var open Transition
+	var openFound bool
+	for _, t := range sm.Transitions {
+		if t.To != to || !t.allowsFrom(from) {
+			continue
+		}
+		if t.Trigger == caller {
+			return t, true
+		}
+		if t.Trigger == "" && !openFound {
+			open, openFound = t, true
+		}
+	}
+	return open, openFound
+}
+
+// findTriggered returns the transition that the caller triggers from a state
+func (sm StateMachine) findTriggered(from string, caller string) (Transition, bool) {
+	for _, t := range sm.Transitions {
+		if t.Trigger == caller && t.allowsFrom(from) {
+			return t, true
+		}
+	}
+	return Transition{}, false
+}
+
+// applyStateMachine validates or performs the status transition of an asset that is about
+// to be written. prior is the stored status, which is empty for a new asset. Illegal
+// transitions and failed guards return an error; a legal transition is recorded in the
+// asset.
+func (a *Asset) applyStateMachine(stub shim.ChaincodeStubInterface, prior string, caller string) error {
+	a.Transition = nil
+	sm, found := statemachinerouter[a.Class]
+	if !found {
+		return nil
+	}
+	current, found := GetObjectAsString(a.State, sm.QProp)
+	if prior == "" {
+		// a new asset starts in the initial state
+		if !found {
+			PutObject(a.State, sm.QProp, sm.Initial)
+			current = sm.Initial
+		}
+		if current != sm.Initial {
+			err := fmt.Errorf("applyStateMachine: class %s asset %s must be created in state %s, not %s", a.Class.Name, a.AssetKey, sm.Initial, current)
+			log.Error(err)
+			return err
+		}
+		return nil
+	}
+	if !found {
+		// the status may not be removed
+		PutObject(a.State, sm.QProp, prior)
+		current = prior
+	}
+	if !containsString(sm.States, current) {
+		err := fmt.Errorf("applyStateMachine: class %s asset %s state %s is not declared", a.Class.Name, a.AssetKey, current)
+		log.Error(err)
+		return err
+	}
+	var t Transition
+	if current == prior {
+		t, found = sm.findTriggered(prior, caller)
+		if !found {
+			// no transition, e.g. a sensor update
+			return nil
+		}
+		PutObject(a.State, sm.QProp, t.To)
+	} else {
+		t, found = sm.findTransition(prior, current, caller)
+		if !found {
+			err := fmt.Errorf("applyStateMachine: class %s asset %s may not move from %s to %s through %s", a.Class.Name, a.AssetKey, prior, current, caller)
+			log.Error(err)
+			return err
+		}
+	}
+	for _, g := range t.Guards {
+		if err := g.Function(stub, a); err != nil {
+			err = fmt.Errorf("applyStateMachine: class %s asset %s transition from %s to %s rejected by guard %s: %s", a.Class.Name, a.AssetKey, prior, t.To, g.Name, err)
+			log.Error(err)
+			return err
+		}
+	}
+	a.Transition = &StateTransition{From: prior, To: t.To, Trigger: caller}
+	return nil
+}
+
+// priorStatus returns the stored status of an asset for its class's state machine
+func (a *Asset) priorStatus() string {
+	sm, found := statemachinerouter[a.Class]
+	if !found || a.State == nil {
+		return ""
+	}
+	s, _ := GetObjectAsString(a.State, sm.QProp)
+	return s
+}
+
+// readStateMachine returns the state machines of all classes, or of the class passed
+// as {"classname": "..."}, keyed by class name
+var readStateMachine ChaincodeFunc = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	type ClassArg struct {
+		ClassName string `json:"classname"`
+	}
+	var arg ClassArg
+	if len(args) > 0 {
+		if err := json.Unmarshal([]byte(args[0]), &arg); err != nil {
+			err = fmt.Errorf("readStateMachine failed to unmarshal arg: %s", err)
+			log.Error(err)
+			return nil, err
+		}
+	}
+	var out = make(map[string]StateMachine)
+	for c, sm := range statemachinerouter {
+		if arg.ClassName == "" || arg.ClassName == c.Name {
+			out[c.Name] = sm
+		}
+	}
+	if arg.ClassName != "" && len(out) == 0 {
+		err := errors.New("readStateMachine: class " + arg.ClassName + " has no state machine")
+		log.Error(err)
+		return nil, err
+	}
+	return json.Marshal(out)
+}
+
+func init() {
+	AddRoute("readStateMachine", "query", SystemClass, readStateMachine)
+}
@@ -0,0 +1,158 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+package iotcontractplatform
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/ibm-watson-iot/blockchain-samples/fabricv1/platform/cttest"
+)
+
+var smTestClass = AssetClass{Name: "smtest", Prefix: "SMT", AssetIDPath: "part.partID"}
+
+var hasAircraftGuard = Guard{"has aircraft", func(stub shim.ChaincodeStubInterface, a *Asset) error {
+	if _, found := GetObjectAsString(a.State, "part.aircraft"); !found {
+		return errors.New("no aircraft")
+	}
+	return nil
+}}
+
+func init() {
+	AddStateMachine(smTestClass, StateMachine{
+		QProp:   "part.status",
+		States:  []string{"new", "inventory", "installed", "scrapped"},
+		Initial: "new",
+		Transitions: []Transition{
+			{From: []string{"new"}, To: "inventory"},
+			{From: []string{"inventory"}, To: "installed", Trigger: "installPart", Guards: []Guard{hasAircraftGuard}},
+			{From: []string{"installed"}, To: "inventory", Trigger: "removePart"},
+			{From: []string{AnyState}, To: "scrapped"},
+		},
+	})
+	AddRoute("createPart", "invoke", smTestClass, func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+		return smTestClass.CreateAsset(stub, args, "createPart", []QPropNV{})
+	})
+	AddRoute("updatePart", "invoke", smTestClass, func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+		return smTestClass.UpdateAsset(stub, args, "updatePart", []QPropNV{})
+	})
+	AddRoute("installPart", "invoke", smTestClass, func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+		return smTestClass.UpdateAsset(stub, args, "installPart", []QPropNV{})
+	})
+	AddRoute("removePart", "invoke", smTestClass, func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+		return smTestClass.DeletePropertiesFromAsset(stub, args, "removePart", []QPropNV{})
+	})
+	AddRoute("deletePropertiesFromPart", "invoke", smTestClass, func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+		return smTestClass.DeletePropertiesFromAsset(stub, args, "deletePropertiesFromPart", []QPropNV{})
+	})
+}
+
+func newStateMachineTestAsset(status string, aircraft string) *Asset {
+	part := map[string]interface{}{"partID": "P1"}
+	if status != "" {
+		part["status"] = status
+	}
+	if aircraft != "" {
+		part["aircraft"] = aircraft
+	}
+	state := map[string]interface{}{"part": part}
+	a := smTestClass.NewAsset()
+	a.State = &state
+	return &a
+}
+
+func TestStateMachineTransitions(t *testing.T) {
+	var tests = []struct {
+		prior    string
+		status   string
+		aircraft string
+		caller   string
+		want     string
+		ok       bool
+	}{
+		{"", "", "", "createPart", "new", true},
+		{"", "inventory", "", "createPart", "", false},
+		{"new", "inventory", "", "updatePart", "inventory", true},
+		{"new", "installed", "A1", "installPart", "", false},
+		{"inventory", "inventory", "A1", "installPart", "installed", true},
+		{"inventory", "inventory", "", "installPart", "", false},
+		{"inventory", "installed", "A1", "updatePart", "", false},
+		{"installed", "", "", "removePart", "inventory", true},
+		{"installed", "installed", "A1", "updatePart", "installed", true},
+		{"installed", "scrapped", "", "updatePart", "scrapped", true},
+		{"new", "lost", "", "updatePart", "", false},
+	}
+	for _, test := range tests {
+		a := newStateMachineTestAsset(test.status, test.aircraft)
+		err := a.applyStateMachine(nil, test.prior, test.caller)
+		if (err == nil) != test.ok {
+			t.Fail()
+			fmt.Printf("*** transition %+v returned error %v\n", test, err)
+			continue
+		}
+		if !test.ok {
+			continue
+		}
+		if got, _ := GetObjectAsString(a.State, "part.status"); got != test.want {
+			t.Fail()
+			fmt.Printf("*** transition %+v left status %s\n", test, got)
+		}
+		moved := test.prior != "" && test.prior != test.want
+		if moved != (a.Transition != nil) {
+			t.Fail()
+			fmt.Printf("*** transition %+v recorded %+v\n", test, a.Transition)
+		}
+	}
+}
+
+func TestStateMachineDeleteProperties(t *testing.T) {
+	stub := cttest.NewMockStub("smdelete", scenarioChaincode{})
+	stub.MockInit("init", []string{`{"version": "1.0"}`})
+	for _, step := range [][]string{
+		{"createPart", `{"part": {"partID": "P1"}}`},
+		{"updatePart", `{"part": {"partID": "P1", "status": "inventory"}}`},
+		{"installPart", `{"part": {"partID": "P1", "aircraft": "A1"}}`},
+	} {
+		if resp := stub.MockInvoke(step[0], step[1:]); resp.Status != shim.OK {
+			t.Fatalf("%s failed: %s", step[0], resp.Message)
+		}
+	}
+
+	// removing the status is refused like any other write
+	resp := stub.MockInvoke("deletePropertiesFromPart", []string{`{"part": {"partID": "P1"}, "qprops": ["part.status"]}`})
+	var a Asset
+	json.Unmarshal(stub.State["SMTP1"], &a)
+	if status, _ := GetObjectAsString(a.State, "part.status"); resp.Status != shim.OK || status != "installed" {
+		t.Fail()
+		fmt.Printf("*** deleting the status left %s: %s\n", status, resp.Message)
+	}
+
+	// a triggered transition fires on a delete properties route
+	resp = stub.MockInvoke("removePart", []string{`{"part": {"partID": "P1"}, "qprops": ["part.aircraft"]}`})
+	if resp.Status != shim.OK {
+		t.Fatalf("removePart failed: %s", resp.Message)
+	}
+	a = Asset{}
+	json.Unmarshal(stub.State["SMTP1"], &a)
+	if status, _ := GetObjectAsString(a.State, "part.status"); status != "inventory" || a.Transition == nil {
+		t.Fail()
+		fmt.Printf("*** removePart left status %s with transition %+v\n", status, a.Transition)
+	}
+}
@@ -0,0 +1,550 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+// v0.1 KL -- in-memory chaincode stub so that contracts can be tested without a peer
+
+// Package cttest runs platform contracts in process, against an in-memory world state,
+// for go test.
+package cttest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/ledger/queryresult"
+	"github.com/hyperledger/fabric/protos/msp"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+const compositeKeyNamespace = "\x00"
+const minUnicodeRuneValue = 0
+const maxUnicodeRuneValue = utf8.MaxRune
+
+// emptyKeySubstitute is the start of a range with an empty startKey, as on the peer
+const emptyKeySubstitute = "\x01"
+
+var _ shim.ChaincodeStubInterface = (*MockStub)(nil)
+
+// DefaultStartTime is the timestamp of the first transaction of a new MockStub
+var DefaultStartTime = time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// DefaultMSPID is the MSP of the identity that signs transactions until SetCreator is
+// called, as every transaction on a peer has a creator
+const DefaultMSPID = "MockMSP"
+
+var defaultCert []byte
+var defaultCertOnce sync.Once
+
+// attrOID is the X.509 extension in which the fabric CA stores attributes
+var attrOID = asn1.ObjectIdentifier{1, 2, 3, 4, 5, 6, 7, 8, 1}
+
+// NewCertificate returns a self-signed PEM certificate with the common name, OUs and fabric
+// CA attributes, for creators in access control tests
+func NewCertificate(commonName string, ous []string, attrs map[string]string) ([]byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName, OrganizationalUnit: ous},
+		NotBefore:    DefaultStartTime.AddDate(-1, 0, 0),
+		NotAfter:     DefaultStartTime.AddDate(100, 0, 0),
+	}
+	if len(attrs) > 0 {
+		value, err := json.Marshal(map[string]interface{}{"attrs": attrs})
+		if err != nil {
+			return nil, err
+		}
+		template.ExtraExtensions = []pkix.Extension{{Id: attrOID, Value: value}}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), nil
+}
+
+// MockEvent is the chaincode event set by one transaction
+type MockEvent struct {
+	Name    string
+	Payload []byte
+}
+
+// MockStub implements shim.ChaincodeStubInterface in memory. Like a peer, it does not
+// show a transaction its own writes, and it commits them only when the transaction
+// succeeds. Private data is kept per collection in Private, apart from State.
+type MockStub struct {
+	Name      string
+	State     map[string][]byte
+	Private   map[string]map[string][]byte
+	History   map[string][]*queryresult.KeyModification
+	Event     *MockEvent
+	Transient map[string][]byte
+	Step      time.Duration
+
+	cc            shim.Chaincode
+	args          [][]byte
+	txid          string
+	txcount       int
+	now           time.Time
+	creator       []byte
+	writes        map[string][]byte
+	privateWrites map[string]map[string][]byte
+}
+
+// NewMockStub returns an empty world state for a chaincode. Each transaction is one Step
+// after the last, starting at DefaultStartTime, and is signed by a member of DefaultMSPID.
+func NewMockStub(name string, cc shim.Chaincode) *MockStub {
+	s := &MockStub{
+		Name:    name,
+		State:   make(map[string][]byte),
+		Private: make(map[string]map[string][]byte),
+		History: make(map[string][]*queryresult.KeyModification),
+		Step:    time.Second,
+		cc:      cc,
+		now:     DefaultStartTime.Add(-time.Second),
+	}
+	defaultCertOnce.Do(func() {
+		defaultCert, _ = NewCertificate("mockuser", nil, nil)
+	})
+	s.SetCreator(DefaultMSPID, defaultCert)
+	return s
+}
+
+// SetCreator sets the identity that signs the following transactions, certPEM is the
+// caller's enrollment certificate
+func (s *MockStub) SetCreator(mspid string, certPEM []byte) error {
+	creator, err := proto.Marshal(&msp.SerializedIdentity{Mspid: mspid, IdBytes: certPEM})
+	if err != nil {
+		return err
+	}
+	s.creator = creator
+	return nil
+}
+
+// SetTime sets the timestamp of the next transaction
+func (s *MockStub) SetTime(t time.Time) {
+	s.now = t.Add(-s.Step)
+}
+
+func (s *MockStub) begin(function string, args []string) {
+	s.txcount++
+	s.txid = fmt.Sprintf("%s-tx-%06d", s.Name, s.txcount)
+	s.now = s.now.Add(s.Step)
+	s.args = make([][]byte, 0, len(args)+1)
+	s.args = append(s.args, []byte(function))
+	for _, a := range args {
+		s.args = append(s.args, []byte(a))
+	}
+	s.writes = make(map[string][]byte)
+	s.privateWrites = make(map[string]map[string][]byte)
+	s.Event = nil
+}
+
+func (s *MockStub) end(resp pb.Response, commit bool) pb.Response {
+	if commit && resp.Status == shim.OK {
+		keys := make([]string, 0, len(s.writes))
+		for k := range s.writes {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		ts := s.timestamp()
+		for _, k := range keys {
+			v := s.writes[k]
+			if v == nil {
+				delete(s.State, k)
+			} else {
+				s.State[k] = v
+			}
+			s.History[k] = append(s.History[k], &queryresult.KeyModification{TxId: s.txid, Value: v, Timestamp: ts, IsDelete: v == nil})
+		}
+		for c, writes := range s.privateWrites {
+			if s.Private[c] == nil {
+				s.Private[c] = make(map[string][]byte)
+			}
+			for k, v := range writes {
+				if v == nil {
+					delete(s.Private[c], k)
+				} else {
+					s.Private[c][k] = v
+				}
+			}
+		}
+	}
+	s.writes = nil
+	s.privateWrites = nil
+	return resp
+}
+
+// MockInit runs the chaincode's Init as one transaction
+func (s *MockStub) MockInit(function string, args []string) pb.Response {
+	s.begin(function, args)
+	return s.end(s.cc.Init(s), true)
+}
+
+// MockInvoke runs the chaincode's Invoke as one transaction, which commits its writes
+// when it succeeds
+func (s *MockStub) MockInvoke(function string, args []string) pb.Response {
+	s.begin(function, args)
+	return s.end(s.cc.Invoke(s), true)
+}
+
+// MockQuery runs the chaincode's Invoke and discards any writes
+func (s *MockStub) MockQuery(function string, args []string) pb.Response {
+	s.begin(function, args)
+	return s.end(s.cc.Invoke(s), false)
+}
+
+func (s *MockStub) timestamp() *timestamp.Timestamp {
+	return &timestamp.Timestamp{Seconds: s.now.Unix(), Nanos: int32(s.now.Nanosecond())}
+}
+
+// GetArgs returns the function and arguments of the transaction
+func (s *MockStub) GetArgs() [][]byte {
+	return s.args
+}
+
+// GetStringArgs returns the function and arguments as strings
+func (s *MockStub) GetStringArgs() []string {
+	var out = make([]string, 0, len(s.args))
+	for _, a := range s.args {
+		out = append(out, string(a))
+	}
+	return out
+}
+
+// GetFunctionAndParameters splits the function from its arguments
+func (s *MockStub) GetFunctionAndParameters() (string, []string) {
+	all := s.GetStringArgs()
+	if len(all) == 0 {
+		return "", []string{}
+	}
+	return all[0], all[1:]
+}
+
+// GetArgsSlice returns the arguments concatenated
+func (s *MockStub) GetArgsSlice() ([]byte, error) {
+	var out []byte
+	for _, a := range s.args {
+		out = append(out, a...)
+	}
+	return out, nil
+}
+
+// GetTxID returns the ID of the transaction
+func (s *MockStub) GetTxID() string {
+	return s.txid
+}
+
+// GetTxTimestamp returns the time of the transaction
+func (s *MockStub) GetTxTimestamp() (*timestamp.Timestamp, error) {
+	return s.timestamp(), nil
+}
+
+// GetCreator returns the serialized identity set by SetCreator
+func (s *MockStub) GetCreator() ([]byte, error) {
+	return s.creator, nil
+}
+
+// GetTransient returns the transient map
+func (s *MockStub) GetTransient() (map[string][]byte, error) {
+	return s.Transient, nil
+}
+
+// GetBinding is not supported
+func (s *MockStub) GetBinding() ([]byte, error) {
+	return nil, nil
+}
+
+// GetSignedProposal is not supported
+func (s *MockStub) GetSignedProposal() (*pb.SignedProposal, error) {
+	return nil, nil
+}
+
+// InvokeChaincode is not supported
+func (s *MockStub) InvokeChaincode(chaincodeName string, args [][]byte, channel string) pb.Response {
+	return shim.Error("MockStub does not support chaincode to chaincode calls")
+}
+
+// GetState returns the committed value of a key
+func (s *MockStub) GetState(key string) ([]byte, error) {
+	return s.State[key], nil
+}
+
+// PutState records a write for the end of the transaction
+func (s *MockStub) PutState(key string, value []byte) error {
+	if s.writes == nil {
+		return errors.New("PutState called outside of a transaction")
+	}
+	if key == "" {
+		return errors.New("PutState called with an empty key")
+	}
+	if value == nil {
+		value = []byte{}
+	}
+	s.writes[key] = value
+	return nil
+}
+
+// DelState records a delete for the end of the transaction
+func (s *MockStub) DelState(key string) error {
+	if s.writes == nil {
+		return errors.New("DelState called outside of a transaction")
+	}
+	s.writes[key] = nil
+	return nil
+}
+
+// GetStateByRange returns committed keys from startKey inclusive to endKey exclusive in
+// key order, an empty endKey is unbounded. As on the peer, the range holds simple keys
+// only, an empty startKey begins after the composite keys and a composite bound fails.
+func (s *MockStub) GetStateByRange(startKey string, endKey string) (shim.StateQueryIteratorInterface, error) {
+	if err := validateSimpleKeys(startKey, endKey); err != nil {
+		return nil, err
+	}
+	if startKey == "" {
+		startKey = emptyKeySubstitute
+	}
+	return rangeKeys(s.State, startKey, endKey), nil
+}
+
+func rangeKeys(values map[string][]byte, startKey string, endKey string) *mockIterator {
+	var keys = make([]string, 0)
+	for k := range values {
+		if k >= startKey && (endKey == "" || k < endKey) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return &mockIterator{values, keys, 0}
+}
+
+func validateSimpleKeys(keys ...string) error {
+	for _, k := range keys {
+		if strings.HasPrefix(k, compositeKeyNamespace) {
+			return fmt.Errorf("first character of the key [%s] contains a null character which is not allowed", k)
+		}
+	}
+	return nil
+}
+
+// GetStateByPartialCompositeKey returns the committed composite keys that start with
+// the given attributes
+func (s *MockStub) GetStateByPartialCompositeKey(objectType string, attributes []string) (shim.StateQueryIteratorInterface, error) {
+	prefix, err := s.CreateCompositeKey(objectType, attributes)
+	if err != nil {
+		return nil, err
+	}
+	return rangeKeys(s.State, prefix, prefix+string(maxUnicodeRuneValue)), nil
+}
+
+// CreateCompositeKey builds a key the same way as the peer
+func (s *MockStub) CreateCompositeKey(objectType string, attributes []string) (string, error) {
+	if err := validateCompositeKeyAttribute(objectType); err != nil {
+		return "", err
+	}
+	ck := compositeKeyNamespace + objectType + string(rune(minUnicodeRuneValue))
+	for _, att := range attributes {
+		if err := validateCompositeKeyAttribute(att); err != nil {
+			return "", err
+		}
+		ck += att + string(rune(minUnicodeRuneValue))
+	}
+	return ck, nil
+}
+
+// SplitCompositeKey returns the object type and attributes of a composite key
+func (s *MockStub) SplitCompositeKey(compositeKey string) (string, []string, error) {
+	var components = make([]string, 0)
+	componentIndex := 1
+	for i := 1; i < len(compositeKey); i++ {
+		if compositeKey[i] == minUnicodeRuneValue {
+			components = append(components, compositeKey[componentIndex:i])
+			componentIndex = i + 1
+		}
+	}
+	if len(components) == 0 {
+		return "", nil, fmt.Errorf("%s is not a composite key", compositeKey)
+	}
+	return components[0], components[1:], nil
+}
+
+func validateCompositeKeyAttribute(str string) error {
+	if !utf8.ValidString(str) {
+		return fmt.Errorf("not a valid utf8 string: [%x]", str)
+	}
+	for index, runeValue := range str {
+		if runeValue == minUnicodeRuneValue || runeValue == maxUnicodeRuneValue {
+			return fmt.Errorf("input contains unicode %#U starting at position [%d], which is reserved", runeValue, index)
+		}
+	}
+	return nil
+}
+
+// GetQueryResult is not supported, rich queries need CouchDB
+func (s *MockStub) GetQueryResult(query string) (shim.StateQueryIteratorInterface, error) {
+	return nil, errors.New("MockStub does not support rich queries")
+}
+
+// GetHistoryForKey returns the committed modifications of a key, oldest first
+func (s *MockStub) GetHistoryForKey(key string) (shim.HistoryQueryIteratorInterface, error) {
+	return &mockHistoryIterator{s.History[key], 0}, nil
+}
+
+// SetEvent records the event, a later call in the same transaction replaces it
+func (s *MockStub) SetEvent(name string, payload []byte) error {
+	if name == "" {
+		return errors.New("event name can not be empty")
+	}
+	s.Event = &MockEvent{name, payload}
+	return nil
+}
+
+// GetPrivateData returns the committed value of a key in a collection
+func (s *MockStub) GetPrivateData(collection string, key string) ([]byte, error) {
+	return s.Private[collection][key], nil
+}
+
+// PutPrivateData records a write to a collection for the end of the transaction
+func (s *MockStub) PutPrivateData(collection string, key string, value []byte) error {
+	if s.privateWrites == nil {
+		return errors.New("PutPrivateData called outside of a transaction")
+	}
+	if collection == "" || key == "" {
+		return errors.New("PutPrivateData called with an empty collection or key")
+	}
+	if value == nil {
+		value = []byte{}
+	}
+	if s.privateWrites[collection] == nil {
+		s.privateWrites[collection] = make(map[string][]byte)
+	}
+	s.privateWrites[collection][key] = value
+	return nil
+}
+
+// DelPrivateData records a delete from a collection for the end of the transaction
+func (s *MockStub) DelPrivateData(collection string, key string) error {
+	if s.privateWrites == nil {
+		return errors.New("DelPrivateData called outside of a transaction")
+	}
+	if s.privateWrites[collection] == nil {
+		s.privateWrites[collection] = make(map[string][]byte)
+	}
+	s.privateWrites[collection][key] = nil
+	return nil
+}
+
+// GetPrivateDataByRange returns committed simple keys of a collection from startKey
+// inclusive to endKey exclusive in key order
+func (s *MockStub) GetPrivateDataByRange(collection string, startKey string, endKey string) (shim.StateQueryIteratorInterface, error) {
+	if err := validateSimpleKeys(startKey, endKey); err != nil {
+		return nil, err
+	}
+	if startKey == "" {
+		startKey = emptyKeySubstitute
+	}
+	return rangeKeys(s.Private[collection], startKey, endKey), nil
+}
+
+// GetPrivateDataByPartialCompositeKey returns the committed composite keys of a collection
+// that start with the given attributes
+func (s *MockStub) GetPrivateDataByPartialCompositeKey(collection string, objectType string, attributes []string) (shim.StateQueryIteratorInterface, error) {
+	prefix, err := s.CreateCompositeKey(objectType, attributes)
+	if err != nil {
+		return nil, err
+	}
+	return rangeKeys(s.Private[collection], prefix, prefix+string(maxUnicodeRuneValue)), nil
+}
+
+// GetPrivateDataQueryResult is not supported, rich queries need CouchDB
+func (s *MockStub) GetPrivateDataQueryResult(collection string, query string) (shim.StateQueryIteratorInterface, error) {
+	return nil, errors.New("MockStub does not support rich queries")
+}
+
+// Keys returns the committed keys that start with prefix, in key order
+func (s *MockStub) Keys(prefix string) []string {
+	var keys = make([]string, 0)
+	for k := range s.State {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+type mockIterator struct {
+	values map[string][]byte
+	keys   []string
+	posn   int
+}
+
+func (m *mockIterator) HasNext() bool {
+	return m.posn < len(m.keys)
+}
+
+func (m *mockIterator) Next() (string, []byte, error) {
+	if !m.HasNext() {
+		return "", nil, errors.New("mockIterator: no more results")
+	}
+	k := m.keys[m.posn]
+	m.posn++
+	return k, m.values[k], nil
+}
+
+func (m *mockIterator) Close() error {
+	return nil
+}
+
+type mockHistoryIterator struct {
+	mods []*queryresult.KeyModification
+	posn int
+}
+
+func (m *mockHistoryIterator) HasNext() bool {
+	return m.posn < len(m.mods)
+}
+
+func (m *mockHistoryIterator) Next() (*queryresult.KeyModification, error) {
+	if !m.HasNext() {
+		return nil, errors.New("mockHistoryIterator: no more results")
+	}
+	m.posn++
+	return m.mods[m.posn-1], nil
+}
+
+func (m *mockHistoryIterator) Close() error {
+	return nil
+}
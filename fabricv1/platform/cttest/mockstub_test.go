@@ -0,0 +1,123 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+package cttest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// kvChaincode puts each pair of args, or fails after writing when the function is "fail"
+type kvChaincode struct{}
+
+func (kvChaincode) Init(stub shim.ChaincodeStubInterface) pb.Response {
+	return shim.Success(nil)
+}
+
+func (kvChaincode) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
+	function, args := stub.GetFunctionAndParameters()
+	for i := 0; i+1 < len(args); i += 2 {
+		if err := stub.PutState(args[i], []byte(args[i+1])); err != nil {
+			return shim.Error(err.Error())
+		}
+		if v, _ := stub.GetState(args[i]); v != nil {
+			return shim.Error("a transaction must not read its own writes")
+		}
+	}
+	if function == "fail" {
+		return shim.Error("failed on purpose")
+	}
+	return shim.Success(nil)
+}
+
+func TestMockStubTransactions(t *testing.T) {
+	stub := NewMockStub("kv", kvChaincode{})
+	if resp := stub.MockInvoke("put", []string{"A1", "one", "A2", "two", "B1", "three"}); resp.Status != shim.OK {
+		t.Fatalf("put failed: %s", resp.Message)
+	}
+	if resp := stub.MockInvoke("fail", []string{"A3", "four"}); resp.Status == shim.OK {
+		t.Fatal("fail succeeded")
+	}
+	if _, found := stub.State["A3"]; found {
+		t.Fail()
+		fmt.Printf("*** writes of a failed transaction were committed\n")
+	}
+	iter, _ := stub.GetStateByRange("A", "B")
+	var keys []string
+	for iter.HasNext() {
+		k, _, _ := iter.Next()
+		keys = append(keys, k)
+	}
+	if fmt.Sprint(keys) != "[A1 A2]" {
+		t.Fail()
+		fmt.Printf("*** range returned %v\n", keys)
+	}
+	ts, _ := stub.GetTxTimestamp()
+	if ts.Seconds != DefaultStartTime.Unix()+1 {
+		t.Fail()
+		fmt.Printf("*** second transaction time is %v\n", ts)
+	}
+}
+
+func TestMockStubCompositeKeys(t *testing.T) {
+	stub := NewMockStub("ck", kvChaincode{})
+	var args []string
+	for _, attrs := range [][]string{{"kit", "K1"}, {"kit", "K2"}, {"pallet", "P1"}} {
+		ck, err := stub.CreateCompositeKey("IDX", attrs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		args = append(args, ck, "x")
+	}
+	stub.MockInvoke("put", args)
+	iter, _ := stub.GetStateByPartialCompositeKey("IDX", []string{"kit"})
+	var ids []string
+	for iter.HasNext() {
+		k, _, _ := iter.Next()
+		objectType, attrs, err := stub.SplitCompositeKey(k)
+		if err != nil || objectType != "IDX" || len(attrs) != 2 {
+			t.Fail()
+			fmt.Printf("*** split %q returned %s %v %v\n", k, objectType, attrs, err)
+			continue
+		}
+		ids = append(ids, attrs[1])
+	}
+	if fmt.Sprint(ids) != "[K1 K2]" {
+		t.Fail()
+		fmt.Printf("*** partial composite key returned %v\n", ids)
+	}
+
+	// plain ranges hold simple keys only
+	stub.MockInvoke("put", []string{"A1", "one"})
+	iter, _ = stub.GetStateByRange("", "")
+	var keys []string
+	for iter.HasNext() {
+		k, _, _ := iter.Next()
+		keys = append(keys, k)
+	}
+	if fmt.Sprint(keys) != "[A1]" {
+		t.Fail()
+		fmt.Printf("*** open range returned %q\n", keys)
+	}
+	if _, err := stub.GetStateByRange(args[0], ""); err == nil {
+		t.Fail()
+		fmt.Println("*** range from a composite key accepted")
+	}
+}
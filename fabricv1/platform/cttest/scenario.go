@@ -0,0 +1,363 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+// v0.1 KL -- scenarios, a sequence of invokes and queries with expected state, alerts
+//            and events, written in YAML or JSON
+
+package cttest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"gopkg.in/yaml.v2"
+)
+
+// Creator is the identity that signs a step, the certificate is PEM text or a file
+// relative to the scenario. Without either, a certificate is made with the common name,
+// OUs and attributes.
+type Creator struct {
+	MSPID      string            `json:"mspid"`
+	Cert       string            `json:"cert"`
+	CertFile   string            `json:"certfile"`
+	CN         string            `json:"cn"`
+	OUs        []string          `json:"ous"`
+	Attributes map[string]string `json:"attributes"`
+}
+
+// Expect holds the checks made after a step. Result, Event and Assets are matched as
+// subsets: every property named must be present and equal, others are ignored. Alerts
+// lists the exact active alerts of each asset key. EventName is the exact name of the
+// chaincode event.
+type Expect struct {
+	Status    string                 `json:"status"`
+	Error     string                 `json:"error"`
+	Result    interface{}            `json:"result"`
+	Event     map[string]interface{} `json:"event"`
+	EventName string                 `json:"eventname"`
+	Assets    map[string]interface{} `json:"assets"`
+	Alerts    map[string][]string    `json:"alerts"`
+	Absent    []string               `json:"absent"`
+}
+
+// Step is one invoke or query. Args that are objects are passed as their JSON text.
+type Step struct {
+	Name    string        `json:"name"`
+	Invoke  string        `json:"invoke"`
+	Query   string        `json:"query"`
+	Args    []interface{} `json:"args"`
+	Time    string        `json:"time"`
+	Creator *Creator      `json:"creator"`
+	Expect  Expect        `json:"expect"`
+}
+
+// Scenario deploys a contract with the version and nickname, then runs the steps in order
+type Scenario struct {
+	Name     string   `json:"name"`
+	Version  string   `json:"version"`
+	Nickname string   `json:"nickname"`
+	Creator  *Creator `json:"creator"`
+	Steps    []Step   `json:"steps"`
+
+	dir string
+}
+
+// LoadScenario reads a scenario from a .yaml, .yml or .json file
+func LoadScenario(path string) (*Scenario, error) {
+	var sc Scenario
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		var y interface{}
+		if err = yaml.Unmarshal(b, &y); err != nil {
+			return nil, fmt.Errorf("scenario %s: %s", path, err)
+		}
+		if b, err = json.Marshal(fromYAML(y)); err != nil {
+			return nil, fmt.Errorf("scenario %s: %s", path, err)
+		}
+	}
+	if err = json.Unmarshal(b, &sc); err != nil {
+		return nil, fmt.Errorf("scenario %s: %s", path, err)
+	}
+	sc.dir = filepath.Dir(path)
+	return &sc, nil
+}
+
+// fromYAML converts the maps that yaml produces into maps that JSON can marshal
+func fromYAML(y interface{}) interface{} {
+	switch v := y.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[fmt.Sprint(k)] = fromYAML(e)
+		}
+		return m
+	case []interface{}:
+		for i, e := range v {
+			v[i] = fromYAML(e)
+		}
+		return v
+	}
+	return y
+}
+
+// RunScenario runs a scenario file against a fresh world state and reports each failed
+// expectation
+func RunScenario(t testing.TB, cc shim.Chaincode, path string) {
+	sc, err := LoadScenario(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range sc.Run(NewMockStub(sc.Name, cc)) {
+		t.Errorf("%s: %s", filepath.Base(path), f)
+	}
+}
+
+// Run deploys the contract into the stub and runs the steps, returning the failures
+func (sc *Scenario) Run(stub *MockStub) []string {
+	var failures = make([]string, 0)
+	if sc.Creator != nil {
+		if err := sc.setCreator(stub, sc.Creator); err != nil {
+			return append(failures, err.Error())
+		}
+	}
+	initArg, _ := json.Marshal(map[string]string{"version": sc.Version, "nickname": sc.Nickname})
+	if resp := stub.MockInit("init", []string{string(initArg)}); resp.Status != shim.OK {
+		return append(failures, "init failed: "+resp.Message)
+	}
+	for i, step := range sc.Steps {
+		name := fmt.Sprintf("step %d", i+1)
+		if step.Name != "" {
+			name += " (" + step.Name + ")"
+		}
+		for _, f := range sc.runStep(stub, step) {
+			failures = append(failures, name+": "+f)
+		}
+	}
+	return failures
+}
+
+func (sc *Scenario) setCreator(stub *MockStub, c *Creator) error {
+	cert := []byte(c.Cert)
+	if c.CertFile != "" {
+		var err error
+		if cert, err = ioutil.ReadFile(filepath.Join(sc.dir, c.CertFile)); err != nil {
+			return fmt.Errorf("creator certificate: %s", err)
+		}
+	}
+	if len(cert) == 0 {
+		var err error
+		if cert, err = NewCertificate(c.CN, c.OUs, c.Attributes); err != nil {
+			return fmt.Errorf("creator certificate: %s", err)
+		}
+	}
+	return stub.SetCreator(c.MSPID, cert)
+}
+
+func stepArgs(args []interface{}) ([]string, error) {
+	var out = make([]string, 0, len(args))
+	for _, a := range args {
+		if s, ok := a.(string); ok {
+			out = append(out, s)
+			continue
+		}
+		b, err := json.Marshal(a)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, string(b))
+	}
+	return out, nil
+}
+
+func (sc *Scenario) runStep(stub *MockStub, step Step) []string {
+	var failures = make([]string, 0)
+	fail := func(format string, a ...interface{}) {
+		failures = append(failures, fmt.Sprintf(format, a...))
+	}
+	if (step.Invoke == "") == (step.Query == "") {
+		fail("a step must name exactly one of invoke or query")
+		return failures
+	}
+	if step.Time != "" {
+		t, err := time.Parse(time.RFC3339Nano, step.Time)
+		if err != nil {
+			fail("time %s: %s", step.Time, err)
+			return failures
+		}
+		stub.SetTime(t)
+	}
+	if step.Creator != nil {
+		if err := sc.setCreator(stub, step.Creator); err != nil {
+			fail("%s", err)
+			return failures
+		}
+	}
+	args, err := stepArgs(step.Args)
+	if err != nil {
+		fail("args: %s", err)
+		return failures
+	}
+
+	var resp pb.Response
+	if step.Invoke != "" {
+		resp = stub.MockInvoke(step.Invoke, args)
+	} else {
+		resp = stub.MockQuery(step.Query, args)
+	}
+
+	want := step.Expect.Status
+	if want == "" {
+		want = "OK"
+		if step.Expect.Error != "" {
+			want = "ERROR"
+		}
+	}
+	got := "OK"
+	if resp.Status != shim.OK {
+		got = "ERROR"
+	}
+	if got != want {
+		fail("status %s, expected %s: %s", got, want, resp.Message)
+		return failures
+	}
+	if step.Expect.Error != "" && !strings.Contains(resp.Message, step.Expect.Error) {
+		fail("error %q does not contain %q", resp.Message, step.Expect.Error)
+	}
+	if step.Expect.Result != nil {
+		var result interface{}
+		if err := json.Unmarshal(resp.Payload, &result); err != nil {
+			fail("result is not JSON: %s", err)
+		} else {
+			failures = append(failures, match("result", step.Expect.Result, result)...)
+		}
+	}
+	if step.Expect.Event != nil {
+		var event map[string]interface{}
+		if stub.Event == nil {
+			fail("no event was set")
+		} else if err := json.Unmarshal(stub.Event.Payload, &event); err != nil {
+			fail("event is not JSON: %s", err)
+		} else {
+			failures = append(failures, match("event", step.Expect.Event, event)...)
+		}
+	}
+	if step.Expect.EventName != "" {
+		if stub.Event == nil {
+			fail("no event was set")
+		} else if stub.Event.Name != step.Expect.EventName {
+			fail("event name is %s, expected %s", stub.Event.Name, step.Expect.EventName)
+		}
+	}
+	for _, key := range sortedKeys(step.Expect.Assets) {
+		a, found := readAsset(stub, key)
+		if !found {
+			fail("asset %s does not exist", key)
+			continue
+		}
+		failures = append(failures, match(key, step.Expect.Assets[key], a)...)
+	}
+	for key, alerts := range step.Expect.Alerts {
+		a, found := readAsset(stub, key)
+		if !found {
+			fail("asset %s does not exist", key)
+			continue
+		}
+		var active = make([]string, 0)
+		if aa, ok := a["alerts"].([]interface{}); ok {
+			for _, alert := range aa {
+				active = append(active, fmt.Sprint(alert))
+			}
+		}
+		wantAlerts := append([]string{}, alerts...)
+		sort.Strings(active)
+		sort.Strings(wantAlerts)
+		if !reflect.DeepEqual(active, wantAlerts) {
+			fail("asset %s active alerts %v, expected %v", key, active, wantAlerts)
+		}
+	}
+	for _, key := range step.Expect.Absent {
+		if len(stub.State[key]) > 0 {
+			fail("key %s exists", key)
+		}
+	}
+	return failures
+}
+
+func readAsset(stub *MockStub, key string) (map[string]interface{}, bool) {
+	var a map[string]interface{}
+	b := stub.State[key]
+	if len(b) == 0 || json.Unmarshal(b, &a) != nil {
+		return nil, false
+	}
+	return a, true
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	var keys = make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// match compares expected with actual, objects as subsets and arrays element by element
+func match(path string, want interface{}, got interface{}) []string {
+	switch w := want.(type) {
+	case map[string]interface{}:
+		g, ok := got.(map[string]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s is %v, expected an object", path, got)}
+		}
+		var failures = make([]string, 0)
+		for _, k := range sortedKeys(w) {
+			v, found := g[k]
+			if !found {
+				failures = append(failures, fmt.Sprintf("%s.%s is missing", path, k))
+				continue
+			}
+			failures = append(failures, match(path+"."+k, w[k], v)...)
+		}
+		return failures
+	case []interface{}:
+		g, ok := got.([]interface{})
+		if !ok || len(g) != len(w) {
+			return []string{fmt.Sprintf("%s is %v, expected %v", path, got, want)}
+		}
+		var failures = make([]string, 0)
+		for i := range w {
+			failures = append(failures, match(fmt.Sprintf("%s[%d]", path, i), w[i], g[i])...)
+		}
+		return failures
+	}
+	if !reflect.DeepEqual(want, got) {
+		return []string{fmt.Sprintf("%s is %v, expected %v", path, got, want)}
+	}
+	return nil
+}
@@ -0,0 +1,495 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+// v0.1 KL -- runtime validation of incoming events against the generated contract schema
+
+package iotcontractplatform
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// Validation modes, strict rejects an invalid event, lenient logs the violations and
+// accepts the event, off skips validation
+const (
+	ValidationStrict  = "strict"
+	ValidationLenient = "lenient"
+	ValidationOff     = "off"
+)
+
+// VALIDATIONMODEKEY is prepended to a class name to store a mode that overrides the
+// mode with which the class registered its schema
+const VALIDATIONMODEKEY string = "IOTCP:ValidationMode:"
+
+// SchemaViolation is one failed constraint, the path is qualified from the root of the
+// event, e.g. "surgicalkit.hospital.address.city"
+type SchemaViolation struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every violation found in an event, its text is JSON so that
+// clients can pick out the paths from the invoke result event
+type ValidationError struct {
+	Class      string            `json:"class"`
+	Function   string            `json:"function"`
+	Violations []SchemaViolation `json:"violations"`
+}
+
+func (e *ValidationError) Error() string {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Sprintf("schema validation failed for class %s function %s: %+v", e.Class, e.Function, e.Violations)
+	}
+	return string(b)
+}
+
+type classValidation struct {
+	Mode string
+	Root map[string]interface{}
+	API  map[string]interface{}
+}
+
+var validationrouter = make(map[AssetClass]classValidation, 0)
+
+// AddValidation registers the schema that processSchema generated for the contract, so
+// that events sent to the class's create, replace and update functions are validated
+// against the args of the function with the same name as the caller. Mode is one of
+// strict, lenient or off.
+func AddValidation(class AssetClass, schemas string, mode string) error {
+	if _, found := validationrouter[class]; found {
+		err := fmt.Errorf("AddValidation: class %s already has a registered schema", class.Name)
+		log.Error(err)
+		return err
+	}
+	if !isValidationMode(mode) {
+		err := fmt.Errorf("AddValidation: class %s unknown mode %s", class.Name, mode)
+		log.Error(err)
+		return err
+	}
+	var root map[string]interface{}
+	if err := json.Unmarshal([]byte(schemas), &root); err != nil {
+		err = fmt.Errorf("AddValidation: class %s schema does not unmarshal: %s", class.Name, err)
+		log.Error(err)
+		return err
+	}
+	api, found := GetObjectAsMap(&root, "API")
+	if !found {
+		err := fmt.Errorf("AddValidation: class %s schema has no API section", class.Name)
+		log.Error(err)
+		return err
+	}
+	validationrouter[class] = classValidation{mode, root, api}
+	log.Debugf("Class %s added schema validation in mode %s", class.Name, mode)
+	return nil
+}
+
+func isValidationMode(mode string) bool {
+	return mode == ValidationStrict || mode == ValidationLenient || mode == ValidationOff
+}
+
+// validationMode returns the mode stored in world state for the class, or the mode
+// with which the class registered its schema
+func (c AssetClass) validationMode(stub shim.ChaincodeStubInterface, cv classValidation) string {
+	modeBytes, err := stub.GetState(VALIDATIONMODEKEY + c.Name)
+	if err != nil || len(modeBytes) == 0 {
+		return cv.Mode
+	}
+	return string(modeBytes)
+}
+
+// validateEvent checks an incoming event against the args schema of the calling function.
+// Partial events, as sent to update functions, are not checked for required properties.
+func (c AssetClass) validateEvent(stub shim.ChaincodeStubInterface, caller string, event *map[string]interface{}, partial bool) error {
+	cv, found := validationrouter[c]
+	if !found {
+		return nil
+	}
+	mode := c.validationMode(stub, cv)
+	if mode == ValidationOff {
+		return nil
+	}
+	schema, found := GetObjectAsMap(&cv.API, caller+".properties.args.items")
+	if !found {
+		log.Warningf("validateEvent: class %s has no args schema for function %s", c.Name, caller)
+		return nil
+	}
+	v := validator{root: cv.Root, partial: partial}
+	v.validate("", *event, schema)
+	if len(v.violations) == 0 {
+		return nil
+	}
+	err := &ValidationError{c.Name, caller, v.violations}
+	if mode == ValidationLenient {
+		log.Warningf("validateEvent accepted invalid event in lenient mode: %s", err)
+		return nil
+	}
+	log.Error(err)
+	return err
+}
+
+// validator implements the draft-04 keywords that appear in contract schemas
+type validator struct {
+	root       map[string]interface{}
+	partial    bool
+	violations []SchemaViolation
+}
+
+func (v *validator) fail(path string, format string, args ...interface{}) {
+	v.violations = append(v.violations, SchemaViolation{path, fmt.Sprintf(format, args...)})
+}
+
+func joinPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// resolve follows a local reference such as "#/definitions/Model/geo"
+func (v *validator) resolve(schema map[string]interface{}) map[string]interface{} {
+	for i := 0; i < 32; i++ {
+		ref, found := schema["$ref"].(string)
+		if !found || !strings.HasPrefix(ref, "#/") {
+			return schema
+		}
+		var o interface{} = v.root
+		for _, p := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			m, ok := o.(map[string]interface{})
+			if !ok {
+				return schema
+			}
+			o = m[p]
+		}
+		next, ok := o.(map[string]interface{})
+		if !ok {
+			return schema
+		}
+		schema = next
+	}
+	return schema
+}
+
+func (v *validator) validate(path string, value interface{}, schema map[string]interface{}) {
+	schema = v.resolve(schema)
+	if !v.validateType(path, value, schema["type"]) {
+		return
+	}
+	if enum, found := schema["enum"].([]interface{}); found {
+		v.validateEnum(path, value, enum)
+	}
+	for _, s := range asSchemaArray(schema["allOf"]) {
+		v.validate(path, value, s)
+	}
+	if anyOf := asSchemaArray(schema["anyOf"]); len(anyOf) > 0 && v.countMatches(path, value, anyOf) == 0 {
+		v.fail(path, "does not match any schema in anyOf")
+	}
+	if oneOf := asSchemaArray(schema["oneOf"]); len(oneOf) > 0 {
+		if n := v.countMatches(path, value, oneOf); n != 1 {
+			v.fail(path, "matches %d schemas in oneOf, expected exactly 1", n)
+		}
+	}
+	if not, found := schema["not"].(map[string]interface{}); found && v.countMatches(path, value, []map[string]interface{}{not}) == 1 {
+		v.fail(path, "matches the schema in not")
+	}
+	switch t := value.(type) {
+	case map[string]interface{}:
+		v.validateObject(path, t, schema)
+	case []interface{}:
+		v.validateArray(path, t, schema)
+	case string:
+		v.validateString(path, t, schema)
+	case float64:
+		v.validateNumber(path, t, schema)
+	}
+}
+
+// countMatches validates against each schema in isolation and counts the successes
+func (v *validator) countMatches(path string, value interface{}, schemas []map[string]interface{}) int {
+	n := 0
+	for _, s := range schemas {
+		sub := validator{root: v.root, partial: v.partial}
+		sub.validate(path, value, s)
+		if len(sub.violations) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func asSchemaArray(o interface{}) []map[string]interface{} {
+	arr, found := o.([]interface{})
+	if !found {
+		return nil
+	}
+	var schemas = make([]map[string]interface{}, 0, len(arr))
+	for _, s := range arr {
+		if m, ok := s.(map[string]interface{}); ok {
+			schemas = append(schemas, m)
+		}
+	}
+	return schemas
+}
+
+func jsonTypeOf(value interface{}) string {
+	switch t := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if t == math.Trunc(t) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return "unknown"
+}
+
+func (v *validator) validateType(path string, value interface{}, t interface{}) bool {
+	var types []string
+	switch tt := t.(type) {
+	case nil:
+		return true
+	case string:
+		types = []string{tt}
+	case []interface{}:
+		for _, s := range tt {
+			if str, ok := s.(string); ok {
+				types = append(types, str)
+			}
+		}
+	default:
+		return true
+	}
+	actual := jsonTypeOf(value)
+	for _, s := range types {
+		if s == actual || (s == "number" && actual == "integer") {
+			return true
+		}
+	}
+	v.fail(path, "expected type %s, found %s", strings.Join(types, " or "), actual)
+	return false
+}
+
+func (v *validator) validateEnum(path string, value interface{}, enum []interface{}) {
+	for _, e := range enum {
+		if reflect.DeepEqual(e, value) {
+			return
+		}
+	}
+	v.fail(path, "value %v is not one of %v", value, enum)
+}
+
+func (v *validator) validateObject(path string, obj map[string]interface{}, schema map[string]interface{}) {
+	props, _ := schema["properties"].(map[string]interface{})
+	patterns, _ := schema["patternProperties"].(map[string]interface{})
+	if !v.partial {
+		if required, found := schema["required"].([]interface{}); found {
+			for _, r := range required {
+				if name, ok := r.(string); ok {
+					if _, present := obj[name]; !present {
+						v.fail(joinPath(path, name), "required property is missing")
+					}
+				}
+			}
+		}
+		if min, found := schema["minProperties"].(float64); found && float64(len(obj)) < min {
+			v.fail(path, "has %d properties, minimum is %v", len(obj), min)
+		}
+	}
+	if max, found := schema["maxProperties"].(float64); found && float64(len(obj)) > max {
+		v.fail(path, "has %d properties, maximum is %v", len(obj), max)
+	}
+	// sorted so that violations are reported in a stable order
+	var names = make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := obj[name]
+		matched := false
+		if ps, found := props[name].(map[string]interface{}); found {
+			v.validate(joinPath(path, name), value, ps)
+			matched = true
+		} else if _, found := props[name]; found {
+			// not a schema, e.g. "method": "invoke"
+			matched = true
+		}
+		for pattern, s := range patterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil || !re.MatchString(name) {
+				continue
+			}
+			matched = true
+			if ps, ok := s.(map[string]interface{}); ok {
+				v.validate(joinPath(path, name), value, ps)
+			}
+		}
+		if matched {
+			continue
+		}
+		switch ap := schema["additionalProperties"].(type) {
+		case bool:
+			if !ap {
+				v.fail(joinPath(path, name), "property is not allowed")
+			}
+		case map[string]interface{}:
+			v.validate(joinPath(path, name), value, ap)
+		}
+	}
+}
+
+func (v *validator) validateArray(path string, arr []interface{}, schema map[string]interface{}) {
+	if min, found := schema["minItems"].(float64); found && float64(len(arr)) < min {
+		v.fail(path, "has %d items, minimum is %v", len(arr), min)
+	}
+	if max, found := schema["maxItems"].(float64); found && float64(len(arr)) > max {
+		v.fail(path, "has %d items, maximum is %v", len(arr), max)
+	}
+	if unique, _ := schema["uniqueItems"].(bool); unique {
+		for i := range arr {
+			for j := i + 1; j < len(arr); j++ {
+				if reflect.DeepEqual(arr[i], arr[j]) {
+					v.fail(path, "items %d and %d are equal", i, j)
+				}
+			}
+		}
+	}
+	switch items := schema["items"].(type) {
+	case map[string]interface{}:
+		for i, e := range arr {
+			v.validate(fmt.Sprintf("%s[%d]", path, i), e, items)
+		}
+	case []interface{}:
+		tuple := asSchemaArray(items)
+		for i, e := range arr {
+			if i < len(tuple) {
+				v.validate(fmt.Sprintf("%s[%d]", path, i), e, tuple[i])
+			} else if add, ok := schema["additionalItems"].(bool); ok && !add {
+				v.fail(fmt.Sprintf("%s[%d]", path, i), "item is not allowed")
+			}
+		}
+	}
+}
+
+func (v *validator) validateString(path string, s string, schema map[string]interface{}) {
+	n := float64(len([]rune(s)))
+	if min, found := schema["minLength"].(float64); found && n < min {
+		v.fail(path, "length %v is less than minimum %v", n, min)
+	}
+	if max, found := schema["maxLength"].(float64); found && n > max {
+		v.fail(path, "length %v is greater than maximum %v", n, max)
+	}
+	if pattern, found := schema["pattern"].(string); found {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			log.Warningf("validateString: schema pattern %s at %s does not compile: %s", pattern, path, err)
+		} else if !re.MatchString(s) {
+			v.fail(path, "value %s does not match pattern %s", s, pattern)
+		}
+	}
+	if format, _ := schema["format"].(string); format == "date-time" {
+		if _, err := time.Parse(time.RFC3339Nano, s); err != nil {
+			v.fail(path, "value %s is not an RFC3339 date-time", s)
+		}
+	}
+}
+
+func (v *validator) validateNumber(path string, f float64, schema map[string]interface{}) {
+	if min, found := schema["minimum"].(float64); found {
+		if exclusive, _ := schema["exclusiveMinimum"].(bool); exclusive && f <= min {
+			v.fail(path, "value %v must be greater than %v", f, min)
+		} else if f < min {
+			v.fail(path, "value %v is less than minimum %v", f, min)
+		}
+	}
+	if max, found := schema["maximum"].(float64); found {
+		if exclusive, _ := schema["exclusiveMaximum"].(bool); exclusive && f >= max {
+			v.fail(path, "value %v must be less than %v", f, max)
+		} else if f > max {
+			v.fail(path, "value %v is greater than maximum %v", f, max)
+		}
+	}
+	if m, found := schema["multipleOf"].(float64); found && m > 0 {
+		if q := f / m; q != math.Trunc(q) {
+			v.fail(path, "value %v is not a multiple of %v", f, m)
+		}
+	}
+}
+
+// setValidationMode overrides the validation mode of a class without redeploying
+var setValidationMode ChaincodeFunc = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	type ModeArg struct {
+		ClassName string `json:"classname"`
+		Mode      string `json:"mode"`
+	}
+	var arg ModeArg
+	if len(args) != 1 {
+		err := errors.New("setValidationMode expects a JSON object with classname and mode")
+		log.Error(err)
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(args[0]), &arg); err != nil {
+		err = fmt.Errorf("setValidationMode failed to unmarshal arg: %s", err)
+		log.Error(err)
+		return nil, err
+	}
+	if !isValidationMode(arg.Mode) {
+		err := fmt.Errorf("setValidationMode: unknown mode %s, expecting strict, lenient or off", arg.Mode)
+		log.Error(err)
+		return nil, err
+	}
+	found := false
+	for c := range validationrouter {
+		if c.Name == arg.ClassName {
+			found = true
+			break
+		}
+	}
+	if !found {
+		err := fmt.Errorf("setValidationMode: class %s has no registered schema", arg.ClassName)
+		log.Error(err)
+		return nil, err
+	}
+	if err := stub.PutState(VALIDATIONMODEKEY+arg.ClassName, []byte(arg.Mode)); err != nil {
+		err = fmt.Errorf("setValidationMode failed to PUT setting: %s", err)
+		log.Error(err)
+		return nil, err
+	}
+	return nil, nil
+}
+
+func init() {
+	AddRoute("setValidationMode", "invoke", SystemClass, setValidationMode)
+}
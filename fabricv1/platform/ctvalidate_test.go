@@ -0,0 +1,114 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+package iotcontractplatform
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+var vschema = `{
+    "type": "object",
+    "properties": {
+        "kit": {
+            "type": "object",
+            "properties": {
+                "skitID": {"type": "string", "pattern": "^SK[0-9]+$"},
+                "maxgforce": {"type": "number", "minimum": 0},
+                "status": {"type": "string", "enum": ["inventory", "transit", "hospital"]},
+                "location": {
+                    "type": "object",
+                    "properties": {
+                        "latitude": {"type": "number"},
+                        "longitude": {"type": "number"}
+                    },
+                    "required": ["latitude", "longitude"]
+                },
+                "timestamp": {"type": "string", "format": "date-time"},
+                "count": {"type": "integer"}
+            },
+            "required": ["skitID"],
+            "additionalProperties": false
+        }
+    }
+}`
+
+func runValidator(t *testing.T, event string, partial bool) []SchemaViolation {
+	var schema map[string]interface{}
+	var e map[string]interface{}
+	if err := json.Unmarshal([]byte(vschema), &schema); err != nil {
+		t.Fatalf("*** schema did not unmarshal: %s", err)
+	}
+	if err := json.Unmarshal([]byte(event), &e); err != nil {
+		t.Fatalf("*** event did not unmarshal: %s", err)
+	}
+	v := validator{root: schema, partial: partial}
+	v.validate("", e, schema)
+	return v.violations
+}
+
+func TestValidateValidEvent(t *testing.T) {
+	violations := runValidator(t, `{"kit":{"skitID":"SK1","maxgforce":1.5,"status":"transit","location":{"latitude":1,"longitude":2},"timestamp":"2016-11-02T10:00:00Z","count":3}}`, false)
+	if len(violations) != 0 {
+		t.Fail()
+		fmt.Printf("*** valid event has violations: %+v\n", violations)
+	}
+}
+
+func TestValidateListsEveryPath(t *testing.T) {
+	violations := runValidator(t, `{"kit":{"skitID":"X1","maxgforce":-1,"status":"lost","location":{"latitude":"north"},"timestamp":"yesterday","count":1.5,"extra":true}}`, false)
+	var want = []string{
+		"kit.count",
+		"kit.extra",
+		"kit.location.latitude",
+		"kit.location.longitude",
+		"kit.maxgforce",
+		"kit.skitID",
+		"kit.status",
+		"kit.timestamp",
+	}
+	var found = make(map[string]bool)
+	for _, v := range violations {
+		found[v.Path] = true
+	}
+	for _, p := range want {
+		if !found[p] {
+			t.Fail()
+			fmt.Printf("*** expected a violation at %s, found %+v\n", p, violations)
+		}
+	}
+	err := &ValidationError{"default", "createAsset", violations}
+	var out ValidationError
+	if json.Unmarshal([]byte(err.Error()), &out) != nil || len(out.Violations) != len(violations) {
+		t.Fail()
+		fmt.Printf("*** validation error is not structured: %s\n", err.Error())
+	}
+}
+
+func TestValidatePartialEvent(t *testing.T) {
+	violations := runValidator(t, `{"kit":{"location":{"latitude":1}}}`, true)
+	if len(violations) != 0 {
+		t.Fail()
+		fmt.Printf("*** partial event should not require properties: %+v\n", violations)
+	}
+	violations = runValidator(t, `{"kit":{"location":{"latitude":1}}}`, false)
+	if len(violations) != 2 {
+		t.Fail()
+		fmt.Printf("*** full event should require skitID and longitude: %+v\n", violations)
+	}
+}
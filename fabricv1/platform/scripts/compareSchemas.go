@@ -0,0 +1,85 @@
+//go:build ignore
+// +build ignore
+
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+// v0.1 KL -- compares the schemas of two versions of a contract, exits with status 1 when
+//            the new version breaks clients of the old one
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ibm-watson-iot/blockchain-samples/fabricv1/platform/ctschema"
+)
+
+var jsonOut = flag.Bool("json", false, "print the changes as a json array")
+var all = flag.Bool("all", true, "print non-breaking changes as well as breaking ones")
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: go run compareSchemas.go [-json] [-all=false] -- old new\n")
+	fmt.Fprintf(os.Stderr, "old and new are readAssetSchemas responses, schema.with.no.refs.json or generated schemas.go files\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() != 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	var schemas [2][]byte
+	for i, filename := range flag.Args() {
+		s, err := ctschema.ReadSchemas(filename)
+		if err != nil {
+			fmt.Printf("** ERR ** %s\n", err)
+			os.Exit(2)
+		}
+		schemas[i] = s
+	}
+	changes, err := ctschema.Compare(schemas[0], schemas[1])
+	if err != nil {
+		fmt.Printf("** ERR ** %s\n", err)
+		os.Exit(2)
+	}
+	breaking := ctschema.Breaking(changes)
+
+	var shown = make([]ctschema.Change, 0, len(changes))
+	for _, c := range changes {
+		if c.Breaking || *all {
+			shown = append(shown, c)
+		}
+	}
+	if *jsonOut {
+		out, _ := json.MarshalIndent(shown, "", "    ")
+		fmt.Println(string(out))
+	} else {
+		for _, c := range shown {
+			fmt.Println(c)
+		}
+		fmt.Printf("%d breaking, %d non-breaking changes\n", breaking, len(changes)-breaking)
+	}
+	if breaking > 0 {
+		os.Exit(1)
+	}
+}
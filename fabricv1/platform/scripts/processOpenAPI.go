@@ -0,0 +1,90 @@
+//go:build ignore
+// +build ignore
+
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+// v0.1 KL -- writes the contract's API as an OpenAPI document, run after processSchema.go
+//            as it reads the schema that processSchema.go generated
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/ibm-watson-iot/blockchain-samples/fabricv1/platform/ctgateway"
+	"github.com/ibm-watson-iot/blockchain-samples/fabricv1/platform/ctschema"
+)
+
+// Config defines the parts of "generate.json" that this script uses
+type Config struct {
+	Schemas struct {
+		GoSchemaFilename string `json:"goSchemaFilename"`
+	} `json:"schemas"`
+	OpenAPI struct {
+		Filename string `json:"filename"`
+		Title    string `json:"title"`
+		Version  string `json:"version"`
+	} `json:"openapi"`
+}
+
+var configFile = flag.String("configFile", "generate.json", "json file that names the schema and the OpenAPI document")
+
+func main() {
+	flag.Parse()
+
+	var config Config
+	configBytes, err := ioutil.ReadFile(*configFile)
+	if err != nil {
+		fmt.Printf("** ERR ** reading %s: %s\n", *configFile, err)
+		os.Exit(1)
+	}
+	if err = json.Unmarshal(configBytes, &config); err != nil {
+		fmt.Printf("** ERR ** unmarshaling %s: %s\n", *configFile, err)
+		os.Exit(1)
+	}
+	if config.OpenAPI.Filename == "" {
+		fmt.Printf("** ERR ** %s has no openapi filename\n", *configFile)
+		os.Exit(1)
+	}
+	if config.OpenAPI.Version == "" {
+		config.OpenAPI.Version = "1.0"
+	}
+	schemas, err := ctschema.ReadSchemas(config.Schemas.GoSchemaFilename)
+	if err != nil {
+		fmt.Printf("** ERR ** %s, run processSchema.go first\n", err)
+		os.Exit(1)
+	}
+	doc, err := ctgateway.OpenAPI(schemas, config.OpenAPI.Title, config.OpenAPI.Version)
+	if err != nil {
+		fmt.Printf("** ERR ** %s\n", err)
+		os.Exit(1)
+	}
+	doc = append(doc, '\n')
+	// rewritten only when the API changes
+	if old, err := ioutil.ReadFile(config.OpenAPI.Filename); err == nil && bytes.Equal(old, doc) {
+		return
+	}
+	if err = ioutil.WriteFile(config.OpenAPI.Filename, doc, 0644); err != nil {
+		fmt.Printf("** ERR ** writing %s: %s\n", config.OpenAPI.Filename, err)
+		os.Exit(1)
+	}
+}
@@ -21,15 +21,21 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
+	"go/format"
+	"go/token"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
+	"unicode"
 )
 
 // Config defines contents of "generate.json" colocated in scripts folder with this script
@@ -39,12 +45,23 @@ type Config struct {
 		GoSchemaFilename string   `json:"goSchemaFilename"`
 		API              []string `json:"API"`
 		Model            []string `json:"Model"`
+		Paged            []string `json:"Paged"`
+		IncludePath      []string `json:"includePath"`
+		IncludeCache     string   `json:"includeCache"`
 	} `json:"schemas"`
 	Samples struct {
 		GoSampleFilename string   `json:"goSampleFilename"`
 		API              []string `json:"API"`
 		Model            []string `json:"Model"`
 	} `json:"samples"`
+	Classes struct {
+		GoClassFilename string        `json:"goClassFilename"`
+		AssetClasses    []ClassConfig `json:"assetClasses"`
+	} `json:"classes"`
+	Types struct {
+		GoTypeFilename string   `json:"goTypeFilename"`
+		Model          []string `json:"Model"`
+	} `json:"types"`
 }
 
 var configFile = flag.String("configFile", "generate.json", "json file that selects API to be exposed")
@@ -112,7 +129,7 @@ func printSyntaxErrorIncludes(js string, err interface{}) string {
 // can print very accurate syntax errors as found by the JSON marshaler
 // relies on the offset table created when reading the schema JSON file and expunging
 // comments and blank lines
-func printSyntaxErrorOffsets(js string, off *[5000]int, err interface{}) string {
+func printSyntaxErrorOffsets(js string, off []int, err interface{}) string {
 	syntax, ok := err.(*json.SyntaxError)
 	if !ok {
 		fmt.Println("*********** ERR trying to get syntax error location **************\n", err)
@@ -126,7 +143,11 @@ func printSyntaxErrorOffsets(js string, off *[5000]int, err interface{}) string
 
 	line, pos := strings.Count(js[:start], "\n"), int(syntax.Offset)-start-1
 
-	e := fmt.Sprintf("Error in line %d: %s \n", off[line]+1, err)
+	inLine := line + 1
+	if line < len(off) {
+		inLine = off[line] + 1
+	}
+	e := fmt.Sprintf("Error in line %d: %s \n", inLine, err)
 	e += fmt.Sprintf("%s\n%s^\n\n", js[start:end], strings.Repeat(" ", pos))
 	fmt.Println(e)
 	return e
@@ -398,6 +419,404 @@ func generateGoSampleFile(schema map[string]interface{}, config Config, imports
 	ioutil.WriteFile(filename, []byte(outString), 0644)
 }
 
+// ClassConfig defines one asset class in the "classes" section of "generate.json", routes
+// are named as in the default class, e.g. "createAsset", and all CRUD and history routes
+// are generated when none are listed. The Go names of the class and its routes start with
+// the name in the form of an exported Go name, so "container" declares ContainerClass.
+type ClassConfig struct {
+	Name        string   `json:"name"`
+	Prefix      string   `json:"prefix"`
+	AssetIDPath string   `json:"assetIDpath"`
+	Routes      []string `json:"routes"`
+}
+
+// classRoute maps a route name to the AssetClass method that it wraps
+type classRoute struct {
+	name   string
+	method string
+	ftype  string
+	caller bool
+}
+
+var classRoutes = []classRoute{
+	{"createAsset", "CreateAsset", "invoke", true},
+	{"replaceAsset", "ReplaceAsset", "invoke", true},
+	{"updateAsset", "UpdateAsset", "invoke", true},
+	{"deleteAsset", "DeleteAsset", "invoke", false},
+	{"deleteAssetStateHistory", "DeleteAssetStateHistory", "invoke", false},
+	{"deleteAllAssets", "DeleteAllAssets", "invoke", false},
+	{"deletePropertiesFromAsset", "DeletePropertiesFromAsset", "invoke", true},
+	{"readAsset", "ReadAsset", "query", false},
+	{"readAssetStateHistory", "ReadAssetStateHistory", "query", false},
+	{"readAllAssets", "ReadAllAssets", "query", false},
+	{"readAssetAsOf", "ReadAssetAsOf", "query", false},
+	{"readAllAssetsAsOf", "ReadAllAssetsAsOf", "query", false},
+	{"readAssetAggregates", "ReadAssetAggregates", "query", false},
+}
+
+// the first ten are the CRUD and history routes that every contract hand-wrote
+const defaultClassRoutes = 10
+
+func findClassRoute(name string) (classRoute, bool) {
+	for _, r := range classRoutes {
+		if r.name == name {
+			return r, true
+		}
+	}
+	return classRoute{}, false
+}
+
+// selects the routes for a class in the order that they are listed in generate.json
+func selectClassRoutes(c ClassConfig) []classRoute {
+	if len(c.Routes) == 0 {
+		return classRoutes[:defaultClassRoutes]
+	}
+	var routes []classRoute
+	var seen = make(map[string]bool)
+	for _, name := range c.Routes {
+		r, found := findClassRoute(name)
+		if !found {
+			fmt.Printf("** ERR ** class %s lists unknown route %s\n", c.Name, name)
+			os.Exit(1)
+		}
+		if !seen[name] {
+			seen[name] = true
+			routes = append(routes, r)
+		}
+	}
+	return routes
+}
+
+func validateClassConfig(c ClassConfig, prefixes map[string]string) {
+	if !token.IsIdentifier(c.Name) {
+		fmt.Printf("** ERR ** class name [%s] must be a valid Go identifier\n", c.Name)
+		os.Exit(1)
+	}
+	if c.Prefix == "" || c.AssetIDPath == "" {
+		fmt.Printf("** ERR ** class %s needs both prefix and assetIDpath\n", c.Name)
+		os.Exit(1)
+	}
+	if other, found := prefixes[c.Prefix]; found {
+		fmt.Printf("** ERR ** class %s has the same prefix %s as class %s\n", c.Name, c.Prefix, other)
+		os.Exit(1)
+	}
+	prefixes[c.Prefix] = c.Name
+}
+
+// Generates a file containing the AssetClass definition, the CRUD and history route wrappers
+// and their registration for each class in generate.json so that the contract need only
+// contain its rules. The output depends only on generate.json, so it is rewritten only
+// when the classes change.
+func generateGoClassFile(config Config, imports string) {
+	var filename = config.Classes.GoClassFilename
+	if filename == "" || len(config.Classes.AssetClasses) == 0 {
+		return
+	}
+	var b bytes.Buffer
+	b.WriteString("// Code generated by processSchema.go from " + filepath.Base(*configFile) + ". DO NOT EDIT.\n")
+	b.WriteString("// Change the classes section of " + filepath.Base(*configFile) + " and run go generate instead.\n\n")
+	b.WriteString("package main\n\n" + imports + "\n\n")
+
+	var prefixes = make(map[string]string)
+	for _, c := range config.Classes.AssetClasses {
+		validateClassConfig(c, prefixes)
+		classVar := goName(c.Name) + "Class"
+		routes := selectClassRoutes(c)
+
+		fmt.Fprintf(&b, "// %s is the asset class for %s, its asset keys start with %s\n", classVar, c.Name, c.Prefix)
+		fmt.Fprintf(&b, "var %s = iot.AssetClass{\n\tName: %q,\n\tPrefix: %q,\n\tAssetIDPath: %q,\n}\n\n", classVar, c.Name, c.Prefix, c.AssetIDPath)
+		for _, r := range routes {
+			fname := r.name + goName(c.Name)
+			fmt.Fprintf(&b, "var %s iot.ChaincodeFunc = func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {\n", fname)
+			if r.caller {
+				fmt.Fprintf(&b, "\treturn %s.%s(stub, args, %q, []iot.QPropNV{})\n}\n\n", classVar, r.method, fname)
+			} else {
+				fmt.Fprintf(&b, "\treturn %s.%s(stub, args)\n}\n\n", classVar, r.method)
+			}
+		}
+		b.WriteString("func init() {\n")
+		for _, r := range routes {
+			fname := r.name + goName(c.Name)
+			fmt.Fprintf(&b, "\tiot.AddRoute(%q, %q, %s, %s)\n", fname, r.ftype, classVar, fname)
+		}
+		b.WriteString("}\n\n")
+	}
+
+	writeGeneratedFile(filename, b.Bytes())
+}
+
+// generates Go types for the Model entries listed in generate.json, each entry is defined
+// by one type and its nested objects by types named after the entry and property
+type typeGenerator struct {
+	model map[string]interface{} // Model definitions with their references intact
+	names map[string]string      // Go types of the Model entries generated so far
+	used  map[string]bool
+	decls []string
+}
+
+// goName turns a schema name like "assetID" or "alerts-active" into an exported Go name
+func goName(name string) string {
+	var out string
+	parts := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, p := range parts {
+		out += strings.ToUpper(p[:1]) + p[1:]
+	}
+	if out == "" || unicode.IsDigit(rune(out[0])) {
+		out = "X" + out
+	}
+	return out
+}
+
+func (g *typeGenerator) uniqueName(name string) string {
+	unique := name
+	for i := 2; g.used[unique]; i++ {
+		unique = fmt.Sprintf("%s%d", name, i)
+	}
+	g.used[unique] = true
+	return unique
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func isStruct(def map[string]interface{}) bool {
+	props, found := def["properties"].(map[string]interface{})
+	return found && len(props) > 0
+}
+
+func modelComment(name string, entry string, def map[string]interface{}) string {
+	comment := "// " + name + " is generated from Model/" + entry
+	if desc, found := def["description"].(string); found && desc != "" {
+		comment += ", " + oneLine(desc)
+	}
+	return comment
+}
+
+// returns the Go type for a schema object, defining struct types for nested objects
+func (g *typeGenerator) typeExpr(def map[string]interface{}, hint string) string {
+	if ref, found := def["$ref"].(string); found {
+		// referenced entries are generated once, named after the entry
+		target := strings.TrimPrefix(ref, "#/definitions/Model/")
+		if name, found := g.names[target]; found {
+			return name
+		}
+		refdef, found := g.model[target].(map[string]interface{})
+		if !found {
+			fmt.Printf("** WARN ** type generation cannot find %s, using interface{}\n", ref)
+			return "interface{}"
+		}
+		if isStruct(refdef) {
+			g.names[target] = g.uniqueName(goName(target))
+			g.defineStruct(g.names[target], modelComment(g.names[target], target, refdef), refdef)
+		} else {
+			g.names[target] = g.typeExpr(refdef, goName(target))
+		}
+		return g.names[target]
+	}
+	t, _ := def["type"].(string)
+	switch t {
+	case "string":
+		return "string"
+	case "number":
+		return "float64"
+	case "integer":
+		return "int64"
+	case "boolean":
+		return "bool"
+	case "array":
+		items, found := def["items"].(map[string]interface{})
+		if !found {
+			return "[]interface{}"
+		}
+		return "[]" + g.typeExpr(items, hint+"Item")
+	case "object":
+		if isStruct(def) {
+			name := g.uniqueName(hint)
+			g.defineStruct(name, "", def)
+			return name
+		}
+		if pprops, found := def["patternProperties"].(map[string]interface{}); found && len(pprops) == 1 {
+			for _, v := range pprops {
+				if vdef, found := v.(map[string]interface{}); found {
+					return "map[string]" + g.typeExpr(vdef, hint+"Value")
+				}
+			}
+		}
+		return "map[string]interface{}"
+	}
+	// oneOf and anything untyped
+	return "interface{}"
+}
+
+// reports whether the Go type of a schema object is nil when it is not set, as slices,
+// maps and interfaces are
+func (g *typeGenerator) isNilable(def map[string]interface{}) bool {
+	if ref, found := def["$ref"].(string); found {
+		refdef, found := g.model[strings.TrimPrefix(ref, "#/definitions/Model/")].(map[string]interface{})
+		return !found || g.isNilable(refdef)
+	}
+	t, _ := def["type"].(string)
+	switch t {
+	case "string", "number", "integer", "boolean":
+		return false
+	case "object":
+		return !isStruct(def)
+	}
+	return true
+}
+
+// defines a struct type, optional scalars and structs are pointers so that a merge leaves
+// out the properties that are not set, optional slices and maps are left nil
+func (g *typeGenerator) defineStruct(name string, comment string, def map[string]interface{}) {
+	// reserve the slot so that nested types follow their parent
+	slot := len(g.decls)
+	g.decls = append(g.decls, "")
+
+	var required = make(map[string]bool)
+	if req, found := def["required"].([]interface{}); found {
+		for _, r := range req {
+			if s, found := r.(string); found {
+				required[s] = true
+			}
+		}
+	}
+	props, _ := def["properties"].(map[string]interface{})
+	var keys []string
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b bytes.Buffer
+	if comment == "" {
+		comment = "// " + name + " is a nested object"
+	}
+	b.WriteString(comment + "\ntype " + name + " struct {\n")
+	var fields = make(map[string]bool)
+	for _, k := range keys {
+		pdef, found := props[k].(map[string]interface{})
+		if !found {
+			// a malformed property, keep its value as the description
+			pdef = map[string]interface{}{"description": fmt.Sprint(props[k])}
+		}
+		field := goName(k)
+		for i := 2; fields[field]; i++ {
+			field = fmt.Sprintf("%s%d", goName(k), i)
+		}
+		fields[field] = true
+		typ := g.typeExpr(pdef, name+goName(k))
+		tag := k
+		if !required[k] {
+			tag += ",omitempty"
+			if !g.isNilable(pdef) {
+				typ = "*" + typ
+			}
+		}
+		fmt.Fprintf(&b, "\t%s %s `json:%q`", field, typ, tag)
+		if desc, found := pdef["description"].(string); found && desc != "" {
+			b.WriteString(" // " + oneLine(desc))
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("}\n\n")
+	g.decls[slot] = b.String()
+}
+
+// Generates a file containing Go types for the Model entries listed in generate.json, with
+// helpers that read them from an asset's state and merge them back into it, so that rules
+// can use typed fields instead of qualified property names.
+func generateGoTypeFile(model map[string]interface{}, config Config) {
+	var filename = config.Types.GoTypeFilename
+	if filename == "" || len(config.Types.Model) == 0 {
+		return
+	}
+	g := typeGenerator{
+		model: model,
+		names: make(map[string]string),
+		used:  make(map[string]bool),
+	}
+	for _, m := range config.Types.Model {
+		if _, found := model[m].(map[string]interface{}); !found {
+			fmt.Printf("** ERR ** Model/%s not found for type generation\n", m)
+			os.Exit(1)
+		}
+		g.names[m] = g.uniqueName(goName(m))
+	}
+
+	var helpers bool
+	for _, m := range config.Types.Model {
+		name := g.names[m]
+		def := model[m].(map[string]interface{})
+		comment := modelComment(name, m, def)
+		if isStruct(def) {
+			g.defineStruct(name, comment, def)
+			helpers = true
+			g.decls = append(g.decls, fmt.Sprintf(`// %sFromState reads the %s at qname in an asset's state, or the whole state when qname is empty
+func %sFromState(state *map[string]interface{}, qname string) (*%s, bool, error) {
+	var x %s
+	found, err := iot.GetObjectAsStruct(state, qname, &x)
+	if !found || err != nil {
+		return nil, found, err
+	}
+	return &x, true, nil
+}
+
+// MergeIntoState deep merges the properties of the %s that are set into an asset's state at qname
+func (x *%s) MergeIntoState(state *map[string]interface{}, qname string) error {
+	return iot.MergeStruct(state, qname, x)
+}
+
+`, name, name, name, name, name, name, name))
+			continue
+		}
+		slot := len(g.decls)
+		g.decls = append(g.decls, "")
+		g.decls[slot] = comment + "\ntype " + name + " " + g.typeExpr(def, name) + "\n\n"
+	}
+
+	var b bytes.Buffer
+	b.WriteString("// Code generated by processSchema.go from " + filepath.Base(config.Schemas.SchemaFilename) + ". DO NOT EDIT.\n")
+	b.WriteString("// Change the Model in the schema or the types section of " + filepath.Base(*configFile) + " and run go generate instead.\n\n")
+	b.WriteString("package main\n\n")
+	if helpers {
+		b.WriteString("import iot \"github.com/ibm-watson-iot/blockchain-samples/fabricv1/platform\"\n\n")
+	}
+	b.WriteString(strings.Join(g.decls, ""))
+	writeGeneratedFile(filename, b.Bytes())
+}
+
+// formats generated source and writes it only when it has changed, so that running go
+// generate again leaves the file alone
+func writeGeneratedFile(filename string, src []byte) {
+	out, err := format.Source(src)
+	if err != nil {
+		fmt.Printf("** ERR ** generated file %s does not parse: %s\n", filename, err)
+		os.Exit(1)
+	}
+	if old, err := ioutil.ReadFile(filename); err == nil && bytes.Equal(old, out) {
+		if *verbose {
+			fmt.Println("Generated file " + filename + " is up to date")
+		}
+		return
+	}
+	if *verbose {
+		fmt.Println("Writing generated file to: " + filename)
+	}
+	ioutil.WriteFile(filename, out, 0644)
+}
+
+// copies the Model before its references are replaced in place
+func copyModel(schema map[string]interface{}) map[string]interface{} {
+	var model map[string]interface{}
+	defs, _ := schema["definitions"].(map[string]interface{})
+	modelBytes, _ := json.Marshal(defs["Model"])
+	_ = json.Unmarshal(modelBytes, &model)
+	return model
+}
+
 func loadModelTables(schema map[string]interface{}) {
 	model, modelfound := schema["definitions"].(map[string]interface{})["Model"].(map[string]interface{})
 	if !modelfound {
@@ -440,57 +859,339 @@ func buildResolvedSchema(schema map[string]interface{}) map[string]interface{} {
 	return newSchema
 }
 
-func getIncludedFile(path string, localpath string) string {
-	var retstr = make([]byte, 0)
-	var err error
-	parts := strings.Split(path, "/#")
-	includeschema := parts[0]
-	level := parts[1]
-	paths := strings.Split(os.Getenv("GOPATH"), ":")
-	for _, s := range paths {
-		retstr, err = ioutil.ReadFile(s + "/src/" + includeschema)
-		if err != nil {
-			if os.IsNotExist(err) {
-				// fmt.Println("****** include file not found on path: " + s + "/src/" + includeschema)
-				continue
+// pagedPrefixes identify the query functions that the platform paginates, contract
+// functions such as readAllAssetsSurgicalKit wrap the platform functions
+var pagedPrefixes = []string{"readAllAssets", "readAssetStateHistory"}
+
+func isPagedFunction(name string) bool {
+	for _, p := range pagedPrefixes {
+		if strings.HasPrefix(name, p) {
+			return true
+		}
+	}
+	for _, p := range config.Schemas.Paged {
+		if name == p {
+			return true
+		}
+	}
+	return false
+}
+
+// adds the pagesize and bookmark arguments to paginated query functions and describes
+// the envelope that is returned when they are used, the plain result is still returned
+// to clients that do not pass them
+func addPagination(api map[string]interface{}) {
+	pagesize, found := lookup["#/definitions/Model/pagesize"]
+	if !found {
+		fmt.Println("Warning: no pagesize in Model, paginated functions not updated")
+		return
+	}
+	bookmark := lookup["#/definitions/Model/bookmark"]
+	for name, fobj := range api {
+		if !isPagedFunction(name) {
+			continue
+		}
+		f, _ := fobj.(map[string]interface{})
+		props, found := f["properties"].(map[string]interface{})
+		if !found {
+			// function is not an object with properties
+			fmt.Printf("** WARN ** paginated function %s has no properties\n", name)
+			continue
+		}
+		if items, found := getObject(fobj, name, "#/args/items").(map[string]interface{}); found {
+			argprops, found := items["properties"].(map[string]interface{})
+			if !found {
+				argprops = make(map[string]interface{}, 0)
+				items["properties"] = argprops
+			}
+			if _, found := argprops["pagesize"]; !found {
+				argprops["pagesize"] = DeepMergeMap(pagesize.(map[string]interface{}), make(map[string]interface{}, 0))
+			}
+			if _, found := argprops["bookmark"]; !found {
+				argprops["bookmark"] = DeepMergeMap(bookmark.(map[string]interface{}), make(map[string]interface{}, 0))
+			}
+		}
+		result, found := props["result"].(map[string]interface{})
+		if !found {
+			continue
+		}
+		props["pagedresult"] = map[string]interface{}{
+			"type":        "object",
+			"description": "returned instead of result when pagesize or bookmark is passed",
+			"properties": map[string]interface{}{
+				"results":  DeepMergeMap(result, make(map[string]interface{}, 0)),
+				"bookmark": DeepMergeMap(bookmark.(map[string]interface{}), make(map[string]interface{}, 0)),
+				"count": map[string]interface{}{
+					"type":        "integer",
+					"description": "number of results in this page",
+				},
+			},
+		}
+	}
+}
+
+// includeLink is one step of an include chain, the line of a schema file that includes ref
+type includeLink struct {
+	file string
+	line int
+	ref  string
+}
+
+// includeError reports a failed include with the chain of includes that led to it
+type includeError struct {
+	chain []includeLink
+	msg   string
+	tried []string
+}
+
+func (e *includeError) Error() string {
+	var s = "include failed: " + e.msg + "\n    include chain:\n"
+	for _, l := range e.chain {
+		s += fmt.Sprintf("        %s:%d includes %s\n", l.file, l.line, l.ref)
+	}
+	if len(e.tried) > 0 {
+		s += "    tried:\n"
+		for _, t := range e.tried {
+			s += "        " + t + "\n"
+		}
+		s += "    -- add the folder holding the schema to includePath in " + *configFile + ", or\n"
+		s += "       set includeCache and run once where the schema can be found to vendor it\n"
+	}
+	return s
+}
+
+// schemaIncluder resolves the file includes in schemas, which are "$ref" lines that name
+// a schema file and a level in it, e.g.
+//
+//	"$ref": "github.com/ibm-watson-iot/blockchain-samples/fabricv1/platform/schema/IOTCPschema.json/#definitions"
+//
+// Included files are found, in order, relative to the including file, under each folder
+// in the search path, in the cache, on the GOPATH and, for http URLs, by fetching them.
+// Schemas that are found outside the cache are copied into it under their id URL.
+type schemaIncluder struct {
+	searchPath []string
+	cacheDir   string
+}
+
+func isURL(ref string) bool {
+	return strings.HasPrefix(ref, "https://") || strings.HasPrefix(ref, "http://")
+}
+
+// the path that a schema is kept under, its id URL without the scheme
+func includeKey(ref string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(ref, "https://"), "http://")
+}
+
+// preprocess replaces the include lines of a schema with the contents of the included
+// level, recursively, and returns the schema with the input line of each output line
+func (si *schemaIncluder) preprocess(filename string, content []byte, chain []includeLink) (string, []int, error) {
+	var out string
+	var line = 1
+	var lineOut = 1
+	var offsets = make([]int, 2)
+	var top = len(chain) == 0
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	scanner.Split(bufio.ScanLines)
+	for scanner.Scan() {
+		l := scanner.Text()
+		ts := strings.TrimSpace(l)
+		if strings.HasPrefix(ts, "#") {
+			if top {
+				fmt.Println("Line: ", line, " is a comment")
+			}
+		} else if ts == "" {
+			if top {
+				fmt.Println("Line: ", line, " is blank")
+			}
+		} else if strings.HasPrefix(ts, "\"$ref\"") && strings.Index(ts, "\"#/") == -1 {
+			ss := strings.Split(ts, "\"")
+			p := ss[len(ss)-2]
+			refArr, err := si.include(p, append(chain, includeLink{filename, line, p}))
+			if err != nil {
+				return "", nil, err
+			}
+			lines := strings.Split(refArr, "\n")
+			// remove open and close brace as we are replacing the reference in place with the contents of the names object
+			lines = lines[1 : len(lines)-1]
+			for _, l2 := range lines {
+				out += l2 + "\n"
+				lineOut++
+			}
+			if len(ss) > 0 && ss[len(ss)-1] == "," {
+				out += ","
 			}
-			panic(errors.New("unknown error reading include file " + s + includeschema + err.Error()))
 		} else {
-			// fmt.Println("****** include file found on path: " + s + "/src/" + includeschema)
-			break
+			out += l + "\n"
+			lineOut++
 		}
+		for len(offsets) <= lineOut {
+			offsets = append(offsets, 0)
+		}
+		offsets[lineOut] = line
+		line++
 	}
-	if retstr == nil || len(retstr) == 0 {
-		fmt.Println("\nIncluded schema file not found on GOPATH: " + os.Getenv("GOPATH") + ": " + includeschema)
-		fmt.Println("    -- please ensure that you have cloned or fetched the platform to your GOPATH")
-		fmt.Println("    -- also, please ensure that you add /local-dev to your GOPATH using the command")
-		fmt.Printf("       'export GOPATH=/opt/gopath:/local-dev' and then run 'go generate' again\n\n")
-		os.Exit(1)
+	if err := scanner.Err(); err != nil {
+		return "", nil, &includeError{chain, fmt.Sprintf("reading %s: %s", filename, err), nil}
+	}
+	return out, offsets, nil
+}
+
+// include returns the level of an included schema, with its own includes resolved
+func (si *schemaIncluder) include(ref string, chain []includeLink) (string, error) {
+	parts := strings.SplitN(ref, "/#", 2)
+	if len(parts) != 2 {
+		return "", &includeError{chain, "reference " + ref + " does not name a level after /#", nil}
+	}
+	includeschema, level := parts[0], parts[1]
+	from := chain[len(chain)-1].file
+	filename, content, tried, err := si.find(includeschema, from)
+	if err != nil {
+		return "", &includeError{chain, err.Error(), tried}
+	}
+	if content == nil {
+		return "", &includeError{chain, includeschema + " not found", tried}
+	}
+	for _, l := range chain {
+		if sameSchema(l.file, filename) {
+			return "", &includeError{chain, "include cycle, " + filename + " is already being included", nil}
+		}
+	}
+	included, _, err := si.preprocess(filename, content, chain)
+	if err != nil {
+		return "", err
 	}
 	var ischema interface{}
-	err = json.Unmarshal(retstr, &ischema)
+	err = json.Unmarshal([]byte(included), &ischema)
 	if err != nil {
 		fmt.Println("*********** UNMARSHAL ERR **************\n", err)
-		synerr := printSyntaxErrorIncludes(string(retstr), err)
-		incfilename := localpath + "schema.with.failed.include.json"
+		synerr := printSyntaxErrorIncludes(included, err)
+		incfilename := "./schema.with.failed.include.json"
 		fmt.Println("Writing filed preprocessed schema to: " + incfilename)
-		_ = ioutil.WriteFile(incfilename, retstr, 0744)
-		panic(errors.New("unmarshal of schema with includes failed with err: " + synerr))
+		_ = ioutil.WriteFile(incfilename, []byte(included), 0744)
+		return "", &includeError{chain, "unmarshal of " + filename + " failed with err: " + synerr, nil}
 	}
 	m, found := ischema.(map[string]interface{})
 	if !found {
-		panic(errors.New("included schema is not map shaped"))
+		return "", &includeError{chain, "included schema " + filename + " is not map shaped", nil}
 	}
 	o := getObject(m, "#/"+level, "#/"+level)
 	if o == nil {
-		panic(errors.New("Level " + level + " not found in schema " + path))
+		return "", &includeError{chain, "level " + level + " not found in schema " + filename, nil}
 	}
-	retstr, err = json.MarshalIndent(o, "", "   ")
+	retstr, err := json.MarshalIndent(o, "", "   ")
 	if err != nil {
-		panic(errors.New("Level " + level + " in schema " + path + " failed to marshal: " + err.Error()))
+		return "", &includeError{chain, "level " + level + " in schema " + filename + " failed to marshal: " + err.Error(), nil}
 	}
-	// fmt.Println("\n\n RETURNING FROM INCLUDE PROCESSING\n\n" + string(retstr) + "\n\n")
-	return string(retstr)
+	return string(retstr), nil
+}
+
+func sameSchema(a string, b string) bool {
+	if isURL(a) || isURL(b) {
+		return a == b
+	}
+	aa, erra := filepath.Abs(a)
+	ab, errb := filepath.Abs(b)
+	return erra == nil && errb == nil && aa == ab
+}
+
+// find reads an included schema, content is nil when it is not found in any of the
+// places that are listed in tried
+func (si *schemaIncluder) find(includeschema string, from string) (string, []byte, []string, error) {
+	var candidates []string
+	var key = filepath.FromSlash(includeKey(includeschema))
+	if !isURL(includeschema) && !isURL(from) {
+		candidates = append(candidates, filepath.Join(filepath.Dir(from), filepath.FromSlash(includeschema)))
+	}
+	for _, sp := range si.searchPath {
+		candidates = append(candidates, filepath.Join(sp, key))
+	}
+	var cached string
+	if si.cacheDir != "" {
+		cached = filepath.Join(si.cacheDir, key)
+		candidates = append(candidates, cached)
+	}
+	for _, gp := range filepath.SplitList(os.Getenv("GOPATH")) {
+		if gp != "" {
+			candidates = append(candidates, filepath.Join(gp, "src", key))
+		}
+	}
+
+	var tried []string
+	for _, c := range candidates {
+		content, err := ioutil.ReadFile(c)
+		if err != nil {
+			if os.IsNotExist(err) {
+				tried = append(tried, c)
+				continue
+			}
+			return "", nil, tried, fmt.Errorf("unknown error reading include file %s: %s", c, err)
+		}
+		if *verbose {
+			fmt.Println("Including " + includeschema + " from " + c)
+		}
+		if c != cached {
+			si.cache(includeschema, content)
+		}
+		return c, content, tried, nil
+	}
+	if isURL(includeschema) {
+		tried = append(tried, includeschema)
+		content, err := fetchSchema(includeschema)
+		if err != nil {
+			return "", nil, tried, err
+		}
+		si.cache(includeschema, content)
+		return includeschema, content, tried, nil
+	}
+	return "", nil, tried, nil
+}
+
+// cache keeps a copy of an included schema under its id URL, or under the reference
+// when it has no id
+func (si *schemaIncluder) cache(includeschema string, content []byte) {
+	if si.cacheDir == "" {
+		return
+	}
+	var id struct {
+		ID string `json:"id"`
+	}
+	_ = json.Unmarshal(content, &id)
+	key := includeKey(includeschema)
+	if id.ID != "" {
+		if includeKey(id.ID) != key {
+			fmt.Printf("** WARN ** included schema %s has id %s, it is cached by its id\n", includeschema, id.ID)
+		}
+		key = includeKey(id.ID)
+	}
+	filename := filepath.Join(si.cacheDir, filepath.FromSlash(key))
+	if old, err := ioutil.ReadFile(filename); err == nil && bytes.Equal(old, content) {
+		return
+	}
+	if *verbose {
+		fmt.Println("Caching included schema in: " + filename)
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		fmt.Printf("** WARN ** cannot create include cache folder: %s\n", err)
+		return
+	}
+	if err := ioutil.WriteFile(filename, content, 0644); err != nil {
+		fmt.Printf("** WARN ** cannot cache included schema: %s\n", err)
+	}
+}
+
+func fetchSchema(url string) ([]byte, error) {
+	client := http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s: %s", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: %s", url, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
 }
 
 // Reads payloadschema.json api file
@@ -526,11 +1227,6 @@ func main() {
 		iot "github.com/ibm-watson-iot/blockchain-samples/fabricv1/platform"
 )`
 
-	var api string
-	var line = 1
-	var lineOut = 1
-	var offsets [5000]int
-
 	filename, _ := filepath.Abs("./" + *configFile)
 	jsonFile, err := ioutil.ReadFile(filename)
 	if err != nil {
@@ -542,45 +1238,17 @@ func main() {
 	}
 
 	// ************** Stage 1
-	// read the schema and preprocess for file includes at the top level
-	filepre, err := os.Open(config.Schemas.SchemaFilename)
+	// read the schema and preprocess for file includes
+	content, err := ioutil.ReadFile(config.Schemas.SchemaFilename)
 	if err != nil {
 		fmt.Printf("** ERR ** [%s] opening input schema file at %s\n", err, config.Schemas.SchemaFilename)
 		return
 	}
-	defer filepre.Close()
-	reader := bufio.NewReader(filepre)
-	scanner := bufio.NewScanner(reader)
-	scanner.Split(bufio.ScanLines)
-	for scanner.Scan() {
-		l := scanner.Text()
-		// fmt.Println("MAIN SCHEMA: " + l)
-		ts := strings.TrimSpace(l)
-		if strings.HasPrefix(ts, "#") {
-			fmt.Println("Line: ", line, " is a comment")
-		} else if ts == "" {
-			fmt.Println("Line: ", line, " is blank")
-		} else if strings.HasPrefix(ts, "\"$ref\"") && strings.Index(ts, "\"#/") == -1 {
-			ss := strings.Split(ts, "\"")
-			p := ss[len(ss)-2]
-			// fmt.Printf("line: %d includes: %s\n", line, p)
-			refArr := getIncludedFile(p, "./")
-			lines := strings.Split(refArr, "\n")
-			// remove open and close brace as we are replacing the reference in place with the contents of the names object
-			lines = lines[1 : len(lines)-1]
-			for _, l2 := range lines {
-				api += l2 + "\n"
-				lineOut++
-			}
-			if len(ss) > 0 && ss[len(ss)-1] == "," {
-				api += ","
-			}
-		} else {
-			api += l + "\n"
-			lineOut++
-		}
-		offsets[lineOut] = line
-		line++
+	includer := schemaIncluder{config.Schemas.IncludePath, config.Schemas.IncludeCache}
+	api, offsets, err := includer.preprocess(config.Schemas.SchemaFilename, content, nil)
+	if err != nil {
+		fmt.Println("** ERR ** " + err.Error())
+		os.Exit(1)
 	}
 
 	// ************** Stage 2
@@ -589,7 +1257,7 @@ func main() {
 	err = json.Unmarshal([]byte(api), &schema)
 	if err != nil {
 		fmt.Println("*********** UNMARSHAL ERR **************\n", err)
-		printSyntaxErrorOffsets(api, &offsets, err)
+		printSyntaxErrorOffsets(api, offsets, err)
 		return
 	}
 
@@ -599,6 +1267,9 @@ func main() {
 		_ = ioutil.WriteFile(prefilename, PrettyPrintBytes(schema), 0744)
 	}
 
+	// the Model with references intact names the generated types
+	model := copyModel(schema)
+
 	// ************** Stage 3
 	// load the lookup tables with the data model, resolves all Model references
 	loadModelTables(schema)
@@ -607,6 +1278,7 @@ func main() {
 	// build final schema by inserting API, resolving all references using lookup table, and
 	// inserting the data model from the lookup table
 	finalschema = buildResolvedSchema(schema)
+	addPagination(finalschema["API"].(map[string]interface{}))
 
 	if *verbose {
 		finalfilename := strings.Split(filename, "/")[0] + "schema.with.no.refs.json"
@@ -616,9 +1288,11 @@ func main() {
 
 	// ************** Stage 5
 	// generate the Go files that the contract needs -- for now, complete schema and
-	// event schema and sample object
+	// event schema and sample object, plus the asset classes and types when configured
 
 	generateGoSchemaFile(finalschema, config, imports, regReadSchemas)
 	generateGoSampleFile(finalschema, config, imports, regReadSamples)
+	generateGoClassFile(config, imports)
+	generateGoTypeFile(model, config)
 
 }
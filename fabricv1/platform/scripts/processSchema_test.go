@@ -0,0 +1,275 @@
+/*
+Copyright (c) 2016 IBM Corporation and other Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+
+Contributors:
+Kim Letkeman - Initial Contribution
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testImports = `
+	import (
+		"github.com/hyperledger/fabric/core/chaincode/shim"
+		iot "github.com/ibm-watson-iot/blockchain-samples/fabricv1/platform"
+)`
+
+func TestGenerateGoClassFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "classes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var cfg Config
+	cfg.Classes.GoClassFilename = filepath.Join(dir, "classes.go")
+	cfg.Classes.AssetClasses = []ClassConfig{
+		{Name: "container", Prefix: "CON", AssetIDPath: "container.barcode", Routes: []string{"readAsset", "createAsset", "readAsset"}},
+		{Name: "SurgicalKit", Prefix: "SKT", AssetIDPath: "surgicalkit.skitID"},
+	}
+	generateGoClassFile(cfg, testImports)
+	src, err := ioutil.ReadFile(cfg.Classes.GoClassFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = parser.ParseFile(token.NewFileSet(), cfg.Classes.GoClassFilename, src, 0); err != nil {
+		t.Fail()
+		fmt.Printf("*** generated file does not parse: %s\n", err)
+	}
+	out := string(src)
+
+	for _, want := range []string{
+		"// Code generated by processSchema.go from generate.json. DO NOT EDIT.",
+		"var ContainerClass = iot.AssetClass{",
+		`Name:        "container",`,
+		`return ContainerClass.CreateAsset(stub, args, "createAssetContainer", []iot.QPropNV{})`,
+		"return ContainerClass.ReadAsset(stub, args)",
+		`iot.AddRoute("readAssetContainer", "query", ContainerClass, readAssetContainer)`,
+		`iot.AddRoute("readAssetStateHistorySurgicalKit", "query", SurgicalKitClass, readAssetStateHistorySurgicalKit)`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Fail()
+			fmt.Printf("*** generated file is missing %s\n", want)
+		}
+	}
+
+	// listed routes keep their order and are registered once, and all ten are the default
+	if strings.Index(out, "var readAssetContainer") > strings.Index(out, "var createAssetContainer") {
+		t.Fail()
+		fmt.Printf("*** container routes are not in the order that they are listed\n")
+	}
+	if n := strings.Count(out, "iot.AddRoute(") - strings.Count(out, "SurgicalKitClass, "); n != 2 {
+		t.Fail()
+		fmt.Printf("*** container has %d routes, expected 2\n", n)
+	}
+	if n := strings.Count(out, "SurgicalKitClass, "); n != defaultClassRoutes {
+		t.Fail()
+		fmt.Printf("*** SurgicalKit has %d routes, expected %d\n", n, defaultClassRoutes)
+	}
+
+	// an unchanged config leaves the file alone
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err = os.Chtimes(cfg.Classes.GoClassFilename, old, old); err != nil {
+		t.Fatal(err)
+	}
+	generateGoClassFile(cfg, testImports)
+	if fi, err := os.Stat(cfg.Classes.GoClassFilename); err != nil || !fi.ModTime().Equal(old) {
+		t.Fail()
+		fmt.Printf("*** an unchanged config rewrote the generated file\n")
+	}
+}
+
+const testTypeModel = `{
+    "kit": {
+        "type": "object",
+        "description": "A kit",
+        "properties": {
+            "id": {"type": "string"},
+            "count": {"type": "integer"},
+            "weight": {"type": "number"},
+            "sealed": {"type": "boolean"},
+            "tags": {"type": "array", "items": {"type": "string"}},
+            "readings": {"type": "object", "patternProperties": {".*": {"type": "number"}}},
+            "owner": {"$ref": "#/definitions/Model/party"},
+            "handlers": {"type": "array", "items": {"$ref": "#/definitions/Model/party"}},
+            "labels": {"$ref": "#/definitions/Model/tagList"},
+            "location": {"type": "object", "properties": {"latitude": {"type": "number"}}},
+            "extra": {"description": "anything"}
+        },
+        "required": ["id"]
+    },
+    "party": {
+        "type": "object",
+        "properties": {"name": {"type": "string"}},
+        "required": ["name"]
+    },
+    "tagList": {"type": "array", "items": {"type": "string"}}
+}`
+
+// testTypeUse compiles only if optional scalars and structs are pointers and optional
+// slices and maps are not
+const testTypeUse = `package main
+
+func main() {
+	var state = make(map[string]interface{})
+	count := int64(2)
+	kit := Kit{
+		Id:       "K1",
+		Count:    &count,
+		Tags:     []string{"a"},
+		Readings: map[string]float64{"t": 1},
+		Owner:    &Party{Name: "p"},
+		Handlers: []Party{{Name: "q"}},
+		Labels:   TagList{"x"},
+		Location: &KitLocation{},
+		Extra:    1,
+	}
+	if err := kit.MergeIntoState(&state, "kit"); err != nil {
+		panic(err)
+	}
+	if _, _, err := KitFromState(&state, "kit"); err != nil {
+		panic(err)
+	}
+}
+`
+
+func TestGenerateGoTypeFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "types")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var model map[string]interface{}
+	if err = json.Unmarshal([]byte(testTypeModel), &model); err != nil {
+		t.Fatal(err)
+	}
+	var cfg Config
+	cfg.Schemas.SchemaFilename = "kit.json"
+	cfg.Types.GoTypeFilename = filepath.Join(dir, "types.go")
+	cfg.Types.Model = []string{"kit", "tagList"}
+	generateGoTypeFile(model, cfg)
+	src, err := ioutil.ReadFile(cfg.Types.GoTypeFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(src)
+
+	for _, want := range []string{
+		"// Code generated by processSchema.go from kit.json. DO NOT EDIT.",
+		"// Kit is generated from Model/kit, A kit",
+		"type TagList []string",
+		"func KitFromState(state *map[string]interface{}, qname string) (*Kit, bool, error) {",
+		"func (x *Kit) MergeIntoState(state *map[string]interface{}, qname string) error {",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fail()
+			fmt.Printf("*** generated file is missing %s\n", want)
+		}
+	}
+
+	// the generated types must compile with the platform
+	if err = ioutil.WriteFile(filepath.Join(dir, "main.go"), []byte(testTypeUse), 0644); err != nil {
+		t.Fatal(err)
+	}
+	build := exec.Command("go", "build", "-o", os.DevNull, ".")
+	build.Dir = dir
+	if msg, err := build.CombinedOutput(); err != nil {
+		t.Fail()
+		fmt.Printf("*** generated types do not compile: %s\n%s\n%s\n", err, msg, out)
+	}
+}
+
+func preprocessFile(t *testing.T, si *schemaIncluder, filename string) (map[string]interface{}, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, _, err := si.preprocess(filename, content, nil)
+	if err != nil {
+		return nil, err
+	}
+	var schema map[string]interface{}
+	if err = json.Unmarshal([]byte(out), &schema); err != nil {
+		t.Fatalf("preprocessed %s does not unmarshal: %s\n%s", filename, err, out)
+	}
+	return schema, nil
+}
+
+func TestSchemaIncluder(t *testing.T) {
+	cache, err := ioutil.TempDir("", "schemacache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(cache)
+
+	// an include relative to the including file is merged in place and cached by its id
+	si := &schemaIncluder{cacheDir: cache}
+	schema, err := preprocessFile(t, si, "testdata/includes/top.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defs, _ := schema["definitions"].(map[string]interface{})
+	if _, found := defs["shared"]; !found {
+		t.Fail()
+		fmt.Printf("*** top.json did not include shared, definitions are %v\n", defs)
+	}
+	if _, found := defs["local"]; !found {
+		t.Fail()
+		fmt.Printf("*** top.json lost its own definitions, they are %v\n", defs)
+	}
+	cached := filepath.Join(cache, "example.com", "schemas", "shared.json")
+	if _, err = os.Stat(cached); err != nil {
+		t.Fail()
+		fmt.Printf("*** shared.json was not cached under its id: %s\n", err)
+	}
+
+	// the id URL is then found in the cache, without fetching it
+	url := filepath.Join(cache, "url.json")
+	ioutil.WriteFile(url, []byte("{\n    \"definitions\": {\n        \"$ref\": \"https://example.com/schemas/shared.json/#definitions\"\n    }\n}\n"), 0644)
+	si = &schemaIncluder{cacheDir: cache}
+	schema, err = preprocessFile(t, si, url)
+	if err != nil {
+		t.Fail()
+		fmt.Printf("*** the cached schema was not found: %s\n", err)
+	} else if defs, _ = schema["definitions"].(map[string]interface{}); defs["shared"] == nil {
+		t.Fail()
+		fmt.Printf("*** the cached schema was not included, definitions are %v\n", defs)
+	}
+}
+
+func TestSchemaIncludeCycle(t *testing.T) {
+	si := &schemaIncluder{}
+	_, err := preprocessFile(t, si, "testdata/includes/cycle_a.json")
+	if err == nil {
+		t.Fatal("an include cycle was accepted")
+	}
+	for _, want := range []string{"include cycle", "cycle_a.json:3 includes cycle_b.json", "cycle_b.json:3 includes cycle_a.json"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fail()
+			fmt.Printf("*** include cycle error is missing %s:\n%s\n", want, err)
+		}
+	}
+}